@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"log"
 	"math/big"
+	"os"
+	"strings"
 
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/zacksfF/evm-tvl-aggregator/internal/aggregator"
 	"github.com/zacksfF/evm-tvl-aggregator/internal/blockchain"
-	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/registry"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/backend"
 	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/memory"
 )
 
@@ -17,17 +20,6 @@ func main() {
 	// Create blockchain manager
 	manager := blockchain.NewManager()
 
-	// Add Ethereum mainnet
-	err := manager.AddChain(blockchain.ChainConfig{
-		Name:        "ethereum",
-		ChainID:     big.NewInt(1),
-		RPCURL:      "https://eth-mainnet.g.alchemy.com/v2/key",
-		NativeToken: "ETH",
-	})
-	if err != nil {
-		log.Fatal(err)
-	}
-
 	// Create price oracle with mock prices for testing
 	priceOracle := aggregator.NewPriceOracle()
 	priceOracle.SetMockPrice("ETH", 2500)
@@ -36,30 +28,43 @@ func main() {
 	priceOracle.SetMockPrice("DAI", 1)
 	priceOracle.SetMockPrice("WETH", 2500)
 
-	// Create storage
-	storage := memory.NewMemoryStorage()
+	// Create storage, in memory unless DATABASE_URL names a backend
+	var store storage.Storage = memory.NewMemoryStorage()
+	if dsn := os.Getenv("DATABASE_URL"); dsn != "" {
+		opened, err := backend.Open(dsn)
+		if err != nil {
+			log.Fatalf("Failed to open %s: %v", backend.Name(dsn), err)
+		}
+		store = opened
+	}
+	defer store.Close()
 
 	// Create TVL calculator
-	calculator := aggregator.NewTVLCalculator(manager, priceOracle, storage)
+	calculator := aggregator.NewTVLCalculator(manager, priceOracle, store)
 
-	// Register a test protocol (Uniswap V2)
-	uniswapProtocol := &models.Protocol{
-		Name:        "uniswap-v2",
-		Type:        models.ProtocolTypeDEX,
-		Description: "Uniswap V2 DEX",
-		Website:     "https://uniswap.org",
-		Chains: map[string][]models.ContractConfig{
-			"ethereum": {
-				{
-					Address: common.HexToAddress("0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc"), // USDC/WETH pool
-					Name:    "USDC-WETH",
-					Type:    "pool",
-				},
-			},
-		},
+	// Load chains from CHAINS_CONFIG and the chains table, protocols from
+	// PROTOCOLS_CONFIG, comma separated files and directories
+	chainPaths := ".tvl-aggregator.yaml"
+	if value := os.Getenv("CHAINS_CONFIG"); value != "" {
+		chainPaths = value
+	}
+	chains := registry.NewChainRegistry(store, manager, strings.Split(chainPaths, ",")...)
+	result, err := chains.Load(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to load chains from %s: %v", chainPaths, err)
+	}
+	for name, failure := range result.Failed {
+		log.Printf("Chain %s: %s", name, failure)
 	}
 
-	calculator.RegisterProtocol(uniswapProtocol)
+	paths := ".tvl-aggregator.yaml"
+	if value := os.Getenv("PROTOCOLS_CONFIG"); value != "" {
+		paths = value
+	}
+	protocols := registry.New(store, calculator, chains, strings.Split(paths, ",")...)
+	if _, err := protocols.Load(context.Background()); err != nil {
+		log.Fatalf("Failed to load protocols from %s: %v", paths, err)
+	}
 
 	// Calculate TVL
 	ctx := context.Background()
@@ -4,42 +4,28 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"math/big"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/zacksfF/evm-tvl-aggregator/internal/aggregator"
 	"github.com/zacksfF/evm-tvl-aggregator/internal/api"
 	"github.com/zacksfF/evm-tvl-aggregator/internal/blockchain"
-	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/registry"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/backend"
 	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/memory"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/retention"
 )
 
 func main() {
 	// Setup blockchain manager
 	manager := blockchain.NewManager()
 
-	// Get RPC URL from environment or use public default
-	rpcURL := os.Getenv("ETH_RPC_URL")
-	if rpcURL == "" {
-		rpcURL = "https://ethereum-rpc.publicnode.com"
-	}
-
-	// Add Ethereum mainnet
-	err := manager.AddChain(blockchain.ChainConfig{
-		Name:        "ethereum",
-		ChainID:     big.NewInt(1),
-		RPCURL:      rpcURL,
-		NativeToken: "ETH",
-	})
-	if err != nil {
-		log.Fatal(err)
-	}
-
 	// Setup price oracle
 	priceOracle := aggregator.NewPriceOracle()
 
@@ -50,32 +36,30 @@ func main() {
 	priceOracle.SetMockPrice("WETH", 2500)
 
 	// Setup storage
-	storage := memory.NewMemoryStorage()
+	store := openStorage()
+	defer store.Close()
+
+	// Roll TVL history up and prune it in the background
+	retentionCtx, stopRetention := context.WithCancel(context.Background())
+	defer stopRetention()
+	startRetention(retentionCtx, store)
 
 	// Create TVL calculator
-	calculator := aggregator.NewTVLCalculator(manager, priceOracle, storage)
-
-	// Register protocols
-	uniswapProtocol := &models.Protocol{
-		Name:        "uniswap-v2",
-		Type:        models.ProtocolTypeDEX,
-		Description: "Uniswap V2 DEX",
-		Website:     "https://uniswap.org",
-		Chains: map[string][]models.ContractConfig{
-			"ethereum": {
-				{
-					Address: common.HexToAddress("0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc"), // USDC/WETH
-					Name:    "USDC-WETH",
-					Type:    "pool",
-				},
-			},
-		},
-	}
-
-	calculator.RegisterProtocol(uniswapProtocol)
+	calculator := aggregator.NewTVLCalculator(manager, priceOracle, store)
+
+	// Load chains and protocols from their config files and reload them on change
+	registryCtx, stopRegistry := context.WithCancel(context.Background())
+	defer stopRegistry()
+	chains := loadChains(registryCtx, store, manager)
+	loadProtocols(registryCtx, store, calculator, chains)
 
 	// Create API handler
-	handler := api.NewHandler(calculator, storage)
+	handler := api.NewHandler(calculator, store)
+
+	// Indexer admin servers to report in /indexer and /health, comma separated
+	if urls := os.Getenv("INDEXER_ADMIN_URL"); urls != "" {
+		handler.SetIndexerURLs(strings.Split(urls, ","))
+	}
 
 	// Create router
 	router := api.NewRouter(handler)
@@ -109,6 +93,7 @@ func main() {
 		fmt.Println("  GET /api/v1/protocols        - List all protocols")
 		fmt.Println("  GET /api/v1/chains           - List supported chains")
 		fmt.Println("  GET /api/v1/stats            - System stats")
+		fmt.Println("  GET /api/v1/indexer          - Indexer progress and errors")
 
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server failed: %v", err)
@@ -132,3 +117,140 @@ func main() {
 
 	fmt.Println(" Server stopped")
 }
+
+// openStorage opens the storage DATABASE_URL names, or PostgreSQL from the
+// DB_* variables, and falls back to in-memory storage without them
+func openStorage() storage.Storage {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		dbHost := os.Getenv("DB_HOST")
+		dbUser := os.Getenv("DB_USER")
+		dbPassword := os.Getenv("DB_PASSWORD")
+		dbName := os.Getenv("DB_NAME")
+
+		if dbHost != "" && dbUser != "" && dbName != "" {
+			dbURL = fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable",
+				dbUser, dbPassword, dbHost, dbName)
+		}
+	}
+
+	if dbURL == "" {
+		log.Println("No database configuration found, using in-memory storage")
+		return memory.NewMemoryStorage()
+	}
+
+	store, err := backend.Open(dbURL)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", backend.Name(dbURL), err)
+	}
+
+	log.Printf("Using %s", backend.Name(dbURL))
+	return store
+}
+
+// startRetention applies the TVL retention policy every RETENTION_INTERVAL,
+// keeping raw snapshots, hourly and daily rollups for RETENTION_RAW_DAYS,
+// RETENTION_HOURLY_DAYS and RETENTION_DAILY_DAYS. An interval of 0 disables
+// it, 0 days keeps a resolution forever.
+func startRetention(ctx context.Context, store storage.TVLStorage) {
+	interval := time.Hour
+	if value := os.Getenv("RETENTION_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid RETENTION_INTERVAL: %v", err)
+		}
+		interval = parsed
+	}
+	if interval <= 0 {
+		log.Println("TVL retention disabled")
+		return
+	}
+
+	policy := retention.DefaultPolicy()
+	for name, target := range map[string]*time.Duration{
+		"RETENTION_RAW_DAYS":    &policy.Raw,
+		"RETENTION_HOURLY_DAYS": &policy.Hourly,
+		"RETENTION_DAILY_DAYS":  &policy.Daily,
+	} {
+		if value := os.Getenv(name); value != "" {
+			days, err := strconv.Atoi(value)
+			if err != nil {
+				log.Fatalf("Invalid %s: %v", name, err)
+			}
+			*target = time.Duration(days) * 24 * time.Hour
+		}
+	}
+
+	engine, err := retention.NewEngine(store, policy)
+	if err != nil {
+		log.Fatalf("Invalid retention policy: %v", err)
+	}
+	go engine.Run(ctx, interval)
+}
+
+// loadChains connects the chains of CHAINS_CONFIG, comma separated YAML or
+// JSON files and directories, and the active chains of the chains table. They
+// are reloaded every CHAINS_RELOAD_INTERVAL, which also retries chains that
+// failed to connect. An interval of 0 disables reloading.
+func loadChains(ctx context.Context, store storage.ChainStorage, manager *blockchain.Manager) *registry.ChainRegistry {
+	paths := ".tvl-aggregator.yaml"
+	if value := os.Getenv("CHAINS_CONFIG"); value != "" {
+		paths = value
+	}
+
+	interval := time.Minute
+	if value := os.Getenv("CHAINS_RELOAD_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid CHAINS_RELOAD_INTERVAL: %v", err)
+		}
+		interval = parsed
+	}
+
+	chains := registry.NewChainRegistry(store, manager, strings.Split(paths, ",")...)
+	result, err := chains.Load(ctx)
+	if err != nil {
+		log.Fatalf("Failed to load chains from %s: %v", paths, err)
+	}
+	log.Printf("Loaded chains from %s: %s", paths, strings.Join(chains.GetSupportedChains(), ", "))
+	for name, failure := range result.Failed {
+		log.Printf("Chain %s: %s", name, failure)
+	}
+
+	if interval > 0 {
+		go chains.Run(ctx, interval)
+	}
+	return chains
+}
+
+// loadProtocols syncs the protocols of PROTOCOLS_CONFIG, comma separated
+// YAML or JSON files and directories, into storage and the calculator, and
+// reloads them when they change, checking every PROTOCOLS_RELOAD_INTERVAL.
+// An interval of 0 disables reloading.
+func loadProtocols(ctx context.Context, store storage.ProtocolStorage, calculator *aggregator.TVLCalculator, chains registry.Chains) {
+	paths := ".tvl-aggregator.yaml"
+	if value := os.Getenv("PROTOCOLS_CONFIG"); value != "" {
+		paths = value
+	}
+
+	interval := 30 * time.Second
+	if value := os.Getenv("PROTOCOLS_RELOAD_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid PROTOCOLS_RELOAD_INTERVAL: %v", err)
+		}
+		interval = parsed
+	}
+
+	protocols := registry.New(store, calculator, chains, strings.Split(paths, ",")...)
+	result, err := protocols.Load(ctx)
+	if err != nil {
+		log.Fatalf("Failed to load protocols from %s: %v", paths, err)
+	}
+	log.Printf("Loaded protocols from %s: %d added, %d updated, %d unchanged, %d removed",
+		paths, len(result.Added), len(result.Updated), result.Unchanged, len(result.Removed))
+
+	if interval > 0 {
+		go protocols.Run(ctx, interval)
+	}
+}
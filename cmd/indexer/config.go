@@ -0,0 +1,309 @@
+// cmd/indexer/config.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/viper"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/blockchain"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/indexer"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/registry"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/backend"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/memory"
+)
+
+// processorConfig is a contract watched by a processor. Contracts of the same
+// type and protocol share one processor.
+type processorConfig struct {
+	Type        string `mapstructure:"type"`     // uniswap-v2, uniswap-v3, aave-v3, curve or erc4626
+	Protocol    string `mapstructure:"protocol"` // protocol name of erc4626 vaults
+	Chain       string `mapstructure:"chain"`
+	Address     string `mapstructure:"address"`
+	DeployBlock uint64 `mapstructure:"deploy_block"`
+}
+
+// closableStorage is indexer storage, with the chains table and TVL
+// history, the command has to close
+type closableStorage interface {
+	indexer.Storage
+	storage.ChainStorage
+	storage.TVLStorage
+	Close() error
+}
+
+// defaultChains indexes Ethereum mainnet from the ETH_* environment
+func defaultChains() []blockchain.ChainConfig {
+	rpcURL := os.Getenv("ETH_RPC_URL")
+	if rpcURL == "" {
+		// Public nodes have restrictions on eth_getLogs, use Alchemy for indexing
+		rpcURL = "https://eth-mainnet.g.alchemy.com/v2/key"
+		log.Println("Using Alchemy RPC for indexing")
+	}
+
+	return []blockchain.ChainConfig{{
+		Name:     "ethereum",
+		ChainID:  big.NewInt(1),
+		RPCURL:   rpcURL,
+		WSURL:    os.Getenv("ETH_WS_URL"),
+		Finality: blockchain.FinalityMode(os.Getenv("ETH_FINALITY")),
+	}}
+}
+
+func defaultProcessors() []processorConfig {
+	return []processorConfig{
+		{Type: "uniswap-v2", Chain: "ethereum", Address: "0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f"},
+		{Type: "aave-v3", Chain: "ethereum", Address: "0x87870Bca3F3fD6335C3F4ce8392D69350B4fA4E2"},
+		// USDC/WETH 0.05% pool
+		{Type: "uniswap-v3", Chain: "ethereum", Address: "0x88e6A0c2dDD26FEEb64F039a2c41296FcB3f5640"},
+		// 3pool
+		{Type: "curve", Chain: "ethereum", Address: "0xbEbc44782C7dB0a1A60Cb6fe97d0b483032FF1C7"},
+		// Spark sDAI vault
+		{Type: "erc4626", Protocol: "spark", Chain: "ethereum", Address: "0x83F20F44975D03b1b09e64809B757c47f942BEeA"},
+	}
+}
+
+// setup builds the indexer from the configuration. The returned registry
+// keeps the indexer's chains in line with the config file and the chains
+// table; the returned function releases the chains and the storage.
+func setup(allowMemory bool) (*indexer.Indexer, *registry.ChainRegistry, string, func(), error) {
+	storage, dbURL, err := openStorage(allowMemory)
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+
+	manager := blockchain.NewManager()
+	chains, err := loadChains(storage, manager)
+	if err != nil {
+		manager.Close()
+		storage.Close()
+		return nil, nil, "", nil, err
+	}
+
+	config := indexer.Config{
+		BatchSize:          viper.GetUint64("batch_size"),
+		MaxBatchSize:       viper.GetUint64("max_batch_size"),
+		WorkerCount:        viper.GetInt("workers"),
+		BlockConfirmations: viper.GetUint64("confirmations"),
+		RetryAttempts:      viper.GetInt("retry_attempts"),
+		RetryDelay:         viper.GetDuration("retry_delay"),
+		StartFromBlock:     viper.GetUint64("start_from_block"),
+		PollInterval:       viper.GetDuration("poll_interval"),
+	}
+
+	idx := indexer.NewIndexer(manager, storage, config)
+	chains.Subscribe(idx)
+	// TVL read at blocks of an abandoned fork is gone with them
+	idx.OnReorg(storage.RollbackTVL)
+	cleanup := func() {
+		manager.Close()
+		storage.Close()
+	}
+
+	if err := registerProcessors(idx); err != nil {
+		cleanup()
+		return nil, nil, "", nil, err
+	}
+
+	return idx, chains, dbURL, cleanup, nil
+}
+
+// loadChains connects the chains of the config file and the chains table,
+// as the API does. Without any, Ethereum mainnet is indexed from the ETH_*
+// environment.
+func loadChains(store storage.ChainStorage, manager *blockchain.Manager) (*registry.ChainRegistry, error) {
+	var paths []string
+	if file := viper.ConfigFileUsed(); file != "" {
+		if _, err := os.Stat(file); err == nil {
+			paths = append(paths, file)
+		}
+	}
+
+	chains := registry.NewChainRegistry(store, manager, paths...)
+	result, err := chains.Load(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chains: %w", err)
+	}
+	for name, failure := range result.Failed {
+		log.Printf("Chain %s: %s", name, failure)
+	}
+
+	if len(chains.GetSupportedChains()) == 0 {
+		for _, config := range defaultChains() {
+			if err := manager.AddChain(config); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return chains, nil
+}
+
+// openStorage opens the storage database_url names, or PostgreSQL from the
+// DB_* environment. Without a database, or when it can't be opened and
+// allowMemory is set, events are kept in memory. The returned URL is set
+// for PostgreSQL only.
+func openStorage(allowMemory bool) (closableStorage, string, error) {
+	dbURL := databaseURL()
+	if dbURL == "" {
+		if !allowMemory {
+			return nil, "", errNoDatabase
+		}
+		log.Println("No database configuration found, using in-memory storage")
+		return memory.NewMemoryStorage(), "", nil
+	}
+
+	storage, err := backend.Open(dbURL)
+	if err != nil {
+		if !allowMemory {
+			return nil, "", err
+		}
+		log.Printf("Failed to open %s (%v), using in-memory storage", backend.Name(dbURL), err)
+		return memory.NewMemoryStorage(), "", nil
+	}
+
+	log.Printf("Using %s", backend.Name(dbURL))
+	if !backend.IsPostgres(dbURL) {
+		return storage, "", nil
+	}
+	return storage, dbURL, nil
+}
+
+var errNoDatabase = errors.New("no database configured, set database_url or DB_HOST, DB_USER and DB_NAME")
+
+// databaseURL returns database_url, or a PostgreSQL URL built from the DB_*
+// environment
+func databaseURL() string {
+	if dbURL := viper.GetString("database_url"); dbURL != "" {
+		return dbURL
+	}
+
+	dbHost := os.Getenv("DB_HOST")
+	dbUser := os.Getenv("DB_USER")
+	dbPassword := os.Getenv("DB_PASSWORD")
+	dbName := os.Getenv("DB_NAME")
+
+	if dbHost == "" || dbUser == "" || dbName == "" {
+		return ""
+	}
+	return fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable",
+		dbUser, dbPassword, dbHost, dbName)
+}
+
+// registerProcessors registers the processors of the configuration. Contracts
+// without a deploy_block start at the last start_from_block blocks; set it to
+// backfill their full history.
+func registerProcessors(idx *indexer.Indexer) error {
+	var configs []processorConfig
+	if err := viper.UnmarshalKey("processors", &configs); err != nil {
+		return fmt.Errorf("invalid processors: %w", err)
+	}
+	if len(configs) == 0 {
+		configs = defaultProcessors()
+	}
+
+	type group struct {
+		typ       string
+		protocol  string
+		contracts []indexer.WatchedContract
+	}
+	var groups []*group
+	byKey := make(map[string]*group)
+
+	for _, config := range configs {
+		if !common.IsHexAddress(config.Address) {
+			return fmt.Errorf("%s processor: invalid address %q", config.Type, config.Address)
+		}
+
+		key := config.Type + "/" + config.Protocol
+		g, ok := byKey[key]
+		if !ok {
+			g = &group{typ: config.Type, protocol: config.Protocol}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.contracts = append(g.contracts, indexer.WatchedContract{
+			Chain:       config.Chain,
+			Address:     common.HexToAddress(config.Address),
+			DeployBlock: config.DeployBlock,
+		})
+	}
+
+	for _, g := range groups {
+		var processor indexer.EventProcessor
+		switch g.typ {
+		case "uniswap-v2":
+			if len(g.contracts) > 1 {
+				return fmt.Errorf("uniswap-v2 processor: only one factory is supported")
+			}
+			processor = indexer.NewUniswapV2Processor(g.contracts[0])
+		case "aave-v3":
+			if len(g.contracts) > 1 {
+				return fmt.Errorf("aave-v3 processor: only one pool is supported")
+			}
+			processor = indexer.NewAaveV3Processor(g.contracts[0])
+		case "uniswap-v3":
+			processor = indexer.NewUniswapV3Processor(g.contracts...)
+		case "curve":
+			processor = indexer.NewCurveProcessor(g.contracts...)
+		case "erc4626":
+			if g.protocol == "" {
+				return fmt.Errorf("erc4626 processor: protocol is required")
+			}
+			processor = indexer.NewERC4626Processor(g.protocol, g.contracts...)
+		default:
+			return fmt.Errorf("unknown processor type %q", g.typ)
+		}
+
+		idx.RegisterProcessor(processor)
+	}
+
+	return nil
+}
+
+// registerSinks registers the event sinks of the configuration. The returned
+// function closes them.
+func registerSinks(idx *indexer.Indexer, dbURL string) (func(), error) {
+	var closers []func() error
+	closeAll := func() {
+		for _, closeSink := range closers {
+			closeSink()
+		}
+	}
+
+	if path := viper.GetString("sink.ndjson"); path != "" {
+		if path == "-" {
+			idx.RegisterSink(indexer.NewNDJSONSink("stdout", os.Stdout))
+		} else {
+			sink, err := indexer.NewNDJSONFileSink(path)
+			if err != nil {
+				return nil, err
+			}
+			closers = append(closers, sink.Close)
+			idx.RegisterSink(sink)
+		}
+	}
+	if url := viper.GetString("sink.webhook"); url != "" {
+		idx.RegisterSink(indexer.NewWebhookSink(indexer.WebhookConfig{URL: url}))
+	}
+	if channel := viper.GetString("sink.notify"); channel != "" {
+		if dbURL == "" {
+			closeAll()
+			return nil, fmt.Errorf("sink.notify requires a PostgreSQL database")
+		}
+		sink, err := indexer.NewPostgresNotifySink(dbURL, channel)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		closers = append(closers, sink.Close)
+		idx.RegisterSink(sink)
+	}
+
+	return closeAll, nil
+}
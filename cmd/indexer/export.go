@@ -0,0 +1,114 @@
+// cmd/indexer/export.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/export"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/backend"
+)
+
+func exportCmd() *cobra.Command {
+	var options export.Options
+	var format, resolution, from, to, output string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export TVL history",
+		Long: `Export TVL snapshots, or hourly or daily rollups, as CSV, NDJSON or Parquet,
+optionally with a row per asset of each snapshot. History is read a window at
+a time, so exports of any size run in bounded memory.
+
+The format defaults to the output file's extension, CSV for stdout.`,
+		Example: `  indexer export --protocol uniswap-v2 --from 2024-01-01 --output tvl.parquet
+  indexer export --chain ethereum --resolution daily --breakdown --format ndjson`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format == "" {
+				format = strings.TrimPrefix(filepath.Ext(output), ".")
+				if output == "-" || format == "" {
+					format = string(export.FormatCSV)
+				}
+			}
+			exportFormat, err := export.ParseFormat(format)
+			if err != nil {
+				return err
+			}
+
+			options.Resolution = models.Resolution(resolution)
+			switch options.Resolution {
+			case "", models.ResolutionRaw, models.ResolutionHourly, models.ResolutionDaily:
+			default:
+				return fmt.Errorf("invalid resolution %q, want raw, hourly or daily", resolution)
+			}
+			if from != "" {
+				if options.From, err = export.ParseTime(from); err != nil {
+					return err
+				}
+			}
+			if to != "" {
+				if options.To, err = export.ParseTime(to); err != nil {
+					return err
+				}
+			}
+
+			dbURL := databaseURL()
+			if dbURL == "" {
+				return errNoDatabase
+			}
+			store, err := backend.Open(dbURL)
+			if err != nil {
+				return fmt.Errorf("failed to open %s: %w", backend.Name(dbURL), err)
+			}
+			defer store.Close()
+
+			out := os.Stdout
+			if output != "-" {
+				if out, err = os.Create(output); err != nil {
+					return err
+				}
+			}
+			buffered := bufio.NewWriterSize(out, 1<<20)
+
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+
+			result, err := export.Export(ctx, store, buffered, exportFormat, options)
+			if err == nil {
+				err = buffered.Flush()
+			}
+			if output != "-" {
+				if closeErr := out.Close(); err == nil {
+					err = closeErr
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("export failed: %w", err)
+			}
+
+			fmt.Fprintf(os.Stderr, "Exported %d snapshots in %d rows\n", result.Snapshots, result.Rows)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "", "csv, ndjson or parquet")
+	cmd.Flags().StringVarP(&output, "output", "o", "-", "File to write, - for stdout")
+	cmd.Flags().StringVar(&options.Protocol, "protocol", "", "Only export this protocol")
+	cmd.Flags().StringVar(&options.Chain, "chain", "", "Only export this chain")
+	cmd.Flags().StringVar(&resolution, "resolution", "raw", "raw, hourly or daily")
+	cmd.Flags().StringVar(&from, "from", "", "Start time, RFC 3339 or YYYY-MM-DD (default the oldest snapshot)")
+	cmd.Flags().StringVar(&to, "to", "", "End time, inclusive (default now)")
+	cmd.Flags().BoolVar(&options.Breakdown, "breakdown", false, "Write a row per asset of each snapshot")
+	cmd.Flags().DurationVar(&options.Window, "window", export.DefaultWindow, "Span of history read at a time")
+
+	return cmd
+}
@@ -0,0 +1,109 @@
+// cmd/indexer/import.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/importer"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/backend"
+)
+
+func importCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import TVL history from other datasets",
+	}
+	cmd.AddCommand(importDefiLlamaCmd())
+	return cmd
+}
+
+func importDefiLlamaCmd() *cobra.Command {
+	var options importer.Options
+
+	cmd := &cobra.Command{
+		Use:   "defillama FILE...",
+		Short: "Import protocol history in DefiLlama's format",
+		Long: `Import the TVL history of protocols saved from DefiLlama's /protocol
+endpoint, one protocol per file (- reads stdin). Every chain's tvl, tokensInUsd
+and tokens become a snapshot per point with its token breakdown; staking,
+borrowed and other categories DefiLlama leaves out of TVL are skipped.
+
+Imported snapshots are tagged with --source and replace the snapshots of the
+same protocol, chain and time, so importing a file again updates its history.
+Their hours and days are rolled up, so they are kept once retention prunes
+them. Files sharing a day with snapshots of another source, computed or
+imported, are refused.`,
+		Example: `  indexer import defillama aave-v3.json
+  curl -s https://api.llama.fi/protocol/uniswap-v2 | indexer import defillama --chain ethereum -`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 && options.Protocol != "" {
+				return errors.New("--protocol names one protocol, import its file alone")
+			}
+
+			dbURL := databaseURL()
+			if dbURL == "" {
+				return errNoDatabase
+			}
+			store, err := backend.Open(dbURL)
+			if err != nil {
+				return fmt.Errorf("failed to open %s: %w", backend.Name(dbURL), err)
+			}
+			defer store.Close()
+
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+
+			for _, path := range args {
+				snapshots, err := readDefiLlama(path, options)
+				if err != nil {
+					return fmt.Errorf("failed to read %s: %w", path, err)
+				}
+				if len(snapshots) == 0 {
+					fmt.Printf("%s: no TVL history\n", path)
+					continue
+				}
+
+				result, err := importer.Import(ctx, store, snapshots)
+				if err != nil {
+					return fmt.Errorf("failed to import %s: %w", path, err)
+				}
+
+				fmt.Printf("%s: imported %d snapshots of %s on %s from %s to %s, %d hourly and %d daily rollups\n",
+					path, result.Snapshots, snapshots[0].Protocol, strings.Join(result.Chains, ", "),
+					result.From.Format("2006-01-02"), result.To.Format("2006-01-02"),
+					result.HourlyRollups, result.DailyRollups)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Protocol, "protocol", "", "Protocol name (default the name in the file as a slug, e.g. aave-v3)")
+	cmd.Flags().StringVar(&options.Chain, "chain", "", "Only import this chain, or the chain of a file without per-chain history")
+	cmd.Flags().StringVar(&options.Source, "source", importer.SourceDefiLlama, "Source the snapshots are tagged with")
+
+	return cmd
+}
+
+// readDefiLlama reads a file, or stdin for -
+func readDefiLlama(path string, options importer.Options) ([]*models.TVLSnapshot, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+	return importer.ReadDefiLlama(r, options)
+}
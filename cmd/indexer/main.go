@@ -3,107 +3,494 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
-	"math/big"
+	"net/http"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
-	"github.com/ethereum/go-ethereum/common"
-	"github.com/zacksfF/evm-tvl-aggregator/internal/blockchain"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"github.com/zacksfF/evm-tvl-aggregator/internal/indexer"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/backend"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/postgres"
 )
 
-func main() {
-	// Create blockchain manager
-	manager := blockchain.NewManager()
-
-	// Get RPC URL from environment or use Alchemy default
-	rpcURL := os.Getenv("ETH_RPC_URL")
-	if rpcURL == "" {
-		// Public nodes have restrictions on eth_getLogs, use Alchemy for indexing
-		rpcURL = "https://eth-mainnet.g.alchemy.com/v2/key"
-		log.Println("Using Alchemy RPC for indexing (required for eth_getLogs without address filter)")
-	}
-
-	// Add Ethereum mainnet
-	err := manager.AddChain(blockchain.ChainConfig{
-		Name:    "ethereum",
-		ChainID: big.NewInt(1),
-		RPCURL:  rpcURL,
-		WSURL:   os.Getenv("ETH_WS_URL"), // Optional: add WSS URL for real-time events
-	})
-	if err != nil {
-		log.Fatal(err)
-	}
+var (
+	cfgFile string
+	rootCmd = &cobra.Command{
+		Use:   "indexer",
+		Short: "EVM event indexer",
+		Long: `Indexes protocol events from EVM chains into the aggregator's storage.
 
-	// Setup storage - try PostgreSQL first, fallback to memory
-	var storage indexer.Storage
+Chains, processors and sinks are read from the config file; without one the
+indexer follows Ethereum mainnet from ETH_RPC_URL with the built-in processors.`,
+		// main prints errors, most of which aren't about usage
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return initConfig()
+		},
+	}
+)
 
-	// Check if we have database environment variables
-	dbHost := os.Getenv("DB_HOST")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
+func init() {
+	// Global flags
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.tvl-indexer.yaml)")
+	rootCmd.PersistentFlags().String("database-url", "", "PostgreSQL connection string (default from DB_* variables)")
+	viper.BindPFlag("database_url", rootCmd.PersistentFlags().Lookup("database-url"))
 
-	if dbHost != "" && dbUser != "" && dbName != "" {
-		dbURL := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable",
-			dbUser, dbPassword, dbHost, dbName)
+	// Add subcommands
+	rootCmd.AddCommand(runCmd())
+	rootCmd.AddCommand(backfillCmd())
+	rootCmd.AddCommand(reindexCmd())
+	rootCmd.AddCommand(statusCmd())
+	rootCmd.AddCommand(replayCmd())
+	rootCmd.AddCommand(migrateCmd())
+	rootCmd.AddCommand(exportCmd())
+	rootCmd.AddCommand(importCmd())
+	rootCmd.AddCommand(reconstructCmd())
+}
 
-		storage, err = indexer.NewPostgresStorage(dbURL)
-		if err != nil {
-			log.Printf("Failed to connect to PostgreSQL (%v), using in-memory storage", err)
-			storage = indexer.NewMemoryStorage()
-		} else {
-			log.Println("Connected to PostgreSQL database")
-		}
+// initConfig reads the config file and environment; a config file named by
+// --config has to be readable
+func initConfig() error {
+	if cfgFile != "" {
+		viper.SetConfigFile(cfgFile)
 	} else {
-		log.Println("No database configuration found, using in-memory storage")
-		storage = indexer.NewMemoryStorage()
+		home, err := os.UserHomeDir()
+		if err == nil {
+			viper.AddConfigPath(home)
+		}
+		viper.AddConfigPath(".")
+		viper.SetConfigType("yaml")
+		viper.SetConfigName(".tvl-indexer")
+	}
+
+	// Environment variables, e.g. INDEXER_BATCH_SIZE or INDEXER_SINK_NDJSON
+	viper.SetEnvPrefix("INDEXER")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+	viper.BindEnv("database_url", "INDEXER_DATABASE_URL", "DATABASE_URL")
+
+	// Set defaults
+	viper.SetDefault("batch_size", 10) // Small batches for reliable processing
+	viper.SetDefault("workers", 1)     // Single worker to avoid rate limits
+	viper.SetDefault("confirmations", 12)
+	viper.SetDefault("retry_attempts", 3)
+	viper.SetDefault("retry_delay", "5s")
+	viper.SetDefault("start_from_block", 100) // Index last 100 blocks
+	viper.SetDefault("poll_interval", "15s")
+	viper.SetDefault("admin_addr", ":9090")
+	viper.SetDefault("chains_reload_interval", "1m")
+
+	if err := viper.ReadInConfig(); err == nil {
+		fmt.Fprintf(os.Stderr, "Using config file: %s\n", viper.ConfigFileUsed())
+	} else if cfgFile != "" {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	return nil
+}
+
+func runCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Index all configured chains",
+		Long:  "Follow every configured chain, resuming from the stored checkpoints, until interrupted",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			idx, chains, dbURL, cleanup, err := setup(true)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			closeSinks, err := registerSinks(idx, dbURL)
+			if err != nil {
+				return err
+			}
+			defer closeSinks()
+
+			// Start indexing
+			ctx, cancel := context.WithCancel(context.Background())
+
+			go func() {
+				if err := idx.Start(ctx); err != nil {
+					log.Printf("Indexer error: %v", err)
+				}
+			}()
+
+			// Follow the config file and the chains table; the indexer
+			// starts, restarts and stops chains as they change
+			if interval := viper.GetDuration("chains_reload_interval"); interval > 0 {
+				go chains.Run(ctx, interval)
+			}
+
+			// Admin endpoints: /status, /chains/{chain} and /processors
+			var admin *http.Server
+			if addr := viper.GetString("admin_addr"); addr != "" {
+				admin = &http.Server{
+					Addr:         addr,
+					Handler:      indexer.NewAdminHandler(idx),
+					ReadTimeout:  15 * time.Second,
+					WriteTimeout: 30 * time.Second,
+				}
+
+				go func() {
+					fmt.Printf("Indexer admin server listening on %s\n", addr)
+					if err := admin.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+						log.Printf("Admin server failed: %v", err)
+					}
+				}()
+			}
+
+			// Wait for interrupt signal
+			sigChan := make(chan os.Signal, 1)
+			signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+			<-sigChan
+			fmt.Println("\nShutting down...")
+
+			cancel()
+			if admin != nil {
+				shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+				admin.Shutdown(shutdownCtx)
+				shutdownCancel()
+			}
+			idx.Stop()
+
+			fmt.Println("Indexer stopped successfully")
+			return nil
+		},
 	}
 
-	// Create indexer
-	config := indexer.Config{
-		BatchSize:          10, // Small batches for reliable processing
-		WorkerCount:        1,  // Single worker to avoid rate limits
-		BlockConfirmations: 12,
-		RetryAttempts:      3,
-		RetryDelay:         5 * time.Second,
-		StartFromBlock:     100, // Index last 100 blocks
+	cmd.Flags().Uint64("batch-size", 0, "Blocks per eth_getLogs request")
+	cmd.Flags().Int("workers", 0, "Concurrent batch workers")
+	cmd.Flags().Uint64("start-from-block", 0, "Blocks behind the head to start contracts without a checkpoint or deploy block")
+	cmd.Flags().String("admin-addr", "", "Address of the admin status server, empty to disable (default :9090)")
+	viper.BindPFlag("batch_size", cmd.Flags().Lookup("batch-size"))
+	viper.BindPFlag("workers", cmd.Flags().Lookup("workers"))
+	viper.BindPFlag("start_from_block", cmd.Flags().Lookup("start-from-block"))
+	viper.BindPFlag("admin_addr", cmd.Flags().Lookup("admin-addr"))
+
+	return cmd
+}
+
+// rangeFlags are the flags of the commands working on a block range
+type rangeFlags struct {
+	chain      string
+	from       uint64
+	to         uint64
+	processors []string
+}
+
+func (f *rangeFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.chain, "chain", "", "Chain to index")
+	cmd.Flags().Uint64Var(&f.from, "from", 0, "First block of the range")
+	cmd.Flags().Uint64Var(&f.to, "to", 0, "Last block of the range")
+	cmd.Flags().StringSliceVar(&f.processors, "processor", nil, "Protocol of a processor to index (repeatable, default all)")
+	cmd.MarkFlagRequired("chain")
+	cmd.MarkFlagRequired("from")
+	cmd.MarkFlagRequired("to")
+}
+
+func backfillCmd() *cobra.Command {
+	var flags rangeFlags
+
+	cmd := &cobra.Command{
+		Use:   "backfill",
+		Short: "Index a block range",
+		Long: `Index a block range for some or all processors without moving their
+checkpoints, e.g. history before the configured deploy blocks. Events already
+stored in the range are updated in place.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			idx, _, _, cleanup, err := setup(false)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+
+			if err := idx.Backfill(ctx, flags.chain, flags.from, flags.to, flags.processors); err != nil {
+				return fmt.Errorf("backfill failed: %w", err)
+			}
+			return nil
+		},
 	}
 
-	idx := indexer.NewIndexer(manager, storage, config)
+	flags.register(cmd)
+	return cmd
+}
+
+func reindexCmd() *cobra.Command {
+	var flags rangeFlags
+
+	cmd := &cobra.Command{
+		Use:   "reindex",
+		Short: "Delete and rebuild a block range",
+		Long: `Delete the events of some or all processors in a block range and index it
+again, e.g. after a processor fix. Run it again if it is interrupted.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			idx, _, _, cleanup, err := setup(false)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+
+			if err := idx.Reindex(ctx, flags.chain, flags.from, flags.to, flags.processors); err != nil {
+				return fmt.Errorf("reindex failed: %w", err)
+			}
+			return nil
+		},
+	}
+
+	flags.register(cmd)
+	return cmd
+}
+
+func statusCmd() *cobra.Command {
+	var chain string
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show indexing progress",
+		Long:  "Show the progress, lag and event counts of every configured chain",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			idx, _, _, cleanup, err := setup(false)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
 
-	// Register Uniswap V2 processor
-	uniswapFactory := common.HexToAddress("0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f")
-	uniswapProcessor := indexer.NewUniswapV2Processor(uniswapFactory)
-	idx.RegisterProcessor(uniswapProcessor)
+			chains := []string{chain}
+			if chain == "" {
+				chains = idx.Chains()
+			}
 
-	// Register Aave V3 processor
-	aavePool := common.HexToAddress("0x87870Bca3F3fD6335C3F4ce8392D69350B4fA4E2")
-	aaveProcessor := indexer.NewAaveV3Processor(aavePool)
-	idx.RegisterProcessor(aaveProcessor)
+			ctx := context.Background()
+			var stats []*indexer.IndexerStats
+			for _, name := range chains {
+				chainStats, err := idx.Stats(ctx, name)
+				if err != nil {
+					return fmt.Errorf("failed to get %s status: %w", name, err)
+				}
+				stats = append(stats, chainStats)
+			}
 
-	// Start indexing
-	ctx, cancel := context.WithCancel(context.Background())
+			if asJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(stats)
+			}
 
-	go func() {
-		if err := idx.Start(ctx); err != nil {
-			log.Printf("Indexer error: %v", err)
+			printStats(stats)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&chain, "chain", "", "Only show this chain")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the status as JSON")
+
+	return cmd
+}
+
+func printStats(stats []*indexer.IndexerStats) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHAIN\tINDEXED\tCURRENT\tLAG\tPROGRESS\tEVENTS\tDEAD LETTERS\tFAILED RANGES\tUPDATED")
+	for _, s := range stats {
+		updated := "never"
+		if !s.LastUpdateTime.IsZero() {
+			updated = s.LastUpdateTime.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.2f%%\t%d\t%d\t%d\t%s\n",
+			s.Chain, s.LastIndexedBlock, s.CurrentBlock, s.Lag, s.IndexingProgress,
+			s.EventsCount, s.DeadLetters, s.FailedRanges, updated)
+	}
+	w.Flush()
+
+	for _, s := range stats {
+		protocols := make([]string, 0, len(s.EventsByProtocol))
+		for protocol := range s.EventsByProtocol {
+			protocols = append(protocols, protocol)
+		}
+		sort.Strings(protocols)
+
+		fmt.Printf("\n%s events by protocol:\n", s.Chain)
+		for _, protocol := range protocols {
+			fmt.Printf("  %-16s %d\n", protocol, s.EventsByProtocol[protocol])
 		}
-	}()
+	}
+}
 
-	// Wait for interrupt signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+func replayCmd() *cobra.Command {
+	var chain, protocol string
 
-	<-sigChan
-	fmt.Println("\nShutting down...")
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Decode dead letters again",
+		Long:  "Decode the stored logs that failed to decode again, e.g. after a processor fix",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			idx, _, _, cleanup, err := setup(false)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
 
-	cancel()
-	idx.Stop()
+			result, err := idx.ReplayDeadLetters(context.Background(), chain, protocol)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Replayed %d dead letters, %d still failing, %d without a processor\n",
+				result.Replayed, result.Failed, result.Skipped)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&chain, "chain", "", "Only replay dead letters of this chain")
+	cmd.Flags().StringVar(&protocol, "protocol", "", "Only replay dead letters of this protocol")
+
+	return cmd
+}
+
+func migrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage the database schema",
+		Long: `Apply, revert or list the schema migrations built into the binary.
+
+Applied migrations are recorded in schema_migrations with a checksum; up and
+down refuse to run when an applied migration was modified since.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "up",
+		Short: "Apply every pending migration",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			migrator, cleanup, err := openMigrator()
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			applied, err := migrator.Up(context.Background())
+			for _, migration := range applied {
+				fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+			}
+			if err != nil {
+				return err
+			}
+			if len(applied) == 0 {
+				fmt.Println("Schema is up to date")
+			}
+			return nil
+		},
+	})
 
-	fmt.Println("Indexer stopped successfully")
+	var steps int
+	down := &cobra.Command{
+		Use:   "down",
+		Short: "Revert the last applied migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			migrator, cleanup, err := openMigrator()
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			reverted, err := migrator.Down(context.Background(), steps)
+			for _, migration := range reverted {
+				fmt.Printf("Reverted %04d_%s\n", migration.Version, migration.Name)
+			}
+			if err != nil {
+				return err
+			}
+			if len(reverted) == 0 {
+				fmt.Println("No migration to revert")
+			}
+			return nil
+		},
+	}
+	down.Flags().IntVar(&steps, "steps", 1, "Number of migrations to revert")
+	cmd.AddCommand(down)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "List the migrations and whether they are applied",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			migrator, cleanup, err := openMigrator()
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			statuses, err := migrator.Status(context.Background())
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED")
+			for _, s := range statuses {
+				applied := "-"
+				if !s.AppliedAt.IsZero() {
+					applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+				}
+				fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, s.State, applied)
+			}
+			return w.Flush()
+		},
+	})
+
+	return cmd
+}
+
+// openMigrator connects to the database without migrating it
+func openMigrator() (*postgres.Migrator, func(), error) {
+	dbURL := databaseURL()
+	if dbURL == "" {
+		return nil, nil, errNoDatabase
+	}
+	if !backend.IsPostgres(dbURL) {
+		return nil, nil, fmt.Errorf("migrations only apply to PostgreSQL, %s needs none", backend.Name(dbURL))
+	}
+
+	db, err := postgres.Connect(dbURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrator, err := postgres.NewMigrator(db)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	return migrator, func() { db.Close() }, nil
+}
+
+func main() {
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 }
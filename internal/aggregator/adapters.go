@@ -0,0 +1,66 @@
+// internal/aggregator/adapters.go
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/blockchain"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+)
+
+// adapter reads the assets a contract holds
+type adapter func(tc *TVLCalculator, ctx context.Context, client *blockchain.Client, contract models.ContractConfig) ([]*models.AssetTVL, error)
+
+// adapters are the calculators protocols and contracts can name. The dex,
+// lending and yield adapters guess the contract's kind and fall back to its
+// balances, and are the defaults of their protocol type.
+var adapters = map[string]adapter{
+	"uniswap-v2":  (*TVLCalculator).calculateUniswapV2TVL,
+	"aave-v3":     (*TVLCalculator).calculateAaveV3TVL,
+	"compound-v3": (*TVLCalculator).calculateCompoundV3TVL,
+	"erc4626":     (*TVLCalculator).calculateYieldTVL,
+	"balances":    (*TVLCalculator).calculateGenericTVL,
+	"dex":         (*TVLCalculator).calculateDexTVL,
+	"lending":     (*TVLCalculator).calculateLendingTVL,
+	"yield":       (*TVLCalculator).calculateYieldTVL,
+}
+
+// HasAdapter tells whether a calculator adapter exists
+func HasAdapter(name string) bool {
+	_, ok := adapters[name]
+	return ok
+}
+
+// Adapters lists the names of the calculator adapters
+func Adapters() []string {
+	names := make([]string, 0, len(adapters))
+	for name := range adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// contractAdapter picks the adapter of a contract: its own, its protocol's,
+// or the default of its protocol's type, the balances it holds for types
+// without one
+func contractAdapter(protocol *models.Protocol, contract models.ContractConfig) (adapter, error) {
+	name := contract.Adapter
+	if name == "" {
+		name = protocol.Adapter
+	}
+	if name == "" {
+		if calculate, ok := adapters[string(protocol.Type)]; ok {
+			return calculate, nil
+		}
+		return adapters["balances"], nil
+	}
+
+	calculate, ok := adapters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown adapter %s", name)
+	}
+	return calculate, nil
+}
@@ -0,0 +1,533 @@
+// internal/aggregator/reconstruct.go
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/blockchain"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// Blocks of events loaded per storage query while replaying
+const replayChunkBlocks = 100000
+
+// Reconstructed snapshots saved per transaction
+const saveChunkSnapshots = 1000
+
+// SourceReconstructed is the source of reconstructed snapshots. Their token
+// amounts are those of the block, but their USD values use the prices of
+// the time of reconstruction.
+const SourceReconstructed = "reconstructed-current-prices"
+
+// ReserveState is the reserves of a Uniswap V2 style pair after a block
+type ReserveState struct {
+	Pair        common.Address `json:"pair"`
+	BlockNumber uint64         `json:"block_number"`
+	Timestamp   time.Time      `json:"timestamp"`
+	Reserve0    *big.Int       `json:"reserve0"`
+	Reserve1    *big.Int       `json:"reserve1"`
+}
+
+// Reconstructor rebuilds DEX TVL history by replaying indexed Sync events.
+// Every Sync carries the full reserves of its pair, so the reserves at any
+// block are known without calling an archive node. Pair tokens come from
+// indexed PairCreated events, the protocol configuration, or the pair itself.
+// USD values use the calculator's price oracle, which knows current prices
+// only, so snapshots are tagged SourceReconstructed; the breakdown keeps the
+// raw token amounts so they can be repriced.
+type Reconstructor struct {
+	calculator *TVLCalculator
+	events     storage.EventStorage
+
+	mu     sync.Mutex
+	pairs  map[string]*pairTokens // chain:pair -> tokens
+	tokens map[string]*tokenInfo  // chain:token -> metadata
+}
+
+type pairTokens struct {
+	token0 common.Address
+	token1 common.Address
+}
+
+type tokenInfo struct {
+	symbol   string
+	decimals uint8
+	price    *big.Float
+}
+
+func NewReconstructor(calculator *TVLCalculator, events storage.EventStorage) *Reconstructor {
+	return &Reconstructor{
+		calculator: calculator,
+		events:     events,
+		pairs:      make(map[string]*pairTokens),
+		tokens:     make(map[string]*tokenInfo),
+	}
+}
+
+// ReserveHistory returns the reserves of a pair after every block in
+// [from, to] that changed them
+func (r *Reconstructor) ReserveHistory(ctx context.Context, protocol, chain string, pair common.Address, from, to uint64) ([]ReserveState, error) {
+	var history []ReserveState
+
+	err := r.replay(ctx, protocol, chain, from, to, func(block uint64, timestamp time.Time, changed map[common.Address]*ReserveState, _ bool) error {
+		if state, ok := changed[pair]; ok {
+			history = append(history, *state)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// Reconstruct returns a TVL snapshot of a protocol on a chain for every block
+// in [from, to] in which one of its pairs emitted a Sync event, valued at
+// current prices. It fails when the tokens of a pair can't be resolved.
+func (r *Reconstructor) Reconstruct(ctx context.Context, protocol, chain string, from, to uint64) ([]*models.TVLSnapshot, error) {
+	var snapshots []*models.TVLSnapshot
+
+	err := r.reconstruct(ctx, protocol, chain, from, to, func(snapshot *models.TVLSnapshot) error {
+		snapshots = append(snapshots, snapshot)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+// ReconstructAndSave reconstructs snapshots and stores them with the
+// calculator's storage as they are made, saveChunkSnapshots per transaction.
+// It returns the number of snapshots saved, which stay saved when a later
+// chunk fails.
+func (r *Reconstructor) ReconstructAndSave(ctx context.Context, protocol, chain string, from, to uint64) (int, error) {
+	var (
+		saved int
+		chunk []*models.TVLSnapshot
+	)
+
+	save := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		err := storage.InTx(ctx, r.calculator.storage, func(tx storage.Tx) error {
+			for _, snapshot := range chunk {
+				if err := tx.SaveTVLSnapshot(ctx, snapshot); err != nil {
+					return fmt.Errorf("failed to save snapshot at block %d: %w", snapshot.BlockNumber, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		saved += len(chunk)
+		chunk = chunk[:0]
+		return nil
+	}
+
+	err := r.reconstruct(ctx, protocol, chain, from, to, func(snapshot *models.TVLSnapshot) error {
+		chunk = append(chunk, snapshot)
+		if len(chunk) < saveChunkSnapshots {
+			return nil
+		}
+		return save()
+	})
+	if err == nil {
+		err = save()
+	}
+
+	return saved, err
+}
+
+// reserveBook is the reserves of a protocol's pairs during a replay, with
+// their tokens summed over the pairs
+type reserveBook struct {
+	reserves map[common.Address]*ReserveState
+	assets   map[common.Address]*models.AssetTVL
+}
+
+// reconstruct calls fn with a snapshot of every block in [from, to] that
+// changed the reserves of one of the protocol's pairs. The reserves before
+// from are those of each pair's last Sync before it, so earlier blocks are
+// not replayed, and a block only revalues the tokens of the pairs it changed.
+func (r *Reconstructor) reconstruct(ctx context.Context, protocol, chain string, from, to uint64, fn func(snapshot *models.TVLSnapshot) error) error {
+	book := &reserveBook{
+		reserves: make(map[common.Address]*ReserveState),
+		assets:   make(map[common.Address]*models.AssetTVL),
+	}
+
+	if err := r.seed(ctx, protocol, chain, from, book); err != nil {
+		return err
+	}
+
+	return r.replay(ctx, protocol, chain, from, to, func(block uint64, timestamp time.Time, changed map[common.Address]*ReserveState, final bool) error {
+		for _, state := range changed {
+			if err := r.apply(ctx, protocol, chain, book, state); err != nil {
+				return err
+			}
+		}
+
+		snapshot := book.snapshot(protocol, chain, block, timestamp)
+		if final {
+			snapshot.Status = models.FinalityFinal
+		}
+		return fn(snapshot)
+	})
+}
+
+// seed fills the book with the reserves before from, taken from the last
+// Sync of each pair, and caches the tokens of the pairs created before it
+func (r *Reconstructor) seed(ctx context.Context, protocol, chain string, from uint64, book *reserveBook) error {
+	// A ToBlock of 0 doesn't filter, and the genesis block has no logs
+	if from <= 1 {
+		return nil
+	}
+
+	created, err := r.events.GetEvents(ctx, storage.EventFilter{
+		Chain:     chain,
+		Protocol:  protocol,
+		EventName: "PairCreated",
+		ToBlock:   from - 1,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load pairs created before block %d: %w", from, err)
+	}
+	for _, event := range created {
+		r.pairCreated(chain, event)
+	}
+
+	latest, err := r.events.GetLatestEvents(ctx, storage.EventFilter{
+		Chain:     chain,
+		Protocol:  protocol,
+		EventName: "Sync",
+		ToBlock:   from - 1,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load reserves before block %d: %w", from, err)
+	}
+	for _, event := range latest {
+		state, ok := syncState(event)
+		if !ok {
+			continue
+		}
+		if err := r.apply(ctx, protocol, chain, book, state); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apply replaces the reserves of a pair in the book and revalues its tokens
+func (r *Reconstructor) apply(ctx context.Context, protocol, chain string, book *reserveBook, state *ReserveState) error {
+	tokens, err := r.pairTokens(ctx, protocol, chain, state.Pair)
+	if err != nil {
+		return fmt.Errorf("failed to resolve the tokens of pair %s: %w", state.Pair.Hex(), err)
+	}
+
+	var previous0, previous1 *big.Int
+	if previous, ok := book.reserves[state.Pair]; ok {
+		previous0, previous1 = previous.Reserve0, previous.Reserve1
+	}
+	book.reserves[state.Pair] = state
+
+	r.addAsset(ctx, book, chain, tokens.token0, state.Reserve0, previous0)
+	r.addAsset(ctx, book, chain, tokens.token1, state.Reserve1, previous1)
+	return nil
+}
+
+// addAsset replaces a pair's previous amount of a token in the book, if
+// any, with its new one
+func (r *Reconstructor) addAsset(ctx context.Context, book *reserveBook, chain string, token common.Address, amount, previous *big.Int) {
+	asset, ok := book.assets[token]
+	if !ok {
+		info := r.tokenInfo(ctx, chain, token)
+		asset = &models.AssetTVL{
+			Token:    token,
+			Symbol:   info.symbol,
+			Amount:   big.NewInt(0),
+			Decimals: info.decimals,
+			PriceUSD: info.price,
+		}
+		book.assets[token] = asset
+	}
+
+	asset.Amount.Add(asset.Amount, amount)
+	if previous != nil {
+		asset.Amount.Sub(asset.Amount, previous)
+	}
+	asset.ValueUSD = tokenValue(asset.Amount, asset.Decimals, asset.PriceUSD)
+}
+
+// snapshot copies the tokens of the book into a snapshot
+func (b *reserveBook) snapshot(protocol, chain string, block uint64, timestamp time.Time) *models.TVLSnapshot {
+	snapshot := &models.TVLSnapshot{
+		Protocol:    protocol,
+		Chain:       chain,
+		BlockNumber: block,
+		TotalUSD:    big.NewFloat(0),
+		Breakdown:   make(map[string]*models.AssetTVL, len(b.assets)),
+		Status:      models.FinalityTentative,
+		Timestamp:   timestamp,
+		Source:      SourceReconstructed,
+	}
+
+	for token, asset := range b.assets {
+		copied := *asset
+		copied.Amount = new(big.Int).Set(asset.Amount)
+		snapshot.Breakdown[token.Hex()] = &copied
+		snapshot.TotalUSD.Add(snapshot.TotalUSD, asset.ValueUSD)
+	}
+
+	if total, _ := snapshot.TotalUSD.Float64(); total > 0 {
+		for _, asset := range snapshot.Breakdown {
+			value, _ := asset.ValueUSD.Float64()
+			asset.Percentage = value / total * 100
+		}
+	}
+
+	return snapshot
+}
+
+// replay walks the protocol's Sync events in [from, to] in chain order and
+// calls fn once per block with the pairs whose reserves changed in it.
+// final reports whether all of the block's events are final.
+func (r *Reconstructor) replay(ctx context.Context, protocol, chain string, from, to uint64, fn func(block uint64, timestamp time.Time, changed map[common.Address]*ReserveState, final bool) error) error {
+	var (
+		current   uint64
+		timestamp time.Time
+		final     = true
+		changed   = make(map[common.Address]*ReserveState)
+	)
+
+	flush := func() error {
+		if len(changed) == 0 {
+			return nil
+		}
+		err := fn(current, timestamp, changed, final)
+		changed = make(map[common.Address]*ReserveState)
+		final = true
+		return err
+	}
+
+	for start := from; start <= to; start += replayChunkBlocks {
+		end := start + replayChunkBlocks - 1
+		if end > to || end < start {
+			end = to
+		}
+
+		events, err := r.events.GetEvents(ctx, storage.EventFilter{
+			Chain:     chain,
+			Protocol:  protocol,
+			FromBlock: start,
+			ToBlock:   end,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to load events %d-%d: %w", start, end, err)
+		}
+
+		for _, event := range events {
+			switch event.EventName {
+			case "PairCreated":
+				r.pairCreated(chain, event)
+
+			case "Sync":
+				state, ok := syncState(event)
+				if !ok {
+					continue
+				}
+
+				if event.BlockNumber != current {
+					if err := flush(); err != nil {
+						return err
+					}
+					current = event.BlockNumber
+					timestamp = event.Timestamp
+				}
+				if event.Status != models.FinalityFinal {
+					final = false
+				}
+
+				// The last Sync of a block holds the pair's reserves after it
+				changed[event.Address] = state
+			}
+		}
+
+		if end == to {
+			break
+		}
+	}
+
+	return flush()
+}
+
+// pairCreated caches the tokens of a PairCreated event's pair
+func (r *Reconstructor) pairCreated(chain string, event *models.Event) {
+	pair, ok1 := eventAddress(event.Data["pair"])
+	token0, ok2 := eventAddress(event.Data["token0"])
+	token1, ok3 := eventAddress(event.Data["token1"])
+	if ok1 && ok2 && ok3 {
+		r.cachePair(chain+":"+pair.Hex(), &pairTokens{token0: token0, token1: token1})
+	}
+}
+
+// syncState reads the reserves of a Sync event
+func syncState(event *models.Event) (*ReserveState, bool) {
+	reserve0, ok0 := eventInt(event.Data["reserve0"])
+	reserve1, ok1 := eventInt(event.Data["reserve1"])
+	if !ok0 || !ok1 {
+		return nil, false
+	}
+
+	return &ReserveState{
+		Pair:        event.Address,
+		BlockNumber: event.BlockNumber,
+		Timestamp:   event.Timestamp,
+		Reserve0:    reserve0,
+		Reserve1:    reserve1,
+	}, true
+}
+
+// pairTokens resolves the tokens of a pair from PairCreated events seen so
+// far, the protocol configuration, or token0()/token1() on the pair, which
+// never change and need no archive state
+func (r *Reconstructor) pairTokens(ctx context.Context, protocol, chain string, pair common.Address) (*pairTokens, error) {
+	key := chain + ":" + pair.Hex()
+	r.mu.Lock()
+	tokens, ok := r.pairs[key]
+	r.mu.Unlock()
+	if ok {
+		return tokens, nil
+	}
+
+	r.calculator.mu.RLock()
+	config, registered := r.calculator.protocols[protocol]
+	r.calculator.mu.RUnlock()
+
+	if registered {
+		for _, contract := range config.Chains[chain] {
+			if contract.Address == pair && len(contract.Tokens) == 2 {
+				tokens := &pairTokens{token0: contract.Tokens[0], token1: contract.Tokens[1]}
+				r.cachePair(key, tokens)
+				return tokens, nil
+			}
+		}
+	}
+
+	client, err := r.calculator.manager.GetClient(chain)
+	if err != nil {
+		return nil, err
+	}
+
+	const pairABI = `[{"constant":true,"inputs":[],"name":"token0","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":true,"inputs":[],"name":"token1","outputs":[{"name":"","type":"address"}],"type":"function"}]`
+
+	contract, err := blockchain.NewContract(pair, pairABI, client)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens = &pairTokens{}
+	if err := contract.Call(ctx, &tokens.token0, "token0"); err != nil {
+		return nil, err
+	}
+	if err := contract.Call(ctx, &tokens.token1, "token1"); err != nil {
+		return nil, err
+	}
+
+	r.cachePair(key, tokens)
+	return tokens, nil
+}
+
+func (r *Reconstructor) cachePair(key string, tokens *pairTokens) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pairs[key] = tokens
+}
+
+// tokenInfo returns a token's symbol, decimals and price, preferring the
+// token storage over calls to the chain
+func (r *Reconstructor) tokenInfo(ctx context.Context, chain string, token common.Address) *tokenInfo {
+	key := chain + ":" + token.Hex()
+	r.mu.Lock()
+	info, ok := r.tokens[key]
+	r.mu.Unlock()
+	if ok {
+		return info
+	}
+
+	info = &tokenInfo{}
+	if stored, err := r.calculator.storage.GetToken(ctx, token.Hex(), chain); err == nil && stored != nil {
+		info.symbol = stored.Symbol
+		info.decimals = stored.Decimals
+	}
+
+	if info.decimals == 0 {
+		if client, err := r.calculator.manager.GetClient(chain); err == nil {
+			basicContract := &blockchain.Contract{Client: client}
+			info.symbol, _ = basicContract.GetSymbol(ctx, token)
+			info.decimals, _ = basicContract.GetDecimals(ctx, token)
+		}
+	}
+	if info.decimals == 0 {
+		info.decimals = 18 // Default
+	}
+
+	price, err := r.calculator.priceOracle.GetTokenPrice(ctx, token.Hex())
+	if err != nil || price == nil {
+		price = big.NewFloat(0)
+	}
+	info.price = price
+
+	r.mu.Lock()
+	r.tokens[key] = info
+	r.mu.Unlock()
+	return info
+}
+
+// tokenValue converts a raw token amount into USD
+func tokenValue(amount *big.Int, decimals uint8, price *big.Float) *big.Float {
+	divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	value := new(big.Float).SetInt(amount)
+	value.Mul(value, price)
+	value.Quo(value, new(big.Float).SetInt(divisor))
+	return value
+}
+
+// eventAddress reads an address from decoded event data, which holds
+// common.Address values before storage and hex strings after a round trip
+func eventAddress(value interface{}) (common.Address, bool) {
+	switch v := value.(type) {
+	case common.Address:
+		return v, true
+	case string:
+		if common.IsHexAddress(v) {
+			return common.HexToAddress(v), true
+		}
+	}
+	return common.Address{}, false
+}
+
+// eventInt reads an integer stored as a decimal string in event data
+func eventInt(value interface{}) (*big.Int, bool) {
+	switch v := value.(type) {
+	case string:
+		return new(big.Int).SetString(strings.TrimSpace(v), 10)
+	case *big.Int:
+		return v, v != nil
+	case float64:
+		n, _ := big.NewFloat(v).Int(nil)
+		return n, true
+	}
+	return nil, false
+}
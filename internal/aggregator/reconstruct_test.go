@@ -0,0 +1,245 @@
+// internal/aggregator/reconstruct_test.go
+package aggregator
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/blockchain"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/memory"
+)
+
+var (
+	weth = common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	usdc = common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	pair = common.HexToAddress("0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc")
+)
+
+func ether(n int64) string {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e18)).String()
+}
+
+func dollars(n int64) string {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e6)).String()
+}
+
+func syncEvent(block uint64, index uint, eth, usd int64, status models.FinalityStatus) *models.Event {
+	return &models.Event{
+		Chain:       "ethereum",
+		Protocol:    "dex",
+		BlockNumber: block,
+		// One transaction per block
+		TransactionHash: common.BigToHash(new(big.Int).SetUint64(block)).Hex(),
+		LogIndex:        index,
+		Address:         pair,
+		EventName:       "Sync",
+		Data:            models.EventData{"reserve0": ether(eth), "reserve1": dollars(usd)},
+		Status:          status,
+		Timestamp:       time.Unix(int64(block)*12, 0).UTC(),
+	}
+}
+
+// newReconstructor replays a WETH/USDC pair at 2000 USDC per ETH, without a
+// chain to call
+func newReconstructor(t *testing.T) *Reconstructor {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.NewMemoryStorage()
+
+	created := &models.Event{
+		Chain:           "ethereum",
+		Protocol:        "dex",
+		BlockNumber:     10,
+		TransactionHash: common.BigToHash(big.NewInt(10)).Hex(),
+		EventName:       "PairCreated",
+		Data:            models.EventData{"pair": pair.Hex(), "token0": weth.Hex(), "token1": usdc.Hex()},
+		Status:          models.FinalityFinal,
+	}
+	err := store.SaveEvents(ctx, []*models.Event{
+		created,
+		syncEvent(11, 0, 1, 2000, models.FinalityFinal),
+		syncEvent(12, 0, 2, 4000, models.FinalityTentative),
+		syncEvent(12, 1, 3, 6000, models.FinalityTentative),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []*models.Token{
+		{Address: weth, Chain: "ethereum", Symbol: "WETH", Decimals: 18},
+		{Address: usdc, Chain: "ethereum", Symbol: "USDC", Decimals: 6},
+	} {
+		if err := store.SaveToken(ctx, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oracle := NewPriceOracle()
+	oracle.SetMockPrice("ETH", 2000)
+	oracle.SetMockPrice("USDC", 1)
+
+	return NewReconstructor(NewTVLCalculator(blockchain.NewManager(), oracle, store), store)
+}
+
+func TestReconstruct(t *testing.T) {
+	ctx := context.Background()
+	r := newReconstructor(t)
+
+	snapshots, err := r.Reconstruct(ctx, "dex", "ethereum", 0, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("Reconstruct made %d snapshots, want 2", len(snapshots))
+	}
+
+	want := []struct {
+		block  uint64
+		total  string
+		status models.FinalityStatus
+	}{
+		{11, "4000.00", models.FinalityFinal},
+		// The last Sync of a block wins
+		{12, "12000.00", models.FinalityTentative},
+	}
+	for i, snapshot := range snapshots {
+		if snapshot.BlockNumber != want[i].block || snapshot.TotalUSD.Text('f', 2) != want[i].total ||
+			snapshot.Status != want[i].status {
+			t.Errorf("snapshot %d = block %d, $%s, %s; want block %d, $%s, %s", i,
+				snapshot.BlockNumber, snapshot.TotalUSD.Text('f', 2), snapshot.Status,
+				want[i].block, want[i].total, want[i].status)
+		}
+		if snapshot.Source != SourceReconstructed {
+			t.Errorf("snapshot %d has source %q, want %q", i, snapshot.Source, SourceReconstructed)
+		}
+		if amount := snapshot.Breakdown[weth.Hex()].Amount.String(); i == 1 && amount != ether(3) {
+			t.Errorf("WETH held at block 12 = %s, want %s", amount, ether(3))
+		}
+	}
+
+}
+
+// recordedEvents records the filters events are loaded with
+type recordedEvents struct {
+	storage.EventStorage
+	filters []storage.EventFilter
+}
+
+func (e *recordedEvents) GetEvents(ctx context.Context, filter storage.EventFilter) ([]*models.Event, error) {
+	e.filters = append(e.filters, filter)
+	return e.EventStorage.GetEvents(ctx, filter)
+}
+
+func TestReconstructFromLastSync(t *testing.T) {
+	ctx := context.Background()
+	r := newReconstructor(t)
+
+	// Another pair of the same tokens changes after the first one
+	second := common.HexToAddress("0x397FF1542f962076d0BFE58eA045FfA2d347ACa0")
+	created := &models.Event{
+		Chain:           "ethereum",
+		Protocol:        "dex",
+		BlockNumber:     13,
+		TransactionHash: common.BigToHash(big.NewInt(13)).Hex(),
+		EventName:       "PairCreated",
+		Data:            models.EventData{"pair": second.Hex(), "token0": weth.Hex(), "token1": usdc.Hex()},
+	}
+	sync := syncEvent(14, 0, 1, 2000, models.FinalityTentative)
+	sync.Address = second
+	if err := r.events.SaveEvents(ctx, []*models.Event{created, sync}); err != nil {
+		t.Fatal(err)
+	}
+
+	events := &recordedEvents{EventStorage: r.events}
+	r = NewReconstructor(r.calculator, events)
+
+	snapshots, err := r.Reconstruct(ctx, "dex", "ethereum", 12, 14)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Block 12 starts from the reserves of block 11, and block 14 adds the
+	// second pair to the unchanged first one
+	if len(snapshots) != 2 || snapshots[0].TotalUSD.Text('f', 2) != "12000.00" ||
+		snapshots[1].TotalUSD.Text('f', 2) != "16000.00" {
+		t.Fatalf("Reconstruct(12-14) = %d snapshots, want $12000 at block 12 and $16000 at block 14", len(snapshots))
+	}
+	if amount := snapshots[1].Breakdown[weth.Hex()].Amount.String(); amount != ether(4) {
+		t.Errorf("WETH held at block 14 = %s, want %s", amount, ether(4))
+	}
+	if amount := snapshots[0].Breakdown[weth.Hex()].Amount.String(); amount != ether(3) {
+		t.Errorf("WETH held at block 12 changed to %s by a later block, want %s", amount, ether(3))
+	}
+
+	// Only pair creations are loaded from before the range
+	for _, filter := range events.filters {
+		if filter.FromBlock < 12 && filter.EventName != "PairCreated" {
+			t.Errorf("Reconstruct(12-14) loaded events with %+v", filter)
+		}
+	}
+}
+
+func TestReconstructUnknownPair(t *testing.T) {
+	ctx := context.Background()
+	r := newReconstructor(t)
+
+	// No PairCreated, no configuration and no chain to ask
+	unknown := syncEvent(13, 0, 1, 2000, models.FinalityTentative)
+	unknown.Address = common.HexToAddress("0x397FF1542f962076d0BFE58eA045FfA2d347ACa0")
+	if err := r.events.SaveEvents(ctx, []*models.Event{unknown}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Reconstruct(ctx, "dex", "ethereum", 0, 20); err == nil {
+		t.Error("Reconstruct of an unresolvable pair succeeded")
+	}
+	if _, err := r.Reconstruct(ctx, "dex", "ethereum", 14, 20); err == nil {
+		t.Error("Reconstruct after the Sync of an unresolvable pair succeeded")
+	}
+}
+
+func TestReconstructAndSave(t *testing.T) {
+	ctx := context.Background()
+	r := newReconstructor(t)
+
+	saved, err := r.ReconstructAndSave(ctx, "dex", "ethereum", 0, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved != 2 {
+		t.Fatalf("ReconstructAndSave saved %d snapshots, want 2", saved)
+	}
+
+	history, err := r.calculator.storage.GetTVLHistory(ctx, storage.TVLFilter{Protocol: "dex", Chain: "ethereum"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[1].BlockNumber != 12 || history[1].Source != SourceReconstructed {
+		t.Errorf("saved history = %d snapshots, want the reconstructed ones of blocks 11 and 12", len(history))
+	}
+}
+
+func TestReconstructConcurrently(t *testing.T) {
+	ctx := context.Background()
+	r := newReconstructor(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := r.ReserveHistory(ctx, "dex", "ethereum", pair, 0, 20); err != nil {
+				t.Error(err)
+			}
+			if _, err := r.Reconstruct(ctx, "dex", "ethereum", 0, 20); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"sort"
 	"sync"
 	"time"
 
@@ -13,6 +14,10 @@ import (
 	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
 )
 
+// defaultConfirmations is how deep a block has to be to count as final on
+// chains without the "finalized" tag or confirmations of their own
+const defaultConfirmations = 12
+
 type TVLCalculator struct {
 	manager     *blockchain.Manager
 	priceOracle *PriceOracle
@@ -45,6 +50,30 @@ func (tc *TVLCalculator) RegisterProtocol(protocol *models.Protocol) {
 	fmt.Printf("Registered protocol: %s\n", protocol.Name)
 }
 
+// UnregisterProtocol stops calculating a protocol's TVL
+func (tc *TVLCalculator) UnregisterProtocol(name string) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if _, ok := tc.protocols[name]; ok {
+		delete(tc.protocols, name)
+		fmt.Printf("Unregistered protocol: %s\n", name)
+	}
+}
+
+// Protocols lists the registered protocols by name
+func (tc *TVLCalculator) Protocols() []*models.Protocol {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+	protocols := make([]*models.Protocol, 0, len(tc.protocols))
+	for _, protocol := range tc.protocols {
+		protocols = append(protocols, protocol)
+	}
+	sort.Slice(protocols, func(i, j int) bool {
+		return protocols[i].Name < protocols[j].Name
+	})
+	return protocols
+}
+
 func (tc *TVLCalculator) CalculateTVL(ctx context.Context, protocolName string) (*models.TVLData, error) {
 	tc.mu.RLock()
 	protocol, exists := tc.protocols[protocolName]
@@ -105,47 +134,154 @@ func (tc *TVLCalculator) CalculateTVL(ctx context.Context, protocolName string)
 		tc.cache.Set(cacheKey, tvlData, 1*time.Minute)
 	}
 
-	// Save snapshot
-	snapshot := &models.TVLSnapshot{
-		Protocol:  protocolName,
-		Timestamp: tvlData.Timestamp,
-		TotalUSD:  tvlData.TotalUSD,
+	// Snapshots saved while still reorgable may be final by now
+	finalized := make(map[string]uint64, len(tvlData.Chains))
+	for chainName := range tvlData.Chains {
+		block, err := tc.finalizedBlock(ctx, chainName)
+		if err != nil {
+			fmt.Printf("Failed to get the finalized block of %s: %v\n", chainName, err)
+			continue
+		}
+		finalized[chainName] = block
 	}
 
-	if err := tc.storage.SaveTVLSnapshot(ctx, snapshot); err != nil {
+	// Save a snapshot per chain with its holdings, all chains or none.
+	// Balances are read at the latest block, so the snapshots can still be reorged.
+	err := storage.InTx(ctx, tc.storage, func(tx storage.Tx) error {
+		for chainName, chainTVL := range tvlData.Chains {
+			snapshot := &models.TVLSnapshot{
+				Protocol:    protocolName,
+				Chain:       chainName,
+				BlockNumber: chainTVL.BlockNumber,
+				TotalUSD:    chainTVL.TotalUSD,
+				Breakdown:   breakdown(chainTVL),
+				Status:      models.FinalityTentative,
+				Timestamp:   tvlData.Timestamp,
+			}
+			if err := tx.SaveTVLSnapshot(ctx, snapshot); err != nil {
+				return fmt.Errorf("failed to save TVL snapshot on %s: %w", chainName, err)
+			}
+
+			if block, ok := finalized[chainName]; ok {
+				if err := tx.FinalizeTVL(ctx, chainName, block); err != nil {
+					return fmt.Errorf("failed to finalize TVL snapshots on %s: %w", chainName, err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		// Log but don't fail
-		fmt.Printf("Failed to save TVL snapshot: %v\n", err)
+		fmt.Printf("Failed to save TVL snapshots of %s: %v\n", protocolName, err)
 	}
 
 	return tvlData, nil
 }
 
+// finalizedBlock returns the highest block of a chain that can no longer be
+// reorged. Chains without the "finalized" tag fall back to latest minus
+// confirmations.
+func (tc *TVLCalculator) finalizedBlock(ctx context.Context, chain string) (uint64, error) {
+	client, err := tc.manager.GetClient(chain)
+	if err != nil {
+		return 0, err
+	}
+	if finalized, err := client.GetFinalizedBlockNumber(ctx); err == nil {
+		return finalized, nil
+	}
+
+	config, err := tc.manager.GetChainConfig(chain)
+	if err != nil {
+		return 0, err
+	}
+	currentBlock, err := client.GetBlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	confirmations := config.Confirmations
+	if confirmations == 0 {
+		confirmations = defaultConfirmations
+	}
+	if currentBlock > confirmations {
+		return currentBlock - confirmations, nil
+	}
+	return 0, nil
+}
+
+// breakdown keys the assets of a chain by token address, adding up a token
+// held by several contracts, and fills in their price and share of the
+// chain's TVL
+func breakdown(chainTVL *models.ChainTVL) map[string]*models.AssetTVL {
+	assets := make(map[string]*models.AssetTVL, len(chainTVL.Assets))
+	for _, asset := range chainTVL.Assets {
+		key := asset.Token.Hex()
+		held, ok := assets[key]
+		if !ok {
+			held = &models.AssetTVL{
+				Token:    asset.Token,
+				Symbol:   asset.Symbol,
+				Name:     asset.Name,
+				Amount:   big.NewInt(0),
+				Decimals: asset.Decimals,
+				PriceUSD: asset.PriceUSD,
+				ValueUSD: big.NewFloat(0),
+			}
+			assets[key] = held
+		}
+
+		if asset.Amount != nil {
+			held.Amount.Add(held.Amount, asset.Amount)
+		}
+		if asset.ValueUSD != nil {
+			held.ValueUSD.Add(held.ValueUSD, asset.ValueUSD)
+		}
+	}
+
+	total, _ := chainTVL.TotalUSD.Float64()
+	for _, asset := range assets {
+		// Most calculators only report the value, the price follows from it
+		if asset.PriceUSD == nil && asset.Amount.Sign() > 0 {
+			units := new(big.Float).SetInt(asset.Amount)
+			units.Quo(units, new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(asset.Decimals)), nil)))
+			asset.PriceUSD = new(big.Float).Quo(asset.ValueUSD, units)
+		}
+		if total > 0 {
+			value, _ := asset.ValueUSD.Float64()
+			asset.Percentage = value / total * 100
+		}
+	}
+
+	return assets
+}
+
 func (tc *TVLCalculator) calculateChainTVL(ctx context.Context, protocol *models.Protocol, chain string, contracts []models.ContractConfig) (*models.ChainTVL, error) {
 	client, err := tc.manager.GetClient(chain)
 	if err != nil {
 		return nil, err
 	}
 
+	// The block the balances are read around, to tell snapshots apart
+	blockNumber, err := client.GetBlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	chainTVL := &models.ChainTVL{
-		Chain:    chain,
-		Assets:   make([]*models.AssetTVL, 0),
-		TotalUSD: big.NewFloat(0),
+		Chain:       chain,
+		BlockNumber: blockNumber,
+		Assets:      make([]*models.AssetTVL, 0),
+		TotalUSD:    big.NewFloat(0),
 	}
 
 	for _, contract := range contracts {
-		var assetTVLs []*models.AssetTVL
-
-		switch protocol.Type {
-		case "lending":
-			assetTVLs, err = tc.calculateLendingTVL(ctx, client, contract)
-		case "dex":
-			assetTVLs, err = tc.calculateDexTVL(ctx, client, contract)
-		case "yield":
-			assetTVLs, err = tc.calculateYieldTVL(ctx, client, contract)
-		default:
-			assetTVLs, err = tc.calculateGenericTVL(ctx, client, contract)
+		calculate, err := contractAdapter(protocol, contract)
+		if err != nil {
+			fmt.Printf("Warning: Failed to calculate TVL for %s on %s: %v\n", contract.Address, chain, err)
+			continue
 		}
 
+		assetTVLs, err := calculate(tc, ctx, client, contract)
 		if err != nil {
 			fmt.Printf("Warning: Failed to calculate TVL for %s on %s: %v\n", contract.Address, chain, err)
 			continue
@@ -0,0 +1,108 @@
+// internal/api/export.go
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/export"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+)
+
+// exportWriteTimeout is how long a write of an export may take. Exports run
+// past the server's write timeout and the request timeout for as long as the
+// client keeps reading.
+const exportWriteTimeout = time.Minute
+
+// streamWriter extends the write deadline of a response before each write
+type streamWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	written int64
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	s.rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	n, err := s.w.Write(p)
+	s.written += int64(n)
+	return n, err
+}
+
+// GET /api/v1/export
+func (h *Handler) ExportTVL(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := export.FormatCSV
+	if value := query.Get("format"); value != "" {
+		parsed, err := export.ParseFormat(value)
+		if err != nil {
+			h.sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		format = parsed
+	}
+
+	options := export.Options{
+		Protocol:   query.Get("protocol"),
+		Chain:      query.Get("chain"),
+		Resolution: models.Resolution(query.Get("resolution")),
+	}
+	switch options.Resolution {
+	case "", models.ResolutionRaw, models.ResolutionHourly, models.ResolutionDaily:
+	default:
+		h.sendError(w, fmt.Sprintf("invalid resolution: %s", options.Resolution), http.StatusBadRequest)
+		return
+	}
+
+	for name, target := range map[string]*time.Time{
+		"from": &options.From,
+		"to":   &options.To,
+	} {
+		if value := query.Get(name); value != "" {
+			parsed, err := export.ParseTime(value)
+			if err != nil {
+				h.sendError(w, fmt.Sprintf("invalid %s: %v", name, err), http.StatusBadRequest)
+				return
+			}
+			*target = parsed
+		}
+	}
+
+	if value := query.Get("breakdown"); value != "" {
+		breakdown, err := strconv.ParseBool(value)
+		if err != nil {
+			h.sendError(w, fmt.Sprintf("invalid breakdown: %s", value), http.StatusBadRequest)
+			return
+		}
+		options.Breakdown = breakdown
+	}
+
+	name := options.Protocol
+	if name == "" {
+		name = "all"
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+		fmt.Sprintf("tvl-%s-%s.%s", name, time.Now().UTC().Format("20060102"), format)))
+
+	// A client that goes away fails the next write, which ends the export
+	ctx := context.WithoutCancel(r.Context())
+	out := &streamWriter{w: w, rc: http.NewResponseController(w)}
+
+	result, err := export.Export(ctx, h.storage, out, format, options)
+	if err != nil {
+		if out.written == 0 {
+			w.Header().Del("Content-Disposition")
+			h.sendError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Export failed after %d bytes: %v", out.written, err)
+		return
+	}
+
+	log.Printf("Exported %d snapshots in %d %s rows", result.Snapshots, result.Rows, format)
+}
@@ -0,0 +1,100 @@
+// internal/api/export_test.go
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/memory"
+)
+
+func TestExportTVL(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStorage()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, protocol := range []string{"aave-v3", "aave-v3", "aave-v3", "uniswap-v2"} {
+		err := store.SaveTVLSnapshot(ctx, &models.TVLSnapshot{
+			Protocol:    protocol,
+			Chain:       "ethereum",
+			BlockNumber: uint64(19000000 + i),
+			TotalUSD:    big.NewFloat(float64(100 * (i + 1))),
+			Breakdown: map[string]*models.AssetTVL{
+				"usdc": {Token: common.BigToAddress(big.NewInt(1)), Symbol: "USDC", Amount: big.NewInt(1), Decimals: 6},
+				"weth": {Token: common.BigToAddress(big.NewInt(2)), Symbol: "WETH", Amount: big.NewInt(2), Decimals: 18},
+			},
+			Status: models.FinalityFinal,
+			// A day apart, the last aave-v3 one past the exported range
+			Timestamp: start.Add(time.Duration(i) * 24 * time.Hour),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := NewHandler(nil, store)
+
+	tests := []struct {
+		name        string
+		query       string
+		status      int
+		contentType string
+		lines       int
+	}{
+		{"csv by default", "protocol=aave-v3&from=2024-01-01&to=2024-01-02", http.StatusOK, "text/csv", 3},
+		{"breakdown", "protocol=aave-v3&from=2024-01-01&to=2024-01-02&breakdown=true", http.StatusOK, "text/csv", 5},
+		{"ndjson", "format=jsonl&protocol=aave-v3&to=2024-01-02T00:00:00Z", http.StatusOK, "application/x-ndjson", 2},
+		{"every protocol", "format=ndjson&from=2024-01-01&to=2024-01-31", http.StatusOK, "application/x-ndjson", 4},
+		{"parquet", "format=parquet&protocol=aave-v3&to=2024-01-02", http.StatusOK, "application/vnd.apache.parquet", -1},
+		{"empty range", "format=ndjson&from=2023-01-01&to=2023-01-31", http.StatusOK, "application/x-ndjson", 0},
+		{"unknown format", "format=xlsx", http.StatusBadRequest, "application/json", -1},
+		{"invalid resolution", "resolution=weekly", http.StatusBadRequest, "application/json", -1},
+		{"invalid from", "from=yesterday", http.StatusBadRequest, "application/json", -1},
+		{"invalid breakdown", "breakdown=maybe", http.StatusBadRequest, "application/json", -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ExportTVL(w, httptest.NewRequest(http.MethodGet, "/api/v1/export?"+test.query, nil))
+
+			if w.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, test.status, w.Body.String())
+			}
+			if got := w.Header().Get("Content-Type"); got != test.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, test.contentType)
+			}
+			disposition := w.Header().Get("Content-Disposition")
+			if test.status == http.StatusOK && !strings.HasPrefix(disposition, "attachment; filename=\"tvl-") {
+				t.Errorf("Content-Disposition = %q, want an attachment", disposition)
+			}
+			if test.status != http.StatusOK && disposition != "" {
+				t.Errorf("Content-Disposition of an error = %q, want none", disposition)
+			}
+
+			if test.lines < 0 {
+				return
+			}
+			lines := 0
+			if test.contentType == "text/csv" {
+				records, err := csv.NewReader(w.Body).ReadAll()
+				if err != nil {
+					t.Fatal(err)
+				}
+				lines = len(records)
+			} else if body := strings.TrimSpace(w.Body.String()); body != "" {
+				lines = len(strings.Split(body, "\n"))
+			}
+			if lines != test.lines {
+				t.Errorf("%d lines, want %d:\n%s", lines, test.lines, w.Body.String())
+			}
+		})
+	}
+}
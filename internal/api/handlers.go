@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"math/big"
 	"net/http"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -15,9 +17,10 @@ import (
 )
 
 type Handler struct {
-	calculator *aggregator.TVLCalculator
-	storage    storage.Storage
-	cache      Cache
+	calculator  *aggregator.TVLCalculator
+	storage     storage.Storage
+	cache       Cache
+	indexerURLs []string
 }
 
 type Cache interface {
@@ -139,10 +142,145 @@ func (h *Handler) GetHistoricalTVL(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
 	chain := r.URL.Query().Get("chain")
 	period := r.URL.Query().Get("period") // 1h, 24h, 7d, 30d
+	finalizedOnly := r.URL.Query().Get("finalized") == "true"
+
+	from, to := periodRange(period)
+
+	// Get historical data, at the resolution the period calls for unless
+	// one is asked for
+	var snapshots []*models.TVLSnapshot
+	var err error
+	resolution := models.Resolution(r.URL.Query().Get("resolution"))
+	switch resolution {
+	case "":
+		resolution = storage.HistoryResolution(from, to)
+		snapshots, err = h.storage.GetHistoricalTVL(ctx, protocol, chain, from, to)
+	case models.ResolutionRaw, models.ResolutionHourly, models.ResolutionDaily:
+		snapshots, err = h.storage.GetTVLHistory(ctx, storage.TVLFilter{
+			Protocol:   protocol,
+			Chain:      chain,
+			Resolution: resolution,
+			From:       from,
+			To:         to,
+		})
+	default:
+		h.sendError(w, fmt.Sprintf("invalid resolution: %s", resolution), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	// Calculate time range based on period
+	// Format response
+	history := make([]map[string]interface{}, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		if finalizedOnly && snapshot.Status != models.FinalityFinal {
+			continue
+		}
+
+		pointResolution := snapshot.Resolution
+		if pointResolution == "" {
+			pointResolution = models.ResolutionRaw
+		}
+
+		tvlFloat, _ := snapshot.TotalUSD.Float64()
+		point := map[string]interface{}{
+			"timestamp":  snapshot.Timestamp,
+			"tvl":        tvlFloat,
+			"status":     snapshot.Status,
+			"resolution": pointResolution,
+		}
+		if snapshot.Source != "" {
+			point["source"] = snapshot.Source
+		}
+		if snapshot.Range != nil {
+			for name, value := range map[string]*big.Float{
+				"open":  snapshot.Range.Open,
+				"high":  snapshot.Range.High,
+				"low":   snapshot.Range.Low,
+				"close": snapshot.Range.Close,
+				"avg":   snapshot.Range.Avg,
+			} {
+				if value != nil {
+					point[name], _ = value.Float64()
+				}
+			}
+			point["samples"] = snapshot.Range.Samples
+		}
+		history = append(history, point)
+	}
+
+	response := map[string]interface{}{
+		"protocol":   protocol,
+		"chain":      chain,
+		"period":     period,
+		"resolution": resolution,
+		"finalized":  finalizedOnly,
+		"history":    history,
+	}
+
+	h.sendJSON(w, response)
+}
+
+// GET /api/v1/tvl/{protocol}/holdings
+func (h *Handler) GetTokenHoldings(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	period := query.Get("period")
+	from, to := periodRange(period)
+
+	filter := storage.HoldingFilter{
+		TVLFilter: storage.TVLFilter{
+			Protocol:   mux.Vars(r)["protocol"],
+			Chain:      query.Get("chain"),
+			Resolution: models.Resolution(query.Get("resolution")),
+			From:       from,
+			To:         to,
+		},
+		Token:  query.Get("token"),
+		Symbol: query.Get("symbol"),
+	}
+
+	switch filter.Resolution {
+	case "", models.ResolutionRaw, models.ResolutionHourly, models.ResolutionDaily:
+	default:
+		h.sendError(w, fmt.Sprintf("invalid resolution: %s", filter.Resolution), http.StatusBadRequest)
+		return
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			h.sendError(w, fmt.Sprintf("invalid limit: %s", value), http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+	if filter.Limit == 0 || filter.Limit > 1000 {
+		filter.Limit = 1000
+	}
+
+	holdings, err := h.storage.GetTokenHoldings(r.Context(), filter)
+	if err != nil {
+		h.sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if holdings == nil {
+		holdings = []*models.TokenHolding{}
+	}
+
+	h.sendJSON(w, map[string]interface{}{
+		"protocol": filter.Protocol,
+		"chain":    filter.Chain,
+		"period":   period,
+		"holdings": holdings,
+	})
+}
+
+// periodRange returns the window a history period of 1h, 24h, 7d or 30d
+// ends now with, 24h by default
+func periodRange(period string) (time.Time, time.Time) {
 	to := time.Now()
-	from := to.Add(-24 * time.Hour) // Default 24h
+	from := to.Add(-24 * time.Hour)
 
 	switch period {
 	case "1h":
@@ -155,28 +293,69 @@ func (h *Handler) GetHistoricalTVL(w http.ResponseWriter, r *http.Request) {
 		from = to.Add(-30 * 24 * time.Hour)
 	}
 
-	// Get historical data
-	snapshots, err := h.storage.GetHistoricalTVL(ctx, protocol, chain, from, to)
+	return from, to
+}
+
+// GET /api/v1/events
+func (h *Handler) GetEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := storage.EventFilter{
+		Chain:     query.Get("chain"),
+		Protocol:  query.Get("protocol"),
+		EventName: query.Get("event"),
+		Address:   query.Get("address"),
+		Limit:     100,
+	}
+	if query.Get("finalized") == "true" {
+		filter.Status = models.FinalityFinal
+	}
+
+	for name, target := range map[string]*uint64{
+		"from_block": &filter.FromBlock,
+		"to_block":   &filter.ToBlock,
+	} {
+		if value := query.Get(name); value != "" {
+			parsed, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				h.sendError(w, fmt.Sprintf("invalid %s: %s", name, value), http.StatusBadRequest)
+				return
+			}
+			*target = parsed
+		}
+	}
+
+	for name, target := range map[string]*int{
+		"limit":  &filter.Limit,
+		"offset": &filter.Offset,
+	} {
+		if value := query.Get(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				h.sendError(w, fmt.Sprintf("invalid %s: %s", name, value), http.StatusBadRequest)
+				return
+			}
+			*target = parsed
+		}
+	}
+	if filter.Limit == 0 || filter.Limit > 1000 {
+		filter.Limit = 1000
+	}
+
+	events, err := h.storage.GetEvents(r.Context(), filter)
 	if err != nil {
 		h.sendError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-
-	// Format response
-	history := make([]map[string]interface{}, 0, len(snapshots))
-	for _, snapshot := range snapshots {
-		tvlFloat, _ := snapshot.TotalUSD.Float64()
-		history = append(history, map[string]interface{}{
-			"timestamp": snapshot.Timestamp,
-			"tvl":       tvlFloat,
-		})
+	if events == nil {
+		events = []*models.Event{}
 	}
 
 	response := map[string]interface{}{
-		"protocol": protocol,
-		"chain":    chain,
-		"period":   period,
-		"history":  history,
+		"events": events,
+		"count":  len(events),
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
 	}
 
 	h.sendJSON(w, response)
@@ -184,16 +363,30 @@ func (h *Handler) GetHistoricalTVL(w http.ResponseWriter, r *http.Request) {
 
 // GET /api/v1/protocols
 func (h *Handler) GetProtocols(w http.ResponseWriter, r *http.Request) {
-	// This would normally fetch from storage
-	// For now, return registered protocols
-	protocols := []map[string]interface{}{
-		{
-			"name":        "uniswap-v2",
-			"type":        "dex",
-			"description": "Uniswap V2 DEX",
-			"chains":      []string{"ethereum"},
-		},
-		// Add more protocols here
+	registered := h.calculator.Protocols()
+
+	protocols := make([]map[string]interface{}, 0, len(registered))
+	for _, protocol := range registered {
+		chains := make([]string, 0, len(protocol.Chains))
+		contracts := 0
+		for chain, chainContracts := range protocol.Chains {
+			chains = append(chains, chain)
+			contracts += len(chainContracts)
+		}
+		sort.Strings(chains)
+
+		entry := map[string]interface{}{
+			"name":        protocol.Name,
+			"type":        protocol.Type,
+			"description": protocol.Description,
+			"website":     protocol.Website,
+			"chains":      chains,
+			"contracts":   contracts,
+		}
+		if protocol.Adapter != "" {
+			entry["adapter"] = protocol.Adapter
+		}
+		protocols = append(protocols, entry)
 	}
 
 	response := map[string]interface{}{
@@ -225,6 +418,15 @@ func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 		"version":   "1.0.0",
 	}
 
+	// The indexers feed events and reconstructed TVL, report them as well
+	if len(h.indexerURLs) > 0 {
+		indexer := indexerHealth(h.fetchIndexerStatus(r.Context()))
+		health["indexer"] = indexer
+		if indexer != "ok" {
+			health["status"] = "degraded"
+		}
+	}
+
 	h.sendJSON(w, health)
 }
 
@@ -0,0 +1,173 @@
+// internal/api/indexer.go
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/indexer"
+)
+
+// SetIndexerURLs sets the admin servers of the indexers whose status the API
+// aggregates, e.g. http://indexer:9090
+func (h *Handler) SetIndexerURLs(urls []string) {
+	h.indexerURLs = nil
+	for _, url := range urls {
+		if url = strings.TrimRight(strings.TrimSpace(url), "/"); url != "" {
+			h.indexerURLs = append(h.indexerURLs, url)
+		}
+	}
+}
+
+// indexerReport is the status of one indexer admin server
+type indexerReport struct {
+	URL    string                `json:"url"`
+	Status string                `json:"status"` // ok, degraded or unreachable
+	Error  string                `json:"error,omitempty"`
+	Report *indexer.StatusReport `json:"-"`
+}
+
+// fetchIndexerStatus reads /status from every indexer concurrently
+func (h *Handler) fetchIndexerStatus(ctx context.Context) []*indexerReport {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	reports := make([]*indexerReport, len(h.indexerURLs))
+	var wg sync.WaitGroup
+	for i, url := range h.indexerURLs {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+
+			report := &indexerReport{URL: url, Status: "ok"}
+			reports[i] = report
+
+			status, err := getIndexerStatus(ctx, url)
+			if err != nil {
+				report.Status = "unreachable"
+				report.Error = err.Error()
+				return
+			}
+
+			report.Report = status
+			if len(status.Errors) > 0 {
+				report.Status = "degraded"
+			}
+		}(i, url)
+	}
+	wg.Wait()
+
+	return reports
+}
+
+func getIndexerStatus(ctx context.Context, url string) (*indexer.StatusReport, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/status", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("indexer returned %s", resp.Status)
+	}
+
+	var status indexer.StatusReport
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("invalid indexer status: %w", err)
+	}
+
+	return &status, nil
+}
+
+// indexerHealth summarizes the indexers as ok, degraded or unreachable
+func indexerHealth(reports []*indexerReport) string {
+	unreachable := 0
+	health := "ok"
+	for _, report := range reports {
+		switch report.Status {
+		case "unreachable":
+			unreachable++
+			health = "degraded"
+		case "degraded":
+			health = "degraded"
+		}
+	}
+
+	if unreachable == len(reports) {
+		return "unreachable"
+	}
+	return health
+}
+
+// GET /api/v1/indexer
+func (h *Handler) GetIndexerStatus(w http.ResponseWriter, r *http.Request) {
+	if len(h.indexerURLs) == 0 {
+		h.sendError(w, "No indexer configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	reports := h.fetchIndexerStatus(r.Context())
+
+	chains := []*indexer.IndexerStats{}
+	processors := []*indexer.ProcessorStats{}
+	chainErrors := make(map[string]string)
+
+	var eventsPerSecond float64
+	var eventsCount, maxLag, errorCount uint64
+	var deadLetters, failedRanges int
+	for _, report := range reports {
+		if report.Report == nil {
+			continue
+		}
+
+		for chain, err := range report.Report.Errors {
+			chainErrors[chain] = err
+		}
+		processors = append(processors, report.Report.Processors...)
+
+		for _, stats := range report.Report.Chains {
+			chains = append(chains, stats)
+
+			eventsPerSecond += stats.EventsPerSecond
+			eventsCount += stats.EventsCount
+			if stats.Lag > maxLag {
+				maxLag = stats.Lag
+			}
+			errorCount += stats.Errors.Indexing + stats.Errors.FailedBatches +
+				stats.Errors.Decode + stats.Errors.Sink
+			deadLetters += stats.DeadLetters
+			failedRanges += stats.FailedRanges
+		}
+	}
+
+	response := map[string]interface{}{
+		"status":     indexerHealth(reports),
+		"indexers":   reports,
+		"chains":     chains,
+		"processors": processors,
+		"totals": map[string]interface{}{
+			"events_per_second": eventsPerSecond,
+			"events_count":      eventsCount,
+			"max_lag":           maxLag,
+			"errors":            errorCount,
+			"dead_letters":      deadLetters,
+			"failed_ranges":     failedRanges,
+		},
+		"timestamp": time.Now(),
+	}
+	if len(chainErrors) > 0 {
+		response["errors"] = chainErrors
+	}
+
+	h.sendJSON(w, response)
+}
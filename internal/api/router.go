@@ -17,13 +17,23 @@ func NewRouter(handler *Handler) http.Handler {
 	v1.HandleFunc("/tvl", handler.GetTotalTVL).Methods("GET")
 	v1.HandleFunc("/tvl/{protocol}", handler.GetProtocolTVL).Methods("GET")
 	v1.HandleFunc("/tvl/{protocol}/history", handler.GetHistoricalTVL).Methods("GET")
+	v1.HandleFunc("/tvl/{protocol}/holdings", handler.GetTokenHoldings).Methods("GET")
+
+	// Bulk export of TVL history
+	v1.HandleFunc("/export", handler.ExportTVL).Methods("GET")
 
 	// Protocol endpoints
 	v1.HandleFunc("/protocols", handler.GetProtocols).Methods("GET")
 
+	// Indexed events
+	v1.HandleFunc("/events", handler.GetEvents).Methods("GET")
+
 	// Chain endpoints
 	v1.HandleFunc("/chains", handler.GetChains).Methods("GET")
 
+	// Indexer status, aggregated from the indexers' admin servers
+	v1.HandleFunc("/indexer", handler.GetIndexerStatus).Methods("GET")
+
 	// Stats endpoint
 	v1.HandleFunc("/stats", handler.GetStats).Methods("GET")
 
@@ -9,6 +9,7 @@ import (
     
     "github.com/ethereum/go-ethereum/common"
     "github.com/ethereum/go-ethereum/ethclient"
+    "github.com/ethereum/go-ethereum/rpc"
 )
 
 // Client represents a connection to an EVM chain
@@ -100,6 +101,24 @@ func (c *Client) GetBlockNumber(ctx context.Context) (uint64, error) {
     return c.client.BlockNumber(ctx)
 }
 
+// GetSafeBlockNumber returns the number of the block tagged "safe"
+func (c *Client) GetSafeBlockNumber(ctx context.Context) (uint64, error) {
+    header, err := c.client.HeaderByNumber(ctx, big.NewInt(int64(rpc.SafeBlockNumber)))
+    if err != nil {
+        return 0, fmt.Errorf("failed to get safe block: %w", err)
+    }
+    return header.Number.Uint64(), nil
+}
+
+// GetFinalizedBlockNumber returns the number of the block tagged "finalized"
+func (c *Client) GetFinalizedBlockNumber(ctx context.Context) (uint64, error) {
+    header, err := c.client.HeaderByNumber(ctx, big.NewInt(int64(rpc.FinalizedBlockNumber)))
+    if err != nil {
+        return 0, fmt.Errorf("failed to get finalized block: %w", err)
+    }
+    return header.Number.Uint64(), nil
+}
+
 // GetBalance returns the ETH balance of an address
 func (c *Client) GetBalance(ctx context.Context, address common.Address) (*big.Int, error) {
     return c.client.BalanceAt(ctx, address, nil)
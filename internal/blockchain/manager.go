@@ -5,19 +5,33 @@ import (
     "fmt"
     "math/big"
     "sync"
+    "time"
+)
+
+// FinalityMode selects which block a consumer treats as the chain head
+type FinalityMode string
+
+const (
+    FinalityLatest    FinalityMode = "latest"    // latest block minus Confirmations
+    FinalitySafe      FinalityMode = "safe"      // the node's "safe" block tag
+    FinalityFinalized FinalityMode = "finalized" // the node's "finalized" block tag
 )
 
 // ChainConfig represents configuration for a blockchain
 type ChainConfig struct {
-    Name      string
-    ChainID   *big.Int
-    RPCURL    string
-    WSURL     string
-    Explorer  string
-    NativeToken string
+    Name          string
+    ChainID       *big.Int
+    RPCURL        string
+    WSURL         string
+    Explorer      string
+    NativeToken   string
+    BlockTime     time.Duration
+    Finality      FinalityMode // empty means FinalityLatest
+    Confirmations uint64       // only used with FinalityLatest, 0 means the consumer's default
 }
 
-// Manager manages multiple blockchain clients
+// Manager manages multiple blockchain clients. Chains can be added, updated
+// and removed while it is in use.
 type Manager struct {
     clients map[string]*Client
     configs map[string]*ChainConfig
@@ -34,18 +48,27 @@ func NewManager() *Manager {
 
 // AddChain adds a new chain to the manager
 func (m *Manager) AddChain(config ChainConfig) error {
-    m.mu.Lock()
-    defer m.mu.Unlock()
-    
-    if _, exists := m.clients[config.Name]; exists {
+    m.mu.RLock()
+    _, exists := m.clients[config.Name]
+    m.mu.RUnlock()
+    if exists {
         return fmt.Errorf("chain %s already exists", config.Name)
     }
     
+    // Connect without holding the lock, other chains stay usable meanwhile
     client, err := NewClient(config.Name, config.ChainID, config.RPCURL, config.WSURL)
     if err != nil {
         return fmt.Errorf("failed to create client for %s: %w", config.Name, err)
     }
     
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    
+    if _, exists := m.clients[config.Name]; exists {
+        client.Close()
+        return fmt.Errorf("chain %s already exists", config.Name)
+    }
+    
     m.clients[config.Name] = client
     m.configs[config.Name] = &config
     
@@ -53,6 +76,79 @@ func (m *Manager) AddChain(config ChainConfig) error {
     return nil
 }
 
+// UpdateChain replaces the configuration of a chain. The chain is
+// reconnected when its chain ID or endpoints change; callers holding its
+// previous client should get it again.
+func (m *Manager) UpdateChain(config ChainConfig) error {
+    m.mu.RLock()
+    current, exists := m.configs[config.Name]
+    m.mu.RUnlock()
+    if !exists {
+        return fmt.Errorf("chain %s not found", config.Name)
+    }
+    
+    var client *Client
+    if current.ChainID.Cmp(config.ChainID) != 0 || current.RPCURL != config.RPCURL || current.WSURL != config.WSURL {
+        var err error
+        client, err = NewClient(config.Name, config.ChainID, config.RPCURL, config.WSURL)
+        if err != nil {
+            return fmt.Errorf("failed to create client for %s: %w", config.Name, err)
+        }
+    }
+    
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    
+    previous, exists := m.clients[config.Name]
+    if !exists {
+        if client != nil {
+            client.Close()
+        }
+        return fmt.Errorf("chain %s not found", config.Name)
+    }
+    
+    m.configs[config.Name] = &config
+    if client != nil {
+        m.clients[config.Name] = client
+        previous.Close()
+    }
+    
+    fmt.Printf("Updated chain: %s (ID: %s)\n", config.Name, config.ChainID)
+    return nil
+}
+
+// RemoveChain disconnects a chain and removes it from the manager
+func (m *Manager) RemoveChain(name string) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    
+    client, exists := m.clients[name]
+    if !exists {
+        return fmt.Errorf("chain %s not found", name)
+    }
+    
+    client.Close()
+    delete(m.clients, name)
+    delete(m.configs, name)
+    
+    fmt.Printf("Removed chain: %s\n", name)
+    return nil
+}
+
+// ChainIDToName returns the name of the configured chain with a chain ID,
+// or chain-<id> when no chain has it
+func (m *Manager) ChainIDToName(chainID *big.Int) string {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    
+    for name, config := range m.configs {
+        if config.ChainID.Cmp(chainID) == 0 {
+            return name
+        }
+    }
+    return fmt.Sprintf("chain-%s", chainID)
+}
+
 // GetClient returns a client for a specific chain
 func (m *Manager) GetClient(chainName string) (*Client, error) {
     m.mu.RLock()
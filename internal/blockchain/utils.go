@@ -8,7 +8,9 @@ import (
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // WeiToEther converts Wei to Ether
@@ -54,6 +56,50 @@ func (c *Client) GetBlockByNumber(ctx context.Context, number *big.Int) (*types.
     return c.client.BlockByNumber(ctx, number)
 }
 
+// GetHeaderByNumber fetches a block header by number (nil for latest)
+func (c *Client) GetHeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+    return c.client.HeaderByNumber(ctx, number)
+}
+
+// GetHeadersByNumber fetches block headers with batched JSON-RPC requests.
+// Blocks the node doesn't know are missing from the result.
+func (c *Client) GetHeadersByNumber(ctx context.Context, numbers []uint64) (map[uint64]*types.Header, error) {
+    const batchSize = 100
+    
+    headers := make(map[uint64]*types.Header, len(numbers))
+    for start := 0; start < len(numbers); start += batchSize {
+        end := start + batchSize
+        if end > len(numbers) {
+            end = len(numbers)
+        }
+        
+        batch := make([]rpc.BatchElem, 0, end-start)
+        results := make([]*types.Header, end-start)
+        for i, number := range numbers[start:end] {
+            batch = append(batch, rpc.BatchElem{
+                Method: "eth_getBlockByNumber",
+                Args:   []interface{}{hexutil.EncodeUint64(number), false},
+                Result: &results[i],
+            })
+        }
+        
+        if err := c.client.Client().BatchCallContext(ctx, batch); err != nil {
+            return nil, fmt.Errorf("failed to fetch headers: %w", err)
+        }
+        
+        for i, elem := range batch {
+            if elem.Error != nil {
+                return nil, fmt.Errorf("failed to fetch header %d: %w", numbers[start+i], elem.Error)
+            }
+            if results[i] != nil {
+                headers[numbers[start+i]] = results[i]
+            }
+        }
+    }
+    
+    return headers, nil
+}
+
 // GetTransaction fetches a transaction by hash
 func (c *Client) GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
     return c.client.TransactionByHash(ctx, txHash)
@@ -109,27 +155,3 @@ func (c *Client) GetNonce(ctx context.Context, address common.Address) (uint64,
 func (c *Client) GetGasPrice(ctx context.Context) (*big.Int, error) {
     return c.client.SuggestGasPrice(ctx)
 }
-
-// ChainIDToName converts chain ID to common chain names
-func ChainIDToName(chainID *big.Int) string {
-    switch chainID.Int64() {
-    case 1:
-        return "ethereum"
-    case 56:
-        return "bsc"
-    case 137:
-        return "polygon"
-    case 250:
-        return "fantom"
-    case 43114:
-        return "avalanche"
-    case 42161:
-        return "arbitrum"
-    case 10:
-        return "optimism"
-    case 8453:
-        return "base"
-    default:
-        return fmt.Sprintf("chain-%d", chainID)
-    }
-}
\ No newline at end of file
@@ -0,0 +1,91 @@
+// internal/export/csv.go
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvEncoder writes a header, then a line per row with amounts in full
+type csvEncoder struct {
+	w         *csv.Writer
+	breakdown bool
+	started   bool
+}
+
+func newCSVEncoder(w io.Writer, breakdown bool) *csvEncoder {
+	return &csvEncoder{w: csv.NewWriter(w), breakdown: breakdown}
+}
+
+func (e *csvEncoder) header() []string {
+	header := append([]string{}, columns...)
+	if e.breakdown {
+		header = append(header, assetColumns...)
+	}
+	return header
+}
+
+func (e *csvEncoder) write(rows []row) error {
+	if !e.started {
+		e.started = true
+		if err := e.w.Write(e.header()); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range rows {
+		s := r.snapshot
+		span := rangeOf(s)
+
+		samples := ""
+		if span.Samples > 0 {
+			samples = strconv.Itoa(span.Samples)
+		}
+		record := []string{
+			strconv.FormatUint(s.ID, 10),
+			s.Protocol,
+			s.Chain,
+			strconv.FormatUint(s.BlockNumber, 10),
+			s.Timestamp.UTC().Format(time.RFC3339),
+			string(s.Status),
+			string(resolutionOf(s)),
+			s.Source,
+			decimal(s.TotalUSD),
+			decimal(span.Open),
+			decimal(span.High),
+			decimal(span.Low),
+			decimal(span.Avg),
+			samples,
+		}
+
+		if e.breakdown {
+			if a := r.asset; a != nil {
+				record = append(record,
+					a.Token.Hex(),
+					a.Symbol,
+					strconv.Itoa(int(a.Decimals)),
+					integer(a.Amount),
+					decimal(a.PriceUSD),
+					decimal(a.ValueUSD),
+					strconv.FormatFloat(a.Percentage, 'f', -1, 64),
+				)
+			} else {
+				record = append(record, make([]string, len(assetColumns))...)
+			}
+		}
+
+		if err := e.w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// close writes the header of an empty export
+func (e *csvEncoder) close() error {
+	return e.write(nil)
+}
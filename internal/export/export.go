@@ -0,0 +1,261 @@
+// internal/export/export.go
+
+// Package export streams TVL history out of a storage as CSV, NDJSON or
+// Parquet. History is read a window of time at a time and written out before
+// the next window is read, so an export of any size holds one window of
+// snapshots in memory.
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// DefaultWindow is the span of history read at a time
+const DefaultWindow = 24 * time.Hour
+
+// Format is the file format of an export
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatNDJSON  Format = "ndjson"
+	FormatParquet Format = "parquet"
+)
+
+// ParseFormat parses a format name, jsonl standing for NDJSON
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(name)); format {
+	case FormatCSV, FormatNDJSON, FormatParquet:
+		return format, nil
+	case "jsonl":
+		return FormatNDJSON, nil
+	default:
+		return "", fmt.Errorf("unknown export format %q, want csv, ndjson or parquet", name)
+	}
+}
+
+// ContentType returns the MIME type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+// ParseTime parses an RFC 3339 time or a UTC date like 2024-01-31
+func ParseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, want RFC 3339 or YYYY-MM-DD", value)
+	}
+	return t, nil
+}
+
+// Options selects the history to export. Zero values don't filter; without
+// From the export starts at the oldest snapshot, without To it ends now.
+type Options struct {
+	Protocol   string
+	Chain      string
+	Resolution models.Resolution // raw snapshots when empty
+	From       time.Time
+	To         time.Time
+
+	// Breakdown writes a row per asset of each snapshot instead of one per
+	// snapshot
+	Breakdown bool
+
+	// Window is the span of history read at a time, DefaultWindow when zero
+	Window time.Duration
+}
+
+// Result counts what an export wrote
+type Result struct {
+	Snapshots int `json:"snapshots"`
+	Rows      int `json:"rows"`
+}
+
+// row is a snapshot, or one asset of it when exporting the breakdown
+type row struct {
+	snapshot *models.TVLSnapshot
+	asset    *models.AssetTVL // nil for the snapshot itself
+}
+
+// encoder writes rows in a format
+type encoder interface {
+	// write writes a window's rows and flushes them to the writer
+	write(rows []row) error
+	// close writes whatever the format ends with
+	close() error
+}
+
+// columns are the names of the exported fields, in order
+var columns = []string{
+	"snapshot_id", "protocol", "chain", "block_number", "timestamp", "status", "resolution", "source",
+	"total_usd", "open_usd", "high_usd", "low_usd", "avg_usd", "samples",
+}
+
+// assetColumns follow columns when exporting the breakdown
+var assetColumns = []string{
+	"token", "symbol", "decimals", "amount", "price_usd", "value_usd", "percentage",
+}
+
+func newEncoder(format Format, w io.Writer, breakdown bool) (encoder, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVEncoder(w, breakdown), nil
+	case FormatNDJSON:
+		return newNDJSONEncoder(w), nil
+	case FormatParquet:
+		return newParquetEncoder(w, breakdown), nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// Export writes the history options select to w, by time then snapshot ID
+func Export(ctx context.Context, s storage.TVLStorage, w io.Writer, format Format, options Options) (*Result, error) {
+	enc, err := newEncoder(format, w, options.Breakdown)
+	if err != nil {
+		return nil, err
+	}
+
+	window := options.Window
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	filter := storage.TVLFilter{
+		Protocol:   options.Protocol,
+		Chain:      options.Chain,
+		Resolution: options.Resolution,
+	}
+
+	to := options.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	from := options.From
+	if from.IsZero() {
+		oldest := filter
+		oldest.To = to
+		oldest.Limit = 1
+		first, err := s.GetTVLHistory(ctx, oldest)
+		if err != nil {
+			return nil, err
+		}
+		from = to.Add(time.Nanosecond) // nothing to export
+		if len(first) > 0 {
+			from = first[0].Timestamp
+		}
+	}
+
+	result := &Result{}
+	for start := from; !start.After(to); start = start.Add(window) {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		// Windows don't overlap, To is inclusive
+		filter.From = start
+		filter.To = start.Add(window - time.Nanosecond)
+		if filter.To.After(to) {
+			filter.To = to
+		}
+
+		snapshots, err := s.GetTVLHistory(ctx, filter)
+		if err != nil {
+			return result, err
+		}
+		if len(snapshots) == 0 {
+			continue
+		}
+
+		rows := toRows(snapshots, options.Breakdown)
+		if err := enc.write(rows); err != nil {
+			return result, err
+		}
+		result.Snapshots += len(snapshots)
+		result.Rows += len(rows)
+	}
+
+	return result, enc.close()
+}
+
+// toRows lists the rows of snapshots, their assets by token then symbol when
+// exporting the breakdown. A snapshot without assets still gets a row.
+func toRows(snapshots []*models.TVLSnapshot, breakdown bool) []row {
+	rows := make([]row, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		var assets []*models.AssetTVL
+		if breakdown {
+			for _, asset := range snapshot.Breakdown {
+				if asset != nil {
+					assets = append(assets, asset)
+				}
+			}
+		}
+		if len(assets) == 0 {
+			rows = append(rows, row{snapshot: snapshot})
+			continue
+		}
+
+		sort.Slice(assets, func(i, j int) bool {
+			if c := bytes.Compare(assets[i].Token[:], assets[j].Token[:]); c != 0 {
+				return c < 0
+			}
+			return assets[i].Symbol < assets[j].Symbol
+		})
+		for _, asset := range assets {
+			rows = append(rows, row{snapshot: snapshot, asset: asset})
+		}
+	}
+	return rows
+}
+
+// rangeOf returns the range of a rollup, an empty one for a raw snapshot
+func rangeOf(snapshot *models.TVLSnapshot) *models.TVLRange {
+	if snapshot.Range == nil {
+		return &models.TVLRange{}
+	}
+	return snapshot.Range
+}
+
+// resolutionOf names the resolution of a snapshot, raw when unset
+func resolutionOf(snapshot *models.TVLSnapshot) models.Resolution {
+	if snapshot.Resolution.IsRaw() {
+		return models.ResolutionRaw
+	}
+	return snapshot.Resolution
+}
+
+// decimal writes an amount in full, nil as empty
+func decimal(value *big.Float) string {
+	if value == nil {
+		return ""
+	}
+	return value.Text('f', -1)
+}
+
+// integer writes a token amount, nil as empty
+func integer(value *big.Int) string {
+	if value == nil {
+		return ""
+	}
+	return value.String()
+}
@@ -0,0 +1,275 @@
+// internal/export/export_test.go
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/parquet-go/parquet-go"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/memory"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func snapshot(block uint64, offset time.Duration, total string, assets ...*models.AssetTVL) *models.TVLSnapshot {
+	value, _ := new(big.Float).SetString(total)
+	s := &models.TVLSnapshot{
+		Protocol:    "aave-v3",
+		Chain:       "ethereum",
+		BlockNumber: block,
+		TotalUSD:    value,
+		Breakdown:   make(map[string]*models.AssetTVL),
+		Status:      models.FinalityFinal,
+		Timestamp:   start.Add(offset),
+	}
+	for i, asset := range assets {
+		s.Breakdown[fmt.Sprint(i)] = asset
+	}
+	return s
+}
+
+func asset(token int64, symbol, amount string) *models.AssetTVL {
+	n, _ := new(big.Int).SetString(amount, 10)
+	return &models.AssetTVL{
+		Token:      common.BigToAddress(big.NewInt(token)),
+		Symbol:     symbol,
+		Amount:     n,
+		Decimals:   18,
+		PriceUSD:   big.NewFloat(2),
+		ValueUSD:   big.NewFloat(10),
+		Percentage: 50,
+	}
+}
+
+func store(t *testing.T, snapshots ...*models.TVLSnapshot) *memory.MemoryStorage {
+	t.Helper()
+	s := memory.NewMemoryStorage()
+	for _, snapshot := range snapshots {
+		if err := s.SaveTVLSnapshot(context.Background(), snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+// exportedRow is the part of a row the tests compare across formats
+type exportedRow struct {
+	block  uint64
+	time   time.Time
+	total  string
+	token  string
+	symbol string
+	amount string
+}
+
+// readBack decodes an export into its rows
+func readBack(t *testing.T, format Format, data []byte) []exportedRow {
+	t.Helper()
+	var rows []exportedRow
+
+	switch format {
+	case FormatCSV:
+		records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) == 0 {
+			t.Fatal("CSV export without a header")
+		}
+		column := make(map[string]int)
+		for i, name := range records[0] {
+			column[name] = i
+		}
+		field := func(record []string, name string) string {
+			if i, ok := column[name]; ok {
+				return record[i]
+			}
+			return ""
+		}
+		for _, record := range records[1:] {
+			block, _ := strconv.ParseUint(field(record, "block_number"), 10, 64)
+			at, _ := time.Parse(time.RFC3339, field(record, "timestamp"))
+			rows = append(rows, exportedRow{block, at, field(record, "total_usd"),
+				field(record, "token"), field(record, "symbol"), field(record, "amount")})
+		}
+
+	case FormatNDJSON:
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			var line ndjsonRow
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				t.Fatal(err)
+			}
+			row := exportedRow{block: line.BlockNumber, time: line.Timestamp, total: *line.TotalUSD}
+			if line.Token != nil {
+				row.token, row.symbol, row.amount = *line.Token, *line.Symbol, *line.Amount
+			}
+			rows = append(rows, row)
+		}
+
+	case FormatParquet:
+		read, err := parquet.Read[parquetAssetRow](bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range read {
+			row := exportedRow{block: line.BlockNumber, time: line.Timestamp.UTC(),
+				total: strconv.FormatFloat(*line.TotalUSD, 'f', -1, 64)}
+			if line.Token != nil {
+				row.token, row.symbol, row.amount = *line.Token, *line.Symbol, *line.Amount
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+func blocks(rows []exportedRow) []uint64 {
+	var list []uint64
+	for _, row := range rows {
+		list = append(list, row.block)
+	}
+	return list
+}
+
+func TestExportWindows(t *testing.T) {
+	ctx := context.Background()
+	s := store(t,
+		snapshot(1, 0, "100"),
+		snapshot(2, 30*time.Minute, "101"),
+		// On a window boundary
+		snapshot(3, time.Hour, "102"),
+		snapshot(4, 2*time.Hour-time.Nanosecond, "103"),
+		// At To, which is inclusive
+		snapshot(5, 2*time.Hour, "104"),
+		snapshot(6, 2*time.Hour+time.Second, "105"),
+	)
+
+	for _, window := range []time.Duration{time.Minute, 45 * time.Minute, time.Hour, DefaultWindow} {
+		for _, from := range []time.Time{start, {}} {
+			var buf bytes.Buffer
+			result, err := Export(ctx, s, &buf, FormatNDJSON, Options{
+				Protocol: "aave-v3",
+				From:     from,
+				To:       start.Add(2 * time.Hour),
+				Window:   window,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := blocks(readBack(t, FormatNDJSON, buf.Bytes()))
+			if want := []uint64{1, 2, 3, 4, 5}; !reflect.DeepEqual(got, want) {
+				t.Errorf("export in windows of %v from %v = blocks %v, want %v", window, from, got, want)
+			}
+			if result.Snapshots != 5 || result.Rows != 5 {
+				t.Errorf("export in windows of %v = %+v, want 5 snapshots and rows", window, result)
+			}
+		}
+	}
+}
+
+func TestExportBreakdownOrder(t *testing.T) {
+	ctx := context.Background()
+	s := store(t,
+		snapshot(1, 0, "30", asset(3, "WBTC", "3"), asset(1, "USDC.e", "2"), asset(1, "USDC", "1")),
+		// Without assets, still a row
+		snapshot(2, time.Minute, "0"),
+	)
+
+	var buf bytes.Buffer
+	result, err := Export(ctx, s, &buf, FormatCSV, Options{To: start.Add(time.Hour), Breakdown: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Snapshots != 2 || result.Rows != 4 {
+		t.Errorf("Export = %+v, want 2 snapshots in 4 rows", result)
+	}
+
+	var got []string
+	for _, row := range readBack(t, FormatCSV, buf.Bytes()) {
+		got = append(got, fmt.Sprintf("%d:%s", row.block, row.symbol))
+	}
+	if want := []string{"1:USDC", "1:USDC.e", "1:WBTC", "2:"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("breakdown rows = %v, want %v", got, want)
+	}
+}
+
+func TestExportEmpty(t *testing.T) {
+	ctx := context.Background()
+	s := store(t)
+
+	for _, format := range []Format{FormatCSV, FormatNDJSON, FormatParquet} {
+		for _, from := range []time.Time{start, {}} {
+			var buf bytes.Buffer
+			result, err := Export(ctx, s, &buf, format, Options{From: from, To: start.Add(time.Hour), Breakdown: true})
+			if err != nil {
+				t.Fatalf("Export(%s) of no history: %v", format, err)
+			}
+			if result.Snapshots != 0 || result.Rows != 0 {
+				t.Errorf("Export(%s) of no history = %+v, want nothing", format, result)
+			}
+			if rows := readBack(t, format, buf.Bytes()); len(rows) != 0 {
+				t.Errorf("Export(%s) of no history has %d rows", format, len(rows))
+			}
+		}
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	// More digits than a float64 or an int64 holds
+	large := "123456789012345678901234567890"
+	s := store(t,
+		snapshot(19000000, 0, "1234.5", asset(1, "USDC", large)),
+		snapshot(19000300, time.Hour, "1500.25", asset(1, "USDC", "7"), asset(2, "WETH", "8")),
+	)
+
+	want := []exportedRow{
+		{19000000, start, "1234.5", common.BigToAddress(big.NewInt(1)).Hex(), "USDC", large},
+		{19000300, start.Add(time.Hour), "1500.25", common.BigToAddress(big.NewInt(1)).Hex(), "USDC", "7"},
+		{19000300, start.Add(time.Hour), "1500.25", common.BigToAddress(big.NewInt(2)).Hex(), "WETH", "8"},
+	}
+	for _, format := range []Format{FormatCSV, FormatNDJSON, FormatParquet} {
+		var buf bytes.Buffer
+		if _, err := Export(ctx, s, &buf, format, Options{Protocol: "aave-v3", Chain: "ethereum",
+			To: start.Add(time.Hour), Breakdown: true}); err != nil {
+			t.Fatalf("Export(%s): %v", format, err)
+		}
+
+		got := readBack(t, format, buf.Bytes())
+		if len(got) != len(want) {
+			t.Fatalf("Export(%s) read back %d rows, want %d", format, len(got), len(want))
+		}
+		for i := range want {
+			if got[i].block != want[i].block || !got[i].time.Equal(want[i].time) || got[i].total != want[i].total ||
+				got[i].token != want[i].token || got[i].symbol != want[i].symbol || got[i].amount != want[i].amount {
+				t.Errorf("Export(%s) row %d = %+v, want %+v", format, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := map[string]Format{"csv": FormatCSV, "NDJSON": FormatNDJSON, "jsonl": FormatNDJSON, "parquet": FormatParquet}
+	for name, want := range tests {
+		if got, err := ParseFormat(name); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Error("ParseFormat(xlsx) succeeded")
+	}
+}
@@ -0,0 +1,101 @@
+// internal/export/ndjson.go
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+)
+
+// ndjsonRow is a line of an NDJSON export. Amounts are strings, JSON numbers
+// would lose digits in most readers.
+type ndjsonRow struct {
+	SnapshotID  uint64                `json:"snapshot_id"`
+	Protocol    string                `json:"protocol"`
+	Chain       string                `json:"chain"`
+	BlockNumber uint64                `json:"block_number"`
+	Timestamp   time.Time             `json:"timestamp"`
+	Status      models.FinalityStatus `json:"status"`
+	Resolution  models.Resolution     `json:"resolution"`
+	Source      string                `json:"source,omitempty"`
+	TotalUSD    *string               `json:"total_usd"`
+	OpenUSD     *string               `json:"open_usd,omitempty"`
+	HighUSD     *string               `json:"high_usd,omitempty"`
+	LowUSD      *string               `json:"low_usd,omitempty"`
+	AvgUSD      *string               `json:"avg_usd,omitempty"`
+	Samples     int                   `json:"samples,omitempty"`
+
+	Token      *string  `json:"token,omitempty"`
+	Symbol     *string  `json:"symbol,omitempty"`
+	Decimals   *uint8   `json:"decimals,omitempty"`
+	Amount     *string  `json:"amount,omitempty"`
+	PriceUSD   *string  `json:"price_usd,omitempty"`
+	ValueUSD   *string  `json:"value_usd,omitempty"`
+	Percentage *float64 `json:"percentage,omitempty"`
+}
+
+// ndjsonEncoder writes a JSON object per row and line
+type ndjsonEncoder struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONEncoder(w io.Writer) *ndjsonEncoder {
+	buffered := bufio.NewWriter(w)
+	return &ndjsonEncoder{w: buffered, enc: json.NewEncoder(buffered)}
+}
+
+// optional returns a pointer to a non-empty string, nil otherwise
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func (e *ndjsonEncoder) write(rows []row) error {
+	for _, r := range rows {
+		s := r.snapshot
+		span := rangeOf(s)
+
+		line := ndjsonRow{
+			SnapshotID:  s.ID,
+			Protocol:    s.Protocol,
+			Chain:       s.Chain,
+			BlockNumber: s.BlockNumber,
+			Timestamp:   s.Timestamp.UTC(),
+			Status:      s.Status,
+			Resolution:  resolutionOf(s),
+			Source:      s.Source,
+			TotalUSD:    optional(decimal(s.TotalUSD)),
+			OpenUSD:     optional(decimal(span.Open)),
+			HighUSD:     optional(decimal(span.High)),
+			LowUSD:      optional(decimal(span.Low)),
+			AvgUSD:      optional(decimal(span.Avg)),
+			Samples:     span.Samples,
+		}
+		if a := r.asset; a != nil {
+			decimals, percentage := a.Decimals, a.Percentage
+			line.Token = optional(a.Token.Hex())
+			line.Symbol = &a.Symbol
+			line.Decimals = &decimals
+			line.Amount = optional(integer(a.Amount))
+			line.PriceUSD = optional(decimal(a.PriceUSD))
+			line.ValueUSD = optional(decimal(a.ValueUSD))
+			line.Percentage = &percentage
+		}
+
+		if err := e.enc.Encode(line); err != nil {
+			return err
+		}
+	}
+
+	return e.w.Flush()
+}
+
+func (e *ndjsonEncoder) close() error {
+	return e.w.Flush()
+}
@@ -0,0 +1,134 @@
+// internal/export/parquet.go
+package export
+
+import (
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// rowGroupRows is how many rows a Parquet row group holds at most. The
+// writer keeps a row group in memory until it is flushed.
+const rowGroupRows = 100_000
+
+// parquetRow is a row of a Parquet export. USD amounts are doubles so
+// readers can compute on them; token amounts can exceed any integer column
+// and are decimal strings.
+type parquetRow struct {
+	SnapshotID  uint64    `parquet:"snapshot_id"`
+	Protocol    string    `parquet:"protocol"`
+	Chain       string    `parquet:"chain"`
+	BlockNumber uint64    `parquet:"block_number"`
+	Timestamp   time.Time `parquet:"timestamp,timestamp(millisecond)"`
+	Status      string    `parquet:"status"`
+	Resolution  string    `parquet:"resolution"`
+	Source      string    `parquet:"source"`
+	TotalUSD    *float64  `parquet:"total_usd,optional"`
+	OpenUSD     *float64  `parquet:"open_usd,optional"`
+	HighUSD     *float64  `parquet:"high_usd,optional"`
+	LowUSD      *float64  `parquet:"low_usd,optional"`
+	AvgUSD      *float64  `parquet:"avg_usd,optional"`
+	Samples     int32     `parquet:"samples"`
+}
+
+// parquetAssetRow is a row of a Parquet export of the breakdown
+type parquetAssetRow struct {
+	parquetRow
+	Token      *string  `parquet:"token,optional"`
+	Symbol     *string  `parquet:"symbol,optional"`
+	Decimals   *int32   `parquet:"decimals,optional"`
+	Amount     *string  `parquet:"amount,optional"`
+	PriceUSD   *float64 `parquet:"price_usd,optional"`
+	ValueUSD   *float64 `parquet:"value_usd,optional"`
+	Percentage *float64 `parquet:"percentage,optional"`
+}
+
+// parquetEncoder writes rows converted to T, a row group at a time
+type parquetEncoder[T any] struct {
+	w       *parquet.GenericWriter[T]
+	convert func(row) T
+	pending int
+}
+
+func newParquetEncoder(w io.Writer, breakdown bool) encoder {
+	if breakdown {
+		return &parquetEncoder[parquetAssetRow]{
+			w:       parquet.NewGenericWriter[parquetAssetRow](w),
+			convert: toParquetAssetRow,
+		}
+	}
+	return &parquetEncoder[parquetRow]{
+		w:       parquet.NewGenericWriter[parquetRow](w),
+		convert: toParquetRow,
+	}
+}
+
+func (e *parquetEncoder[T]) write(rows []row) error {
+	converted := make([]T, len(rows))
+	for i, r := range rows {
+		converted[i] = e.convert(r)
+	}
+	if _, err := e.w.Write(converted); err != nil {
+		return err
+	}
+
+	e.pending += len(rows)
+	if e.pending < rowGroupRows {
+		return nil
+	}
+	e.pending = 0
+	return e.w.Flush()
+}
+
+// close writes the last row group and the footer
+func (e *parquetEncoder[T]) close() error {
+	return e.w.Close()
+}
+
+func toParquetRow(r row) parquetRow {
+	s := r.snapshot
+	span := rangeOf(s)
+
+	return parquetRow{
+		SnapshotID:  s.ID,
+		Protocol:    s.Protocol,
+		Chain:       s.Chain,
+		BlockNumber: s.BlockNumber,
+		Timestamp:   s.Timestamp.UTC(),
+		Status:      string(s.Status),
+		Resolution:  string(resolutionOf(s)),
+		Source:      s.Source,
+		TotalUSD:    float(s.TotalUSD),
+		OpenUSD:     float(span.Open),
+		HighUSD:     float(span.High),
+		LowUSD:      float(span.Low),
+		AvgUSD:      float(span.Avg),
+		Samples:     int32(span.Samples),
+	}
+}
+
+func toParquetAssetRow(r row) parquetAssetRow {
+	converted := parquetAssetRow{parquetRow: toParquetRow(r)}
+	if a := r.asset; a != nil {
+		token, symbol, decimals, percentage := a.Token.Hex(), a.Symbol, int32(a.Decimals), a.Percentage
+		converted.Token = &token
+		converted.Symbol = &symbol
+		converted.Decimals = &decimals
+		converted.Amount = optional(integer(a.Amount))
+		converted.PriceUSD = float(a.PriceUSD)
+		converted.ValueUSD = float(a.ValueUSD)
+		converted.Percentage = &percentage
+	}
+	return converted
+}
+
+// float converts an amount to a double, nil as null
+func float(value *big.Float) *float64 {
+	if value == nil {
+		return nil
+	}
+	f, _ := value.Float64()
+	return &f
+}
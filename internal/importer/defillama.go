@@ -0,0 +1,337 @@
+// internal/importer/defillama.go
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+)
+
+// SourceDefiLlama is the source of history imported from DefiLlama
+const SourceDefiLlama = "defillama"
+
+// llamaDecimals is the precision imported token amounts are stored at.
+// DefiLlama lists amounts in whole tokens without the token's decimals.
+const llamaDecimals = 18
+
+// llamaProtocol is a protocol as DefiLlama's /protocol endpoint returns it
+type llamaProtocol struct {
+	Name      string                `json:"name"`
+	ChainTvls map[string]llamaChain `json:"chainTvls"`
+	llamaChain
+}
+
+// llamaChain is the history of a chain, or of the protocol as a whole
+type llamaChain struct {
+	TVL         []llamaPoint  `json:"tvl"`
+	TokensInUsd []llamaTokens `json:"tokensInUsd"`
+	Tokens      []llamaTokens `json:"tokens"`
+}
+
+type llamaPoint struct {
+	Date              llamaNumber `json:"date"`
+	TotalLiquidityUSD llamaNumber `json:"totalLiquidityUSD"`
+}
+
+type llamaTokens struct {
+	Date   llamaNumber            `json:"date"`
+	Tokens map[string]llamaNumber `json:"tokens"`
+}
+
+// llamaNumber is a JSON number kept as written. Some dumps quote numbers.
+type llamaNumber string
+
+func (n *llamaNumber) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*n = ""
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		data = []byte(text)
+	}
+	*n = llamaNumber(data)
+	return nil
+}
+
+func (n llamaNumber) float() (*big.Float, error) {
+	if n == "" {
+		return nil, nil
+	}
+	value, ok := new(big.Float).SetPrec(128).SetString(string(n))
+	if !ok {
+		return nil, fmt.Errorf("invalid number %q", string(n))
+	}
+	return value, nil
+}
+
+func (n llamaNumber) time() (time.Time, error) {
+	seconds, err := strconv.ParseFloat(string(n), 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}, fmt.Errorf("invalid date %q", string(n))
+	}
+	return time.Unix(int64(seconds), 0).UTC(), nil
+}
+
+// llamaCategories are the chainTvls entries that are not a chain's own TVL,
+// alone or after a chain as in "Ethereum-borrowed". DefiLlama leaves them
+// out of a protocol's TVL.
+var llamaCategories = map[string]bool{
+	"borrowed":       true,
+	"staking":        true,
+	"pool2":          true,
+	"vesting":        true,
+	"offers":         true,
+	"treasury":       true,
+	"doublecounted":  true,
+	"liquidstaking":  true,
+	"dcandlsoverlap": true,
+}
+
+// llamaChains maps DefiLlama chain names to ours where lowercasing doesn't
+var llamaChains = map[string]string{
+	"binance":    "bsc",
+	"op mainnet": "optimism",
+	"avax":       "avalanche",
+	"xdai":       "gnosis",
+}
+
+// ReadDefiLlama reads a protocol in DefiLlama's format, the /protocol
+// endpoint's JSON, as one snapshot per chain and point of its tvl history.
+// Each snapshot's breakdown holds the tokensInUsd and tokens of its time.
+//
+// Snapshots are final, have no block number and are tagged with the
+// options' source. A file without per-chain history imports the protocol's
+// total as the history of the options' chain.
+func ReadDefiLlama(r io.Reader, options Options) ([]*models.TVLSnapshot, error) {
+	var protocol llamaProtocol
+	if err := json.NewDecoder(r).Decode(&protocol); err != nil {
+		return nil, fmt.Errorf("invalid DefiLlama protocol: %w", err)
+	}
+
+	name := options.Protocol
+	if name == "" {
+		name = slug(protocol.Name)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("the dataset has no protocol name, set one")
+	}
+	source := options.Source
+	if source == "" {
+		source = SourceDefiLlama
+	}
+
+	chains := make(map[string]llamaChain)
+	for llamaName, history := range protocol.ChainTvls {
+		if isCategory(llamaName) {
+			continue
+		}
+		chain := chainName(llamaName)
+		if options.Chain != "" && chain != options.Chain {
+			continue
+		}
+		if _, ok := chains[chain]; ok {
+			return nil, fmt.Errorf("%q and another chain both map to %s", llamaName, chain)
+		}
+		chains[chain] = history
+	}
+	if len(protocol.ChainTvls) == 0 && len(protocol.TVL) > 0 {
+		if options.Chain == "" {
+			return nil, fmt.Errorf("%s has no per-chain history, set the chain its total is on", name)
+		}
+		chains[options.Chain] = protocol.llamaChain
+	}
+
+	names := make([]string, 0, len(chains))
+	for chain := range chains {
+		names = append(names, chain)
+	}
+	sort.Strings(names)
+
+	var snapshots []*models.TVLSnapshot
+	for _, chain := range names {
+		chainSnapshots, err := readChain(chains[chain], name, chain, source)
+		if err != nil {
+			return nil, fmt.Errorf("%s on %s: %w", name, chain, err)
+		}
+		snapshots = append(snapshots, chainSnapshots...)
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].Timestamp.Before(snapshots[j].Timestamp)
+	})
+
+	return snapshots, nil
+}
+
+// readChain converts the history of a chain to snapshots
+func readChain(history llamaChain, protocol, chain, source string) ([]*models.TVLSnapshot, error) {
+	values, err := byTime(history.TokensInUsd)
+	if err != nil {
+		return nil, err
+	}
+	amounts, err := byTime(history.Tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]*models.TVLSnapshot, 0, len(history.TVL))
+	seen := make(map[time.Time]bool, len(history.TVL))
+	for _, point := range history.TVL {
+		at, err := point.Date.time()
+		if err != nil {
+			return nil, err
+		}
+		total, err := point.TotalLiquidityUSD.float()
+		if err != nil {
+			return nil, fmt.Errorf("at %s: %w", at.Format(time.RFC3339), err)
+		}
+		if total == nil || seen[at] {
+			continue
+		}
+		seen[at] = true
+
+		breakdown, err := assets(values[at], amounts[at], total)
+		if err != nil {
+			return nil, fmt.Errorf("at %s: %w", at.Format(time.RFC3339), err)
+		}
+
+		snapshots = append(snapshots, &models.TVLSnapshot{
+			Protocol:  protocol,
+			Chain:     chain,
+			TotalUSD:  total,
+			Breakdown: breakdown,
+			Status:    models.FinalityFinal,
+			Timestamp: at,
+			Source:    source,
+		})
+	}
+
+	return snapshots, nil
+}
+
+// byTime indexes token lists by their time
+func byTime(lists []llamaTokens) (map[time.Time]map[string]llamaNumber, error) {
+	indexed := make(map[time.Time]map[string]llamaNumber, len(lists))
+	for _, list := range lists {
+		at, err := list.Date.time()
+		if err != nil {
+			return nil, err
+		}
+		indexed[at] = list.Tokens
+	}
+	return indexed, nil
+}
+
+// assets builds a breakdown from the USD values and amounts of tokens.
+// Tokens listed by address are keyed by it like computed breakdowns, others
+// by their name under the zero address.
+func assets(values, amounts map[string]llamaNumber, total *big.Float) (map[string]*models.AssetTVL, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	scale := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(llamaDecimals), nil))
+	totalUSD, _ := total.Float64()
+
+	breakdown := make(map[string]*models.AssetTVL, len(values))
+	for name, number := range values {
+		value, err := number.float()
+		if err != nil {
+			return nil, fmt.Errorf("value of %s: %w", name, err)
+		}
+		if value == nil {
+			continue
+		}
+
+		key, asset := token(name)
+		asset.Decimals = llamaDecimals
+		asset.ValueUSD = value
+		if totalUSD > 0 {
+			usd, _ := value.Float64()
+			asset.Percentage = usd / totalUSD * 100
+		}
+
+		amount, err := amounts[name].float()
+		if err != nil {
+			return nil, fmt.Errorf("amount of %s: %w", name, err)
+		}
+		if amount != nil {
+			asset.Amount, _ = new(big.Float).Mul(amount, scale).Int(nil)
+			if amount.Sign() > 0 {
+				asset.PriceUSD = new(big.Float).Quo(value, amount)
+			}
+		}
+
+		if _, ok := breakdown[key]; ok {
+			return nil, fmt.Errorf("token %s is listed twice", key)
+		}
+		breakdown[key] = asset
+	}
+
+	return breakdown, nil
+}
+
+// token parses a DefiLlama token name: an address, possibly after its chain
+// as in "ethereum:0x...", or a symbol or price feed like "coingecko:tether"
+func token(name string) (string, *models.AssetTVL) {
+	address := name
+	if i := strings.LastIndexByte(name, ':'); i >= 0 {
+		address = name[i+1:]
+	}
+	if common.IsHexAddress(address) {
+		token := common.HexToAddress(address)
+		return token.Hex(), &models.AssetTVL{Token: token}
+	}
+
+	return name, &models.AssetTVL{Symbol: strings.TrimPrefix(name, "coingecko:")}
+}
+
+// isCategory tells whether a chainTvls entry is a category of TVL rather
+// than a chain's own
+func isCategory(name string) bool {
+	category := strings.ToLower(name)
+	if i := strings.LastIndexByte(category, '-'); i >= 0 {
+		category = category[i+1:]
+	}
+	return llamaCategories[category]
+}
+
+// chainName maps a DefiLlama chain name to ours
+func chainName(name string) string {
+	lower := strings.ToLower(name)
+	if chain, ok := llamaChains[lower]; ok {
+		return chain
+	}
+	return slug(lower)
+}
+
+// slug lowercases a name and joins its words with dashes
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
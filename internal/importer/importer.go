@@ -0,0 +1,224 @@
+// internal/importer/importer.go
+
+// Package importer loads TVL history computed elsewhere into a storage, so
+// charts reach back before the aggregator was deployed. Imported snapshots
+// carry the name of their dataset as their source, which tells them apart
+// from snapshots the aggregator computed.
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// Options configures how a dataset is read
+type Options struct {
+	// Protocol names the protocol, the dataset's name as a slug when empty
+	Protocol string
+	// Chain only imports this chain. A dataset with a protocol's total
+	// rather than per-chain history is imported as this chain's.
+	Chain string
+	// Source tags the snapshots, the dataset's format when empty
+	Source string
+}
+
+// Result counts what an import wrote
+type Result struct {
+	Snapshots     int       `json:"snapshots"`
+	HourlyRollups int       `json:"hourly_rollups"`
+	DailyRollups  int       `json:"daily_rollups"`
+	Chains        []string  `json:"chains"`
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+}
+
+// ErrMixedSources is returned by Import for a day that would hold snapshots
+// of several sources: its rollups would blend them into one
+var ErrMixedSources = errors.New("importer: snapshots of several sources in one day")
+
+// Import saves snapshots and rolls up the hours and days they fall in, so
+// history older than the raw retention, which the retention engine won't
+// roll up again, is kept once its snapshots are pruned.
+//
+// Snapshots without a block replace those of the same protocol, chain and
+// time, so importing a dataset again updates it. A touched hour is rolled up
+// from every snapshot stored in it and a touched day from every hourly
+// rollup, like the retention engine does, so Import refuses with
+// ErrMixedSources snapshots sharing a day with snapshots or rollups of
+// another source.
+//
+// Everything is written in one transaction: a failed import leaves nothing
+// behind.
+func Import(ctx context.Context, s storage.Storage, snapshots []*models.TVLSnapshot) (*Result, error) {
+	var result *Result
+	err := storage.InTx(ctx, s, func(tx storage.Tx) error {
+		var err error
+		result, err = importSnapshots(ctx, tx, snapshots)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// importSnapshots is Import inside its transaction
+func importSnapshots(ctx context.Context, s storage.TVLStorage, snapshots []*models.TVLSnapshot) (*Result, error) {
+	type series struct {
+		protocol string
+		chain    string
+	}
+	hours := make(map[series]map[time.Time]bool)
+	sources := make(map[series]map[time.Time]string) // source of each touched day
+	replaced := make(map[series]map[int64]bool)      // times of snapshots without a block
+	chains := make(map[string]bool)
+
+	result := &Result{}
+	for _, snapshot := range snapshots {
+		key := series{snapshot.Protocol, snapshot.Chain}
+		hour := snapshot.Timestamp.UTC().Truncate(time.Hour)
+		day := hour.Truncate(24 * time.Hour)
+		if hours[key] == nil {
+			hours[key] = make(map[time.Time]bool)
+			sources[key] = make(map[time.Time]string)
+			replaced[key] = make(map[int64]bool)
+		}
+		if source, ok := sources[key][day]; ok && source != snapshot.Source {
+			return result, fmt.Errorf("%w: %s on %s on %s has %s and %s snapshots", ErrMixedSources,
+				key.protocol, key.chain, day.Format("2006-01-02"), describe(source), describe(snapshot.Source))
+		}
+		hours[key][hour] = true
+		sources[key][day] = snapshot.Source
+		if snapshot.BlockNumber == 0 {
+			replaced[key][snapshot.Timestamp.UnixNano()] = true
+		}
+	}
+
+	// Stored snapshots the import doesn't replace, and the rollups of hours
+	// it doesn't touch, are rolled up with it
+	for key, days := range sources {
+		for day, source := range days {
+			filter := storage.TVLFilter{
+				Protocol: key.protocol,
+				Chain:    key.chain,
+				From:     day,
+				To:       day.Add(24*time.Hour - time.Nanosecond),
+			}
+			for _, resolution := range []models.Resolution{"", models.ResolutionHourly} {
+				filter.Resolution = resolution
+				stored, err := s.GetTVLHistory(ctx, filter)
+				if err != nil {
+					return result, err
+				}
+				for _, snapshot := range stored {
+					if snapshot.Source == source ||
+						(resolution == "" && snapshot.BlockNumber == 0 && replaced[key][snapshot.Timestamp.UnixNano()]) ||
+						(resolution != "" && hours[key][snapshot.Timestamp.UTC()]) {
+						continue
+					}
+					return result, fmt.Errorf("%w: %s on %s on %s already has %s snapshots, not %s", ErrMixedSources,
+						key.protocol, key.chain, day.Format("2006-01-02"), describe(snapshot.Source), describe(source))
+				}
+			}
+		}
+	}
+
+	for _, snapshot := range snapshots {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if err := s.SaveTVLSnapshot(ctx, snapshot); err != nil {
+			return result, fmt.Errorf("failed to save %s on %s at %s: %w",
+				snapshot.Protocol, snapshot.Chain, snapshot.Timestamp.Format(time.RFC3339), err)
+		}
+		result.Snapshots++
+		chains[snapshot.Chain] = true
+
+		if result.From.IsZero() || snapshot.Timestamp.Before(result.From) {
+			result.From = snapshot.Timestamp
+		}
+		if snapshot.Timestamp.After(result.To) {
+			result.To = snapshot.Timestamp
+		}
+	}
+
+	for key, touched := range hours {
+		days := make(map[time.Time]bool)
+		for hour := range touched {
+			days[hour.Truncate(24*time.Hour)] = true
+		}
+
+		for _, day := range sortedTimes(days) {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+
+			filter := storage.TVLFilter{
+				Protocol: key.protocol,
+				Chain:    key.chain,
+				From:     day,
+				To:       day.Add(24*time.Hour - time.Nanosecond),
+			}
+			stored, err := s.GetTVLHistory(ctx, filter)
+			if err != nil {
+				return result, err
+			}
+
+			// Hours without imported snapshots keep their rollups
+			var hourly []*models.TVLSnapshot
+			for _, rollup := range storage.Rollup(stored, models.ResolutionHourly) {
+				if touched[rollup.Timestamp] {
+					hourly = append(hourly, rollup)
+				}
+			}
+			if err := s.SaveTVLRollups(ctx, hourly); err != nil {
+				return result, fmt.Errorf("failed to save hourly rollups: %w", err)
+			}
+			result.HourlyRollups += len(hourly)
+
+			filter.Resolution = models.ResolutionHourly
+			rolled, err := s.GetTVLHistory(ctx, filter)
+			if err != nil {
+				return result, err
+			}
+			daily := storage.Rollup(rolled, models.ResolutionDaily)
+			if err := s.SaveTVLRollups(ctx, daily); err != nil {
+				return result, fmt.Errorf("failed to save daily rollups: %w", err)
+			}
+			result.DailyRollups += len(daily)
+		}
+	}
+
+	result.Chains = make([]string, 0, len(chains))
+	for chain := range chains {
+		result.Chains = append(result.Chains, chain)
+	}
+	sort.Strings(result.Chains)
+
+	return result, nil
+}
+
+// describe names a source in errors
+func describe(source string) string {
+	if source == "" {
+		return "computed"
+	}
+	return source
+}
+
+func sortedTimes(set map[time.Time]bool) []time.Time {
+	times := make([]time.Time, 0, len(set))
+	for t := range set {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool {
+		return times[i].Before(times[j])
+	})
+	return times
+}
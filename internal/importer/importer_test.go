@@ -0,0 +1,77 @@
+// internal/importer/importer_test.go
+package importer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/memory"
+)
+
+var day = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func point(hour int, total float64, source string) *models.TVLSnapshot {
+	return &models.TVLSnapshot{
+		Protocol:  "aave-v3",
+		Chain:     "ethereum",
+		TotalUSD:  big.NewFloat(total),
+		Status:    models.FinalityFinal,
+		Timestamp: day.Add(time.Duration(hour) * time.Hour),
+		Source:    source,
+	}
+}
+
+func TestImportRefusesMixedSources(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewMemoryStorage()
+
+	computed := point(1, 100, "")
+	computed.BlockNumber = 19000000
+	if err := s.SaveTVLSnapshot(ctx, computed); err != nil {
+		t.Fatal(err)
+	}
+
+	// A day with a computed snapshot can't take imported ones
+	_, err := Import(ctx, s, []*models.TVLSnapshot{point(5, 200, SourceDefiLlama)})
+	if !errors.Is(err, ErrMixedSources) {
+		t.Fatalf("Import into a day of computed snapshots = %v, want ErrMixedSources", err)
+	}
+	history, err := s.GetTVLHistory(ctx, storage.TVLFilter{Protocol: "aave-v3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 {
+		t.Errorf("refused import left %d snapshots, want the computed one", len(history))
+	}
+
+	// Nor can one import mix sources in a day
+	_, err = Import(ctx, s, []*models.TVLSnapshot{point(25, 200, SourceDefiLlama), point(26, 300, "other")})
+	if !errors.Is(err, ErrMixedSources) {
+		t.Fatalf("Import of two sources in a day = %v, want ErrMixedSources", err)
+	}
+
+	// Other days are imported, and again with the same source
+	for i := 0; i < 2; i++ {
+		result, err := Import(ctx, s, []*models.TVLSnapshot{point(25, 200, SourceDefiLlama), point(26, 300, SourceDefiLlama)})
+		if err != nil {
+			t.Fatalf("Import %d: %v", i, err)
+		}
+		if result.Snapshots != 2 || result.HourlyRollups != 2 || result.DailyRollups != 1 {
+			t.Errorf("Import %d = %d snapshots, %d hourly and %d daily rollups; want 2, 2 and 1",
+				i, result.Snapshots, result.HourlyRollups, result.DailyRollups)
+		}
+	}
+
+	daily, err := s.GetTVLHistory(ctx, storage.TVLFilter{Protocol: "aave-v3", Resolution: models.ResolutionDaily})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(daily) != 1 || daily[0].Source != SourceDefiLlama || daily[0].TotalUSD.Text('f', 0) != "300" {
+		t.Errorf("daily rollups = %d, want one of the imported day closing at 300", len(daily))
+	}
+}
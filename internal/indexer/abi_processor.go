@@ -0,0 +1,229 @@
+// internal/indexer/abi_processor.go
+package indexer
+
+import (
+    "fmt"
+    "math/big"
+    "reflect"
+    "strings"
+    "unicode"
+
+    "github.com/ethereum/go-ethereum/accounts/abi"
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/ethereum/go-ethereum/common/hexutil"
+    "github.com/ethereum/go-ethereum/core/types"
+    "github.com/zacksfF/evm-tvl-aggregator/internal/models"
+)
+
+// ABIEventProcessor decodes events using a contract ABI, so supporting a new
+// protocol takes configuration instead of a hand written processor.
+// Argument names are stored in EventData in snake_case (amount0In becomes
+// amount0_in), big integers as decimal strings and bytes as hex.
+type ABIEventProcessor struct {
+    protocolName string
+    contracts    []WatchedContract
+    events       map[common.Hash]abiEvent
+}
+
+type abiEvent struct {
+    name      string
+    indexed   abi.Arguments
+    data      abi.Arguments
+    numTopics int
+}
+
+// NewABIEventProcessor creates a processor for the named events of an ABI.
+// No event names means every event in the ABI.
+func NewABIEventProcessor(protocolName string, abiJSON string, eventNames []string, contracts ...WatchedContract) (*ABIEventProcessor, error) {
+    parsed, err := abi.JSON(strings.NewReader(abiJSON))
+    if err != nil {
+        return nil, fmt.Errorf("failed to parse ABI: %w", err)
+    }
+
+    if len(eventNames) == 0 {
+        for name := range parsed.Events {
+            eventNames = append(eventNames, name)
+        }
+    }
+
+    p := &ABIEventProcessor{
+        protocolName: protocolName,
+        contracts:    contracts,
+        events:       make(map[common.Hash]abiEvent),
+    }
+
+    for _, name := range eventNames {
+        event, ok := parsed.Events[name]
+        if !ok {
+            return nil, fmt.Errorf("event %s not found in ABI", name)
+        }
+        if event.Anonymous {
+            return nil, fmt.Errorf("event %s is anonymous and can't be matched by signature", name)
+        }
+
+        // Unnamed arguments would collide in EventData, name them by position
+        inputs := make(abi.Arguments, len(event.Inputs))
+        copy(inputs, event.Inputs)
+        for i := range inputs {
+            if inputs[i].Name == "" {
+                inputs[i].Name = fmt.Sprintf("arg%d", i)
+            }
+        }
+
+        indexed := make(abi.Arguments, 0, len(inputs))
+        for _, input := range inputs {
+            if input.Indexed {
+                indexed = append(indexed, input)
+            }
+        }
+
+        p.events[event.ID] = abiEvent{
+            name:      event.RawName,
+            indexed:   indexed,
+            data:      inputs.NonIndexed(),
+            numTopics: len(indexed) + 1,
+        }
+    }
+
+    return p, nil
+}
+
+// NewABIEventProcessorFromConfig creates a processor for a contract of a
+// protocol configuration, using its ABI and deploy block
+func NewABIEventProcessorFromConfig(protocolName string, chain string, config models.ContractConfig, eventNames []string) (*ABIEventProcessor, error) {
+    if config.ABI == "" {
+        return nil, fmt.Errorf("contract %s has no ABI", config.Address.Hex())
+    }
+
+    return NewABIEventProcessor(protocolName, config.ABI, eventNames, WatchedContract{
+        Chain:       chain,
+        Address:     config.Address,
+        DeployBlock: config.DeployBlock,
+    })
+}
+
+func (p *ABIEventProcessor) GetProtocolName() string {
+    return p.protocolName
+}
+
+func (p *ABIEventProcessor) GetContracts() []WatchedContract {
+    return p.contracts
+}
+
+func (p *ABIEventProcessor) GetEventSignatures() []common.Hash {
+    signatures := make([]common.Hash, 0, len(p.events))
+    for sig := range p.events {
+        signatures = append(signatures, sig)
+    }
+    return signatures
+}
+
+func (p *ABIEventProcessor) Process(log types.Log) (*Event, error) {
+    name, data, err := p.Decode(log)
+    if err != nil {
+        return nil, err
+    }
+
+    return &Event{
+        BlockNumber:     log.BlockNumber,
+        BlockHash:       log.BlockHash.Hex(),
+        TransactionHash: log.TxHash.Hex(),
+        LogIndex:        log.Index,
+        Address:         log.Address,
+        EventName:       name,
+        Protocol:        p.protocolName,
+        Data:            data,
+    }, nil
+}
+
+// Decode returns the event name and the decoded indexed and non-indexed
+// arguments of a log
+func (p *ABIEventProcessor) Decode(log types.Log) (string, EventData, error) {
+    if len(log.Topics) == 0 {
+        return "", nil, fmt.Errorf("no topics in log")
+    }
+
+    event, ok := p.events[log.Topics[0]]
+    if !ok {
+        return "", nil, fmt.Errorf("unknown event signature")
+    }
+
+    if len(log.Topics) != event.numTopics {
+        return "", nil, fmt.Errorf("invalid %s topics: expected %d, got %d", event.name, event.numTopics, len(log.Topics))
+    }
+
+    values := make(map[string]interface{})
+    if len(event.indexed) > 0 {
+        if err := abi.ParseTopicsIntoMap(values, event.indexed, log.Topics[1:]); err != nil {
+            return "", nil, fmt.Errorf("failed to decode %s topics: %w", event.name, err)
+        }
+    }
+
+    if len(event.data) > 0 {
+        if err := event.data.UnpackIntoMap(values, log.Data); err != nil {
+            return "", nil, fmt.Errorf("failed to decode %s data: %w", event.name, err)
+        }
+    }
+
+    data := make(EventData, len(values))
+    for name, value := range values {
+        data[toSnakeCase(name)] = normalizeABIValue(value)
+    }
+
+    return event.name, data, nil
+}
+
+// normalizeABIValue converts decoded values into JSON friendly forms
+func normalizeABIValue(value interface{}) interface{} {
+    switch v := value.(type) {
+    case *big.Int:
+        return v.String()
+    case common.Address:
+        return v
+    case common.Hash:
+        return v.Hex()
+    case []byte:
+        return hexutil.Encode(v)
+    }
+
+    rv := reflect.ValueOf(value)
+    switch rv.Kind() {
+    case reflect.Array, reflect.Slice:
+        if rv.Type().Elem().Kind() == reflect.Uint8 {
+            bytes := make([]byte, rv.Len())
+            reflect.Copy(reflect.ValueOf(bytes), rv)
+            return hexutil.Encode(bytes)
+        }
+
+        values := make([]interface{}, rv.Len())
+        for i := range values {
+            values[i] = normalizeABIValue(rv.Index(i).Interface())
+        }
+        return values
+    }
+
+    return value
+}
+
+// toSnakeCase converts ABI argument names to the EventData key style,
+// e.g. amount0In -> amount0_in, useATokens -> use_a_tokens
+func toSnakeCase(name string) string {
+    runes := []rune(strings.TrimLeft(name, "_"))
+
+    var b strings.Builder
+    for i, r := range runes {
+        if unicode.IsUpper(r) {
+            if i > 0 {
+                prev := runes[i-1]
+                nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+                if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+                    b.WriteRune('_')
+                }
+            }
+            r = unicode.ToLower(r)
+        }
+        b.WriteRune(r)
+    }
+
+    return b.String()
+}
@@ -0,0 +1,242 @@
+// internal/indexer/abi_processor_test.go
+package indexer
+
+import (
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testEventsABI = `[
+	{"anonymous":false,"name":"Transfer","type":"event","inputs":[
+		{"indexed":true,"name":"from","type":"address"},
+		{"indexed":true,"name":"to","type":"address"},
+		{"indexed":false,"name":"value","type":"uint256"}]},
+	{"anonymous":false,"name":"Rebalanced","type":"event","inputs":[
+		{"indexed":true,"name":"vaultId","type":"uint256"},
+		{"indexed":true,"name":"label","type":"string"},
+		{"indexed":false,"name":"newWeights","type":"uint256[]"},
+		{"indexed":false,"name":"assets","type":"address[]"},
+		{"indexed":false,"name":"memo","type":"string"},
+		{"indexed":false,"name":"payload","type":"bytes"},
+		{"indexed":false,"name":"root","type":"bytes32"},
+		{"indexed":false,"name":"isFinal","type":"bool"},
+		{"indexed":false,"name":"","type":"int8"}]}
+]`
+
+var (
+	transferID   = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	rebalancedID = crypto.Keccak256Hash([]byte("Rebalanced(uint256,string,uint256[],address[],string,bytes,bytes32,bool,int8)"))
+)
+
+func TestNewABIEventProcessor(t *testing.T) {
+	anonymousABI := `[{"anonymous":true,"name":"Hidden","type":"event","inputs":[]}]`
+
+	tests := []struct {
+		name       string
+		abiJSON    string
+		eventNames []string
+		want       []common.Hash
+		err        string
+	}{
+		{"every event", testEventsABI, nil, []common.Hash{transferID, rebalancedID}, ""},
+		{"named events", testEventsABI, []string{"Transfer"}, []common.Hash{transferID}, ""},
+		{"unknown event", testEventsABI, []string{"Approval"}, nil, "event Approval not found in ABI"},
+		{"anonymous event", anonymousABI, nil, nil, "event Hidden is anonymous"},
+		{"invalid ABI", `[{"type":`, nil, nil, "failed to parse ABI"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := NewABIEventProcessor("test", test.abiJSON, test.eventNames)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("NewABIEventProcessor = %v, want an error containing %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := p.GetEventSignatures()
+			sort.Slice(got, func(i, j int) bool { return got[i].Hex() < got[j].Hex() })
+			want := append([]common.Hash{}, test.want...)
+			sort.Slice(want, func(i, j int) bool { return want[i].Hex() < want[j].Hex() })
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("GetEventSignatures = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestABIEventProcessorDecode(t *testing.T) {
+	p, err := NewABIEventProcessor("test", testEventsABI, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := abi.JSON(strings.NewReader(testEventsABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := common.HexToAddress("0x28C6c06298d514Db089934071355E5743bf21d60")
+	to := common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+
+	transferData, err := parsed.Events["Transfer"].Inputs.NonIndexed().Pack(big.NewInt(2500000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root [32]byte
+	root[0] = 0xaa
+	rebalancedData, err := parsed.Events["Rebalanced"].Inputs.NonIndexed().Pack(
+		[]*big.Int{big.NewInt(6000), big.NewInt(4000)},
+		[]common.Address{from, to},
+		"weekly",
+		[]byte{1, 2, 3},
+		root,
+		true,
+		int8(-3),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		log  types.Log
+		want EventData
+		err  string
+	}{
+		{
+			name: "indexed and non-indexed",
+			log: types.Log{
+				Topics: []common.Hash{transferID, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+				Data:   transferData,
+			},
+			want: EventData{"from": from, "to": to, "value": "2500000"},
+		},
+		{
+			// Indexed dynamic types are hashed into their topic
+			name: "dynamic types and arrays",
+			log: types.Log{
+				Topics: []common.Hash{rebalancedID, common.BigToHash(big.NewInt(7)), crypto.Keccak256Hash([]byte("core"))},
+				Data:   rebalancedData,
+			},
+			want: EventData{
+				"vault_id":    "7",
+				"label":       crypto.Keccak256Hash([]byte("core")).Hex(),
+				"new_weights": []interface{}{"6000", "4000"},
+				"assets":      []interface{}{from, to},
+				"memo":        "weekly",
+				"payload":     "0x010203",
+				"root":        common.Hash(root).Hex(),
+				"is_final":    true,
+				"arg8":        int8(-3),
+			},
+		},
+		{
+			name: "no topics",
+			log:  types.Log{Data: transferData},
+			err:  "no topics in log",
+		},
+		{
+			name: "unknown topic",
+			log:  types.Log{Topics: []common.Hash{crypto.Keccak256Hash([]byte("Approval(address,address,uint256)"))}},
+			err:  "unknown event signature",
+		},
+		{
+			name: "missing topic",
+			log:  types.Log{Topics: []common.Hash{transferID, common.BytesToHash(from.Bytes())}, Data: transferData},
+			err:  "invalid Transfer topics: expected 3, got 2",
+		},
+		{
+			name: "short data",
+			log: types.Log{
+				Topics: []common.Hash{transferID, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+				Data:   transferData[:16],
+			},
+			err: "failed to decode Transfer data",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name, data, err := p.Decode(test.log)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("Decode = %v, want an error containing %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := parsed.Events[name].ID; want != test.log.Topics[0] {
+				t.Errorf("Decode named the event %s, which doesn't match its topic", name)
+			}
+			if !reflect.DeepEqual(data, test.want) {
+				t.Errorf("Decode data = %v, want %v", data, test.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeABIValue(t *testing.T) {
+	address := common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{"big int", big.NewInt(-42), "-42"},
+		{"large big int", new(big.Int).Lsh(big.NewInt(1), 200), new(big.Int).Lsh(big.NewInt(1), 200).String()},
+		{"address", address, address},
+		{"hash", common.HexToHash("0x01"), common.HexToHash("0x01").Hex()},
+		{"bytes", []byte{0xde, 0xad}, "0xdead"},
+		{"fixed bytes", [4]byte{0xca, 0xfe, 0, 1}, "0xcafe0001"},
+		{"big int array", [2]*big.Int{big.NewInt(1), big.NewInt(2)}, []interface{}{"1", "2"}},
+		{"address slice", []common.Address{address}, []interface{}{address}},
+		{"nested", [][]*big.Int{{big.NewInt(1)}, {}}, []interface{}{[]interface{}{"1"}, []interface{}{}}},
+		{"bool", true, true},
+		{"small int", uint8(7), uint8(7)},
+		{"string", "memo", "memo"},
+	}
+	for _, test := range tests {
+		if got := normalizeABIValue(test.value); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("normalizeABIValue(%s) = %#v, want %#v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestToSnakeCase(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"amount", "amount"},
+		{"amount0In", "amount0_in"},
+		{"amount1Out", "amount1_out"},
+		{"sqrtPriceX96", "sqrt_price_x96"},
+		{"useATokens", "use_a_tokens"},
+		{"tickLower", "tick_lower"},
+		{"_reserve0", "reserve0"},
+		{"__owner", "owner"},
+		{"token_amounts", "token_amounts"},
+		{"ID", "id"},
+		{"tokenURI", "token_uri"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := toSnakeCase(test.name); got != test.want {
+			t.Errorf("toSnakeCase(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
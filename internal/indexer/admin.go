@@ -0,0 +1,111 @@
+// internal/indexer/admin.go
+package indexer
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "time"
+
+    "github.com/gorilla/mux"
+)
+
+// StatusReport is the indexer status served by the admin endpoints
+type StatusReport struct {
+    StartedAt  time.Time         `json:"started_at"`
+    Chains     []*IndexerStats   `json:"chains"`
+    Processors []*ProcessorStats `json:"processors"`
+    // Chains whose status could not be read, e.g. because the RPC is down
+    Errors map[string]string `json:"errors,omitempty"`
+}
+
+// Status reports every chain and processor. A chain that fails is listed
+// in Errors instead of failing the whole report.
+func (idx *Indexer) Status(ctx context.Context) *StatusReport {
+    report := &StatusReport{
+        StartedAt:  idx.metrics.started,
+        Chains:     []*IndexerStats{},
+        Processors: []*ProcessorStats{},
+    }
+
+    for _, chain := range idx.Chains() {
+        stats, err := idx.Stats(ctx, chain)
+        if err == nil {
+            var processors []*ProcessorStats
+            if processors, err = idx.ProcessorStats(ctx, chain); err == nil {
+                report.Chains = append(report.Chains, stats)
+                report.Processors = append(report.Processors, processors...)
+                continue
+            }
+        }
+
+        if report.Errors == nil {
+            report.Errors = make(map[string]string)
+        }
+        report.Errors[chain] = err.Error()
+    }
+
+    return report
+}
+
+// NewAdminHandler serves the indexer status:
+//
+//    GET /status           every chain and processor
+//    GET /chains/{chain}   one chain and its processors
+//    GET /processors       every processor on every chain
+func NewAdminHandler(idx *Indexer) http.Handler {
+    r := mux.NewRouter()
+
+    r.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+        writeJSON(w, http.StatusOK, idx.Status(r.Context()))
+    }).Methods("GET")
+
+    r.HandleFunc("/chains/{chain}", func(w http.ResponseWriter, r *http.Request) {
+        chain := mux.Vars(r)["chain"]
+        if _, err := idx.manager.GetClient(chain); err != nil {
+            writeError(w, http.StatusNotFound, "Chain not found: "+chain)
+            return
+        }
+
+        stats, err := idx.Stats(r.Context(), chain)
+        if err != nil {
+            writeError(w, http.StatusBadGateway, err.Error())
+            return
+        }
+
+        processors, err := idx.ProcessorStats(r.Context(), chain)
+        if err != nil {
+            writeError(w, http.StatusBadGateway, err.Error())
+            return
+        }
+
+        writeJSON(w, http.StatusOK, map[string]interface{}{
+            "chain":      stats,
+            "processors": processors,
+        })
+    }).Methods("GET")
+
+    r.HandleFunc("/processors", func(w http.ResponseWriter, r *http.Request) {
+        processors, err := idx.ProcessorStats(r.Context(), "")
+        if err != nil {
+            writeError(w, http.StatusBadGateway, err.Error())
+            return
+        }
+
+        writeJSON(w, http.StatusOK, map[string]interface{}{
+            "processors": processors,
+        })
+    }).Methods("GET")
+
+    return r
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(data)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+    writeJSON(w, status, map[string]string{"error": message})
+}
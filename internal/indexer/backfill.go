@@ -0,0 +1,168 @@
+// internal/indexer/backfill.go
+package indexer
+
+import (
+    "context"
+    "fmt"
+
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// Backfill indexes [from, to] of a chain for the given processors (all when
+// empty), e.g. history before the contracts' checkpoints. Checkpoints don't
+// move, so the range may overlap what is indexed already: stored events are
+// updated in place. Sinks past the range are rewound to deliver it.
+func (idx *Indexer) Backfill(ctx context.Context, chain string, from, to uint64, protocols []string) error {
+    if from > to {
+        return fmt.Errorf("invalid block range %d-%d", from, to)
+    }
+
+    client, err := idx.manager.GetClient(chain)
+    if err != nil {
+        return fmt.Errorf("failed to get client: %w", err)
+    }
+
+    if err := idx.restoreContracts(ctx, chain); err != nil {
+        return fmt.Errorf("failed to restore contracts: %w", err)
+    }
+
+    cur, err := idx.cursorForProtocols(chain, protocols)
+    if err != nil {
+        return err
+    }
+
+    head, err := idx.headBlock(ctx, chain, client)
+    if err != nil {
+        return err
+    }
+    if to > head {
+        return fmt.Errorf("block %d is past the indexable head %d", to, head)
+    }
+
+    finalized, err := idx.finalizedBlock(ctx, chain, client)
+    if err != nil {
+        return err
+    }
+
+    for start := from; start <= to; {
+        end := start + idx.batchSize(chain) - 1
+        if end > to || end < start {
+            end = to
+        }
+
+        job := blockRange{from: start, to: end, finalized: finalized}
+        batch, err := idx.processBatchWithRetry(ctx, chain, client, cur, job)
+        if err != nil {
+            return fmt.Errorf("failed to index blocks %d-%d: %w", start, end, err)
+        }
+
+        // Only contracts discovered in the range need a checkpoint
+        batch.Checkpoints = idx.discoveredCheckpoints(chain, cur, start, end)
+
+        if err := idx.commitBackfill(ctx, chain, batch, start); err != nil {
+            return fmt.Errorf("failed to commit blocks %d-%d: %w", start, end, err)
+        }
+
+        fmt.Printf("[%s] Backfilled blocks %d-%d (%s): %d events\n", chain, start, end, cur, len(batch.Events))
+
+        if end == to {
+            break
+        }
+        start = end + 1
+    }
+
+    return nil
+}
+
+// Reindex deletes the events of the given processors (all when empty) in
+// [from, to] and indexes the range again. If it fails part way, running it
+// again completes the range.
+func (idx *Indexer) Reindex(ctx context.Context, chain string, from, to uint64, protocols []string) error {
+    if from > to || to == 0 {
+        return fmt.Errorf("invalid block range %d-%d", from, to)
+    }
+
+    if len(protocols) == 0 {
+        idx.mu.RLock()
+        for name := range idx.processors {
+            protocols = append(protocols, name)
+        }
+        idx.mu.RUnlock()
+    }
+
+    // Check the processors before deleting anything
+    if _, err := idx.cursorForProtocols(chain, protocols); err != nil {
+        return err
+    }
+
+    idx.reorgMu.Lock()
+    for _, protocol := range protocols {
+        deleted, err := idx.storage.DeleteEvents(ctx, storage.EventFilter{
+            Chain:     chain,
+            Protocol:  protocol,
+            FromBlock: from,
+            ToBlock:   to,
+        })
+        if err != nil {
+            idx.reorgMu.Unlock()
+            return fmt.Errorf("failed to delete %s events: %w", protocol, err)
+        }
+        fmt.Printf("[%s] Deleted %d %s events in blocks %d-%d\n", chain, deleted, protocol, from, to)
+    }
+    idx.reorgMu.Unlock()
+
+    return idx.Backfill(ctx, chain, from, to, protocols)
+}
+
+func (idx *Indexer) commitBackfill(ctx context.Context, chain string, batch *Batch, from uint64) error {
+    idx.reorgMu.Lock()
+    defer idx.reorgMu.Unlock()
+
+    if err := idx.storage.CommitBatch(ctx, batch); err != nil {
+        return err
+    }
+    idx.metrics.recordBatch(chain, batch)
+
+    return idx.rewindSinks(ctx, chain, from)
+}
+
+// cursorForProtocols returns a cursor over the contracts the named
+// processors watch on a chain, or over all of them when none are named
+func (idx *Indexer) cursorForProtocols(chain string, protocols []string) (*cursor, error) {
+    cur := &cursor{contracts: make(map[common.Address]string)}
+
+    idx.mu.RLock()
+    defer idx.mu.RUnlock()
+
+    var processors []EventProcessor
+    if len(protocols) == 0 {
+        for _, processor := range idx.processors {
+            processors = append(processors, processor)
+        }
+    }
+    for _, protocol := range protocols {
+        processor, ok := idx.processors[protocol]
+        if !ok {
+            return nil, fmt.Errorf("no processor registered for %s", protocol)
+        }
+        processors = append(processors, processor)
+    }
+
+    for _, processor := range processors {
+        contracts, all := watchedOn(chain, processor)
+        if all {
+            cur.wildcard = true
+            continue
+        }
+        for _, contract := range contracts {
+            cur.contracts[contract.Address] = processor.GetProtocolName()
+        }
+    }
+
+    if !cur.wildcard && len(cur.contracts) == 0 {
+        return nil, fmt.Errorf("no processor watches contracts on %s", chain)
+    }
+
+    return cur, nil
+}
@@ -0,0 +1,266 @@
+// internal/indexer/cursor.go
+package indexer
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "strings"
+
+    "github.com/ethereum/go-ethereum/common"
+)
+
+// cursor is a group of contracts that resume indexing at the same block and
+// can therefore share one eth_getLogs query
+type cursor struct {
+    next      uint64
+    wildcard  bool                      // processors watching every address
+    contracts map[common.Address]string // address -> protocol
+}
+
+func (c *cursor) String() string {
+    if c.wildcard {
+        return "all contracts"
+    }
+    return fmt.Sprintf("%d contracts", len(c.contracts))
+}
+
+// addresses returns the log filter addresses, nil when any address matches
+func (c *cursor) addresses() []common.Address {
+    if c.wildcard {
+        return nil
+    }
+
+    addresses := make([]common.Address, 0, len(c.contracts))
+    for address := range c.contracts {
+        addresses = append(addresses, address)
+    }
+
+    return addresses
+}
+
+// covers reports whether a processor has logs in the cursor's query
+func (c *cursor) covers(chain string, processor EventProcessor) bool {
+    contracts, all := watchedOn(chain, processor)
+    if all {
+        return c.wildcard
+    }
+
+    for _, contract := range contracts {
+        if _, ok := c.contracts[contract.Address]; ok {
+            return true
+        }
+    }
+
+    return false
+}
+
+// handles reports whether a processor should decode a log from address
+func (c *cursor) handles(chain string, processor EventProcessor, address common.Address) bool {
+    contracts, all := watchedOn(chain, processor)
+    if all {
+        return c.wildcard
+    }
+
+    if _, ok := c.contracts[address]; !ok {
+        return false
+    }
+
+    for _, contract := range contracts {
+        if contract.Address == address {
+            return true
+        }
+    }
+
+    return false
+}
+
+// checkpoints returns the checkpoints of every contract in the cursor at block
+func (c *cursor) checkpoints(chain string, block uint64) []*Checkpoint {
+    checkpoints := make([]*Checkpoint, 0, len(c.contracts)+1)
+    if c.wildcard {
+        checkpoints = append(checkpoints, &Checkpoint{Chain: chain, Block: block})
+    }
+
+    for address, protocol := range c.contracts {
+        checkpoints = append(checkpoints, &Checkpoint{
+            Chain:    chain,
+            Contract: address.Hex(),
+            Protocol: protocol,
+            Block:    block,
+        })
+    }
+
+    return checkpoints
+}
+
+// watchedOn returns the contracts a processor watches on a chain. all is true
+// when the processor declares no contracts and handles logs from any address.
+func watchedOn(chain string, processor EventProcessor) (contracts []WatchedContract, all bool) {
+    declared := processor.GetContracts()
+    if len(declared) == 0 {
+        return nil, true
+    }
+
+    for _, contract := range declared {
+        if contract.Chain == "" || contract.Chain == chain {
+            contracts = append(contracts, contract)
+        }
+    }
+
+    return contracts, false
+}
+
+// loadCursors groups the contracts watched on a chain by the block they resume
+// at, ordered from the furthest behind. A contract resumes after its
+// checkpoint, or at its DeployBlock when it has none, so contracts added later
+// backfill on their own until they catch up with the others.
+func (idx *Indexer) loadCursors(ctx context.Context, chain string, head uint64) ([]*cursor, error) {
+    stored, err := idx.storage.GetCheckpoints(ctx, chain)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get checkpoints: %w", err)
+    }
+
+    checkpoints := make(map[string]uint64, len(stored))
+    for _, checkpoint := range stored {
+        checkpoints[strings.ToLower(checkpoint.Contract)] = checkpoint.Block
+    }
+
+    resume := func(key string, deployBlock uint64) uint64 {
+        if block, ok := checkpoints[strings.ToLower(key)]; ok {
+            return block + 1
+        }
+        if deployBlock > 0 {
+            return deployBlock
+        }
+        return idx.defaultStartBlock(head)
+    }
+
+    byBlock := make(map[uint64]*cursor)
+    group := func(next uint64) *cursor {
+        cur, ok := byBlock[next]
+        if !ok {
+            cur = &cursor{next: next, contracts: make(map[common.Address]string)}
+            byBlock[next] = cur
+        }
+        return cur
+    }
+
+    idx.mu.RLock()
+    for _, processor := range idx.processors {
+        contracts, all := watchedOn(chain, processor)
+        if all {
+            group(resume("", 0)).wildcard = true
+            continue
+        }
+
+        for _, contract := range contracts {
+            cur := group(resume(contract.Address.Hex(), contract.DeployBlock))
+            cur.contracts[contract.Address] = processor.GetProtocolName()
+        }
+    }
+    idx.mu.RUnlock()
+
+    cursors := make([]*cursor, 0, len(byBlock))
+    for _, cur := range byBlock {
+        cursors = append(cursors, cur)
+    }
+
+    sort.Slice(cursors, func(i, j int) bool {
+        return cursors[i].next < cursors[j].next
+    })
+
+    return cursors, nil
+}
+
+// watchAll returns a cursor over every contract watched on a chain
+func (idx *Indexer) watchAll(chain string) *cursor {
+    all := &cursor{contracts: make(map[common.Address]string)}
+
+    idx.mu.RLock()
+    defer idx.mu.RUnlock()
+
+    for _, processor := range idx.processors {
+        contracts, wildcard := watchedOn(chain, processor)
+        if wildcard {
+            all.wildcard = true
+            continue
+        }
+        for _, contract := range contracts {
+            all.contracts[contract.Address] = processor.GetProtocolName()
+        }
+    }
+
+    return all
+}
+
+// discoveredCheckpoints returns checkpoints for contracts a processor found
+// while indexing [from, to] (e.g. new pairs of a factory), placed just before
+// their deploy block. Persisting them before the cursor's own checkpoint
+// moves past the discovery makes sure a restart neither forgets them nor
+// skips their history.
+func (idx *Indexer) discoveredCheckpoints(chain string, cur *cursor, from, to uint64) []*Checkpoint {
+    idx.mu.RLock()
+    defer idx.mu.RUnlock()
+
+    var checkpoints []*Checkpoint
+    for _, processor := range idx.processors {
+        if _, ok := processor.(ContractTracker); !ok || !cur.covers(chain, processor) {
+            continue
+        }
+
+        contracts, _ := watchedOn(chain, processor)
+        for _, contract := range contracts {
+            if _, ok := cur.contracts[contract.Address]; ok {
+                continue
+            }
+            if contract.DeployBlock == 0 || contract.DeployBlock < from || contract.DeployBlock > to {
+                continue
+            }
+
+            checkpoints = append(checkpoints, &Checkpoint{
+                Chain:    chain,
+                Contract: contract.Address.Hex(),
+                Protocol: processor.GetProtocolName(),
+                Block:    contract.DeployBlock - 1,
+            })
+        }
+    }
+
+    return checkpoints
+}
+
+// restoreContracts hands contracts found in the checkpoints back to the
+// processors that discovered them before a restart
+func (idx *Indexer) restoreContracts(ctx context.Context, chain string) error {
+    checkpoints, err := idx.storage.GetCheckpoints(ctx, chain)
+    if err != nil {
+        return err
+    }
+
+    idx.mu.RLock()
+    defer idx.mu.RUnlock()
+
+    for _, checkpoint := range checkpoints {
+        if checkpoint.Contract == "" || !common.IsHexAddress(checkpoint.Contract) {
+            continue
+        }
+
+        processor, ok := idx.processors[checkpoint.Protocol]
+        if !ok {
+            continue
+        }
+
+        tracker, ok := processor.(ContractTracker)
+        if !ok {
+            continue
+        }
+
+        tracker.TrackContract(WatchedContract{
+            Chain:   chain,
+            Address: common.HexToAddress(checkpoint.Contract),
+        })
+    }
+
+    return nil
+}
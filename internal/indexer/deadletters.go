@@ -0,0 +1,175 @@
+// internal/indexer/deadletters.go
+package indexer
+
+import (
+    "context"
+    "fmt"
+
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/ethereum/go-ethereum/common/hexutil"
+    "github.com/ethereum/go-ethereum/core/types"
+    "github.com/zacksfF/evm-tvl-aggregator/internal/models"
+)
+
+// ReplayResult counts the outcome of replaying dead letters
+type ReplayResult struct {
+    Replayed int // decoded and stored, or no longer handled by the processor
+    Failed   int // still failing to decode
+    Skipped  int // no processor registered for the protocol
+}
+
+// newDeadLetter keeps the raw log a processor failed to decode
+func newDeadLetter(chain string, protocol string, log types.Log, err error) *DeadLetter {
+    topics := make([]string, len(log.Topics))
+    for i, topic := range log.Topics {
+        topics[i] = topic.Hex()
+    }
+
+    return &DeadLetter{
+        Chain:           chain,
+        Protocol:        protocol,
+        Address:         log.Address,
+        BlockNumber:     log.BlockNumber,
+        BlockHash:       log.BlockHash.Hex(),
+        TransactionHash: log.TxHash.Hex(),
+        LogIndex:        log.Index,
+        Topics:          topics,
+        Data:            hexutil.Encode(log.Data),
+        Error:           err.Error(),
+    }
+}
+
+// deadLetterLog rebuilds the raw log of a dead letter
+func deadLetterLog(letter *DeadLetter) (types.Log, error) {
+    data, err := hexutil.Decode(letter.Data)
+    if err != nil {
+        return types.Log{}, fmt.Errorf("invalid data: %w", err)
+    }
+
+    topics := make([]common.Hash, len(letter.Topics))
+    for i, topic := range letter.Topics {
+        topics[i] = common.HexToHash(topic)
+    }
+
+    return types.Log{
+        Address:     letter.Address,
+        Topics:      topics,
+        Data:        data,
+        BlockNumber: letter.BlockNumber,
+        BlockHash:   common.HexToHash(letter.BlockHash),
+        TxHash:      common.HexToHash(letter.TransactionHash),
+        Index:       letter.LogIndex,
+    }, nil
+}
+
+// ReplayDeadLetters decodes the dead letters of a chain and protocol ("" for
+// all) again with the registered processors, e.g. after a decoding fix.
+// Decoded events are stored and their dead letters deleted in one commit;
+// letters that still fail keep their latest error. Sinks past the replayed
+// blocks are rewound so they receive the new events.
+func (idx *Indexer) ReplayDeadLetters(ctx context.Context, chain, protocol string) (*ReplayResult, error) {
+    letters, err := idx.storage.GetDeadLetters(ctx, chain, protocol)
+    if err != nil {
+        return nil, fmt.Errorf("failed to load dead letters: %w", err)
+    }
+
+    result := &ReplayResult{}
+    batches := make(map[string]*Batch)
+    finalized := make(map[string]uint64)
+
+    for _, letter := range letters {
+        idx.mu.RLock()
+        processor, ok := idx.processors[letter.Protocol]
+        idx.mu.RUnlock()
+        if !ok {
+            result.Skipped++
+            continue
+        }
+
+        batch, ok := batches[letter.Chain]
+        if !ok {
+            batch = &Batch{Chain: letter.Chain}
+            batches[letter.Chain] = batch
+            finalized[letter.Chain] = idx.replayFinalizedBlock(ctx, letter.Chain)
+        }
+
+        log, err := deadLetterLog(letter)
+        var event *Event
+        if err == nil {
+            event, err = processor.Process(log)
+        }
+        if err != nil {
+            letter.Error = err.Error()
+            batch.DeadLetters = append(batch.DeadLetters, letter)
+            result.Failed++
+            continue
+        }
+
+        batch.Replayed = append(batch.Replayed, letter.ID)
+        result.Replayed++
+        if event == nil {
+            continue
+        }
+
+        event.Chain = letter.Chain
+        event.BlockHash = letter.BlockHash
+        event.Timestamp = letter.Timestamp
+        if len(letter.Topics) > 0 {
+            event.EventSignature = letter.Topics[0]
+        }
+        event.Status = models.FinalityTentative
+        if event.BlockNumber <= finalized[letter.Chain] {
+            event.Status = models.FinalityFinal
+        }
+        batch.Events = append(batch.Events, event)
+    }
+
+    for chain, batch := range batches {
+        if err := idx.commitReplay(ctx, chain, batch); err != nil {
+            return result, err
+        }
+    }
+
+    return result, nil
+}
+
+// replayFinalizedBlock returns the finalized block of a chain, or 0 when the
+// chain can't be reached so replayed events stay tentative
+func (idx *Indexer) replayFinalizedBlock(ctx context.Context, chain string) uint64 {
+    if idx.manager == nil {
+        return 0
+    }
+
+    client, err := idx.manager.GetClient(chain)
+    if err != nil {
+        return 0
+    }
+
+    finalized, err := idx.finalizedBlock(ctx, chain, client)
+    if err != nil {
+        return 0
+    }
+    return finalized
+}
+
+func (idx *Indexer) commitReplay(ctx context.Context, chain string, batch *Batch) error {
+    idx.reorgMu.Lock()
+    defer idx.reorgMu.Unlock()
+
+    if err := idx.storage.CommitBatch(ctx, batch); err != nil {
+        return fmt.Errorf("failed to commit replayed events for %s: %w", chain, err)
+    }
+
+    if len(batch.Events) == 0 {
+        return nil
+    }
+
+    first := batch.Events[0].BlockNumber
+    for _, event := range batch.Events {
+        if event.BlockNumber < first {
+            first = event.BlockNumber
+        }
+    }
+
+    return idx.rewindSinks(ctx, chain, first)
+}
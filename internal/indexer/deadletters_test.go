@@ -0,0 +1,116 @@
+// internal/indexer/deadletters_test.go
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/blockchain"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/memory"
+)
+
+func TestReplayDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStorage()
+	idx := NewIndexer(blockchain.NewManager(), store, Config{})
+
+	processor, err := NewABIEventProcessor("token", testEventsABI, []string{"Transfer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx.RegisterProcessor(processor)
+
+	from := common.HexToAddress("0x28C6c06298d514Db089934071355E5743bf21d60")
+	to := common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	transfer := func(block uint64, topics ...common.Hash) types.Log {
+		return types.Log{
+			Address:     common.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7"),
+			Topics:      append([]common.Hash{transferID}, topics...),
+			Data:        common.LeftPadBytes(big.NewInt(2500000).Bytes(), 32),
+			BlockNumber: block,
+			BlockHash:   common.BigToHash(new(big.Int).SetUint64(block)),
+			TxHash:      common.BigToHash(new(big.Int).SetUint64(block + 1000)),
+		}
+	}
+
+	decodable := newDeadLetter("ethereum", "token", transfer(150, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())),
+		errors.New("unknown event signature"))
+	decodable.Timestamp = time.Unix(150*12, 0).UTC()
+	// A topic short, no processor fix can decode it
+	broken := newDeadLetter("ethereum", "token", transfer(160, common.BytesToHash(from.Bytes())),
+		errors.New("unknown event signature"))
+	unhandled := newDeadLetter("ethereum", "lend", transfer(170), errors.New("unknown event signature"))
+
+	if err := store.SaveDeadLetters(ctx, []*models.DeadLetter{decodable, broken, unhandled}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveSinkOffset(ctx, "ndjson", "ethereum", 200); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := idx.ReplayDeadLetters(ctx, "ethereum", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Replayed != 1 || result.Failed != 1 || result.Skipped != 1 {
+		t.Errorf("ReplayDeadLetters = %+v, want 1 replayed, 1 failed and 1 skipped", result)
+	}
+
+	// The decoded letter became an event
+	events, err := store.GetEvents(ctx, storage.EventFilter{Chain: "ethereum"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("%d events after the replay, want the decoded transfer", len(events))
+	}
+	event := events[0]
+	if event.BlockNumber != 150 || event.EventName != "Transfer" || event.Protocol != "token" ||
+		event.Data["value"] != "2500000" || fmt.Sprint(event.Data["to"]) != to.Hex() ||
+		event.EventSignature != transferID.Hex() || event.BlockHash != decodable.BlockHash ||
+		!event.Timestamp.Equal(decodable.Timestamp) || event.Status != models.FinalityTentative {
+		t.Errorf("replayed event = %+v, want the tentative transfer of block 150", event)
+	}
+
+	// Its letter is gone, the others stay and the failing one has its new error
+	letters, err := store.GetDeadLetters(ctx, "ethereum", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 2 || letters[0].BlockNumber != 160 || letters[1].BlockNumber != 170 {
+		t.Fatalf("%d dead letters after the replay, want those of blocks 160 and 170", len(letters))
+	}
+	if !strings.Contains(letters[0].Error, "invalid Transfer topics") {
+		t.Errorf("error of the letter that still fails = %q, want the one of the replay", letters[0].Error)
+	}
+	if letters[1].Error != "unknown event signature" {
+		t.Errorf("error of the skipped letter = %q, want it unchanged", letters[1].Error)
+	}
+
+	// Sinks past the replayed event deliver it
+	offsets, err := store.GetSinkOffsets(ctx, "ethereum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offsets["ndjson"] != 149 {
+		t.Errorf("sink offset after the replay = %d, want 149", offsets["ndjson"])
+	}
+
+	// Replaying again only tries the letter that still fails
+	result, err = idx.ReplayDeadLetters(ctx, "ethereum", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Replayed != 0 || result.Failed != 1 || result.Skipped != 0 {
+		t.Errorf("second ReplayDeadLetters = %+v, want 1 failed", result)
+	}
+}
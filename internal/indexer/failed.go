@@ -0,0 +1,144 @@
+// internal/indexer/failed.go
+package indexer
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "time"
+
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/zacksfF/evm-tvl-aggregator/internal/blockchain"
+)
+
+// processBatchWithRetry processes a batch, retrying up to RetryAttempts times
+func (idx *Indexer) processBatchWithRetry(ctx context.Context, chain string, client *blockchain.Client, cur *cursor, job blockRange) (*Batch, error) {
+    attempts := idx.config.RetryAttempts
+    if attempts < 1 {
+        attempts = 1
+    }
+
+    var err error
+    for attempt := 1; attempt <= attempts; attempt++ {
+        var batch *Batch
+        if batch, err = idx.processBatch(ctx, chain, client, cur, job.from, job.to, job.finalized); err == nil {
+            return batch, nil
+        }
+
+        if attempt == attempts {
+            break
+        }
+
+        select {
+        case <-ctx.Done():
+            return nil, ctx.Err()
+        case <-time.After(idx.config.RetryDelay):
+        }
+    }
+
+    return nil, err
+}
+
+// retryFailedRanges indexes the persisted failed ranges of a chain again and
+// forgets the ones that succeed
+func (idx *Indexer) retryFailedRanges(ctx context.Context, chain string, client *blockchain.Client) {
+    ranges, err := idx.storage.GetFailedRanges(ctx, chain)
+    if err != nil {
+        fmt.Printf("[%s] Failed to load failed ranges: %v\n", chain, err)
+        return
+    }
+    if len(ranges) == 0 {
+        return
+    }
+
+    finalized, err := idx.finalizedBlock(ctx, chain, client)
+    if err != nil {
+        fmt.Printf("[%s] Failed to get finalized block: %v\n", chain, err)
+        return
+    }
+
+    for _, failed := range ranges {
+        if ctx.Err() != nil {
+            return
+        }
+
+        cur := idx.cursorFor(chain, failed.Contracts)
+        if !cur.wildcard && len(cur.contracts) == 0 {
+            // None of the contracts is watched anymore
+            if err := idx.storage.DeleteFailedRange(ctx, failed.ID); err != nil {
+                fmt.Printf("[%s] Failed to delete range %d-%d: %v\n", chain, failed.FromBlock, failed.ToBlock, err)
+            }
+            continue
+        }
+
+        generation := idx.reorgGeneration(chain)
+        batch, err := idx.processBatch(ctx, chain, client, cur, failed.FromBlock, failed.ToBlock, finalized)
+        if err != nil {
+            failed.LastError = err.Error()
+            if err := idx.storage.SaveFailedRange(ctx, failed); err != nil {
+                fmt.Printf("[%s] Failed to update range %d-%d: %v\n", chain, failed.FromBlock, failed.ToBlock, err)
+            }
+            fmt.Printf("[%s] Retry of blocks %d-%d failed (attempt %d): %v\n", chain, failed.FromBlock, failed.ToBlock, failed.Attempts, err)
+            continue
+        }
+
+        // The cursors' checkpoints are past this range already, only contracts
+        // discovered in it need one
+        batch.Checkpoints = idx.discoveredCheckpoints(chain, cur, failed.FromBlock, failed.ToBlock)
+        batch.Resolved = failed.ID
+
+        idx.reorgMu.Lock()
+        if idx.reorgGens[chain] != generation {
+            // Fetched before a rollback, maybe from the abandoned fork; the
+            // range stays for the next retry
+            idx.reorgMu.Unlock()
+            fmt.Printf("[%s] Reorg during the retry of blocks %d-%d, retrying later\n", chain, failed.FromBlock, failed.ToBlock)
+            continue
+        }
+        err = idx.storage.CommitBatch(ctx, batch)
+        idx.reorgMu.Unlock()
+        if err != nil {
+            fmt.Printf("[%s] Failed to commit blocks %d-%d: %v\n", chain, failed.FromBlock, failed.ToBlock, err)
+            continue
+        }
+
+        idx.metrics.recordBatch(chain, batch)
+        fmt.Printf("[%s] Recovered blocks %d-%d: %d events\n", chain, failed.FromBlock, failed.ToBlock, len(batch.Events))
+    }
+}
+
+// keys identifies the cursor's contracts, "" standing for every address
+func (c *cursor) keys() []string {
+    keys := make([]string, 0, len(c.contracts)+1)
+    if c.wildcard {
+        keys = append(keys, "")
+    }
+
+    for address := range c.contracts {
+        keys = append(keys, address.Hex())
+    }
+
+    sort.Strings(keys)
+    return keys
+}
+
+// cursorFor rebuilds a cursor from contract keys, keeping only the contracts
+// that are still watched
+func (idx *Indexer) cursorFor(chain string, keys []string) *cursor {
+    all := idx.watchAll(chain)
+    cur := &cursor{contracts: make(map[common.Address]string)}
+
+    for _, key := range keys {
+        if key == "" {
+            cur.wildcard = all.wildcard
+            continue
+        }
+
+        address := common.HexToAddress(key)
+        if protocol, ok := all.contracts[address]; ok {
+            cur.contracts[address] = protocol
+        }
+    }
+
+    return cur
+}
@@ -0,0 +1,158 @@
+// internal/indexer/headers.go
+package indexer
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/ethereum/go-ethereum/common"
+    "github.com/zacksfF/evm-tvl-aggregator/internal/blockchain"
+)
+
+// Headers kept per chain; batches and the reorg check mostly touch recent blocks
+const headerCacheSize = 4096
+
+// headerCache remembers the hash and timestamp of fetched block headers
+type headerCache struct {
+    mu     sync.Mutex
+    chains map[string]map[uint64]*Block
+}
+
+func newHeaderCache() *headerCache {
+    return &headerCache{chains: make(map[string]map[uint64]*Block)}
+}
+
+func (c *headerCache) get(chain string, number uint64) (*Block, bool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    block, ok := c.chains[chain][number]
+    return block, ok
+}
+
+func (c *headerCache) put(chain string, blocks []*Block) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    cached := c.chains[chain]
+    if cached == nil {
+        cached = make(map[uint64]*Block)
+        c.chains[chain] = cached
+    }
+
+    for _, block := range blocks {
+        cached[block.Number] = block
+    }
+
+    if len(cached) <= headerCacheSize {
+        return
+    }
+
+    // Evict the oldest blocks
+    numbers := make([]uint64, 0, len(cached))
+    for number := range cached {
+        numbers = append(numbers, number)
+    }
+    sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+    for _, number := range numbers[:len(numbers)-headerCacheSize] {
+        delete(cached, number)
+    }
+}
+
+// invalidate drops headers at or above fromBlock after a reorg
+func (c *headerCache) invalidate(chain string, fromBlock uint64) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    for number := range c.chains[chain] {
+        if number >= fromBlock {
+            delete(c.chains[chain], number)
+        }
+    }
+}
+
+// blockHeaders returns the on-chain hash and timestamp of blocks, fetching
+// the ones missing from the cache in one batched request
+func (idx *Indexer) blockHeaders(ctx context.Context, chain string, client *blockchain.Client, numbers []uint64) (map[uint64]*Block, error) {
+    blocks := make(map[uint64]*Block, len(numbers))
+    var missing []uint64
+
+    for _, number := range numbers {
+        if _, seen := blocks[number]; seen {
+            continue
+        }
+        if block, ok := idx.headers.get(chain, number); ok {
+            blocks[number] = block
+            continue
+        }
+        blocks[number] = nil
+        missing = append(missing, number)
+    }
+
+    if len(missing) > 0 {
+        headers, err := client.GetHeadersByNumber(ctx, missing)
+        if err != nil {
+            return nil, err
+        }
+
+        fetched := make([]*Block, 0, len(headers))
+        for _, number := range missing {
+            header, ok := headers[number]
+            if !ok {
+                return nil, fmt.Errorf("header for block %d not found", number)
+            }
+
+            block := &Block{
+                Chain:      chain,
+                Number:     number,
+                Hash:       header.Hash().Hex(),
+                ParentHash: header.ParentHash.Hex(),
+                Timestamp:  time.Unix(int64(header.Time), 0).UTC(),
+            }
+            blocks[number] = block
+            fetched = append(fetched, block)
+        }
+
+        idx.headers.put(chain, fetched)
+    }
+
+    return blocks, nil
+}
+
+// stampEvents sets the block hash and on-chain timestamp of events. A log
+// whose block hash differs from the canonical header was reorged out while
+// the batch was fetched, so the batch has to be fetched again.
+func stampEvents(events []*Event, blocks map[uint64]*Block) error {
+    for _, event := range events {
+        block, ok := blocks[event.BlockNumber]
+        if !ok || block == nil {
+            return fmt.Errorf("no header for block %d", event.BlockNumber)
+        }
+
+        if event.BlockHash != "" && event.BlockHash != (common.Hash{}).Hex() && event.BlockHash != block.Hash {
+            return fmt.Errorf("log in block %d has hash %s, canonical is %s", event.BlockNumber, event.BlockHash, block.Hash)
+        }
+
+        event.BlockHash = block.Hash
+        event.Timestamp = block.Timestamp
+    }
+
+    return nil
+}
+
+// BlockAtTime returns the last indexed block mined at or before t. Blocks
+// are recorded for every block with events and the end of every batch.
+func (idx *Indexer) BlockAtTime(ctx context.Context, chain string, t time.Time) (*Block, error) {
+    block, err := idx.storage.GetBlockAtTime(ctx, chain, t)
+    if err != nil {
+        return nil, err
+    }
+    if block == nil {
+        return nil, fmt.Errorf("no block indexed on %s at or before %s", chain, t.Format(time.RFC3339))
+    }
+
+    return block, nil
+}
@@ -3,45 +3,79 @@ package indexer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/zacksfF/evm-tvl-aggregator/internal/blockchain"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
 )
 
 type Indexer struct {
-    manager      *blockchain.Manager
-    storage      Storage
-    processors   map[string]EventProcessor
-    config       Config
-    wg           sync.WaitGroup
-    mu           sync.RWMutex
+    manager       *blockchain.Manager
+    storage       Storage
+    processors    map[string]EventProcessor
+    reorgHandlers []ReorgHandler
+    sinks         []EventSink
+    sinkWake      map[string][]chan struct{} // per-chain wake-ups of the sink loops, guarded by sinkMu
+    sinkMu        sync.Mutex
+    reorgMu       sync.Mutex
+    reorgGens     map[string]uint64 // per-chain count of rollbacks, guarded by reorgMu
+    headers       *headerCache
+    metrics       *metrics
+    batchSizes    map[string]uint64 // per-chain eth_getLogs range, adapted to the provider
+    batchMu       sync.Mutex
+    config        Config
+    loopMu        sync.Mutex
+    ctx           context.Context       // the context of Start, guarded by loopMu
+    loops         map[string]*chainLoop // running chain loops, guarded by loopMu
+    sinkChains    map[string]bool       // chains whose sinks are running, guarded by loopMu
+    wg            sync.WaitGroup
+    mu            sync.RWMutex
 }
 
 type Config struct {
     BatchSize          uint64
+    MaxBatchSize       uint64         // upper bound when growing batches, 0 means 16 * BatchSize
     WorkerCount        int
-    BlockConfirmations uint64
+    BlockConfirmations uint64         // default for chains using FinalityLatest
     RetryAttempts      int
     RetryDelay         time.Duration
-    StartFromBlock     uint64  // 0 means latest - 1000
+    StartFromBlock     uint64         // 0 means latest - 1000
+    MaxReorgDepth      int            // indexed blocks checked for a fork point, 0 means 64
+    PollInterval       time.Duration  // how often to look for new blocks once caught up, 0 means 15s
 }
 
-type Storage interface {
-    SaveBlock(ctx context.Context, block *Block) error
-    GetLastIndexedBlock(ctx context.Context, chain string) (uint64, error)
-    SaveEvents(ctx context.Context, events []*Event) error
-    GetEvents(ctx context.Context, chain string, from, to uint64) ([]*Event, error)
-}
+// errReorged aborts a range whose batches were fetched before a rollback
+var errReorged = errors.New("chain reorganized while indexing")
+
+// Storage is where the indexer keeps blocks, events and its progress
+type Storage = storage.IndexerStorage
+
+// ReorgHandler is notified after the indexer rolled back a chain to forkBlock,
+// so derived data (e.g. TVL snapshots) past the fork can be discarded too
+type ReorgHandler func(ctx context.Context, chain string, forkBlock uint64) error
 
 type EventProcessor interface {
     Process(log types.Log) (*Event, error)
     GetEventSignatures() []common.Hash
     GetProtocolName() string
+    // GetContracts returns the contracts whose logs the processor handles.
+    // An empty list means logs from any address are processed.
+    GetContracts() []WatchedContract
+}
+
+// ContractTracker is implemented by processors that discover contracts while
+// indexing (e.g. pairs created by a factory). The indexer hands contracts
+// found in its checkpoints back to the processor after a restart.
+type ContractTracker interface {
+    TrackContract(contract WatchedContract)
 }
 
 func NewIndexer(manager *blockchain.Manager, storage Storage, config Config) *Indexer {
@@ -49,6 +83,13 @@ func NewIndexer(manager *blockchain.Manager, storage Storage, config Config) *In
         manager:    manager,
         storage:    storage,
         processors: make(map[string]EventProcessor),
+        batchSizes: make(map[string]uint64),
+        reorgGens:  make(map[string]uint64),
+        sinkWake:   make(map[string][]chan struct{}),
+        loops:      make(map[string]*chainLoop),
+        sinkChains: make(map[string]bool),
+        headers:    newHeaderCache(),
+        metrics:    newMetrics(),
         config:     config,
     }
 }
@@ -62,211 +103,625 @@ func (idx *Indexer) RegisterProcessor(processor EventProcessor) {
     fmt.Printf("Registered processor for protocol: %s\n", name)
 }
 
-func (idx *Indexer) Start(ctx context.Context) error {
-    chains := idx.manager.GetSupportedChains()
+// OnReorg registers a handler that is called whenever a chain is rolled back
+func (idx *Indexer) OnReorg(handler ReorgHandler) {
+    idx.mu.Lock()
+    defer idx.mu.Unlock()
     
-    for _, chain := range chains {
-        idx.wg.Add(1)
-        go func(chainName string) {
-            defer idx.wg.Done()
-            
-            if err := idx.indexChain(ctx, chainName); err != nil {
-                fmt.Printf("Error indexing chain %s: %v\n", chainName, err)
-            }
-        }(chain)
+    idx.reorgHandlers = append(idx.reorgHandlers, handler)
+}
+
+// chainLoop is the indexing of one chain, stopped by cancel and finished
+// once done is closed
+type chainLoop struct {
+    cancel context.CancelFunc
+    done   chan struct{}
+}
+
+// Start indexes the manager's chains until ctx is done. Chains connected or
+// updated later are indexed once passed to StartChain.
+func (idx *Indexer) Start(ctx context.Context) error {
+    idx.loopMu.Lock()
+    idx.ctx = ctx
+    for _, chain := range idx.manager.GetSupportedChains() {
+        idx.startChain(chain)
     }
+    idx.loopMu.Unlock()
     
+    <-ctx.Done()
+    // Chains started from now on see ctx done; wait for those started before
+    idx.loopMu.Lock()
+    idx.loopMu.Unlock()
     idx.wg.Wait()
     return nil
 }
 
+// StartChain starts indexing a chain of the manager, unless the indexer
+// isn't running or already indexes it
+func (idx *Indexer) StartChain(chain string) {
+    idx.loopMu.Lock()
+    defer idx.loopMu.Unlock()
+    
+    idx.startChain(chain)
+}
+
+// StopChain stops indexing a chain and waits until its client is no longer
+// in use, so the manager can replace or close it. The chain's sinks keep
+// delivering what was indexed.
+func (idx *Indexer) StopChain(chain string) {
+    idx.loopMu.Lock()
+    loop := idx.loops[chain]
+    delete(idx.loops, chain)
+    idx.loopMu.Unlock()
+    
+    if loop == nil {
+        return
+    }
+    loop.cancel()
+    <-loop.done
+}
+
+// startChain starts the loop of a chain; loopMu must be held
+func (idx *Indexer) startChain(chain string) {
+    if idx.ctx == nil || idx.ctx.Err() != nil {
+        return
+    }
+    if loop := idx.loops[chain]; loop != nil {
+        select {
+        case <-loop.done:
+            // Failed earlier; start over
+        default:
+            return
+        }
+    }
+    
+    if !idx.sinkChains[chain] {
+        idx.sinkChains[chain] = true
+        idx.startSinks(idx.ctx, chain)
+    }
+    
+    ctx, cancel := context.WithCancel(idx.ctx)
+    loop := &chainLoop{cancel: cancel, done: make(chan struct{})}
+    idx.loops[chain] = loop
+    
+    idx.wg.Add(1)
+    go func() {
+        defer idx.wg.Done()
+        defer close(loop.done)
+        defer cancel()
+        
+        if err := idx.indexChain(ctx, chain); err != nil && ctx.Err() == nil {
+            fmt.Printf("Error indexing chain %s: %v\n", chain, err)
+        }
+    }()
+}
+
 func (idx *Indexer) indexChain(ctx context.Context, chainName string) error {
     client, err := idx.manager.GetClient(chainName)
     if err != nil {
         return fmt.Errorf("failed to get client: %w", err)
     }
     
-    // Get starting block
-    startBlock, err := idx.getStartBlock(ctx, chainName, client)
-    if err != nil {
-        return fmt.Errorf("failed to get start block: %w", err)
+    if err := idx.restoreContracts(ctx, chainName); err != nil {
+        return fmt.Errorf("failed to restore contracts: %w", err)
     }
     
-    fmt.Printf("Starting indexer for %s from block %d\n", chainName, startBlock)
+    fmt.Printf("Starting indexer for %s\n", chainName)
     
-    // Start real-time listener if WebSocket available
+    // Start real-time listener if WebSocket available, and have it done
+    // with the client before returning
     if client.IsWebSocketAvailable() {
-        go idx.listenToRealtimeEvents(ctx, chainName, client)
+        var listener sync.WaitGroup
+        defer listener.Wait()
+        
+        listener.Add(1)
+        go func() {
+            defer listener.Done()
+            idx.listenToRealtimeEvents(ctx, chainName, client)
+        }()
     }
     
-    // Start historical indexing
-    return idx.indexHistoricalEvents(ctx, chainName, client, startBlock)
+    pollInterval := idx.config.PollInterval
+    if pollInterval == 0 {
+        pollInterval = 15 * time.Second
+    }
+    
+    for {
+        caughtUp, err := idx.indexNextRange(ctx, chainName, client)
+        if err != nil {
+            if ctx.Err() != nil {
+                return ctx.Err()
+            }
+            fmt.Printf("[%s] Indexing error: %v\n", chainName, err)
+            idx.metrics.recordIndexingError(chainName)
+            caughtUp = true
+        }
+        
+        if !caughtUp {
+            continue
+        }
+        
+        // Revisit ranges that failed earlier while there is nothing new
+        idx.retryFailedRanges(ctx, chainName, client)
+        
+        // Wait for the head of the chain to move
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-time.After(pollInterval):
+        }
+    }
 }
 
-func (idx *Indexer) getStartBlock(ctx context.Context, chain string, client *blockchain.Client) (uint64, error) {
-    // Check last indexed block from storage
-    lastIndexed, err := idx.storage.GetLastIndexedBlock(ctx, chain)
-    if err == nil && lastIndexed > 0 {
-        return lastIndexed + 1, nil
+// indexNextRange advances the cursor that is furthest behind, up to the point
+// where it catches up with the next cursor or the head. It reports whether
+// every cursor has reached the head.
+func (idx *Indexer) indexNextRange(ctx context.Context, chain string, client *blockchain.Client) (bool, error) {
+    head, err := idx.headBlock(ctx, chain, client)
+    if err != nil {
+        return false, err
+    }
+    
+    // Make sure what we indexed so far is still canonical before building on
+    // it; a rollback rewinds the checkpoints the cursors are loaded from
+    if _, _, err := idx.detectReorg(ctx, chain, client); err != nil {
+        return false, fmt.Errorf("failed to check for reorg: %w", err)
+    }
+    
+    cursors, err := idx.loadCursors(ctx, chain, head)
+    if err != nil {
+        return false, err
+    }
+    if len(cursors) == 0 || cursors[0].next > head {
+        return true, nil
+    }
+    
+    current := cursors[0]
+    to := head
+    if len(cursors) > 1 && cursors[1].next-1 < to {
+        // Stop where the next cursor starts so both merge into one query
+        to = cursors[1].next - 1
+    }
+    
+    if err := idx.indexHistoricalEvents(ctx, chain, client, current, current.next, to); err != nil {
+        if errors.Is(err, errReorged) {
+            return false, nil // Start over from the rewound checkpoints
+        }
+        return false, err
+    }
+    
+    return false, nil
+}
+
+// headBlock returns the highest block the indexer may index for a chain,
+// following the chain's configured finality mode
+func (idx *Indexer) headBlock(ctx context.Context, chain string, client *blockchain.Client) (uint64, error) {
+    config, err := idx.manager.GetChainConfig(chain)
+    if err != nil {
+        return 0, err
+    }
+    
+    switch config.Finality {
+    case blockchain.FinalitySafe:
+        return client.GetSafeBlockNumber(ctx)
+    case blockchain.FinalityFinalized:
+        return client.GetFinalizedBlockNumber(ctx)
     }
     
-    // If no previous index or error, start from current - config.StartFromBlock
     currentBlock, err := client.GetBlockNumber(ctx)
     if err != nil {
         return 0, err
     }
     
+    confirmations := config.Confirmations
+    if confirmations == 0 {
+        confirmations = idx.config.BlockConfirmations
+    }
+    
+    if currentBlock > confirmations {
+        return currentBlock - confirmations, nil
+    }
+    return 0, nil
+}
+
+// finalizedBlock returns the highest block that can no longer be reorged.
+// Chains without the "finalized" tag fall back to latest minus confirmations.
+func (idx *Indexer) finalizedBlock(ctx context.Context, chain string, client *blockchain.Client) (uint64, error) {
+    if finalized, err := client.GetFinalizedBlockNumber(ctx); err == nil {
+        return finalized, nil
+    }
+    
+    config, err := idx.manager.GetChainConfig(chain)
+    if err != nil {
+        return 0, err
+    }
+    
+    currentBlock, err := client.GetBlockNumber(ctx)
+    if err != nil {
+        return 0, err
+    }
+    
+    confirmations := config.Confirmations
+    if confirmations == 0 {
+        confirmations = idx.config.BlockConfirmations
+    }
+    
+    if currentBlock > confirmations {
+        return currentBlock - confirmations, nil
+    }
+    return 0, nil
+}
+
+// defaultStartBlock is where contracts without a checkpoint or deploy block start
+func (idx *Indexer) defaultStartBlock(head uint64) uint64 {
     startFrom := idx.config.StartFromBlock
     if startFrom == 0 {
         startFrom = 1000 // Default to last 1000 blocks
     }
     
-    if currentBlock > startFrom {
-        return currentBlock - startFrom, nil
+    if head > startFrom {
+        return head - startFrom
     }
     
-    return 0, nil
+    return 0
 }
 
-func (idx *Indexer) indexHistoricalEvents(ctx context.Context, chain string, client *blockchain.Client, fromBlock uint64) error {
-    currentBlock, err := client.GetBlockNumber(ctx)
+// indexHistoricalEvents indexes [fromBlock, currentBlock] for a cursor.
+// Workers fetch batches concurrently, but batches are committed strictly in
+// block order, each together with its checkpoint. The checkpoint is therefore
+// a contiguous watermark: everything below it is stored, nothing above it is
+// assumed to be, and a restart resumes exactly where the last commit ended.
+func (idx *Indexer) indexHistoricalEvents(ctx context.Context, chain string, client *blockchain.Client, cur *cursor, fromBlock, currentBlock uint64) error {
+    finalized, err := idx.finalizedBlock(ctx, chain, client)
     if err != nil {
         return err
     }
     
-    // Account for confirmations
-    if currentBlock > idx.config.BlockConfirmations {
-        currentBlock -= idx.config.BlockConfirmations
-    }
+    generation := idx.reorgGeneration(chain)
+    
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
     
-    batchSize := idx.config.BatchSize
     workerChan := make(chan blockRange, idx.config.WorkerCount)
+    results := make(chan batchResult, idx.config.WorkerCount)
     
     // Start workers
-    workerCtx, cancel := context.WithCancel(ctx)
-    defer cancel()
-    
+    var workers sync.WaitGroup
     for i := 0; i < idx.config.WorkerCount; i++ {
-        idx.wg.Add(1)
-        go idx.worker(workerCtx, chain, client, workerChan)
+        workers.Add(1)
+        go idx.worker(ctx, chain, client, cur, workerChan, results, &workers)
     }
     
+    go func() {
+        workers.Wait()
+        close(results)
+    }()
+    
     // Send work to workers
-    for fromBlock < currentBlock {
-        toBlock := fromBlock + batchSize - 1
-        if toBlock > currentBlock {
-            toBlock = currentBlock
+    go func() {
+        defer close(workerChan)
+        
+        for from := fromBlock; from <= currentBlock; {
+            to := from + idx.batchSize(chain) - 1
+            if to > currentBlock {
+                to = currentBlock
+            }
+            
+            select {
+            case workerChan <- blockRange{from: from, to: to, finalized: finalized}:
+                from = to + 1
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+    
+    // Commit completed batches in order
+    pending := make(map[uint64]batchResult)
+    next := fromBlock
+    var commitErr error
+    for result := range results {
+        if commitErr != nil {
+            continue // Drain until the workers are gone
         }
         
-        select {
-        case workerChan <- blockRange{from: fromBlock, to: toBlock}:
-            fromBlock = toBlock + 1
-        case <-ctx.Done():
-            close(workerChan)
-            return ctx.Err()
+        pending[result.job.from] = result
+        for {
+            ready, ok := pending[next]
+            if !ok {
+                break
+            }
+            delete(pending, next)
+            
+            if err := idx.commitBatch(ctx, chain, cur, ready, generation); err != nil {
+                commitErr = err
+                cancel()
+                break
+            }
+            next = ready.job.to + 1
         }
     }
     
-    close(workerChan)
+    if commitErr != nil {
+        return commitErr
+    }
+    if next <= currentBlock {
+        return ctx.Err()
+    }
+    
+    // Events that were stored while still reorgable may be final by now
+    if err := idx.storage.FinalizeEvents(ctx, chain, finalized); err != nil {
+        fmt.Printf("[%s] Failed to finalize events: %v\n", chain, err)
+    }
+    
     return nil
 }
 
 type blockRange struct {
-    from uint64
-    to   uint64
+    from      uint64
+    to        uint64
+    finalized uint64 // highest block that can no longer be reorged
+}
+
+type batchResult struct {
+    job   blockRange
+    batch *Batch
+    err   error
 }
 
-func (idx *Indexer) worker(ctx context.Context, chain string, client *blockchain.Client, jobs <-chan blockRange) {
-    defer idx.wg.Done()
+func (idx *Indexer) worker(ctx context.Context, chain string, client *blockchain.Client, cur *cursor, jobs <-chan blockRange, results chan<- batchResult, workers *sync.WaitGroup) {
+    defer workers.Done()
     
     for job := range jobs {
-        if err := idx.processBatch(ctx, chain, client, job.from, job.to); err != nil {
+        batch, err := idx.processBatchWithRetry(ctx, chain, client, cur, job)
+        if err != nil {
             fmt.Printf("Error processing batch %d-%d: %v\n", job.from, job.to, err)
-            // Implement retry logic here if needed
         }
+        results <- batchResult{job: job, batch: batch, err: err}
         
         // Small delay to avoid overwhelming the RPC
         time.Sleep(100 * time.Millisecond)
     }
 }
 
-func (idx *Indexer) processBatch(ctx context.Context, chain string, client *blockchain.Client, from, to uint64) error {
-    signatures := idx.getAllEventSignatures()
-    if len(signatures) == 0 {
-        return nil // No events to index
+// commitBatch stores a batch with the cursor's checkpoints. A batch that
+// failed is stored as a failed range for the retry loop, which lets the
+// watermark move past it without losing it.
+func (idx *Indexer) commitBatch(ctx context.Context, chain string, cur *cursor, result batchResult, generation uint64) error {
+    if ctx.Err() != nil {
+        return ctx.Err()
     }
     
-    filter := blockchain.EventFilter{
-        FromBlock: big.NewInt(int64(from)),
-        ToBlock:   big.NewInt(int64(to)),
-        Topics:    [][]common.Hash{signatures},
+    batch := result.batch
+    if result.err != nil {
+        batch = &Batch{
+            Chain: chain,
+            Failed: &FailedRange{
+                Chain:     chain,
+                Contracts: cur.keys(),
+                FromBlock: result.job.from,
+                ToBlock:   result.job.to,
+                LastError: result.err.Error(),
+            },
+        }
     }
     
-    logs, err := client.GetLogs(ctx, filter)
-    if err != nil {
-        return fmt.Errorf("failed to get logs: %w", err)
+    batch.Checkpoints = append(
+        idx.discoveredCheckpoints(chain, cur, result.job.from, result.job.to),
+        cur.checkpoints(chain, result.job.to)...,
+    )
+    
+    idx.reorgMu.Lock()
+    defer idx.reorgMu.Unlock()
+    
+    // Batches fetched before a rollback may belong to the abandoned fork
+    if idx.reorgGens[chain] != generation {
+        return errReorged
     }
     
-    events := make([]*Event, 0, len(logs))
-    for _, log := range logs {
-        event := idx.processLog(chain, log)
-        if event != nil {
-            events = append(events, event)
+    if err := idx.storage.CommitBatch(ctx, batch); err != nil {
+        return fmt.Errorf("failed to commit blocks %d-%d: %w", result.job.from, result.job.to, err)
+    }
+    
+    idx.metrics.recordBatch(chain, batch)
+    idx.wakeSinks(chain)
+    
+    if result.err == nil {
+        fmt.Printf("[%s] Indexed blocks %d-%d (%s): %d events\n", chain, result.job.from, result.job.to, cur, len(batch.Events))
+    }
+    return nil
+}
+
+// processBatch fetches and decodes the logs of [from, to] for a cursor. The
+// result is only written by commitBatch.
+func (idx *Indexer) processBatch(ctx context.Context, chain string, client *blockchain.Client, cur *cursor, from, to, finalized uint64) (*Batch, error) {
+    batch := &Batch{Chain: chain}
+    
+    if signatures := idx.getEventSignatures(chain, cur); len(signatures) > 0 {
+        filter := blockchain.EventFilter{
+            Addresses: cur.addresses(),
+            Topics:    [][]common.Hash{signatures},
+        }
+        
+        logs, err := idx.fetchLogs(ctx, chain, client, filter, from, to)
+        if err != nil {
+            return nil, err
         }
+        
+        for _, log := range logs {
+            event, letter := idx.processLog(chain, cur, log)
+            if letter != nil {
+                batch.DeadLetters = append(batch.DeadLetters, letter)
+            }
+            if event != nil {
+                event.Status = models.FinalityTentative
+                if event.BlockNumber <= finalized {
+                    event.Status = models.FinalityFinal
+                }
+                batch.Events = append(batch.Events, event)
+            }
+        }
+    }
+    
+    // Record every block with events and the end of the range with their
+    // on-chain hash and timestamp
+    numbers := []uint64{to}
+    for _, event := range batch.Events {
+        numbers = append(numbers, event.BlockNumber)
+    }
+    for _, letter := range batch.DeadLetters {
+        numbers = append(numbers, letter.BlockNumber)
+    }
+    
+    blocks, err := idx.blockHeaders(ctx, chain, client, numbers)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get headers: %w", err)
     }
     
-    // Save events
-    if len(events) > 0 {
-        if err := idx.storage.SaveEvents(ctx, events); err != nil {
-            return fmt.Errorf("failed to save events: %w", err)
+    if err := stampEvents(batch.Events, blocks); err != nil {
+        return nil, err
+    }
+    for _, letter := range batch.DeadLetters {
+        letter.Timestamp = blocks[letter.BlockNumber].Timestamp
+    }
+    
+    for _, block := range blocks {
+        batch.Blocks = append(batch.Blocks, block)
+    }
+    sort.Slice(batch.Blocks, func(i, j int) bool {
+        return batch.Blocks[i].Number < batch.Blocks[j].Number
+    })
+    
+    return batch, nil
+}
+
+// detectReorg compares the most recently indexed block against the chain and,
+// if it is no longer canonical, rolls storage (events, blocks and
+// checkpoints) back to the fork point.
+// It returns the first block that has to be re-indexed.
+func (idx *Indexer) detectReorg(ctx context.Context, chain string, client *blockchain.Client) (uint64, bool, error) {
+    idx.reorgMu.Lock()
+    defer idx.reorgMu.Unlock()
+    
+    depth := idx.config.MaxReorgDepth
+    if depth <= 0 {
+        depth = 64
+    }
+    
+    blocks, err := idx.storage.GetRecentBlocks(ctx, chain, depth)
+    if err != nil {
+        return 0, false, err
+    }
+    if len(blocks) == 0 {
+        return 0, false, nil
+    }
+    
+    var forkBlock uint64
+    diverged := false
+    for _, block := range blocks {
+        if block.Hash == "" {
+            // Blocks recorded without a hash can't be verified, trust them
+            forkBlock = block.Number + 1
+            break
         }
+        
+        header, err := client.GetHeaderByNumber(ctx, new(big.Int).SetUint64(block.Number))
+        if err != nil {
+            return 0, false, fmt.Errorf("failed to get header for block %d: %w", block.Number, err)
+        }
+        if header.Hash().Hex() == block.Hash {
+            // Common ancestor: everything after it has to be re-indexed
+            forkBlock = block.Number + 1
+            break
+        }
+        
+        diverged = true
+        forkBlock = block.Number
+    }
+    
+    if !diverged {
+        return 0, false, nil
     }
     
-    // Update last indexed block
-    block := &Block{
-        Chain:     chain,
-        Number:    to,
-        Timestamp: time.Now(),
+    if err := idx.rollback(ctx, chain, forkBlock); err != nil {
+        return 0, false, err
     }
     
-    if err := idx.storage.SaveBlock(ctx, block); err != nil {
-        return fmt.Errorf("failed to save block: %w", err)
+    return forkBlock, true, nil
+}
+
+// rollback must be called with reorgMu held
+func (idx *Indexer) rollback(ctx context.Context, chain string, forkBlock uint64) error {
+    fmt.Printf("[%s] Reorg detected, rolling back to block %d\n", chain, forkBlock)
+    
+    if err := idx.storage.Rollback(ctx, chain, forkBlock); err != nil {
+        return fmt.Errorf("failed to roll back to block %d: %w", forkBlock, err)
+    }
+    idx.reorgGens[chain]++
+    idx.headers.invalidate(chain, forkBlock)
+    idx.metrics.recordReorg(chain)
+    
+    idx.mu.RLock()
+    handlers := append([]ReorgHandler(nil), idx.reorgHandlers...)
+    idx.mu.RUnlock()
+    
+    for _, handler := range handlers {
+        if err := handler(ctx, chain, forkBlock); err != nil {
+            fmt.Printf("[%s] Reorg handler failed: %v\n", chain, err)
+        }
     }
     
-    fmt.Printf("[%s] Indexed blocks %d-%d: %d events\n", chain, from, to, len(events))
     return nil
 }
 
-func (idx *Indexer) processLog(chain string, log types.Log) *Event {
+func (idx *Indexer) reorgGeneration(chain string) uint64 {
+    idx.reorgMu.Lock()
+    defer idx.reorgMu.Unlock()
+    return idx.reorgGens[chain]
+}
+
+// processLog decodes a log with the first processor handling it. A log the
+// processor fails to decode comes back as a dead letter instead.
+func (idx *Indexer) processLog(chain string, cur *cursor, log types.Log) (*Event, *DeadLetter) {
     idx.mu.RLock()
     defer idx.mu.RUnlock()
     
     for _, processor := range idx.processors {
+        if !cur.handles(chain, processor, log.Address) {
+            continue
+        }
+        
         signatures := processor.GetEventSignatures()
         for _, sig := range signatures {
             if len(log.Topics) > 0 && log.Topics[0] == sig {
                 event, err := processor.Process(log)
                 if err != nil {
-                    fmt.Printf("Error processing log: %v\n", err)
-                    return nil
+                    fmt.Printf("[%s] %s failed to decode log %s:%d: %v\n", chain, processor.GetProtocolName(), log.TxHash.Hex(), log.Index, err)
+                    return nil, newDeadLetter(chain, processor.GetProtocolName(), log, err)
                 }
                 if event != nil {
                     event.Chain = chain
-                    return event
+                    event.EventSignature = sig.Hex()
+                    return event, nil
                 }
             }
         }
     }
     
-    return nil
+    return nil, nil
 }
 
-func (idx *Indexer) getAllEventSignatures() []common.Hash {
+// getEventSignatures returns the signatures of all processors handling logs
+// for a cursor
+func (idx *Indexer) getEventSignatures(chain string, cur *cursor) []common.Hash {
     idx.mu.RLock()
     defer idx.mu.RUnlock()
     
     sigMap := make(map[common.Hash]bool)
     for _, processor := range idx.processors {
+        if !cur.covers(chain, processor) {
+            continue
+        }
         for _, sig := range processor.GetEventSignatures() {
             sigMap[sig] = true
         }
@@ -283,13 +738,17 @@ func (idx *Indexer) getAllEventSignatures() []common.Hash {
 func (idx *Indexer) listenToRealtimeEvents(ctx context.Context, chain string, client *blockchain.Client) {
     fmt.Printf("Starting real-time event listener for %s\n", chain)
     
-    signatures := idx.getAllEventSignatures()
+    // Follow every watched contract at the head
+    all := idx.watchAll(chain)
+    
+    signatures := idx.getEventSignatures(chain, all)
     if len(signatures) == 0 {
         return
     }
     
     filter := blockchain.EventFilter{
-        Topics: [][]common.Hash{signatures},
+        Addresses: all.addresses(),
+        Topics:    [][]common.Hash{signatures},
     }
     
     logs, sub, err := client.SubscribeLogs(ctx, filter)
@@ -306,10 +765,25 @@ func (idx *Indexer) listenToRealtimeEvents(ctx context.Context, chain string, cl
             return
             
         case log := <-logs:
-            event := idx.processLog(chain, log)
+            if log.Removed {
+                // The block carrying this log was reorged out of the chain
+                idx.reorgMu.Lock()
+                err := idx.rollback(ctx, chain, log.BlockNumber)
+                idx.reorgMu.Unlock()
+                if err != nil {
+                    fmt.Printf("Failed to roll back %s: %v\n", chain, err)
+                }
+                continue
+            }
+            
+            // Logs that fail to decode are recorded by the historical batch
+            // covering their block
+            event, _ := idx.processLog(chain, all, log)
             if event != nil {
-                if err := idx.storage.SaveEvents(ctx, []*Event{event}); err != nil {
+                if err := idx.commitRealtime(ctx, chain, client, event); err != nil {
+                    // The historical loop will pick the event up
                     fmt.Printf("Failed to save real-time event: %v\n", err)
+                    continue
                 }
                 fmt.Printf("[%s] New event: %s at block %d\n", chain, event.EventName, log.BlockNumber)
             }
@@ -321,6 +795,30 @@ func (idx *Indexer) listenToRealtimeEvents(ctx context.Context, chain string, cl
     }
 }
 
+// commitRealtime stores an event seen at the head with its block, so
+// detectReorg notices when the block is reorged out
+func (idx *Indexer) commitRealtime(ctx context.Context, chain string, client *blockchain.Client, event *Event) error {
+    blocks, err := idx.blockHeaders(ctx, chain, client, []uint64{event.BlockNumber})
+    if err != nil {
+        return err
+    }
+    if err := stampEvents([]*Event{event}, blocks); err != nil {
+        return err
+    }
+    
+    // Real-time events are at the head and can still be reorged
+    event.Status = models.FinalityTentative
+    
+    idx.reorgMu.Lock()
+    defer idx.reorgMu.Unlock()
+    
+    return idx.storage.CommitBatch(ctx, &Batch{
+        Chain:  chain,
+        Events: []*Event{event},
+        Blocks: []*Block{blocks[event.BlockNumber]},
+    })
+}
+
 func (idx *Indexer) Stop() {
     fmt.Println("Stopping indexer...")
     idx.wg.Wait()
@@ -0,0 +1,131 @@
+// internal/indexer/logs.go
+package indexer
+
+import (
+    "context"
+    "fmt"
+    "math/big"
+    "strings"
+
+    "github.com/ethereum/go-ethereum/core/types"
+    "github.com/zacksfF/evm-tvl-aggregator/internal/blockchain"
+)
+
+// Provider errors meaning the eth_getLogs range has to be narrowed
+var rangeTooLargeErrors = []string{
+    "query returned more than",
+    "more than 10000 results",
+    "block range too large",
+    "block range is too large",
+    "exceed maximum block range",
+    "exceeds max block range",
+    "response size exceeded",
+    "log response size exceeded",
+    "range too wide",
+}
+
+// A response with fewer logs than this lets the batch grow
+const smallResponseLogs = 1000
+
+func isRangeTooLarge(err error) bool {
+    if err == nil {
+        return false
+    }
+
+    msg := strings.ToLower(err.Error())
+    for _, pattern := range rangeTooLargeErrors {
+        if strings.Contains(msg, pattern) {
+            return true
+        }
+    }
+
+    return false
+}
+
+// fetchLogs queries logs in [from, to], bisecting the range whenever the
+// provider rejects it as too large. The chain's batch size follows the
+// largest range that was accepted.
+func (idx *Indexer) fetchLogs(ctx context.Context, chain string, client *blockchain.Client, filter blockchain.EventFilter, from, to uint64) ([]types.Log, error) {
+    filter.FromBlock = new(big.Int).SetUint64(from)
+    filter.ToBlock = new(big.Int).SetUint64(to)
+
+    logs, err := client.GetLogs(ctx, filter)
+    if err == nil {
+        if len(logs) < smallResponseLogs {
+            idx.growBatchSize(chain, to-from+1)
+        }
+        return logs, nil
+    }
+
+    if !isRangeTooLarge(err) || from == to {
+        return nil, fmt.Errorf("failed to get logs for blocks %d-%d: %w", from, to, err)
+    }
+
+    mid := from + (to-from)/2
+    idx.shrinkBatchSize(chain, mid-from+1)
+
+    left, err := idx.fetchLogs(ctx, chain, client, filter, from, mid)
+    if err != nil {
+        return nil, err
+    }
+
+    right, err := idx.fetchLogs(ctx, chain, client, filter, mid+1, to)
+    if err != nil {
+        return nil, err
+    }
+
+    return append(left, right...), nil
+}
+
+// batchSize returns the current eth_getLogs range for a chain
+func (idx *Indexer) batchSize(chain string) uint64 {
+    idx.batchMu.Lock()
+    defer idx.batchMu.Unlock()
+
+    if size, ok := idx.batchSizes[chain]; ok {
+        return size
+    }
+    return idx.config.BatchSize
+}
+
+func (idx *Indexer) maxBatchSize() uint64 {
+    if idx.config.MaxBatchSize > 0 {
+        return idx.config.MaxBatchSize
+    }
+    return idx.config.BatchSize * 16
+}
+
+// growBatchSize doubles the batch after a small response for a full batch
+func (idx *Indexer) growBatchSize(chain string, accepted uint64) {
+    idx.batchMu.Lock()
+    defer idx.batchMu.Unlock()
+
+    current, ok := idx.batchSizes[chain]
+    if !ok {
+        current = idx.config.BatchSize
+    }
+    if accepted < current {
+        return
+    }
+
+    grown := current * 2
+    if max := idx.maxBatchSize(); grown > max {
+        grown = max
+    }
+    idx.batchSizes[chain] = grown
+}
+
+// shrinkBatchSize caps the batch at a range the provider is about to accept
+func (idx *Indexer) shrinkBatchSize(chain string, size uint64) {
+    idx.batchMu.Lock()
+    defer idx.batchMu.Unlock()
+
+    if size == 0 {
+        size = 1
+    }
+
+    current, ok := idx.batchSizes[chain]
+    if !ok || size < current {
+        idx.batchSizes[chain] = size
+    }
+}
@@ -0,0 +1,168 @@
+// internal/indexer/metrics.go
+package indexer
+
+import (
+    "sync"
+    "time"
+)
+
+// Events per second are averaged over this window
+const rateWindow = time.Minute
+
+// ErrorCounts counts the errors of a chain since the indexer started
+type ErrorCounts struct {
+    Indexing      uint64 `json:"indexing"`       // failed indexing rounds, e.g. RPC errors
+    FailedBatches uint64 `json:"failed_batches"` // batches stored as failed ranges
+    Decode        uint64 `json:"decode"`         // logs stored as dead letters
+    Sink          uint64 `json:"sink"`           // failed sink deliveries
+}
+
+// metrics keeps in-process counters for the status endpoints
+type metrics struct {
+    mu      sync.Mutex
+    started time.Time
+    chains  map[string]*chainMetrics
+}
+
+type chainMetrics struct {
+    errors    ErrorCounts
+    reorgs    uint64
+    protocols map[string]*protocolMetrics
+}
+
+type protocolMetrics struct {
+    samples      []eventSample // commits within the rate window
+    decodeErrors uint64
+}
+
+type eventSample struct {
+    at    time.Time
+    count int
+}
+
+func newMetrics() *metrics {
+    return &metrics{
+        started: time.Now(),
+        chains:  make(map[string]*chainMetrics),
+    }
+}
+
+// chain must be called with mu held
+func (m *metrics) chain(name string) *chainMetrics {
+    c, ok := m.chains[name]
+    if !ok {
+        c = &chainMetrics{protocols: make(map[string]*protocolMetrics)}
+        m.chains[name] = c
+    }
+    return c
+}
+
+// protocol must be called with mu held
+func (c *chainMetrics) protocol(name string) *protocolMetrics {
+    p, ok := c.protocols[name]
+    if !ok {
+        p = &protocolMetrics{}
+        c.protocols[name] = p
+    }
+    return p
+}
+
+// recordBatch counts the events and dead letters of a committed batch
+func (m *metrics) recordBatch(chain string, batch *Batch) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    now := time.Now()
+    c := m.chain(chain)
+
+    counts := make(map[string]int)
+    for _, event := range batch.Events {
+        counts[event.Protocol]++
+    }
+    for protocol, count := range counts {
+        p := c.protocol(protocol)
+        p.samples = append(prune(p.samples, now), eventSample{at: now, count: count})
+    }
+
+    for _, letter := range batch.DeadLetters {
+        c.protocol(letter.Protocol).decodeErrors++
+        c.errors.Decode++
+    }
+    if batch.Failed != nil {
+        c.errors.FailedBatches++
+    }
+}
+
+func (m *metrics) recordIndexingError(chain string) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.chain(chain).errors.Indexing++
+}
+
+func (m *metrics) recordSinkError(chain string) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.chain(chain).errors.Sink++
+}
+
+func (m *metrics) recordReorg(chain string) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.chain(chain).reorgs++
+}
+
+// chainStats returns the error counts, reorgs and event rate of a chain
+func (m *metrics) chainStats(chain string) (ErrorCounts, uint64, float64) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    c := m.chain(chain)
+    var events int
+    for _, p := range c.protocols {
+        events += m.windowEvents(p)
+    }
+
+    return c.errors, c.reorgs, m.rate(events)
+}
+
+// protocolStats returns the event rate and decode errors of a protocol
+func (m *metrics) protocolStats(chain, protocol string) (float64, uint64) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    p := m.chain(chain).protocol(protocol)
+    return m.rate(m.windowEvents(p)), p.decodeErrors
+}
+
+// windowEvents must be called with mu held
+func (m *metrics) windowEvents(p *protocolMetrics) int {
+    p.samples = prune(p.samples, time.Now())
+
+    var events int
+    for _, sample := range p.samples {
+        events += sample.count
+    }
+    return events
+}
+
+// rate averages events over the window, or over the uptime while it is shorter
+func (m *metrics) rate(events int) float64 {
+    window := time.Since(m.started)
+    if window > rateWindow {
+        window = rateWindow
+    }
+    if window <= 0 {
+        return 0
+    }
+    return float64(events) / window.Seconds()
+}
+
+// prune drops the samples that fell out of the rate window
+func prune(samples []eventSample, now time.Time) []eventSample {
+    cutoff := now.Add(-rateWindow)
+    i := 0
+    for i < len(samples) && samples[i].at.Before(cutoff) {
+        i++
+    }
+    return samples[i:]
+}
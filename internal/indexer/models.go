@@ -6,35 +6,28 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
 )
 
-// Block represents an indexed block
-type Block struct {
-	ID        uint64    `json:"id"`
-	Chain     string    `json:"chain"`
-	Number    uint64    `json:"number"`
-	Hash      string    `json:"hash,omitempty"`
-	Timestamp time.Time `json:"timestamp"`
-}
+// The indexer stores its data through storage.IndexerStorage, in the same
+// tables as the rest of the aggregator
+type (
+	Block       = models.Block
+	Event       = models.Event
+	EventData   = models.EventData
+	Checkpoint  = models.Checkpoint
+	FailedRange = models.FailedRange
+	Batch       = models.EventBatch
+	DeadLetter  = models.DeadLetter
+)
 
-// Event represents a decoded blockchain event
-type Event struct {
-	ID              uint64         `json:"id"`
-	Chain           string         `json:"chain"`
-	BlockNumber     uint64         `json:"block_number"`
-	BlockHash       string         `json:"block_hash"`
-	TransactionHash string         `json:"transaction_hash"`
-	LogIndex        uint           `json:"log_index"`
-	Address         common.Address `json:"address"`
-	EventName       string         `json:"event_name"`
-	Protocol        string         `json:"protocol"`
-	Data            EventData      `json:"data"`
-	Timestamp       time.Time      `json:"timestamp"`
+// WatchedContract is a contract whose logs a processor handles
+type WatchedContract struct {
+	Chain       string         `json:"chain,omitempty"` // empty means every chain
+	Address     common.Address `json:"address"`
+	DeployBlock uint64         `json:"deploy_block,omitempty"`
 }
 
-// EventData contains decoded event data
-type EventData map[string]interface{}
-
 // Common event types
 type TransferEventData struct {
 	From   common.Address `json:"from"`
@@ -73,10 +66,32 @@ type LiquidityEventData struct {
 
 // IndexerStats provides statistics about indexing progress
 type IndexerStats struct {
+	Chain            string            `json:"chain"`
+	LastIndexedBlock uint64            `json:"last_indexed_block"`
+	CurrentBlock     uint64            `json:"current_block"`
+	Lag              uint64            `json:"lag"` // blocks behind CurrentBlock
+	EventsCount      uint64            `json:"events_count"`
+	EventsByProtocol map[string]uint64 `json:"events_by_protocol"`
+	EventsPerSecond  float64           `json:"events_per_second"` // over the last minute
+	Errors           ErrorCounts       `json:"errors"`            // since the indexer started
+	Reorgs           uint64            `json:"reorgs"`            // since the indexer started
+	DeadLetters      int               `json:"dead_letters"`
+	FailedRanges     int               `json:"failed_ranges"`
+	IndexingProgress float64           `json:"indexing_progress"` // percent of the watched range
+	LastUpdateTime   time.Time         `json:"last_update_time"`
+}
+
+// ProcessorStats reports the progress of a processor on a chain
+type ProcessorStats struct {
+	Protocol         string    `json:"protocol"`
 	Chain            string    `json:"chain"`
+	Contracts        int       `json:"contracts"` // 0 when watching every address
 	LastIndexedBlock uint64    `json:"last_indexed_block"`
 	CurrentBlock     uint64    `json:"current_block"`
+	Lag              uint64    `json:"lag"`
 	EventsCount      uint64    `json:"events_count"`
-	IndexingProgress float64   `json:"indexing_progress"`
+	EventsPerSecond  float64   `json:"events_per_second"`
+	DecodeErrors     uint64    `json:"decode_errors"` // since the indexer started
+	DeadLetters      int       `json:"dead_letters"`
 	LastUpdateTime   time.Time `json:"last_update_time"`
 }
@@ -4,31 +4,71 @@ package indexer
 import (
     "fmt"
     "math/big"
+    "strings"
+    "sync"
     
     "github.com/ethereum/go-ethereum/common"
     "github.com/ethereum/go-ethereum/core/types"
     "github.com/ethereum/go-ethereum/crypto"
 )
 
-// UniswapV2Processor processes Uniswap V2 events
+// UniswapV2Processor processes Uniswap V2 events. It watches the factory for
+// new pairs and the pairs for swaps and liquidity changes.
 type UniswapV2Processor struct {
     protocolName string
-    factoryAddr  common.Address
+    factoryAddr  WatchedContract
+    mu           sync.RWMutex
+    pairs        map[common.Address]WatchedContract
 }
 
-func NewUniswapV2Processor(factory common.Address) *UniswapV2Processor {
-    return &UniswapV2Processor{
+func NewUniswapV2Processor(factory WatchedContract, pairs ...WatchedContract) *UniswapV2Processor {
+    p := &UniswapV2Processor{
         protocolName: "uniswap-v2",
         factoryAddr:  factory,
+        pairs:        make(map[common.Address]WatchedContract),
     }
+    
+    for _, pair := range pairs {
+        p.TrackContract(pair)
+    }
+    
+    return p
 }
 
 func (p *UniswapV2Processor) GetProtocolName() string {
     return p.protocolName
 }
 
+func (p *UniswapV2Processor) GetContracts() []WatchedContract {
+    p.mu.RLock()
+    defer p.mu.RUnlock()
+    
+    contracts := make([]WatchedContract, 0, len(p.pairs)+1)
+    contracts = append(contracts, p.factoryAddr)
+    for _, pair := range p.pairs {
+        contracts = append(contracts, pair)
+    }
+    
+    return contracts
+}
+
+// TrackContract adds a pair to the watched contracts
+func (p *UniswapV2Processor) TrackContract(contract WatchedContract) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    
+    if contract.Chain == "" {
+        contract.Chain = p.factoryAddr.Chain
+    }
+    
+    if _, ok := p.pairs[contract.Address]; !ok {
+        p.pairs[contract.Address] = contract
+    }
+}
+
 func (p *UniswapV2Processor) GetEventSignatures() []common.Hash {
     return []common.Hash{
+        crypto.Keccak256Hash([]byte("PairCreated(address,address,address,uint256)")),
         crypto.Keccak256Hash([]byte("Swap(address,uint256,uint256,uint256,uint256,address)")),
         crypto.Keccak256Hash([]byte("Mint(address,uint256,uint256)")),
         crypto.Keccak256Hash([]byte("Burn(address,uint256,uint256,address)")),
@@ -51,6 +91,8 @@ func (p *UniswapV2Processor) Process(log types.Log) (*Event, error) {
     }
     
     switch log.Topics[0] {
+    case crypto.Keccak256Hash([]byte("PairCreated(address,address,address,uint256)")):
+        return p.processPairCreated(event, log)
     case crypto.Keccak256Hash([]byte("Swap(address,uint256,uint256,uint256,uint256,address)")):
         return p.processSwap(event, log)
     case crypto.Keccak256Hash([]byte("Mint(address,uint256,uint256)")):
@@ -64,6 +106,36 @@ func (p *UniswapV2Processor) Process(log types.Log) (*Event, error) {
     }
 }
 
+func (p *UniswapV2Processor) processPairCreated(event *Event, log types.Log) (*Event, error) {
+    event.EventName = "PairCreated"
+    
+    if log.Address != p.factoryAddr.Address {
+        return nil, nil // Pair created by another factory
+    }
+    
+    if len(log.Topics) < 3 || len(log.Data) < 64 {
+        return nil, fmt.Errorf("invalid pair created data")
+    }
+    
+    pair := common.BytesToAddress(log.Data[0:32])
+    index := new(big.Int).SetBytes(log.Data[32:64])
+    
+    // Index the new pair from the block it was created in
+    p.TrackContract(WatchedContract{
+        Address:     pair,
+        DeployBlock: log.BlockNumber,
+    })
+    
+    event.Data = EventData{
+        "token0": common.BytesToAddress(log.Topics[1].Bytes()),
+        "token1": common.BytesToAddress(log.Topics[2].Bytes()),
+        "pair":   pair,
+        "index":  index.String(),
+    }
+    
+    return event, nil
+}
+
 func (p *UniswapV2Processor) processSwap(event *Event, log types.Log) (*Event, error) {
     event.EventName = "Swap"
     
@@ -146,134 +218,54 @@ func (p *UniswapV2Processor) processSync(event *Event, log types.Log) (*Event, e
     return event, nil
 }
 
-// AaveV3Processor processes Aave V3 events
+// aaveV3PoolEventsABI holds the Aave V3 Pool events the indexer follows
+const aaveV3PoolEventsABI = `[
+    {"anonymous":false,"name":"Supply","type":"event","inputs":[
+        {"indexed":true,"name":"reserve","type":"address"},
+        {"indexed":false,"name":"user","type":"address"},
+        {"indexed":true,"name":"onBehalfOf","type":"address"},
+        {"indexed":false,"name":"amount","type":"uint256"},
+        {"indexed":true,"name":"referralCode","type":"uint16"}]},
+    {"anonymous":false,"name":"Withdraw","type":"event","inputs":[
+        {"indexed":true,"name":"reserve","type":"address"},
+        {"indexed":true,"name":"user","type":"address"},
+        {"indexed":true,"name":"to","type":"address"},
+        {"indexed":false,"name":"amount","type":"uint256"}]},
+    {"anonymous":false,"name":"Borrow","type":"event","inputs":[
+        {"indexed":true,"name":"reserve","type":"address"},
+        {"indexed":false,"name":"user","type":"address"},
+        {"indexed":true,"name":"onBehalfOf","type":"address"},
+        {"indexed":false,"name":"amount","type":"uint256"},
+        {"indexed":false,"name":"interestRateMode","type":"uint8"},
+        {"indexed":false,"name":"borrowRate","type":"uint256"},
+        {"indexed":true,"name":"referralCode","type":"uint16"}]},
+    {"anonymous":false,"name":"Repay","type":"event","inputs":[
+        {"indexed":true,"name":"reserve","type":"address"},
+        {"indexed":true,"name":"user","type":"address"},
+        {"indexed":true,"name":"repayer","type":"address"},
+        {"indexed":false,"name":"amount","type":"uint256"},
+        {"indexed":false,"name":"useATokens","type":"bool"}]}
+]`
+
+// AaveV3Processor processes Aave V3 Pool events
 type AaveV3Processor struct {
-    protocolName string
-    poolAddr     common.Address
+    *ABIEventProcessor
 }
 
-func NewAaveV3Processor(pool common.Address) *AaveV3Processor {
+func NewAaveV3Processor(pool WatchedContract) *AaveV3Processor {
     return &AaveV3Processor{
-        protocolName: "aave-v3",
-        poolAddr:     pool,
-    }
-}
-
-func (p *AaveV3Processor) GetProtocolName() string {
-    return p.protocolName
-}
-
-func (p *AaveV3Processor) GetEventSignatures() []common.Hash {
-    return []common.Hash{
-        crypto.Keccak256Hash([]byte("Supply(address,address,address,uint256,uint16)")),
-        crypto.Keccak256Hash([]byte("Withdraw(address,address,address,uint256)")),
-        crypto.Keccak256Hash([]byte("Borrow(address,address,address,uint256,uint8,uint256,uint16)")),
-        crypto.Keccak256Hash([]byte("Repay(address,address,address,uint256,bool)")),
-    }
-}
-
-func (p *AaveV3Processor) Process(log types.Log) (*Event, error) {
-    if len(log.Topics) == 0 {
-        return nil, fmt.Errorf("no topics in log")
-    }
-    
-    event := &Event{
-        BlockNumber:     log.BlockNumber,
-        BlockHash:       log.BlockHash.Hex(),
-        TransactionHash: log.TxHash.Hex(),
-        LogIndex:        log.Index,
-        Address:         log.Address,
-        Protocol:        p.protocolName,
-    }
-    
-    switch log.Topics[0] {
-    case crypto.Keccak256Hash([]byte("Supply(address,address,address,uint256,uint16)")):
-        return p.processSupply(event, log)
-    case crypto.Keccak256Hash([]byte("Withdraw(address,address,address,uint256)")):
-        return p.processWithdraw(event, log)
-    case crypto.Keccak256Hash([]byte("Borrow(address,address,address,uint256,uint8,uint256,uint16)")):
-        return p.processBorrow(event, log)
-    case crypto.Keccak256Hash([]byte("Repay(address,address,address,uint256,bool)")):
-        return p.processRepay(event, log)
-    default:
-        return nil, fmt.Errorf("unknown event signature")
-    }
-}
-
-func (p *AaveV3Processor) processSupply(event *Event, log types.Log) (*Event, error) {
-    event.EventName = "Supply"
-    
-    if len(log.Topics) < 4 {
-        return nil, fmt.Errorf("invalid supply topics")
-    }
-    
-    amount := new(big.Int).SetBytes(log.Data[0:32])
-    
-    event.Data = EventData{
-        "reserve":   common.BytesToAddress(log.Topics[1].Bytes()),
-        "user":      common.BytesToAddress(log.Topics[2].Bytes()),
-        "on_behalf": common.BytesToAddress(log.Topics[3].Bytes()),
-        "amount":    amount.String(),
+        ABIEventProcessor: mustABIEventProcessor("aave-v3", aaveV3PoolEventsABI, pool),
     }
-    
-    return event, nil
-}
-
-func (p *AaveV3Processor) processWithdraw(event *Event, log types.Log) (*Event, error) {
-    event.EventName = "Withdraw"
-    
-    if len(log.Topics) < 4 {
-        return nil, fmt.Errorf("invalid withdraw topics")
-    }
-    
-    amount := new(big.Int).SetBytes(log.Data[0:32])
-    
-    event.Data = EventData{
-        "reserve": common.BytesToAddress(log.Topics[1].Bytes()),
-        "user":    common.BytesToAddress(log.Topics[2].Bytes()),
-        "to":      common.BytesToAddress(log.Topics[3].Bytes()),
-        "amount":  amount.String(),
-    }
-    
-    return event, nil
-}
-
-func (p *AaveV3Processor) processBorrow(event *Event, log types.Log) (*Event, error) {
-    event.EventName = "Borrow"
-    
-    if len(log.Topics) < 4 {
-        return nil, fmt.Errorf("invalid borrow topics")
-    }
-    
-    amount := new(big.Int).SetBytes(log.Data[0:32])
-    
-    event.Data = EventData{
-        "reserve":    common.BytesToAddress(log.Topics[1].Bytes()),
-        "on_behalf":  common.BytesToAddress(log.Topics[2].Bytes()),
-        "user":       common.BytesToAddress(log.Topics[3].Bytes()),
-        "amount":     amount.String(),
-    }
-    
-    return event, nil
 }
 
-func (p *AaveV3Processor) processRepay(event *Event, log types.Log) (*Event, error) {
-    event.EventName = "Repay"
-    
-    if len(log.Topics) < 4 {
-        return nil, fmt.Errorf("invalid repay topics")
-    }
-    
-    amount := new(big.Int).SetBytes(log.Data[0:32])
-    
-    event.Data = EventData{
-        "reserve": common.BytesToAddress(log.Topics[1].Bytes()),
-        "user":    common.BytesToAddress(log.Topics[2].Bytes()),
-        "repayer": common.BytesToAddress(log.Topics[3].Bytes()),
-        "amount":  amount.String(),
-    }
-    
-    return event, nil
+// mustABIEventProcessor builds a processor from an ABI compiled into the
+// binary, where a parse error is a programming error
+func mustABIEventProcessor(protocolName string, abiJSON string, contracts ...WatchedContract) *ABIEventProcessor {
+    p, err := NewABIEventProcessor(protocolName, abiJSON, nil, contracts...)
+    if err != nil {
+        panic(fmt.Sprintf("invalid %s ABI: %v", protocolName, err))
+    }
+    return p
 }
 
 // GenericERC20Processor for standard ERC20 transfers
@@ -293,6 +285,14 @@ func (p *GenericERC20Processor) GetProtocolName() string {
     return p.protocolName
 }
 
+func (p *GenericERC20Processor) GetContracts() []WatchedContract {
+    contracts := make([]WatchedContract, 0, len(p.tokens))
+    for _, token := range p.tokens {
+        contracts = append(contracts, WatchedContract{Address: token})
+    }
+    return contracts
+}
+
 func (p *GenericERC20Processor) GetEventSignatures() []common.Hash {
     return []common.Hash{
         crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")),
@@ -336,4 +336,176 @@ func (p *GenericERC20Processor) Process(log types.Log) (*Event, error) {
     }
     
     return event, nil
-}
\ No newline at end of file
+}
+
+// uniswapV3PoolEventsABI holds the Uniswap V3 pool events the indexer follows
+const uniswapV3PoolEventsABI = `[
+    {"anonymous":false,"name":"Swap","type":"event","inputs":[
+        {"indexed":true,"name":"sender","type":"address"},
+        {"indexed":true,"name":"recipient","type":"address"},
+        {"indexed":false,"name":"amount0","type":"int256"},
+        {"indexed":false,"name":"amount1","type":"int256"},
+        {"indexed":false,"name":"sqrtPriceX96","type":"uint160"},
+        {"indexed":false,"name":"liquidity","type":"uint128"},
+        {"indexed":false,"name":"tick","type":"int24"}]},
+    {"anonymous":false,"name":"Mint","type":"event","inputs":[
+        {"indexed":false,"name":"sender","type":"address"},
+        {"indexed":true,"name":"owner","type":"address"},
+        {"indexed":true,"name":"tickLower","type":"int24"},
+        {"indexed":true,"name":"tickUpper","type":"int24"},
+        {"indexed":false,"name":"amount","type":"uint128"},
+        {"indexed":false,"name":"amount0","type":"uint256"},
+        {"indexed":false,"name":"amount1","type":"uint256"}]},
+    {"anonymous":false,"name":"Burn","type":"event","inputs":[
+        {"indexed":true,"name":"owner","type":"address"},
+        {"indexed":true,"name":"tickLower","type":"int24"},
+        {"indexed":true,"name":"tickUpper","type":"int24"},
+        {"indexed":false,"name":"amount","type":"uint128"},
+        {"indexed":false,"name":"amount0","type":"uint256"},
+        {"indexed":false,"name":"amount1","type":"uint256"}]},
+    {"anonymous":false,"name":"Collect","type":"event","inputs":[
+        {"indexed":true,"name":"owner","type":"address"},
+        {"indexed":false,"name":"recipient","type":"address"},
+        {"indexed":true,"name":"tickLower","type":"int24"},
+        {"indexed":true,"name":"tickUpper","type":"int24"},
+        {"indexed":false,"name":"amount0","type":"uint128"},
+        {"indexed":false,"name":"amount1","type":"uint128"}]}
+]`
+
+// UniswapV3Processor processes Uniswap V3 pool events. Swap data carries the
+// pool price after the swap (sqrt_price_x96, tick) and its liquidity.
+type UniswapV3Processor struct {
+    *ABIEventProcessor
+}
+
+func NewUniswapV3Processor(pools ...WatchedContract) *UniswapV3Processor {
+    return &UniswapV3Processor{
+        ABIEventProcessor: mustABIEventProcessor("uniswap-v3", uniswapV3PoolEventsABI, pools...),
+    }
+}
+
+// CurveProcessor processes the events of Curve plain pools and first
+// generation crypto pools: TokenExchange, TokenExchangeUnderlying,
+// RemoveLiquidityOne, and the AddLiquidity/RemoveLiquidity variants of 2, 3
+// and 4 coin plain pools and 2 and 3 coin crypto pools. The -ng pools emit
+// other liquidity events and aren't decoded.
+type CurveProcessor struct {
+    *ABIEventProcessor
+}
+
+func NewCurveProcessor(pools ...WatchedContract) *CurveProcessor {
+    return &CurveProcessor{
+        ABIEventProcessor: mustABIEventProcessor("curve", curvePoolEventsABI(), pools...),
+    }
+}
+
+// curvePoolEventsABI builds the Curve pool events. Pools of different sizes
+// emit the liquidity events with fixed size arrays, so each size has its own
+// signature.
+func curvePoolEventsABI() string {
+    event := func(name string, inputs ...string) string {
+        return fmt.Sprintf(`{"anonymous":false,"name":"%s","type":"event","inputs":[%s]}`, name, strings.Join(inputs, ","))
+    }
+    input := func(name, typ string, indexed bool) string {
+        return fmt.Sprintf(`{"indexed":%t,"name":"%s","type":"%s"}`, indexed, name, typ)
+    }
+
+    events := []string{
+        // Plain pools index coins with int128, crypto pools with uint256
+        event("TokenExchange",
+            input("buyer", "address", true),
+            input("sold_id", "int128", false),
+            input("tokens_sold", "uint256", false),
+            input("bought_id", "int128", false),
+            input("tokens_bought", "uint256", false)),
+        event("TokenExchange",
+            input("buyer", "address", true),
+            input("sold_id", "uint256", false),
+            input("tokens_sold", "uint256", false),
+            input("bought_id", "uint256", false),
+            input("tokens_bought", "uint256", false)),
+        event("TokenExchangeUnderlying",
+            input("buyer", "address", true),
+            input("sold_id", "int128", false),
+            input("tokens_sold", "uint256", false),
+            input("bought_id", "int128", false),
+            input("tokens_bought", "uint256", false)),
+        event("RemoveLiquidityOne",
+            input("provider", "address", true),
+            input("token_amount", "uint256", false),
+            input("coin_amount", "uint256", false)),
+        // Crypto pools also name the coin withdrawn
+        event("RemoveLiquidityOne",
+            input("provider", "address", true),
+            input("token_amount", "uint256", false),
+            input("coin_index", "uint256", false),
+            input("coin_amount", "uint256", false)),
+    }
+
+    for n := 2; n <= 4; n++ {
+        amounts := fmt.Sprintf("uint256[%d]", n)
+        events = append(events,
+            event("AddLiquidity",
+                input("provider", "address", true),
+                input("token_amounts", amounts, false),
+                input("fees", amounts, false),
+                input("invariant", "uint256", false),
+                input("token_supply", "uint256", false)),
+            event("RemoveLiquidity",
+                input("provider", "address", true),
+                input("token_amounts", amounts, false),
+                input("fees", amounts, false),
+                input("token_supply", "uint256", false)),
+            event("RemoveLiquidityImbalance",
+                input("provider", "address", true),
+                input("token_amounts", amounts, false),
+                input("fees", amounts, false),
+                input("invariant", "uint256", false),
+                input("token_supply", "uint256", false)),
+        )
+
+        // Crypto pools hold 2 or 3 coins and charge a single fee
+        if n <= 3 {
+            events = append(events,
+                event("AddLiquidity",
+                    input("provider", "address", true),
+                    input("token_amounts", amounts, false),
+                    input("fee", "uint256", false),
+                    input("token_supply", "uint256", false)),
+                event("RemoveLiquidity",
+                    input("provider", "address", true),
+                    input("token_amounts", amounts, false),
+                    input("token_supply", "uint256", false)),
+            )
+        }
+    }
+
+    return "[" + strings.Join(events, ",") + "]"
+}
+
+// erc4626EventsABI holds the ERC-4626 vault events
+const erc4626EventsABI = `[
+    {"anonymous":false,"name":"Deposit","type":"event","inputs":[
+        {"indexed":true,"name":"sender","type":"address"},
+        {"indexed":true,"name":"owner","type":"address"},
+        {"indexed":false,"name":"assets","type":"uint256"},
+        {"indexed":false,"name":"shares","type":"uint256"}]},
+    {"anonymous":false,"name":"Withdraw","type":"event","inputs":[
+        {"indexed":true,"name":"sender","type":"address"},
+        {"indexed":true,"name":"receiver","type":"address"},
+        {"indexed":true,"name":"owner","type":"address"},
+        {"indexed":false,"name":"assets","type":"uint256"},
+        {"indexed":false,"name":"shares","type":"uint256"}]}
+]`
+
+// ERC4626Processor processes Deposit and Withdraw events of ERC-4626 vaults.
+// Vaults belong to many protocols, so the protocol name is configurable.
+type ERC4626Processor struct {
+    *ABIEventProcessor
+}
+
+func NewERC4626Processor(protocolName string, vaults ...WatchedContract) *ERC4626Processor {
+    return &ERC4626Processor{
+        ABIEventProcessor: mustABIEventProcessor(protocolName, erc4626EventsABI, vaults...),
+    }
+}
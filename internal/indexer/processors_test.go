@@ -0,0 +1,345 @@
+// internal/indexer/processors_test.go
+package indexer
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	uniswapV3Pool   = common.HexToAddress("0x88e6A0c2dDD26FEEb64F039a2c41296FcB3f5640") // USDC/WETH 0.05%
+	swapRouter      = common.HexToAddress("0xE592427A0AEce92De3Edee1F18E0157C05861564")
+	positionManager = common.HexToAddress("0xC36442b4a4522E871399CD717aBDD847Ab11FE88")
+	curvePool       = common.HexToAddress("0xbEbc44782C7dB0a1A60Cb6fe97d0b483032FF1C7") // 3pool
+	vault           = common.HexToAddress("0x83F20F44975D03b1b09e64809B757c47f942BEeA") // sDAI
+	holder          = common.HexToAddress("0x28C6c06298d514Db089934071355E5743bf21d60")
+)
+
+// word ABI encodes an integer, given as an int or a decimal string, or an
+// address into 32 bytes
+func word(value interface{}) []byte {
+	switch v := value.(type) {
+	case int:
+		return math.U256Bytes(big.NewInt(int64(v)))
+	case string:
+		n, ok := new(big.Int).SetString(v, 10)
+		if !ok {
+			panic("invalid integer " + v)
+		}
+		return math.U256Bytes(n)
+	case common.Address:
+		return common.LeftPadBytes(v.Bytes(), 32)
+	}
+	panic(fmt.Sprintf("can't encode %T", value))
+}
+
+// logFixture is a log as a contract emits it: the event's canonical
+// signature, its indexed arguments in topics and the others in data
+type logFixture struct {
+	signature string
+	topics    []interface{}
+	data      []interface{}
+	want      EventData
+}
+
+// checkFixtures processes the fixtures as logs of contract, and checks that
+// they cover every event the processor decodes
+func checkFixtures(t *testing.T, p *ABIEventProcessor, contract common.Address, fixtures []logFixture) {
+	t.Helper()
+
+	covered := make(map[common.Hash]bool)
+	for i, fixture := range fixtures {
+		signature := crypto.Keccak256Hash([]byte(fixture.signature))
+		covered[signature] = true
+
+		log := types.Log{
+			Address:     contract,
+			Topics:      []common.Hash{signature},
+			BlockNumber: 19000000,
+			TxHash:      common.BigToHash(big.NewInt(int64(i))),
+			Index:       uint(i),
+		}
+		for _, value := range fixture.topics {
+			log.Topics = append(log.Topics, common.BytesToHash(word(value)))
+		}
+		for _, value := range fixture.data {
+			log.Data = append(log.Data, word(value)...)
+		}
+
+		event, err := p.Process(log)
+		if err != nil {
+			t.Errorf("Process(%s): %v", fixture.signature, err)
+			continue
+		}
+		name := fixture.signature[:strings.Index(fixture.signature, "(")]
+		if event.EventName != name || event.Protocol != p.GetProtocolName() || event.Address != contract {
+			t.Errorf("Process(%s) = %s of %s at %s, want %s of %s at %s", fixture.signature,
+				event.EventName, event.Protocol, event.Address.Hex(), name, p.GetProtocolName(), contract.Hex())
+		}
+		if !reflect.DeepEqual(event.Data, fixture.want) {
+			t.Errorf("Process(%s) data = %v, want %v", fixture.signature, event.Data, fixture.want)
+		}
+	}
+
+	for _, signature := range p.GetEventSignatures() {
+		if !covered[signature] {
+			t.Errorf("no fixture for the event with signature %s", signature.Hex())
+		}
+	}
+}
+
+func TestUniswapV3Processor(t *testing.T) {
+	p := NewUniswapV3Processor(WatchedContract{Chain: "ethereum", Address: uniswapV3Pool})
+
+	checkFixtures(t, p.ABIEventProcessor, uniswapV3Pool, []logFixture{
+		{
+			signature: "Swap(address,address,int256,int256,uint160,uint128,int24)",
+			topics:    []interface{}{swapRouter, holder},
+			data: []interface{}{"-2500000000", "1000000000000000000",
+				"1771595571142957166518320255467520", "12345678901234567890", 201234},
+			want: EventData{
+				"sender":         swapRouter,
+				"recipient":      holder,
+				"amount0":        "-2500000000",
+				"amount1":        "1000000000000000000",
+				"sqrt_price_x96": "1771595571142957166518320255467520",
+				"liquidity":      "12345678901234567890",
+				"tick":           "201234",
+			},
+		},
+		{
+			signature: "Mint(address,address,int24,int24,uint128,uint256,uint256)",
+			topics:    []interface{}{positionManager, -887270, 887270},
+			data:      []interface{}{positionManager, "45000000000000", "5000000000", "2000000000000000000"},
+			want: EventData{
+				"sender":     positionManager,
+				"owner":      positionManager,
+				"tick_lower": "-887270",
+				"tick_upper": "887270",
+				"amount":     "45000000000000",
+				"amount0":    "5000000000",
+				"amount1":    "2000000000000000000",
+			},
+		},
+		{
+			signature: "Burn(address,int24,int24,uint128,uint256,uint256)",
+			topics:    []interface{}{positionManager, 200000, 202000},
+			data:      []interface{}{"45000000000000", "0", "2000000000000000000"},
+			want: EventData{
+				"owner":      positionManager,
+				"tick_lower": "200000",
+				"tick_upper": "202000",
+				"amount":     "45000000000000",
+				"amount0":    "0",
+				"amount1":    "2000000000000000000",
+			},
+		},
+		{
+			signature: "Collect(address,address,int24,int24,uint128,uint128)",
+			topics:    []interface{}{positionManager, 200000, 202000},
+			data:      []interface{}{holder, "1250000", "500000000000000"},
+			want: EventData{
+				"owner":      positionManager,
+				"recipient":  holder,
+				"tick_lower": "200000",
+				"tick_upper": "202000",
+				"amount0":    "1250000",
+				"amount1":    "500000000000000",
+			},
+		},
+	})
+}
+
+func TestCurveProcessor(t *testing.T) {
+	p := NewCurveProcessor(WatchedContract{Chain: "ethereum", Address: curvePool})
+
+	fixtures := []logFixture{
+		{
+			signature: "TokenExchange(address,int128,uint256,int128,uint256)",
+			topics:    []interface{}{holder},
+			data:      []interface{}{0, "1000000000000000000000", 1, "999800000"},
+			want: EventData{
+				"buyer":         holder,
+				"sold_id":       "0",
+				"tokens_sold":   "1000000000000000000000",
+				"bought_id":     "1",
+				"tokens_bought": "999800000",
+			},
+		},
+		{
+			signature: "TokenExchange(address,uint256,uint256,uint256,uint256)",
+			topics:    []interface{}{holder},
+			data:      []interface{}{0, "5000000000", 2, "2000000000000000000"},
+			want: EventData{
+				"buyer":         holder,
+				"sold_id":       "0",
+				"tokens_sold":   "5000000000",
+				"bought_id":     "2",
+				"tokens_bought": "2000000000000000000",
+			},
+		},
+		{
+			signature: "TokenExchangeUnderlying(address,int128,uint256,int128,uint256)",
+			topics:    []interface{}{holder},
+			data:      []interface{}{3, "2000000000", 0, "1999000000000000000000"},
+			want: EventData{
+				"buyer":         holder,
+				"sold_id":       "3",
+				"tokens_sold":   "2000000000",
+				"bought_id":     "0",
+				"tokens_bought": "1999000000000000000000",
+			},
+		},
+		{
+			signature: "RemoveLiquidityOne(address,uint256,uint256)",
+			topics:    []interface{}{holder},
+			data:      []interface{}{"980000000000000000000", "1000000000"},
+			want: EventData{
+				"provider":     holder,
+				"token_amount": "980000000000000000000",
+				"coin_amount":  "1000000000",
+			},
+		},
+		{
+			signature: "RemoveLiquidityOne(address,uint256,uint256,uint256)",
+			topics:    []interface{}{holder},
+			data:      []interface{}{"1500000000000000000", 2, "900000000000000000"},
+			want: EventData{
+				"provider":     holder,
+				"token_amount": "1500000000000000000",
+				"coin_index":   "2",
+				"coin_amount":  "900000000000000000",
+			},
+		},
+	}
+
+	// The liquidity events of each pool size, with amounts 1000, 2000...
+	// and fees 1, 2...
+	for n := 2; n <= 4; n++ {
+		array := fmt.Sprintf("uint256[%d]", n)
+		var amounts, fees []interface{}
+		var wantAmounts, wantFees []interface{}
+		for i := 1; i <= n; i++ {
+			amounts = append(amounts, i*1000)
+			fees = append(fees, i)
+			wantAmounts = append(wantAmounts, fmt.Sprint(i*1000))
+			wantFees = append(wantFees, fmt.Sprint(i))
+		}
+		data := func(values ...interface{}) []interface{} {
+			var words []interface{}
+			for _, value := range values {
+				if list, ok := value.([]interface{}); ok {
+					words = append(words, list...)
+				} else {
+					words = append(words, value)
+				}
+			}
+			return words
+		}
+
+		fixtures = append(fixtures,
+			logFixture{
+				signature: fmt.Sprintf("AddLiquidity(address,%s,%s,uint256,uint256)", array, array),
+				topics:    []interface{}{holder},
+				data:      data(amounts, fees, "3000000", "2900000"),
+				want: EventData{
+					"provider":      holder,
+					"token_amounts": wantAmounts,
+					"fees":          wantFees,
+					"invariant":     "3000000",
+					"token_supply":  "2900000",
+				},
+			},
+			logFixture{
+				signature: fmt.Sprintf("RemoveLiquidity(address,%s,%s,uint256)", array, array),
+				topics:    []interface{}{holder},
+				data:      data(amounts, fees, "2800000"),
+				want: EventData{
+					"provider":      holder,
+					"token_amounts": wantAmounts,
+					"fees":          wantFees,
+					"token_supply":  "2800000",
+				},
+			},
+			logFixture{
+				signature: fmt.Sprintf("RemoveLiquidityImbalance(address,%s,%s,uint256,uint256)", array, array),
+				topics:    []interface{}{holder},
+				data:      data(amounts, fees, "2700000", "2600000"),
+				want: EventData{
+					"provider":      holder,
+					"token_amounts": wantAmounts,
+					"fees":          wantFees,
+					"invariant":     "2700000",
+					"token_supply":  "2600000",
+				},
+			},
+		)
+
+		if n > 3 {
+			continue
+		}
+		fixtures = append(fixtures,
+			logFixture{
+				signature: fmt.Sprintf("AddLiquidity(address,%s,uint256,uint256)", array),
+				topics:    []interface{}{holder},
+				data:      data(amounts, "12000", "2500000"),
+				want: EventData{
+					"provider":      holder,
+					"token_amounts": wantAmounts,
+					"fee":           "12000",
+					"token_supply":  "2500000",
+				},
+			},
+			logFixture{
+				signature: fmt.Sprintf("RemoveLiquidity(address,%s,uint256)", array),
+				topics:    []interface{}{holder},
+				data:      data(amounts, "2400000"),
+				want: EventData{
+					"provider":      holder,
+					"token_amounts": wantAmounts,
+					"token_supply":  "2400000",
+				},
+			},
+		)
+	}
+
+	checkFixtures(t, p.ABIEventProcessor, curvePool, fixtures)
+}
+
+func TestERC4626Processor(t *testing.T) {
+	p := NewERC4626Processor("spark", WatchedContract{Chain: "ethereum", Address: vault})
+
+	checkFixtures(t, p.ABIEventProcessor, vault, []logFixture{
+		{
+			signature: "Deposit(address,address,uint256,uint256)",
+			topics:    []interface{}{holder, holder},
+			data:      []interface{}{"1000000000000000000000", "950000000000000000000"},
+			want: EventData{
+				"sender": holder,
+				"owner":  holder,
+				"assets": "1000000000000000000000",
+				"shares": "950000000000000000000",
+			},
+		},
+		{
+			signature: "Withdraw(address,address,address,uint256,uint256)",
+			topics:    []interface{}{swapRouter, holder, holder},
+			data:      []interface{}{"500000000000000000000", "475000000000000000000"},
+			want: EventData{
+				"sender":   swapRouter,
+				"receiver": holder,
+				"owner":    holder,
+				"assets":   "500000000000000000000",
+				"shares":   "475000000000000000000",
+			},
+		},
+	})
+}
@@ -0,0 +1,192 @@
+// internal/indexer/reorg_test.go
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/blockchain"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/memory"
+)
+
+// fakeChain serves eth_chainId and eth_getBlockByNumber over JSON-RPC.
+// Blocks from forkFrom on get a different hash once fork is set.
+type fakeChain struct {
+	mu       sync.Mutex
+	fork     bool
+	forkFrom uint64
+}
+
+func (c *fakeChain) header(number uint64) *types.Header {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	header := &types.Header{
+		Number:     new(big.Int).SetUint64(number),
+		Difficulty: big.NewInt(0),
+		GasLimit:   30_000_000,
+		Time:       number * 12,
+	}
+	if c.fork && number >= c.forkFrom {
+		header.Extra = []byte("fork")
+	}
+	return header
+}
+
+func (c *fakeChain) reply(request json.RawMessage) map[string]interface{} {
+	var call struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	response := map[string]interface{}{"jsonrpc": "2.0"}
+	if err := json.Unmarshal(request, &call); err != nil {
+		response["error"] = map[string]interface{}{"code": -32700, "message": err.Error()}
+		return response
+	}
+	response["id"] = call.ID
+
+	switch call.Method {
+	case "eth_chainId":
+		response["result"] = "0x1"
+	case "eth_getBlockByNumber":
+		var number hexutil.Uint64
+		if len(call.Params) == 0 || json.Unmarshal(call.Params[0], &number) != nil {
+			response["error"] = map[string]interface{}{"code": -32602, "message": "invalid block number"}
+			break
+		}
+		response["result"] = c.header(uint64(number))
+	default:
+		response["error"] = map[string]interface{}{"code": -32601, "message": "method not found: " + call.Method}
+	}
+	return response
+}
+
+func (c *fakeChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		json.NewEncoder(w).Encode(c.reply(body))
+		return
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	responses := make([]map[string]interface{}, 0, len(batch))
+	for _, request := range batch {
+		responses = append(responses, c.reply(request))
+	}
+	json.NewEncoder(w).Encode(responses)
+}
+
+func TestRealtimeReorgRollsBack(t *testing.T) {
+	ctx := context.Background()
+	chain := &fakeChain{forkFrom: 102}
+	server := httptest.NewServer(chain)
+	defer server.Close()
+
+	client, err := blockchain.NewClient("ethereum", big.NewInt(1), server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	store := memory.NewMemoryStorage()
+	idx := NewIndexer(blockchain.NewManager(), store, Config{})
+	idx.OnReorg(store.RollbackTVL)
+
+	for _, block := range []uint64{101, 102} {
+		event := &Event{
+			Chain:           "ethereum",
+			Protocol:        "dex",
+			BlockNumber:     block,
+			TransactionHash: common.BigToHash(new(big.Int).SetUint64(block)).Hex(),
+			Address:         common.HexToAddress("0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc"),
+			EventName:       "Sync",
+		}
+		if err := idx.commitRealtime(ctx, "ethereum", client, event); err != nil {
+			t.Fatal(err)
+		}
+
+		err := store.SaveTVLSnapshot(ctx, &models.TVLSnapshot{
+			Protocol:    "dex",
+			Chain:       "ethereum",
+			BlockNumber: block,
+			TotalUSD:    new(big.Float).SetUint64(block),
+			Timestamp:   time.Unix(int64(block)*12, 0).UTC(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Real-time events are recorded with the hash of their block
+	blocks, err := store.GetRecentBlocks(ctx, "ethereum", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 2 || blocks[0].Number != 102 || blocks[0].Hash != chain.header(102).Hash().Hex() {
+		t.Fatalf("recorded blocks = %v, want 102 and 101 with their hashes", blocks)
+	}
+
+	// Nothing changed on chain
+	if _, reorged, err := idx.detectReorg(ctx, "ethereum", client); err != nil || reorged {
+		t.Fatalf("detectReorg on an unchanged chain = %v, %v; want no reorg", reorged, err)
+	}
+
+	chain.mu.Lock()
+	chain.fork = true
+	chain.mu.Unlock()
+
+	forkBlock, reorged, err := idx.detectReorg(ctx, "ethereum", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reorged || forkBlock != 102 {
+		t.Fatalf("detectReorg = %d, %v; want a reorg from block 102", forkBlock, reorged)
+	}
+
+	events, err := store.GetEvents(ctx, storage.EventFilter{Chain: "ethereum"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].BlockNumber != 101 {
+		t.Errorf("%d events left after the reorg, want the one of block 101", len(events))
+	}
+
+	blocks, err = store.GetRecentBlocks(ctx, "ethereum", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 || blocks[0].Number != 101 {
+		t.Errorf("%d blocks left after the reorg, want block 101", len(blocks))
+	}
+
+	snapshots, err := store.GetTVLHistory(ctx, storage.TVLFilter{Protocol: "dex", Chain: "ethereum"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 || snapshots[0].BlockNumber != 101 {
+		t.Errorf("%d snapshots left after the reorg, want the one of block 101", len(snapshots))
+	}
+}
@@ -0,0 +1,203 @@
+// internal/indexer/sink.go
+package indexer
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// Blocks of events read from storage per delivery to a sink
+const sinkRangeBlocks = 10000
+
+// EventSink receives indexed events for downstream consumers. Events are
+// delivered at least once and in block and log order per chain: a sink's
+// offset is only saved after Send succeeded, and it is rewound with the
+// indexer checkpoints on a reorg, so re-indexed events are sent again.
+// Consumers deduplicate by (chain, transaction_hash, log_index).
+type EventSink interface {
+    // Name identifies the sink's offset in storage and must stay stable
+    Name() string
+    Send(ctx context.Context, chain string, events []*Event) error
+}
+
+// RegisterSink adds a sink that receives every event committed by the
+// indexer, starting from the sink's saved offset
+func (idx *Indexer) RegisterSink(sink EventSink) {
+    idx.mu.Lock()
+    defer idx.mu.Unlock()
+
+    idx.sinks = append(idx.sinks, sink)
+    fmt.Printf("Registered event sink: %s\n", sink.Name())
+}
+
+// startSinks runs one delivery loop per sink for a chain
+func (idx *Indexer) startSinks(ctx context.Context, chain string) {
+    idx.mu.RLock()
+    sinks := append([]EventSink(nil), idx.sinks...)
+    idx.mu.RUnlock()
+
+    for _, sink := range sinks {
+        wake := make(chan struct{}, 1)
+
+        idx.sinkMu.Lock()
+        idx.sinkWake[chain] = append(idx.sinkWake[chain], wake)
+        idx.sinkMu.Unlock()
+
+        idx.wg.Add(1)
+        go func(sink EventSink) {
+            defer idx.wg.Done()
+            idx.deliverEvents(ctx, chain, sink, wake)
+        }(sink)
+    }
+}
+
+// wakeSinks tells the sinks of a chain that new events were committed
+func (idx *Indexer) wakeSinks(chain string) {
+    idx.sinkMu.Lock()
+    defer idx.sinkMu.Unlock()
+
+    for _, wake := range idx.sinkWake[chain] {
+        select {
+        case wake <- struct{}{}:
+        default:
+        }
+    }
+}
+
+// deliverEvents sends committed events to a sink until ctx is done
+func (idx *Indexer) deliverEvents(ctx context.Context, chain string, sink EventSink, wake <-chan struct{}) {
+    pollInterval := idx.config.PollInterval
+    if pollInterval == 0 {
+        pollInterval = 15 * time.Second
+    }
+
+    for {
+        err := idx.flushSink(ctx, chain, sink)
+        if errors.Is(err, errReorged) {
+            // Start over from the rewound offset
+            continue
+        }
+        if err != nil && ctx.Err() == nil {
+            fmt.Printf("[%s] Failed to deliver events to %s: %v\n", chain, sink.Name(), err)
+            idx.metrics.recordSinkError(chain)
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-wake:
+        case <-time.After(pollInterval):
+        }
+    }
+}
+
+// flushSink sends the events between the sink's offset and the indexer's
+// watermark, saving the offset after each delivered range
+func (idx *Indexer) flushSink(ctx context.Context, chain string, sink EventSink) error {
+    offsets, err := idx.storage.GetSinkOffsets(ctx, chain)
+    if err != nil {
+        return fmt.Errorf("failed to load offset: %w", err)
+    }
+
+    var from uint64
+    if offset, ok := offsets[sink.Name()]; ok {
+        from = offset + 1
+    }
+
+    // Only events below the watermark are complete; real-time events past
+    // it are delivered once the historical batches cover them
+    watermark, err := idx.storage.GetLastIndexedBlock(ctx, chain)
+    if err != nil {
+        return fmt.Errorf("failed to get last indexed block: %w", err)
+    }
+
+    for from <= watermark {
+        if ctx.Err() != nil {
+            return ctx.Err()
+        }
+
+        to := from + sinkRangeBlocks - 1
+        if to > watermark || to < from {
+            to = watermark
+        }
+
+        generation := idx.reorgGeneration(chain)
+
+        events, err := idx.storage.GetEvents(ctx, storage.EventFilter{
+            Chain:     chain,
+            FromBlock: from,
+            ToBlock:   to,
+        })
+        if err != nil {
+            return fmt.Errorf("failed to load events %d-%d: %w", from, to, err)
+        }
+
+        if len(events) > 0 {
+            if err := sink.Send(ctx, chain, events); err != nil {
+                return fmt.Errorf("blocks %d-%d: %w", from, to, err)
+            }
+        }
+
+        if err := idx.saveSinkOffset(ctx, chain, sink, to, generation); err != nil {
+            return err
+        }
+
+        from = to + 1
+    }
+
+    return nil
+}
+
+// saveSinkOffset records a delivered range unless a rollback happened while
+// it was sent, in which case the rewound offset has to stay
+func (idx *Indexer) saveSinkOffset(ctx context.Context, chain string, sink EventSink, block uint64, generation uint64) error {
+    idx.reorgMu.Lock()
+    defer idx.reorgMu.Unlock()
+
+    if idx.reorgGens[chain] != generation {
+        return errReorged
+    }
+
+    if err := idx.storage.SaveSinkOffset(ctx, sink.Name(), chain, block); err != nil {
+        return fmt.Errorf("failed to save offset %d: %w", block, err)
+    }
+    return nil
+}
+
+// rewindSinks makes the sinks of a chain that are past block deliver it
+// again, for events stored below their offsets. It must be called with
+// reorgMu held.
+func (idx *Indexer) rewindSinks(ctx context.Context, chain string, block uint64) error {
+    offsets, err := idx.storage.GetSinkOffsets(ctx, chain)
+    if err != nil {
+        return fmt.Errorf("failed to load sink offsets: %w", err)
+    }
+
+    rewound := false
+    for sink, offset := range offsets {
+        if offset < block {
+            continue
+        }
+
+        var rewind uint64
+        if block > 0 {
+            rewind = block - 1
+        }
+        if err := idx.storage.SaveSinkOffset(ctx, sink, chain, rewind); err != nil {
+            return fmt.Errorf("failed to rewind sink %s: %w", sink, err)
+        }
+        rewound = true
+    }
+
+    if rewound {
+        // In-flight deliveries must not move the offsets past the new events
+        idx.reorgGens[chain]++
+        idx.wakeSinks(chain)
+    }
+
+    return nil
+}
@@ -0,0 +1,231 @@
+// internal/indexer/sinks.go
+package indexer
+
+import (
+    "bytes"
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "sync"
+    "time"
+
+    _ "github.com/lib/pq"
+)
+
+// NDJSONSink writes events as newline-delimited JSON, one event per line
+type NDJSONSink struct {
+    name string
+    mu   sync.Mutex
+    w    io.Writer
+    file *os.File
+}
+
+// NewNDJSONSink writes events to w, e.g. os.Stdout
+func NewNDJSONSink(name string, w io.Writer) *NDJSONSink {
+    return &NDJSONSink{name: name, w: w}
+}
+
+// NewNDJSONFileSink appends events to a file, syncing it after every batch
+func NewNDJSONFileSink(path string) (*NDJSONSink, error) {
+    file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+    if err != nil {
+        return nil, fmt.Errorf("failed to open %s: %w", path, err)
+    }
+
+    return &NDJSONSink{name: "ndjson:" + path, w: file, file: file}, nil
+}
+
+func (s *NDJSONSink) Name() string {
+    return s.name
+}
+
+func (s *NDJSONSink) Send(ctx context.Context, chain string, events []*Event) error {
+    var buf bytes.Buffer
+    encoder := json.NewEncoder(&buf)
+    for _, event := range events {
+        if err := encoder.Encode(event); err != nil {
+            return err
+        }
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, err := s.w.Write(buf.Bytes()); err != nil {
+        return err
+    }
+    if s.file != nil {
+        return s.file.Sync()
+    }
+    return nil
+}
+
+// Close closes the file of a file sink
+func (s *NDJSONSink) Close() error {
+    if s.file != nil {
+        return s.file.Close()
+    }
+    return nil
+}
+
+// WebhookConfig configures a WebhookSink
+type WebhookConfig struct {
+    URL        string
+    Headers    map[string]string
+    Timeout    time.Duration // per request, 0 means 10s
+    MaxRetries int           // retries of a failed request, 0 means 5
+    RetryDelay time.Duration // first backoff, doubled on every retry, 0 means 1s
+}
+
+// WebhookSink POSTs batches of events as {"chain": ..., "events": [...]}.
+// Network errors, 408, 429 and 5xx responses are retried with exponential
+// backoff; other responses fail the batch, which is sent again later.
+type WebhookSink struct {
+    config WebhookConfig
+    client *http.Client
+}
+
+func NewWebhookSink(config WebhookConfig) *WebhookSink {
+    if config.Timeout == 0 {
+        config.Timeout = 10 * time.Second
+    }
+    if config.MaxRetries == 0 {
+        config.MaxRetries = 5
+    }
+    if config.RetryDelay == 0 {
+        config.RetryDelay = time.Second
+    }
+
+    return &WebhookSink{
+        config: config,
+        client: &http.Client{Timeout: config.Timeout},
+    }
+}
+
+func (s *WebhookSink) Name() string {
+    return "webhook:" + s.config.URL
+}
+
+func (s *WebhookSink) Send(ctx context.Context, chain string, events []*Event) error {
+    body, err := json.Marshal(map[string]interface{}{
+        "chain":  chain,
+        "events": events,
+    })
+    if err != nil {
+        return err
+    }
+
+    delay := s.config.RetryDelay
+    for attempt := 0; ; attempt++ {
+        retry, err := s.post(ctx, body)
+        if err == nil {
+            return nil
+        }
+        if !retry || attempt >= s.config.MaxRetries {
+            return err
+        }
+
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-time.After(delay):
+        }
+        delay *= 2
+    }
+}
+
+// post sends one request and tells whether a failure is worth retrying
+func (s *WebhookSink) post(ctx context.Context, body []byte) (bool, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(body))
+    if err != nil {
+        return false, err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    for key, value := range s.config.Headers {
+        req.Header.Set(key, value)
+    }
+
+    resp, err := s.client.Do(req)
+    if err != nil {
+        return ctx.Err() == nil, err
+    }
+    defer resp.Body.Close()
+    io.Copy(io.Discard, resp.Body)
+
+    if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+        return false, nil
+    }
+
+    retry := resp.StatusCode == http.StatusRequestTimeout ||
+        resp.StatusCode == http.StatusTooManyRequests ||
+        resp.StatusCode >= 500
+    return retry, fmt.Errorf("webhook returned %s", resp.Status)
+}
+
+// Postgres rejects NOTIFY payloads of 8000 bytes or more
+const maxNotifyPayload = 7999
+
+// PostgresNotifySink publishes every event as a JSON NOTIFY payload on a
+// channel. The notifications of a batch are sent in one transaction, so
+// listeners get all of them in order or none. Events too large for a
+// payload are sent with Data replaced by {"truncated": true}; listeners can
+// load them from the events table or the API.
+type PostgresNotifySink struct {
+    db      *sql.DB
+    channel string
+}
+
+func NewPostgresNotifySink(connectionString string, channel string) (*PostgresNotifySink, error) {
+    db, err := sql.Open("postgres", connectionString)
+    if err != nil {
+        return nil, err
+    }
+
+    if err := db.Ping(); err != nil {
+        db.Close()
+        return nil, err
+    }
+
+    return &PostgresNotifySink{db: db, channel: channel}, nil
+}
+
+func (s *PostgresNotifySink) Name() string {
+    return "notify:" + s.channel
+}
+
+func (s *PostgresNotifySink) Send(ctx context.Context, chain string, events []*Event) error {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    for _, event := range events {
+        payload, err := json.Marshal(event)
+        if err != nil {
+            return err
+        }
+
+        if len(payload) > maxNotifyPayload {
+            truncated := *event
+            truncated.Data = EventData{"truncated": true}
+            if payload, err = json.Marshal(&truncated); err != nil {
+                return err
+            }
+        }
+
+        if _, err := tx.ExecContext(ctx, "SELECT pg_notify($1, $2)", s.channel, string(payload)); err != nil {
+            return fmt.Errorf("failed to notify %s: %w", s.channel, err)
+        }
+    }
+
+    return tx.Commit()
+}
+
+func (s *PostgresNotifySink) Close() error {
+    return s.db.Close()
+}
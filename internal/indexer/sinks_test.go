@@ -0,0 +1,224 @@
+// internal/indexer/sinks_test.go
+package indexer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/blockchain"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/memory"
+)
+
+func sinkEvent(block uint64, index uint) *Event {
+	return &Event{
+		Chain:           "ethereum",
+		Protocol:        "dex",
+		BlockNumber:     block,
+		TransactionHash: common.BigToHash(new(big.Int).SetUint64(block)).Hex(),
+		LogIndex:        index,
+		Address:         common.HexToAddress("0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc"),
+		EventName:       "Sync",
+		Data:            EventData{"reserve0": "1000", "reserve1": "2000"},
+	}
+}
+
+func TestNDJSONSink(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	sink := NewNDJSONSink("stdout", &buf)
+
+	if err := sink.Send(ctx, "ethereum", []*Event{sinkEvent(100, 0), sinkEvent(100, 1)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(ctx, "ethereum", []*Event{sinkEvent(101, 0)}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("line %q is not an event: %v", scanner.Text(), err)
+		}
+		if event.Data["reserve1"] != "2000" {
+			t.Errorf("event data = %v, want the sent reserves", event.Data)
+		}
+		got = append(got, fmt.Sprintf("%d/%d", event.BlockNumber, event.LogIndex))
+	}
+	if want := "100/0 100/1 101/0"; strings.Join(got, " ") != want {
+		t.Errorf("NDJSON lines = %v, want blocks/log indexes %s", got, want)
+	}
+	if sink.Name() != "stdout" {
+		t.Errorf("Name = %q, want stdout", sink.Name())
+	}
+}
+
+// webhookServer answers with the status codes in order, then 200
+type webhookServer struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []time.Time
+	bodies   []map[string]json.RawMessage
+	headers  []http.Header
+}
+
+func (s *webhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var body map[string]json.RawMessage
+	json.NewDecoder(r.Body).Decode(&body)
+	s.requests = append(s.requests, time.Now())
+	s.bodies = append(s.bodies, body)
+	s.headers = append(s.headers, r.Header.Clone())
+
+	status := http.StatusOK
+	if len(s.statuses) > 0 {
+		status, s.statuses = s.statuses[0], s.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (s *webhookServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+func TestWebhookSinkRetries(t *testing.T) {
+	ctx := context.Background()
+	const delay = 20 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		requests int
+		fails    bool
+	}{
+		{"success", nil, 3, 1, false},
+		{"retried until success", []int{503, 429, 408}, 3, 4, false},
+		{"retries exhausted", []int{500, 500, 500}, 2, 3, true},
+		{"client error", []int{400}, 3, 1, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := &webhookServer{statuses: test.statuses}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			sink := NewWebhookSink(WebhookConfig{
+				URL:        server.URL,
+				Headers:    map[string]string{"Authorization": "Bearer secret"},
+				MaxRetries: test.retries,
+				RetryDelay: delay,
+			})
+			err := sink.Send(ctx, "ethereum", []*Event{sinkEvent(100, 0)})
+			if (err != nil) != test.fails {
+				t.Fatalf("Send = %v, want failure %v", err, test.fails)
+			}
+
+			if handler.count() != test.requests {
+				t.Fatalf("%d requests, want %d", handler.count(), test.requests)
+			}
+			// Every retry waits twice as long as the one before
+			for i := 1; i < len(handler.requests); i++ {
+				want := delay << (i - 1)
+				if waited := handler.requests[i].Sub(handler.requests[i-1]); waited < want {
+					t.Errorf("retry %d came after %v, want at least %v", i, waited, want)
+				}
+			}
+			for i, body := range handler.bodies {
+				var events []*Event
+				if string(body["chain"]) != `"ethereum"` || json.Unmarshal(body["events"], &events) != nil || len(events) != 1 {
+					t.Errorf("request %d body = %v, want the chain and its event", i, body)
+				}
+				if handler.headers[i].Get("Authorization") != "Bearer secret" ||
+					handler.headers[i].Get("Content-Type") != "application/json" {
+					t.Errorf("request %d headers = %v, want the configured ones", i, handler.headers[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookSinkCanceled(t *testing.T) {
+	server := httptest.NewServer(&webhookServer{statuses: []int{503, 503, 503}})
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	sink := NewWebhookSink(WebhookConfig{URL: server.URL, RetryDelay: time.Hour})
+	if err := sink.Send(ctx, "ethereum", []*Event{sinkEvent(100, 0)}); err != context.DeadlineExceeded {
+		t.Errorf("Send while backing off = %v, want the context's error", err)
+	}
+}
+
+func TestSinkOffsetOnFailure(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemoryStorage()
+	idx := NewIndexer(blockchain.NewManager(), store, Config{})
+
+	if err := store.SaveEvents(ctx, []*models.Event{sinkEvent(100, 0), sinkEvent(101, 0)}); err != nil {
+		t.Fatal(err)
+	}
+	for _, block := range []uint64{100, 101} {
+		if err := store.SaveBlock(ctx, &models.Block{Chain: "ethereum", Number: block}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handler := &webhookServer{statuses: []int{400}}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	sink := NewWebhookSink(WebhookConfig{URL: server.URL, RetryDelay: time.Millisecond})
+
+	// A failed delivery leaves the offset where it was
+	if err := idx.flushSink(ctx, "ethereum", sink); err == nil {
+		t.Fatal("flushSink to a failing webhook succeeded")
+	}
+	offsets, err := store.GetSinkOffsets(ctx, "ethereum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset, ok := offsets[sink.Name()]; ok {
+		t.Fatalf("offset after a failed delivery = %d, want none", offset)
+	}
+
+	// The next one sends the same events and moves it to the watermark
+	if err := idx.flushSink(ctx, "ethereum", sink); err != nil {
+		t.Fatal(err)
+	}
+	offsets, err = store.GetSinkOffsets(ctx, "ethereum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offsets[sink.Name()] != 101 {
+		t.Errorf("offset after delivery = %d, want 101", offsets[sink.Name()])
+	}
+	if handler.count() != 2 || string(handler.bodies[0]["events"]) != string(handler.bodies[1]["events"]) {
+		t.Errorf("%d requests, want the failed events sent again", handler.count())
+	}
+
+	// Nothing new, nothing sent
+	if err := idx.flushSink(ctx, "ethereum", sink); err != nil {
+		t.Fatal(err)
+	}
+	if handler.count() != 2 {
+		t.Errorf("%d requests after a flush without new events, want 2", handler.count())
+	}
+}
@@ -0,0 +1,220 @@
+// internal/indexer/stats.go
+package indexer
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "strings"
+
+    "github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// Chains returns the names of the chains the indexer follows
+func (idx *Indexer) Chains() []string {
+    chains := idx.manager.GetSupportedChains()
+    sort.Strings(chains)
+    return chains
+}
+
+// Stats reports the indexing progress of a chain. Progress is measured from
+// the earliest block a watched contract starts at to the indexable head.
+func (idx *Indexer) Stats(ctx context.Context, chain string) (*IndexerStats, error) {
+    client, err := idx.manager.GetClient(chain)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get client: %w", err)
+    }
+
+    stats := &IndexerStats{
+        Chain:            chain,
+        EventsByProtocol: make(map[string]uint64),
+    }
+
+    if stats.CurrentBlock, err = client.GetBlockNumber(ctx); err != nil {
+        return nil, fmt.Errorf("failed to get current block: %w", err)
+    }
+
+    head, err := idx.headBlock(ctx, chain, client)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get head block: %w", err)
+    }
+
+    if stats.LastIndexedBlock, err = idx.storage.GetLastIndexedBlock(ctx, chain); err != nil {
+        return nil, fmt.Errorf("failed to get last indexed block: %w", err)
+    }
+    if stats.CurrentBlock > stats.LastIndexedBlock {
+        stats.Lag = stats.CurrentBlock - stats.LastIndexedBlock
+    }
+
+    if stats.EventsCount, err = idx.storage.CountEvents(ctx, storage.EventFilter{Chain: chain}); err != nil {
+        return nil, fmt.Errorf("failed to count events: %w", err)
+    }
+
+    start := head
+    idx.mu.RLock()
+    processors := make([]EventProcessor, 0, len(idx.processors))
+    for _, processor := range idx.processors {
+        processors = append(processors, processor)
+    }
+    idx.mu.RUnlock()
+
+    for _, processor := range processors {
+        contracts, all := watchedOn(chain, processor)
+        if !all && len(contracts) == 0 {
+            continue
+        }
+
+        protocol := processor.GetProtocolName()
+        count, err := idx.storage.CountEvents(ctx, storage.EventFilter{Chain: chain, Protocol: protocol})
+        if err != nil {
+            return nil, fmt.Errorf("failed to count %s events: %w", protocol, err)
+        }
+        stats.EventsByProtocol[protocol] = count
+
+        if all {
+            contracts = []WatchedContract{{}}
+        }
+        for _, contract := range contracts {
+            begin := contract.DeployBlock
+            if begin == 0 {
+                begin = idx.defaultStartBlock(head)
+            }
+            if begin < start {
+                start = begin
+            }
+        }
+    }
+
+    stats.Errors, stats.Reorgs, stats.EventsPerSecond = idx.metrics.chainStats(chain)
+
+    stats.IndexingProgress = 100
+    if head > start && stats.LastIndexedBlock < head {
+        indexed := float64(0)
+        if stats.LastIndexedBlock > start {
+            indexed = float64(stats.LastIndexedBlock - start)
+        }
+        stats.IndexingProgress = indexed / float64(head-start) * 100
+    }
+
+    letters, err := idx.storage.CountDeadLetters(ctx, chain, "")
+    if err != nil {
+        return nil, fmt.Errorf("failed to count dead letters: %w", err)
+    }
+    stats.DeadLetters = int(letters)
+
+    failed, err := idx.storage.GetFailedRanges(ctx, chain)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get failed ranges: %w", err)
+    }
+    stats.FailedRanges = len(failed)
+
+    checkpoints, err := idx.storage.GetCheckpoints(ctx, chain)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get checkpoints: %w", err)
+    }
+    for _, checkpoint := range checkpoints {
+        if checkpoint.UpdatedAt.After(stats.LastUpdateTime) {
+            stats.LastUpdateTime = checkpoint.UpdatedAt
+        }
+    }
+
+    return stats, nil
+}
+
+// ProcessorStats reports the progress of every processor on a chain, or on
+// all chains when chain is empty. A processor is as far as its contract that
+// is furthest behind.
+func (idx *Indexer) ProcessorStats(ctx context.Context, chain string) ([]*ProcessorStats, error) {
+    chains := []string{chain}
+    if chain == "" {
+        chains = idx.Chains()
+    }
+
+    idx.mu.RLock()
+    processors := make([]EventProcessor, 0, len(idx.processors))
+    for _, processor := range idx.processors {
+        processors = append(processors, processor)
+    }
+    idx.mu.RUnlock()
+
+    sort.Slice(processors, func(i, j int) bool {
+        return processors[i].GetProtocolName() < processors[j].GetProtocolName()
+    })
+
+    var results []*ProcessorStats
+    for _, chain := range chains {
+        client, err := idx.manager.GetClient(chain)
+        if err != nil {
+            return nil, fmt.Errorf("failed to get client: %w", err)
+        }
+
+        current, err := client.GetBlockNumber(ctx)
+        if err != nil {
+            return nil, fmt.Errorf("failed to get current block of %s: %w", chain, err)
+        }
+
+        stored, err := idx.storage.GetCheckpoints(ctx, chain)
+        if err != nil {
+            return nil, fmt.Errorf("failed to get checkpoints: %w", err)
+        }
+        checkpoints := make(map[string]*Checkpoint, len(stored))
+        for _, checkpoint := range stored {
+            checkpoints[strings.ToLower(checkpoint.Contract)] = checkpoint
+        }
+
+        for _, processor := range processors {
+            contracts, all := watchedOn(chain, processor)
+            if !all && len(contracts) == 0 {
+                continue
+            }
+
+            protocol := processor.GetProtocolName()
+            stats := &ProcessorStats{
+                Protocol:     protocol,
+                Chain:        chain,
+                Contracts:    len(contracts),
+                CurrentBlock: current,
+            }
+
+            keys := []string{""}
+            if !all {
+                keys = keys[:0]
+                for _, contract := range contracts {
+                    keys = append(keys, strings.ToLower(contract.Address.Hex()))
+                }
+            }
+            for i, key := range keys {
+                // Contracts without a checkpoint have not started yet
+                var block uint64
+                if checkpoint, ok := checkpoints[key]; ok {
+                    block = checkpoint.Block
+                    if checkpoint.UpdatedAt.After(stats.LastUpdateTime) {
+                        stats.LastUpdateTime = checkpoint.UpdatedAt
+                    }
+                }
+                if i == 0 || block < stats.LastIndexedBlock {
+                    stats.LastIndexedBlock = block
+                }
+            }
+            if current > stats.LastIndexedBlock {
+                stats.Lag = current - stats.LastIndexedBlock
+            }
+
+            if stats.EventsCount, err = idx.storage.CountEvents(ctx, storage.EventFilter{Chain: chain, Protocol: protocol}); err != nil {
+                return nil, fmt.Errorf("failed to count %s events: %w", protocol, err)
+            }
+
+            letters, err := idx.storage.CountDeadLetters(ctx, chain, protocol)
+            if err != nil {
+                return nil, fmt.Errorf("failed to count dead letters: %w", err)
+            }
+            stats.DeadLetters = int(letters)
+
+            stats.EventsPerSecond, stats.DecodeErrors = idx.metrics.protocolStats(chain, protocol)
+
+            results = append(results, stats)
+        }
+    }
+
+    return results, nil
+}
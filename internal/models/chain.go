@@ -7,17 +7,20 @@ import (
 
 // Chain represents a blockchain
 type Chain struct {
-	ID          uint64    `json:"id" db:"id"`
-	Name        string    `json:"name" db:"name"`
-	ChainID     *big.Int  `json:"chain_id" db:"chain_id"`
-	RPCEndpoint string    `json:"rpc_endpoint" db:"rpc_endpoint"`
-	WSEndpoint  string    `json:"ws_endpoint" db:"ws_endpoint"`
-	Explorer    string    `json:"explorer" db:"explorer"`
-	NativeToken string    `json:"native_token" db:"native_token"`
-	BlockTime   int       `json:"block_time" db:"block_time"` // seconds
-	IsTestnet   bool      `json:"is_testnet" db:"is_testnet"`
-	IsActive    bool      `json:"is_active" db:"is_active"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	ID            uint64    `json:"id" db:"id"`
+	Name          string    `json:"name" db:"name"`
+	ChainID       *big.Int  `json:"chain_id" db:"chain_id"`
+	RPCEndpoint   string    `json:"rpc_endpoint" db:"rpc_endpoint"`
+	WSEndpoint    string    `json:"ws_endpoint" db:"ws_endpoint"`
+	Explorer      string    `json:"explorer" db:"explorer"`
+	NativeToken   string    `json:"native_token" db:"native_token"`
+	BlockTime     int       `json:"block_time" db:"block_time"`                 // seconds
+	Finality      string    `json:"finality,omitempty" db:"finality"`           // latest, safe or finalized
+	Confirmations uint64    `json:"confirmations,omitempty" db:"confirmations"` // with latest finality
+	IsTestnet     bool      `json:"is_testnet" db:"is_testnet"`
+	IsActive      bool      `json:"is_active" db:"is_active"`
+	Source        string    `json:"source,omitempty" db:"source"` // the file defining it, empty for chains of the table alone
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
 }
 
 // ChainStats represents statistics for a chain
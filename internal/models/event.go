@@ -19,17 +19,84 @@ type Event struct {
 	EventName       string         `json:"event_name" db:"event_name"`
 	EventSignature  string         `json:"event_signature" db:"event_signature"`
 	Data            EventData      `json:"data" db:"data"`
+	Status          FinalityStatus `json:"status" db:"status"`
 	Timestamp       time.Time      `json:"timestamp" db:"timestamp"`
 }
 
+// FinalityStatus tells whether stored data can still be changed by a reorg
+type FinalityStatus string
+
+const (
+	FinalityTentative FinalityStatus = "tentative"
+	FinalityFinal     FinalityStatus = "final"
+)
+
 // EventData is a flexible map for event data
 type EventData map[string]interface{}
 
 // Block represents an indexed block
 type Block struct {
+	ID         uint64    `json:"id" db:"id"`
+	Chain      string    `json:"chain" db:"chain"`
+	Number     uint64    `json:"number" db:"number"`
+	Hash       string    `json:"hash" db:"hash"`
+	ParentHash string    `json:"parent_hash,omitempty" db:"parent_hash"`
+	Timestamp  time.Time `json:"timestamp" db:"timestamp"`
+}
+
+// Checkpoint records the last block indexed for a contract on a chain. An
+// empty Contract is the checkpoint of processors watching every address.
+type Checkpoint struct {
+	Chain     string    `json:"chain" db:"chain"`
+	Contract  string    `json:"contract" db:"contract_address"`
+	Protocol  string    `json:"protocol,omitempty" db:"protocol"`
+	Block     uint64    `json:"block" db:"last_processed_block"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// FailedRange is a block range that could not be indexed for a set of
+// contracts (hex addresses, "" for processors watching every address)
+type FailedRange struct {
 	ID        uint64    `json:"id" db:"id"`
 	Chain     string    `json:"chain" db:"chain"`
-	Number    uint64    `json:"number" db:"number"`
-	Hash      string    `json:"hash" db:"hash"`
-	Timestamp time.Time `json:"timestamp" db:"timestamp"`
+	Contracts []string  `json:"contracts" db:"contracts"`
+	FromBlock uint64    `json:"from_block" db:"from_block"`
+	ToBlock   uint64    `json:"to_block" db:"to_block"`
+	Attempts  int       `json:"attempts" db:"attempts"`
+	LastError string    `json:"last_error" db:"last_error"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// DeadLetter is a raw log its processor failed to decode, kept so it can be
+// replayed once the processor is fixed
+type DeadLetter struct {
+	ID              uint64         `json:"id" db:"id"`
+	Chain           string         `json:"chain" db:"chain"`
+	Protocol        string         `json:"protocol" db:"protocol"`
+	Address         common.Address `json:"address" db:"address"`
+	BlockNumber     uint64         `json:"block_number" db:"block_number"`
+	BlockHash       string         `json:"block_hash" db:"block_hash"`
+	TransactionHash string         `json:"transaction_hash" db:"transaction_hash"`
+	LogIndex        uint           `json:"log_index" db:"log_index"`
+	Topics          []string       `json:"topics" db:"topics"` // hex
+	Data            string         `json:"data" db:"data"`     // hex
+	Error           string         `json:"error" db:"error"`
+	Attempts        int            `json:"attempts" db:"attempts"`
+	Timestamp       time.Time      `json:"timestamp" db:"timestamp"`
+	CreatedAt       time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// EventBatch holds the writes for one indexed block range, committed
+// atomically
+type EventBatch struct {
+	Chain       string
+	Events      []*Event
+	Blocks      []*Block // blocks with events and the last block of the range
+	Checkpoints []*Checkpoint
+	DeadLetters []*DeadLetter
+	Failed      *FailedRange // set when the range could not be indexed
+	Resolved    uint64       // ID of a failed range this batch indexed
+	Replayed    []uint64     // IDs of dead letters this batch decoded
 }
@@ -6,7 +6,8 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 )
 
-// Protocol represents a DeFi protocol
+// Protocol represents a DeFi protocol. Its contracts' TVL is read by the
+// calculator adapter it names, or by the default one of its type.
 type Protocol struct {
 	ID          uint64                      `json:"id" db:"id"`
 	Name        string                      `json:"name" db:"name"`
@@ -14,6 +15,7 @@ type Protocol struct {
 	Description string                      `json:"description" db:"description"`
 	Website     string                      `json:"website" db:"website"`
 	Logo        string                      `json:"logo" db:"logo"`
+	Adapter     string                      `json:"adapter,omitempty" db:"adapter"`
 	Chains      map[string][]ContractConfig `json:"chains"`
 	CreatedAt   time.Time                   `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time                   `json:"updated_at" db:"updated_at"`
@@ -31,7 +33,8 @@ const (
 	ProtocolTypeInsurance  ProtocolType = "insurance"
 )
 
-// ContractConfig represents a smart contract configuration
+// ContractConfig represents a smart contract configuration. Its adapter
+// overrides its protocol's.
 type ContractConfig struct {
 	Address     common.Address   `json:"address"`
 	Name        string           `json:"name"`
@@ -42,4 +45,5 @@ type ContractConfig struct {
 	VaultID     string           `json:"vault_id,omitempty"`
 	DeployBlock uint64           `json:"deploy_block,omitempty"`
 	ABI         string           `json:"abi,omitempty"`
+	Adapter     string           `json:"adapter,omitempty"`
 }
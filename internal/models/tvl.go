@@ -7,7 +7,12 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 )
 
-// TVLSnapshot represents a point-in-time TVL measurement
+// TVLSnapshot represents a point-in-time TVL measurement. A rollup is a
+// snapshot summarizing the snapshots of one interval: Timestamp is the start
+// of the interval, BlockNumber, TotalUSD and Breakdown are those of its last
+// snapshot, and Range holds the rest. Snapshots imported from another
+// dataset name it as their Source and have no BlockNumber unless the
+// dataset had one.
 type TVLSnapshot struct {
 	ID          uint64               `json:"id" db:"id"`
 	ProtocolID  uint64               `json:"protocol_id" db:"protocol_id"`
@@ -16,7 +21,48 @@ type TVLSnapshot struct {
 	BlockNumber uint64               `json:"block_number" db:"block_number"`
 	TotalUSD    *big.Float           `json:"total_usd"`
 	Breakdown   map[string]*AssetTVL `json:"breakdown"`
+	Status      FinalityStatus       `json:"status" db:"status"`
 	Timestamp   time.Time            `json:"timestamp" db:"timestamp"`
+	Resolution  Resolution           `json:"resolution,omitempty" db:"resolution"` // empty for raw snapshots
+	Range       *TVLRange            `json:"range,omitempty"`                      // set on rollups
+	Source      string               `json:"source,omitempty" db:"source"`         // empty for snapshots the aggregator computed
+}
+
+// Resolution is the interval a point of TVL history covers
+type Resolution string
+
+const (
+	ResolutionRaw    Resolution = "raw"
+	ResolutionHourly Resolution = "hourly"
+	ResolutionDaily  Resolution = "daily"
+)
+
+// Interval returns the length of the resolution's intervals, 0 for raw
+// snapshots
+func (r Resolution) Interval() time.Duration {
+	switch r {
+	case ResolutionHourly:
+		return time.Hour
+	case ResolutionDaily:
+		return 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// IsRaw tells whether the resolution is that of raw snapshots
+func (r Resolution) IsRaw() bool {
+	return r == "" || r == ResolutionRaw
+}
+
+// TVLRange summarizes the TVL of the snapshots a rollup covers
+type TVLRange struct {
+	Open    *big.Float `json:"open"`
+	High    *big.Float `json:"high"`
+	Low     *big.Float `json:"low"`
+	Close   *big.Float `json:"close"`
+	Avg     *big.Float `json:"avg"`
+	Samples int        `json:"samples"`
 }
 
 // TVLData represents current TVL data
@@ -47,6 +93,19 @@ type AssetTVL struct {
 	Percentage float64        `json:"percentage,omitempty"`
 }
 
+// TokenHolding is an asset of a TVL snapshot: what a protocol held of a
+// token on a chain at the snapshot's block
+type TokenHolding struct {
+	SnapshotID  uint64         `json:"snapshot_id" db:"snapshot_id"`
+	Protocol    string         `json:"protocol" db:"protocol"`
+	Chain       string         `json:"chain" db:"chain"`
+	BlockNumber uint64         `json:"block_number" db:"block_number"`
+	Status      FinalityStatus `json:"status" db:"status"`
+	Timestamp   time.Time      `json:"timestamp" db:"timestamp"`
+	Resolution  Resolution     `json:"resolution,omitempty" db:"resolution"` // empty for raw snapshots
+	AssetTVL
+}
+
 // TVLHistory represents historical TVL data
 type TVLHistory struct {
 	Protocol   string         `json:"protocol"`
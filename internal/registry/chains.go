@@ -0,0 +1,515 @@
+// internal/registry/chains.go
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/blockchain"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// chainConfig is a chain as the chains of a file define it, a map by name
+// or a list of chains with their name
+type chainConfig struct {
+	Name          string `mapstructure:"name"`
+	ChainID       int64  `mapstructure:"chain_id"`
+	RPCURL        string `mapstructure:"rpc_url"`
+	WSURL         string `mapstructure:"ws_url"`
+	Explorer      string `mapstructure:"explorer"`
+	NativeToken   string `mapstructure:"native_token"`
+	BlockTime     int    `mapstructure:"block_time"` // seconds
+	Finality      string `mapstructure:"finality"`
+	Confirmations uint64 `mapstructure:"confirmations"`
+	Testnet       bool   `mapstructure:"testnet"`
+	Enabled       *bool  `mapstructure:"enabled"`
+}
+
+var finalityModes = map[blockchain.FinalityMode]bool{
+	"":                           true,
+	blockchain.FinalityLatest:    true,
+	blockchain.FinalitySafe:      true,
+	blockchain.FinalityFinalized: true,
+}
+
+// readChains reads the chains of a YAML or JSON file
+func readChains(path string) ([]chainConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	switch v.Get("chains").(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		var chains []chainConfig
+		if err := v.UnmarshalKey("chains", &chains); err != nil {
+			return nil, fmt.Errorf("invalid chains: %w", err)
+		}
+		return chains, nil
+	case map[string]interface{}:
+		var byName map[string]chainConfig
+		if err := v.UnmarshalKey("chains", &byName); err != nil {
+			return nil, fmt.Errorf("invalid chains: %w", err)
+		}
+		chains := make([]chainConfig, 0, len(byName))
+		for name, chain := range byName {
+			chain.Name = name
+			chains = append(chains, chain)
+		}
+		sort.Slice(chains, func(i, j int) bool {
+			return chains[i].Name < chains[j].Name
+		})
+		return chains, nil
+	default:
+		return nil, errors.New("chains must be a map by name or a list")
+	}
+}
+
+// ChainDefinition is a validated chain of a file, its Source the file.
+// Enabled is nil when the file leaves it to the chains table.
+type ChainDefinition struct {
+	Chain   *models.Chain
+	Enabled *bool
+}
+
+// ParseChains reads and validates the chains of files and directories.
+// Every problem found is reported, not only the first.
+//
+// Names and chain IDs must be unique, finality modes known, and chains
+// that are not disabled need an RPC URL.
+func ParseChains(paths []string) ([]*ChainDefinition, error) {
+	list, err := files(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var chains []*ChainDefinition
+	var problems []error
+	names := make(map[string]string)
+	ids := make(map[string]string)
+
+	for _, file := range list {
+		configs, err := readChains(file)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", file, err))
+			continue
+		}
+
+		for i, config := range configs {
+			label := config.Name
+			if label == "" {
+				label = fmt.Sprintf("chain %d", i+1)
+			}
+			chain, errs := parseChain(config)
+			for _, err := range errs {
+				problems = append(problems, fmt.Errorf("%s: %s: %w", file, label, err))
+			}
+			if chain == nil {
+				continue
+			}
+
+			if other, ok := names[chain.Name]; ok {
+				problems = append(problems, fmt.Errorf("%s: %s: already defined in %s", file, label, other))
+				continue
+			}
+			names[chain.Name] = file
+			if other, ok := ids[chain.ChainID.String()]; ok {
+				problems = append(problems, fmt.Errorf("%s: %s: chain ID %s is also the ID of %s",
+					file, label, chain.ChainID, other))
+				continue
+			}
+			ids[chain.ChainID.String()] = chain.Name
+
+			chain.Source = file
+			chains = append(chains, &ChainDefinition{Chain: chain, Enabled: config.Enabled})
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+
+	sort.Slice(chains, func(i, j int) bool {
+		return chains[i].Chain.Name < chains[j].Chain.Name
+	})
+	return chains, nil
+}
+
+// parseChain validates a chain, returning it unless it has no name or ID
+func parseChain(config chainConfig) (*models.Chain, []error) {
+	if config.Name == "" {
+		return nil, []error{errors.New("no name")}
+	}
+	if config.ChainID <= 0 {
+		return nil, []error{errors.New("no chain_id")}
+	}
+
+	var problems []error
+	chain := &models.Chain{
+		Name:          config.Name,
+		ChainID:       big.NewInt(config.ChainID),
+		RPCEndpoint:   config.RPCURL,
+		WSEndpoint:    config.WSURL,
+		Explorer:      config.Explorer,
+		NativeToken:   config.NativeToken,
+		BlockTime:     config.BlockTime,
+		Finality:      config.Finality,
+		Confirmations: config.Confirmations,
+		IsTestnet:     config.Testnet,
+		IsActive:      config.Enabled == nil || *config.Enabled,
+	}
+
+	if !finalityModes[blockchain.FinalityMode(config.Finality)] {
+		problems = append(problems, fmt.Errorf("unknown finality %q, want latest, safe or finalized", config.Finality))
+	}
+	if config.BlockTime < 0 {
+		problems = append(problems, fmt.Errorf("negative block_time %d", config.BlockTime))
+	}
+	if chain.IsActive && expand(config.RPCURL) == "" {
+		problems = append(problems, errors.New("no rpc_url"))
+	}
+
+	return chain, problems
+}
+
+// expand replaces ${VAR} and $VAR in an endpoint with the environment, and
+// ${VAR:-default} with the default when VAR is empty, so RPC keys can stay
+// out of files and the chains table
+func expand(text string) string {
+	return os.Expand(text, func(name string) string {
+		if i := strings.Index(name, ":-"); i >= 0 {
+			if value := os.Getenv(name[:i]); value != "" {
+				return value
+			}
+			return name[i+2:]
+		}
+		return os.Getenv(name)
+	})
+}
+
+// managerConfig is the manager's configuration of a chain, with its
+// endpoints expanded
+func managerConfig(chain *models.Chain) blockchain.ChainConfig {
+	return blockchain.ChainConfig{
+		Name:          chain.Name,
+		ChainID:       new(big.Int).Set(chain.ChainID),
+		RPCURL:        expand(chain.RPCEndpoint),
+		WSURL:         expand(chain.WSEndpoint),
+		Explorer:      chain.Explorer,
+		NativeToken:   chain.NativeToken,
+		BlockTime:     time.Duration(chain.BlockTime) * time.Second,
+		Finality:      blockchain.FinalityMode(chain.Finality),
+		Confirmations: chain.Confirmations,
+	}
+}
+
+// ChainResult tells what a chain load changed. Chains that failed to
+// connect are retried on the next load.
+type ChainResult struct {
+	Added   []string          `json:"added"`
+	Updated []string          `json:"updated"`
+	Removed []string          `json:"removed"`
+	Failed  map[string]string `json:"failed,omitempty"`
+}
+
+// ChainRegistry keeps the blockchain manager's chains in line with the
+// chains table and files.
+//
+// Files win over the table: a chain of a file is saved to the table,
+// replacing the row of the same name, and its row is deleted once the chain
+// is dropped from the files. Whether it is active is the file's enabled
+// where set, else the row's is_active, so a file chain can be deactivated
+// in the table; a new row is active. Rows of no file are the table's alone,
+// and rows of files another registry reads are left to it.
+//
+// Active chains are connected. Chains the registry connected that are
+// deactivated or deleted are disconnected; chains added to the manager by
+// other means are left alone. Listeners are stopped from using a chain
+// before its client is replaced or closed.
+type ChainRegistry struct {
+	paths   []string
+	storage storage.ChainStorage
+	manager *blockchain.Manager
+
+	mu        sync.Mutex
+	active    map[string]*models.Chain
+	added     map[string]bool
+	listeners []ChainListener
+}
+
+// ChainListener follows the chains a registry connects, e.g. to run a loop
+// per chain with its client
+type ChainListener interface {
+	// StartChain is called once a chain is connected or updated
+	StartChain(name string)
+	// StopChain is called before a chain is updated or disconnected, while
+	// its client is still open, and must not return until the chain's
+	// client is no longer in use
+	StopChain(name string)
+}
+
+// NewChainRegistry creates a registry of the chains in files and
+// directories and, unless store is nil, the chains table
+func NewChainRegistry(store storage.ChainStorage, manager *blockchain.Manager, paths ...string) *ChainRegistry {
+	return &ChainRegistry{
+		paths:   paths,
+		storage: store,
+		manager: manager,
+		active:  make(map[string]*models.Chain),
+		added:   make(map[string]bool),
+	}
+}
+
+// Subscribe has listener follow the chains connected from now on
+func (r *ChainRegistry) Subscribe(listener ChainListener) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.listeners = append(r.listeners, listener)
+}
+
+// GetSupportedChains lists the active chains, connected or not
+func (r *ChainRegistry) GetSupportedChains() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.active))
+	for name := range r.active {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load reads the chains, saves those of the files and applies the active
+// ones to the manager. Nothing changes when a file is invalid; a chain that
+// fails to connect is reported in the result's Failed, not as an error.
+func (r *ChainRegistry) Load(ctx context.Context) (*ChainResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	defined, err := ParseChains(r.paths)
+	if err != nil {
+		return nil, err
+	}
+
+	chains := make(map[string]*models.Chain)
+	if r.storage != nil {
+		stored, err := r.storage.GetChains(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read chains: %w", err)
+		}
+		for _, chain := range stored {
+			chains[chain.Name] = chain
+		}
+	}
+	inFiles := make(map[string]bool, len(defined))
+	for _, definition := range defined {
+		chain := definition.Chain
+		stored := chains[chain.Name]
+		inFiles[chain.Name] = true
+
+		switch {
+		case definition.Enabled != nil:
+			chain.IsActive = *definition.Enabled
+		case stored != nil:
+			chain.IsActive = stored.IsActive
+		default:
+			chain.IsActive = true
+		}
+
+		if r.storage != nil && !sameChain(stored, chain) {
+			if err := r.storage.SaveChain(ctx, chain); err != nil {
+				return nil, fmt.Errorf("failed to save %s: %w", chain.Name, err)
+			}
+		}
+		chains[chain.Name] = chain
+	}
+
+	// Rows of chains dropped from the files
+	var dropped []string
+	for name, chain := range chains {
+		if chain.Source != "" && !inFiles[name] && r.owns(chain.Source) {
+			dropped = append(dropped, name)
+		}
+	}
+	sort.Strings(dropped)
+	for _, name := range dropped {
+		if err := r.storage.DeleteChain(ctx, name); err != nil {
+			return nil, fmt.Errorf("failed to delete %s: %w", name, err)
+		}
+		delete(chains, name)
+	}
+
+	active := make(map[string]*models.Chain)
+	for name, chain := range chains {
+		if chain.IsActive {
+			active[name] = chain
+		}
+	}
+	r.active = active
+
+	return r.apply(), nil
+}
+
+// apply connects, updates and disconnects the manager's chains
+func (r *ChainRegistry) apply() *ChainResult {
+	result := &ChainResult{}
+	fail := func(name string, err error) {
+		if result.Failed == nil {
+			result.Failed = make(map[string]string)
+		}
+		result.Failed[name] = err.Error()
+	}
+
+	names := make([]string, 0, len(r.active))
+	for name := range r.active {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		config := managerConfig(r.active[name])
+		current, err := r.manager.GetChainConfig(name)
+		if err != nil {
+			if err := r.manager.AddChain(config); err != nil {
+				fail(name, err)
+				continue
+			}
+			r.added[name] = true
+			result.Added = append(result.Added, name)
+			r.start(name)
+			continue
+		}
+
+		if sameConfig(current, &config) {
+			continue
+		}
+		r.stop(name)
+		err = r.manager.UpdateChain(config)
+		// A failed update keeps the chain as it was
+		r.start(name)
+		if err != nil {
+			fail(name, err)
+			continue
+		}
+		result.Updated = append(result.Updated, name)
+	}
+
+	var removed []string
+	for name := range r.added {
+		if r.active[name] == nil {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		delete(r.added, name)
+		r.stop(name)
+		if err := r.manager.RemoveChain(name); err != nil {
+			fail(name, err)
+			continue
+		}
+		result.Removed = append(result.Removed, name)
+	}
+
+	return result
+}
+
+func (r *ChainRegistry) start(name string) {
+	for _, listener := range r.listeners {
+		listener.StartChain(name)
+	}
+}
+
+func (r *ChainRegistry) stop(name string) {
+	for _, listener := range r.listeners {
+		listener.StopChain(name)
+	}
+}
+
+// sameChain tells whether a stored chain matches its definition, whichever
+// file saved it
+func sameChain(stored, defined *models.Chain) bool {
+	return stored != nil &&
+		stored.ChainID != nil && stored.ChainID.Cmp(defined.ChainID) == 0 &&
+		stored.RPCEndpoint == defined.RPCEndpoint &&
+		stored.WSEndpoint == defined.WSEndpoint &&
+		stored.Explorer == defined.Explorer &&
+		stored.NativeToken == defined.NativeToken &&
+		stored.BlockTime == defined.BlockTime &&
+		stored.Finality == defined.Finality &&
+		stored.Confirmations == defined.Confirmations &&
+		stored.IsTestnet == defined.IsTestnet &&
+		stored.IsActive == defined.IsActive &&
+		stored.Source != ""
+}
+
+// owns tells whether source is one of the registry's files, or was one of
+// its directories' files. Rows of other files belong to other registries
+// sharing the table.
+func (r *ChainRegistry) owns(source string) bool {
+	source = filepath.Clean(source)
+	for _, path := range r.paths {
+		path = filepath.Clean(path)
+		if source == path || filepath.Dir(source) == path {
+			return true
+		}
+	}
+	return false
+}
+
+func sameConfig(a, b *blockchain.ChainConfig) bool {
+	return a.ChainID.Cmp(b.ChainID) == 0 &&
+		a.RPCURL == b.RPCURL &&
+		a.WSURL == b.WSURL &&
+		a.Explorer == b.Explorer &&
+		a.NativeToken == b.NativeToken &&
+		a.BlockTime == b.BlockTime &&
+		a.Finality == b.Finality &&
+		a.Confirmations == b.Confirmations
+}
+
+// Run reloads the chains every interval until ctx is done, so rows changed
+// in the chains table and edited files take effect and chains that failed
+// to connect are retried. An invalid file is logged and the chains loaded
+// before are kept.
+func (r *ChainRegistry) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		result, err := r.Load(ctx)
+		if err != nil {
+			log.Printf("Failed to reload chains: %v", err)
+			continue
+		}
+		if len(result.Added)+len(result.Updated)+len(result.Removed) > 0 {
+			log.Printf("Reloaded chains: %d added, %d updated, %d removed",
+				len(result.Added), len(result.Updated), len(result.Removed))
+		}
+		for name, failure := range result.Failed {
+			log.Printf("Chain %s: %s", name, failure)
+		}
+	}
+}
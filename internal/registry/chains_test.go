@@ -0,0 +1,285 @@
+// internal/registry/chains_test.go
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/blockchain"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/memory"
+)
+
+// rpcServer answers eth_chainId with the chain ID of the request's path, so
+// a chain at <url>/137 connects as chain 137
+func rpcServer(t *testing.T) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		id, ok := new(big.Int).SetString(strings.TrimPrefix(r.URL.Path, "/"), 10)
+		if !ok || request.Method != "eth_chainId" {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"error":{"code":-32601,"message":"unsupported"}}`, request.ID)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x%x"}`, request.ID, id)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// chainsFile writes a chains file, "RPC" in text standing for the RPC
+// server's URL
+func chainsFile(t *testing.T, path, rpc, text string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(strings.ReplaceAll(text, "RPC", rpc)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func load(t *testing.T, r *ChainRegistry) *ChainResult {
+	t.Helper()
+
+	result, err := r.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(result.Failed) > 0 {
+		t.Fatalf("Load failed to connect %v", result.Failed)
+	}
+	return result
+}
+
+func checkChains(t *testing.T, r *ChainRegistry, manager *blockchain.Manager, want ...string) {
+	t.Helper()
+
+	if got := r.GetSupportedChains(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("active chains = %v, want %v", got, want)
+	}
+	if got := manager.GetSupportedChains(); len(got) != len(want) {
+		t.Errorf("manager chains = %v, want %v", got, want)
+	}
+}
+
+func TestChainRegistrySources(t *testing.T) {
+	ctx := context.Background()
+	rpc := rpcServer(t)
+	path := filepath.Join(t.TempDir(), "chains.yaml")
+	store := memory.NewMemoryStorage()
+	manager := blockchain.NewManager()
+	defer manager.Close()
+
+	// A chain of the table alone
+	local := &models.Chain{Name: "local", ChainID: big.NewInt(31337), RPCEndpoint: rpc + "/31337", IsActive: true}
+	if err := store.SaveChain(ctx, local); err != nil {
+		t.Fatal(err)
+	}
+
+	chainsFile(t, path, rpc, `
+chains:
+  ethereum: {chain_id: 1, rpc_url: RPC/1}
+  polygon: {chain_id: 137, rpc_url: RPC/137}
+`)
+	r := NewChainRegistry(store, manager, path)
+	result := load(t, r)
+	if !reflect.DeepEqual(result.Added, []string{"ethereum", "local", "polygon"}) {
+		t.Errorf("first load added %v, want [ethereum local polygon]", result.Added)
+	}
+	checkChains(t, r, manager, "ethereum", "local", "polygon")
+
+	saved, err := store.GetChain(ctx, "polygon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Source != path || !saved.IsActive {
+		t.Errorf("saved polygon = %+v, want an active row of %s", saved, path)
+	}
+
+	// Deactivating a file chain in the table sticks
+	saved.IsActive = false
+	if err := store.SaveChain(ctx, saved); err != nil {
+		t.Fatal(err)
+	}
+	result = load(t, r)
+	if !reflect.DeepEqual(result.Removed, []string{"polygon"}) {
+		t.Errorf("load after deactivating removed %v, want [polygon]", result.Removed)
+	}
+	checkChains(t, r, manager, "ethereum", "local")
+
+	load(t, r)
+	checkChains(t, r, manager, "ethereum", "local")
+	if saved, _ := store.GetChain(ctx, "polygon"); saved.IsActive {
+		t.Error("reloading the file reactivated polygon")
+	}
+
+	// A file's enabled wins over the table
+	chainsFile(t, path, rpc, `
+chains:
+  ethereum: {chain_id: 1, rpc_url: RPC/1, enabled: false}
+  polygon: {chain_id: 137, rpc_url: RPC/137, enabled: true}
+`)
+	load(t, r)
+	checkChains(t, r, manager, "local", "polygon")
+	if saved, _ := store.GetChain(ctx, "ethereum"); saved.IsActive {
+		t.Error("ethereum disabled in its file is active in the table")
+	}
+
+	// Dropping a chain from the files deletes its row; the table's own
+	// chains stay
+	chainsFile(t, path, rpc, `
+chains:
+  ethereum: {chain_id: 1, rpc_url: RPC/1}
+`)
+	result = load(t, r)
+	if !reflect.DeepEqual(result.Removed, []string{"polygon"}) {
+		t.Errorf("load after dropping polygon removed %v, want [polygon]", result.Removed)
+	}
+	if _, err := store.GetChain(ctx, "polygon"); err == nil {
+		t.Error("polygon dropped from the file is still in the table")
+	}
+	if _, err := store.GetChain(ctx, "local"); err != nil {
+		t.Errorf("the table's own chain was deleted: %v", err)
+	}
+	// ethereum was disabled by its file, which no longer says; its row does
+	checkChains(t, r, manager, "local")
+
+	// An invalid file changes nothing
+	chainsFile(t, path, rpc, `
+chains:
+  broken: {rpc_url: RPC/5}
+`)
+	if _, err := r.Load(ctx); err == nil {
+		t.Error("Load of an invalid file: want an error")
+	}
+	if _, err := store.GetChain(ctx, "ethereum"); err != nil {
+		t.Errorf("an invalid file deleted ethereum: %v", err)
+	}
+	checkChains(t, r, manager, "local")
+}
+
+// recorder records the calls of a registry, and whether the chain's client
+// was still open when it was told to stop
+type recorder struct {
+	manager *blockchain.Manager
+	calls   []string
+}
+
+func (r *recorder) StartChain(name string) {
+	r.calls = append(r.calls, "start "+name)
+}
+
+func (r *recorder) StopChain(name string) {
+	if _, err := r.manager.GetClient(name); err != nil {
+		r.calls = append(r.calls, "stop "+name+" after its client closed")
+		return
+	}
+	r.calls = append(r.calls, "stop "+name)
+}
+
+func TestChainRegistryListeners(t *testing.T) {
+	rpc := rpcServer(t)
+	path := filepath.Join(t.TempDir(), "chains.yaml")
+	manager := blockchain.NewManager()
+	defer manager.Close()
+
+	r := NewChainRegistry(nil, manager, path)
+	listener := &recorder{manager: manager}
+	r.Subscribe(listener)
+
+	steps := []struct {
+		file string
+		want []string
+	}{
+		{`
+chains:
+  ethereum: {chain_id: 1, rpc_url: RPC/1}
+  polygon: {chain_id: 137, rpc_url: RPC/137}
+`, []string{"start ethereum", "start polygon"}},
+		// Unchanged chains are left running
+		{`
+chains:
+  ethereum: {chain_id: 1, rpc_url: RPC/1}
+  polygon: {chain_id: 137, rpc_url: RPC/137}
+`, nil},
+		{`
+chains:
+  ethereum: {chain_id: 1, rpc_url: RPC/1, confirmations: 20}
+  polygon: {chain_id: 137, rpc_url: RPC/137, enabled: false}
+`, []string{"stop ethereum", "start ethereum", "stop polygon"}},
+	}
+	for i, step := range steps {
+		listener.calls = nil
+		chainsFile(t, path, rpc, step.file)
+		load(t, r)
+		if strings.Join(listener.calls, ", ") != strings.Join(step.want, ", ") {
+			t.Errorf("load %d: calls %v, want %v", i+1, listener.calls, step.want)
+		}
+	}
+}
+
+func TestChainRegistriesSharingTable(t *testing.T) {
+	ctx := context.Background()
+	rpc := rpcServer(t)
+	dir := t.TempDir()
+	apiPath := filepath.Join(dir, "api.yaml")
+	indexerPath := filepath.Join(dir, "indexer.yaml")
+	store := memory.NewMemoryStorage()
+	manager := blockchain.NewManager()
+	defer manager.Close()
+	apiManager := blockchain.NewManager()
+	defer apiManager.Close()
+
+	chainsFile(t, apiPath, rpc, `
+chains:
+  ethereum: {chain_id: 1, rpc_url: RPC/1}
+`)
+	chainsFile(t, indexerPath, rpc, `
+chains:
+  ethereum: {chain_id: 1, rpc_url: RPC/1}
+  base: {chain_id: 8453, rpc_url: RPC/8453}
+`)
+	api := NewChainRegistry(store, apiManager, apiPath)
+	indexer := NewChainRegistry(store, manager, indexerPath)
+	load(t, api)
+	load(t, indexer)
+
+	// The same chain defined alike in both files stays the first one's
+	if saved, _ := store.GetChain(ctx, "ethereum"); saved == nil || saved.Source != apiPath {
+		t.Errorf("ethereum row = %+v, want the row of %s", saved, apiPath)
+	}
+
+	// Reloading the API leaves the indexer's chain alone
+	load(t, api)
+	if _, err := store.GetChain(ctx, "base"); err != nil {
+		t.Errorf("the API's registry deleted the indexer's chain: %v", err)
+	}
+
+	// Dropping it from the indexer's file deletes it
+	chainsFile(t, indexerPath, rpc, `
+chains:
+  ethereum: {chain_id: 1, rpc_url: RPC/1}
+`)
+	load(t, indexer)
+	if _, err := store.GetChain(ctx, "base"); err == nil {
+		t.Error("base dropped from the indexer's file is still in the table")
+	}
+	checkChains(t, indexer, manager, "ethereum")
+}
@@ -0,0 +1,276 @@
+// internal/registry/config.go
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/viper"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/aggregator"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+)
+
+// protocolConfig is a protocol as the protocols list of a file defines it
+type protocolConfig struct {
+	Name        string                      `mapstructure:"name"`
+	Type        string                      `mapstructure:"type"`
+	Description string                      `mapstructure:"description"`
+	Website     string                      `mapstructure:"website"`
+	Logo        string                      `mapstructure:"logo"`
+	Adapter     string                      `mapstructure:"adapter"`
+	Enabled     *bool                       `mapstructure:"enabled"`
+	Chains      map[string][]contractConfig `mapstructure:"chains"`
+}
+
+type contractConfig struct {
+	Address     string   `mapstructure:"address"`
+	Name        string   `mapstructure:"name"`
+	Type        string   `mapstructure:"type"`
+	Version     string   `mapstructure:"version"`
+	Adapter     string   `mapstructure:"adapter"`
+	Tokens      []string `mapstructure:"tokens"`
+	PoolID      string   `mapstructure:"pool_id"`
+	VaultID     string   `mapstructure:"vault_id"`
+	DeployBlock uint64   `mapstructure:"deploy_block"`
+	ABI         string   `mapstructure:"abi"`
+}
+
+// Definition is a validated protocol of a file
+type Definition struct {
+	Protocol *models.Protocol
+	Enabled  bool
+	File     string
+}
+
+var protocolTypes = map[models.ProtocolType]bool{
+	models.ProtocolTypeDEX:        true,
+	models.ProtocolTypeLending:    true,
+	models.ProtocolTypeYield:      true,
+	models.ProtocolTypeBridge:     true,
+	models.ProtocolTypeDerivative: true,
+	models.ProtocolTypeInsurance:  true,
+}
+
+// extensions are the file types a directory's definitions are read from
+var extensions = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// files lists the definition files of paths, the YAML and JSON files of a
+// directory by name
+func files(paths []string) ([]string, error) {
+	var list []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			list = append(list, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && extensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+				list = append(list, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	return list, nil
+}
+
+// readFile reads the protocols list of a YAML or JSON file
+func readFile(path string) ([]protocolConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	var protocols []protocolConfig
+	if err := v.UnmarshalKey("protocols", &protocols); err != nil {
+		return nil, fmt.Errorf("invalid protocols: %w", err)
+	}
+	return protocols, nil
+}
+
+// Parse reads and validates the protocol definitions of files and
+// directories. Every problem found is reported, not only the first.
+//
+// Names must be unique, types and adapters known, and the chains of enabled
+// protocols known. Addresses must be EIP-55 checksummed, and a contract may
+// only be defined once per chain.
+func Parse(paths []string, chains []string) ([]*Definition, error) {
+	list, err := files(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(chains))
+	for _, chain := range chains {
+		known[chain] = true
+	}
+
+	var definitions []*Definition
+	var problems []error
+	names := make(map[string]string)
+	contracts := make(map[string]string)
+
+	for _, file := range list {
+		configs, err := readFile(file)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", file, err))
+			continue
+		}
+
+		for i, config := range configs {
+			definition, errs := parse(config, known)
+			label := config.Name
+			if label == "" {
+				label = fmt.Sprintf("protocol %d", i+1)
+			}
+			for _, err := range errs {
+				problems = append(problems, fmt.Errorf("%s: %s: %w", file, label, err))
+			}
+			if definition == nil {
+				continue
+			}
+
+			if other, ok := names[config.Name]; ok {
+				problems = append(problems, fmt.Errorf("%s: %s: already defined in %s", file, label, other))
+				continue
+			}
+			names[config.Name] = file
+			definition.File = file
+
+			for chain, chainContracts := range definition.Protocol.Chains {
+				for _, contract := range chainContracts {
+					key := chain + ":" + contract.Address.Hex()
+					if other, ok := contracts[key]; ok {
+						problems = append(problems, fmt.Errorf("%s: %s: %s contract %s is also a contract of %s",
+							file, label, chain, contract.Address.Hex(), other))
+						continue
+					}
+					contracts[key] = config.Name
+				}
+			}
+
+			definitions = append(definitions, definition)
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Protocol.Name < definitions[j].Protocol.Name
+	})
+	return definitions, nil
+}
+
+// parse validates a protocol, returning it unless it has no name
+func parse(config protocolConfig, chains map[string]bool) (*Definition, []error) {
+	var problems []error
+	if config.Name == "" {
+		return nil, []error{errors.New("no name")}
+	}
+
+	protocolType := models.ProtocolType(config.Type)
+	if !protocolTypes[protocolType] {
+		problems = append(problems, fmt.Errorf("unknown type %q", config.Type))
+	}
+	if config.Adapter != "" && !aggregator.HasAdapter(config.Adapter) {
+		problems = append(problems, fmt.Errorf("unknown adapter %q, want one of %s",
+			config.Adapter, strings.Join(aggregator.Adapters(), ", ")))
+	}
+
+	definition := &Definition{
+		Protocol: &models.Protocol{
+			Name:        config.Name,
+			Type:        protocolType,
+			Description: config.Description,
+			Website:     config.Website,
+			Logo:        config.Logo,
+			Adapter:     config.Adapter,
+			Chains:      make(map[string][]models.ContractConfig, len(config.Chains)),
+		},
+		Enabled: config.Enabled == nil || *config.Enabled,
+	}
+
+	// A disabled protocol may be on a chain that is not configured
+	if definition.Enabled && len(config.Chains) == 0 {
+		problems = append(problems, errors.New("no chains"))
+	}
+
+	for chain, contracts := range config.Chains {
+		if definition.Enabled && !chains[chain] {
+			problems = append(problems, fmt.Errorf("unknown chain %s", chain))
+		}
+
+		for _, contract := range contracts {
+			parsed, errs := parseContract(contract)
+			for _, err := range errs {
+				problems = append(problems, fmt.Errorf("%s contract %s: %w", chain, contract.Address, err))
+			}
+			definition.Protocol.Chains[chain] = append(definition.Protocol.Chains[chain], parsed)
+		}
+	}
+
+	return definition, problems
+}
+
+func parseContract(config contractConfig) (models.ContractConfig, []error) {
+	var problems []error
+
+	address, err := checksummed(config.Address)
+	if err != nil {
+		problems = append(problems, err)
+	}
+	if config.Adapter != "" && !aggregator.HasAdapter(config.Adapter) {
+		problems = append(problems, fmt.Errorf("unknown adapter %q, want one of %s",
+			config.Adapter, strings.Join(aggregator.Adapters(), ", ")))
+	}
+
+	contract := models.ContractConfig{
+		Address:     address,
+		Name:        config.Name,
+		Type:        config.Type,
+		Version:     config.Version,
+		PoolID:      config.PoolID,
+		VaultID:     config.VaultID,
+		DeployBlock: config.DeployBlock,
+		ABI:         config.ABI,
+		Adapter:     config.Adapter,
+	}
+	for _, token := range config.Tokens {
+		address, err := checksummed(token)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("token: %w", err))
+			continue
+		}
+		contract.Tokens = append(contract.Tokens, address)
+	}
+
+	return contract, problems
+}
+
+// checksummed parses an address written with its EIP-55 checksum, which
+// catches mistyped addresses
+func checksummed(text string) (common.Address, error) {
+	if !common.IsHexAddress(text) || !strings.HasPrefix(text, "0x") {
+		return common.Address{}, fmt.Errorf("invalid address %q", text)
+	}
+	address := common.HexToAddress(text)
+	if address.Hex() != text {
+		return common.Address{}, fmt.Errorf("address %s is not checksummed, want %s", text, address.Hex())
+	}
+	return address, nil
+}
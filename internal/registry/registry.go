@@ -0,0 +1,307 @@
+// internal/registry/registry.go
+
+// Package registry loads chains and protocols from declarative YAML or JSON
+// files, so adding either is a config change rather than a code change.
+// Chains are kept in the chains table and the blockchain manager, protocols
+// in storage and the TVL calculator.
+//
+// A file lists chains by name under chains, and protocols under protocols,
+// each with its type, the adapter its TVL is calculated with and its
+// contracts by chain:
+//
+//	chains:
+//	  ethereum:
+//	    chain_id: 1
+//	    rpc_url: "${ETH_RPC_URL:-https://ethereum-rpc.publicnode.com}"
+//	    native_token: ETH
+//	    block_time: 12
+//	    finality: finalized
+//
+//	protocols:
+//	  - name: uniswap-v2
+//	    type: dex
+//	    adapter: uniswap-v2
+//	    chains:
+//	      ethereum:
+//	        - address: "0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc"
+//	          name: USDC-WETH
+//	          deploy_block: 10008355
+package registry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// Calculator is where loaded protocols are registered, the TVL calculator
+type Calculator interface {
+	RegisterProtocol(protocol *models.Protocol)
+	UnregisterProtocol(name string)
+}
+
+// Chains lists the chains protocols may be on, the blockchain manager's
+type Chains interface {
+	GetSupportedChains() []string
+}
+
+// Result tells what a load changed
+type Result struct {
+	Added     []string `json:"added"`
+	Updated   []string `json:"updated"`
+	Removed   []string `json:"removed"`
+	Unchanged int      `json:"unchanged"`
+}
+
+// Registry keeps the protocols of files in storage and the calculator.
+//
+// Enabled protocols are saved and registered. Disabled ones, and those
+// removed from the files since the last load, are deleted from storage and
+// unregistered; their TVL history is kept. Protocols saved in storage by
+// other means are left alone.
+type Registry struct {
+	paths      []string
+	storage    storage.ProtocolStorage
+	calculator Calculator
+	chains     Chains
+
+	mu       sync.Mutex
+	loaded   map[string]*models.Protocol
+	modTimes map[string]time.Time
+}
+
+// New creates a registry of the protocols in files and directories
+func New(store storage.ProtocolStorage, calculator Calculator, chains Chains, paths ...string) *Registry {
+	return &Registry{
+		paths:      paths,
+		storage:    store,
+		calculator: calculator,
+		chains:     chains,
+		loaded:     make(map[string]*models.Protocol),
+	}
+}
+
+// Load reads the files and syncs their protocols. Nothing changes when a
+// file is invalid.
+func (r *Registry) Load(ctx context.Context) (*Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTimes, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	// Invalid files are read again once they change, a failed sync on the
+	// next check
+	r.modTimes = modTimes
+	definitions, err := Parse(r.paths, r.chains.GetSupportedChains())
+	if err != nil {
+		return nil, err
+	}
+	result, err := r.sync(ctx, definitions)
+	if err != nil {
+		r.modTimes = nil
+	}
+	return result, err
+}
+
+// sync saves and registers enabled definitions and removes the others
+func (r *Registry) sync(ctx context.Context, definitions []*Definition) (*Result, error) {
+	result := &Result{}
+	enabled := make(map[string]bool, len(definitions))
+	for _, definition := range definitions {
+		name := definition.Protocol.Name
+		if !definition.Enabled {
+			continue
+		}
+		enabled[name] = true
+
+		changed, added, err := r.save(ctx, definition.Protocol)
+		if err != nil {
+			return result, fmt.Errorf("failed to save %s: %w", name, err)
+		}
+		switch {
+		case added:
+			result.Added = append(result.Added, name)
+		case changed:
+			result.Updated = append(result.Updated, name)
+		default:
+			result.Unchanged++
+		}
+
+		r.loaded[name] = definition.Protocol
+		r.calculator.RegisterProtocol(definition.Protocol)
+	}
+
+	// Disabled protocols are removed wherever they are, dropped ones only
+	// where this registry loaded them
+	var removals []string
+	for _, definition := range definitions {
+		if !definition.Enabled {
+			removals = append(removals, definition.Protocol.Name)
+		}
+	}
+	for name := range r.loaded {
+		if !enabled[name] && !contains(removals, name) {
+			removals = append(removals, name)
+		}
+	}
+	sort.Strings(removals)
+
+	for _, name := range removals {
+		removed, err := r.remove(ctx, name)
+		if err != nil {
+			return result, err
+		}
+		if removed {
+			result.Removed = append(result.Removed, name)
+		}
+	}
+
+	return result, nil
+}
+
+// save saves a protocol, or updates it where storage has it differently
+func (r *Registry) save(ctx context.Context, protocol *models.Protocol) (changed, added bool, err error) {
+	existing, err := r.storage.GetProtocol(ctx, protocol.Name)
+	if err != nil {
+		// Not stored yet, or storage fails to save it too
+		return true, true, r.storage.SaveProtocol(ctx, protocol)
+	}
+	if sameProtocol(existing, protocol) {
+		protocol.ID, protocol.CreatedAt, protocol.UpdatedAt = existing.ID, existing.CreatedAt, existing.UpdatedAt
+		return false, false, nil
+	}
+	return true, false, r.storage.UpdateProtocol(ctx, protocol)
+}
+
+// remove deletes a protocol from storage, if there, and the calculator
+func (r *Registry) remove(ctx context.Context, name string) (bool, error) {
+	r.calculator.UnregisterProtocol(name)
+	delete(r.loaded, name)
+
+	if _, err := r.storage.GetProtocol(ctx, name); err != nil {
+		return false, nil
+	}
+	if err := r.storage.DeleteProtocol(ctx, name); err != nil {
+		return false, fmt.Errorf("failed to delete %s: %w", name, err)
+	}
+	return true, nil
+}
+
+func contains(names []string, name string) bool {
+	for _, other := range names {
+		if other == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sameProtocol tells whether a stored protocol matches its definition
+func sameProtocol(stored, defined *models.Protocol) bool {
+	if stored.Type != defined.Type || stored.Description != defined.Description ||
+		stored.Website != defined.Website || stored.Logo != defined.Logo ||
+		stored.Adapter != defined.Adapter || len(stored.Chains) != len(defined.Chains) {
+		return false
+	}
+	for chain, contracts := range defined.Chains {
+		if len(contracts) != len(stored.Chains[chain]) {
+			return false
+		}
+		for i, contract := range contracts {
+			if !reflect.DeepEqual(normalize(contract), normalize(stored.Chains[chain][i])) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// normalize treats a contract without tokens the same however it was stored
+func normalize(contract models.ContractConfig) models.ContractConfig {
+	if len(contract.Tokens) == 0 {
+		contract.Tokens = nil
+	}
+	return contract
+}
+
+// Run reloads the protocols whenever their files change, checking every
+// interval until ctx is done. An invalid change is logged and the protocols
+// loaded before are kept.
+func (r *Registry) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := r.changed()
+		if err != nil {
+			log.Printf("Protocol registry: %v", err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+
+		result, err := r.Load(ctx)
+		if err != nil {
+			log.Printf("Failed to reload protocols: %v", err)
+			continue
+		}
+		log.Printf("Reloaded protocols: %d added, %d updated, %d removed",
+			len(result.Added), len(result.Updated), len(result.Removed))
+	}
+}
+
+// changed tells whether a file was changed, added or removed since the last
+// load, or whether that load failed to sync
+func (r *Registry) changed() (bool, error) {
+	modTimes, err := r.stat()
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.modTimes == nil || len(modTimes) != len(r.modTimes) {
+		return true, nil
+	}
+	for file, modTime := range modTimes {
+		if !r.modTimes[file].Equal(modTime) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// stat reads the modification times of the files
+func (r *Registry) stat() (map[string]time.Time, error) {
+	list, err := files(r.paths)
+	if err != nil {
+		return nil, err
+	}
+
+	modTimes := make(map[string]time.Time, len(list))
+	for _, file := range list {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[file] = info.ModTime()
+	}
+	return modTimes, nil
+}
@@ -0,0 +1,91 @@
+// internal/storage/aggregate.go
+package storage
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+)
+
+// Aggregate sums the latest snapshot of every protocol on every chain into
+// an AggregatedTVL. Snapshots without a chain only count for protocols that
+// have no per-chain snapshots.
+func Aggregate(latest []*models.TVLSnapshot) *models.AggregatedTVL {
+	byProtocol := make(map[string][]*models.TVLSnapshot)
+	for _, snapshot := range latest {
+		byProtocol[snapshot.Protocol] = append(byProtocol[snapshot.Protocol], snapshot)
+	}
+
+	aggregated := &models.AggregatedTVL{
+		Timestamp:   time.Now(),
+		Protocols:   make(map[string]*models.TVLData),
+		TotalUSD:    big.NewFloat(0),
+		ChainTotals: make(map[string]*big.Float),
+	}
+
+	for protocol, snapshots := range byProtocol {
+		perChain := false
+		for _, snapshot := range snapshots {
+			if snapshot.Chain != "" {
+				perChain = true
+			}
+		}
+
+		data := &models.TVLData{
+			Protocol: protocol,
+			Chains:   make(map[string]*models.ChainTVL),
+			TotalUSD: big.NewFloat(0),
+		}
+		for _, snapshot := range snapshots {
+			if perChain && snapshot.Chain == "" {
+				continue
+			}
+			if snapshot.Timestamp.After(data.Timestamp) {
+				data.Timestamp = snapshot.Timestamp
+			}
+			if snapshot.TotalUSD == nil {
+				continue
+			}
+
+			data.TotalUSD.Add(data.TotalUSD, snapshot.TotalUSD)
+			if snapshot.Chain == "" {
+				continue
+			}
+
+			data.Chains[snapshot.Chain] = &models.ChainTVL{
+				Chain:       snapshot.Chain,
+				BlockNumber: snapshot.BlockNumber,
+				Assets:      BreakdownAssets(snapshot.Breakdown),
+				TotalUSD:    snapshot.TotalUSD,
+			}
+			if aggregated.ChainTotals[snapshot.Chain] == nil {
+				aggregated.ChainTotals[snapshot.Chain] = big.NewFloat(0)
+			}
+			aggregated.ChainTotals[snapshot.Chain].Add(aggregated.ChainTotals[snapshot.Chain], snapshot.TotalUSD)
+		}
+
+		aggregated.Protocols[protocol] = data
+		aggregated.TotalUSD.Add(aggregated.TotalUSD, data.TotalUSD)
+	}
+
+	return aggregated
+}
+
+// BreakdownAssets lists the assets of a breakdown by key
+func BreakdownAssets(breakdown map[string]*models.AssetTVL) []*models.AssetTVL {
+	keys := make([]string, 0, len(breakdown))
+	for key := range breakdown {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	assets := make([]*models.AssetTVL, 0, len(keys))
+	for _, key := range keys {
+		if asset := breakdown[key]; asset != nil {
+			assets = append(assets, asset)
+		}
+	}
+	return assets
+}
@@ -0,0 +1,433 @@
+// internal/storage/bolt/bolt.go
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// Buckets of the database file. Composite keys join their parts with a zero
+// byte and encode numbers big-endian, so cursors walk them in order.
+const (
+	protocolsBucket   = "protocols"        // name
+	snapshotsBucket   = "tvl_snapshots"    // protocol, chain, time, id
+	snapshotBlocks    = "tvl_blocks"       // protocol, chain, block[, time] -> snapshots key
+	rollupsBucket     = "tvl_rollups"      // resolution, protocol, chain, time
+	chainsBucket      = "chains"           // name
+	chainStatsBucket  = "chain_stats"      // name
+	eventsBucket      = "events"           // chain, block, log index, transaction
+	eventLogs         = "event_logs"       // chain, transaction, log index -> events key
+	blocksBucket      = "indexed_blocks"   // chain, number
+	checkpointsBucket = "checkpoints"      // chain, contract
+	failedBucket      = "failed_ranges"    // id
+	lettersBucket     = "dead_letters"     // id
+	letterLogs        = "dead_letter_logs" // chain, transaction, log index, protocol -> id
+	sinksBucket       = "sink_offsets"     // chain, sink
+	tokensBucket      = "tokens"           // chain, lowercase address
+	pricesBucket      = "token_prices"     // token id, time, id
+)
+
+var buckets = []string{
+	protocolsBucket, snapshotsBucket, snapshotBlocks, rollupsBucket, chainsBucket, chainStatsBucket,
+	eventsBucket, eventLogs, blocksBucket, checkpointsBucket, failedBucket,
+	lettersBucket, letterLogs, sinksBucket, tokensBucket, pricesBucket,
+}
+
+// BoltStorage implements Storage in a single bbolt database file. The file
+// is locked by the process that opens it.
+type BoltStorage struct {
+	db *bbolt.DB
+	tx *boltTx // set on storages of a transaction
+}
+
+// NewBoltStorage opens or creates the database file at path
+func NewBoltStorage(path string) (*BoltStorage, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range buckets {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStorage{db: db}, nil
+}
+
+// txState is a bbolt write transaction shared by a BoltTx and the
+// transactions begun from it. Every write records the value it replaced, so
+// a nested transaction can roll back to where it began.
+type txState struct {
+	mu   sync.Mutex
+	tx   *bbolt.Tx
+	undo []undoEntry
+}
+
+type undoEntry struct {
+	bucket string
+	key    []byte
+	value  []byte // nil when the key did not exist
+}
+
+// boltTx is a transaction, or a transaction nested in one
+type boltTx struct {
+	state  *txState
+	nested bool
+	mark   int  // length of the undo log when a nested transaction began
+	done   bool // guarded by state.mu
+}
+
+// BoltTx implements the Tx interface. Every storage method runs inside the
+// transaction; a transaction begun from a BoltTx is nested in it.
+type BoltTx struct {
+	*BoltStorage
+}
+
+// BeginTx starts a write transaction. Writes from outside it wait until it
+// ends; reads from outside see the data as it was before it.
+func (bs *BoltStorage) BeginTx(ctx context.Context) (storage.Tx, error) {
+	if bs.tx != nil {
+		bs.tx.state.mu.Lock()
+		defer bs.tx.state.mu.Unlock()
+
+		if bs.tx.done {
+			return nil, sql.ErrTxDone
+		}
+		nested := &boltTx{state: bs.tx.state, nested: true, mark: len(bs.tx.state.undo)}
+		return &BoltTx{&BoltStorage{db: bs.db, tx: nested}}, nil
+	}
+
+	tx, err := bs.db.Begin(true)
+	if err != nil {
+		return nil, err
+	}
+	return &BoltTx{&BoltStorage{db: bs.db, tx: &boltTx{state: &txState{tx: tx}}}}, nil
+}
+
+// Commit commits the transaction. A nested transaction's writes land with
+// the transaction it was begun from.
+func (bt *BoltTx) Commit() error {
+	state := bt.tx.state
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if bt.tx.done {
+		return sql.ErrTxDone
+	}
+	bt.tx.done = true
+
+	if bt.tx.nested {
+		return nil
+	}
+	return state.tx.Commit()
+}
+
+// Rollback discards the transaction's writes
+func (bt *BoltTx) Rollback() error {
+	state := bt.tx.state
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if bt.tx.done {
+		return sql.ErrTxDone
+	}
+	bt.tx.done = true
+
+	if !bt.tx.nested {
+		return state.tx.Rollback()
+	}
+
+	for i := len(state.undo) - 1; i >= bt.tx.mark; i-- {
+		entry := state.undo[i]
+		b := state.tx.Bucket([]byte(entry.bucket))
+
+		var err error
+		if entry.value == nil {
+			err = b.Delete(entry.key)
+		} else {
+			err = b.Put(entry.key, entry.value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	state.undo = state.undo[:bt.tx.mark]
+
+	return nil
+}
+
+// Close rolls the transaction back unless it was committed
+func (bt *BoltTx) Close() error {
+	if err := bt.Rollback(); err != nil && err != sql.ErrTxDone {
+		return err
+	}
+	return nil
+}
+
+// Close closes the database file
+func (bs *BoltStorage) Close() error {
+	return bs.db.Close()
+}
+
+// view runs fn in a read transaction, or in the storage's transaction
+func (bs *BoltStorage) view(fn func(tx *bbolt.Tx) error) error {
+	if bs.tx == nil {
+		return bs.db.View(fn)
+	}
+
+	bs.tx.state.mu.Lock()
+	defer bs.tx.state.mu.Unlock()
+
+	if bs.tx.done {
+		return sql.ErrTxDone
+	}
+	return fn(bs.tx.state.tx)
+}
+
+// update runs fn in a write transaction, or in the storage's transaction
+func (bs *BoltStorage) update(fn func(w *writer) error) error {
+	if bs.tx == nil {
+		return bs.db.Update(func(tx *bbolt.Tx) error {
+			return fn(&writer{tx: tx})
+		})
+	}
+
+	state := bs.tx.state
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if bs.tx.done {
+		return sql.ErrTxDone
+	}
+
+	// A failed method leaves no partial writes behind
+	mark := len(state.undo)
+	err := fn(&writer{tx: state.tx, undo: &state.undo})
+	if err != nil {
+		for i := len(state.undo) - 1; i >= mark; i-- {
+			entry := state.undo[i]
+			if entry.value == nil {
+				state.tx.Bucket([]byte(entry.bucket)).Delete(entry.key)
+			} else {
+				state.tx.Bucket([]byte(entry.bucket)).Put(entry.key, entry.value)
+			}
+		}
+		state.undo = state.undo[:mark]
+	}
+	return err
+}
+
+// writer writes to the buckets of a write transaction
+type writer struct {
+	tx   *bbolt.Tx
+	undo *[]undoEntry // nil outside a BoltTx
+}
+
+func (w *writer) remember(bucket string, key []byte) {
+	if w.undo == nil {
+		return
+	}
+
+	var previous []byte
+	if value := w.tx.Bucket([]byte(bucket)).Get(key); value != nil {
+		previous = append([]byte{}, value...)
+	}
+	*w.undo = append(*w.undo, undoEntry{bucket: bucket, key: append([]byte{}, key...), value: previous})
+}
+
+func (w *writer) put(bucket string, key, value []byte) error {
+	w.remember(bucket, key)
+	return w.tx.Bucket([]byte(bucket)).Put(key, value)
+}
+
+func (w *writer) putJSON(bucket string, key []byte, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return w.put(bucket, key, value)
+}
+
+func (w *writer) delete(bucket string, key []byte) error {
+	w.remember(bucket, key)
+	return w.tx.Bucket([]byte(bucket)).Delete(key)
+}
+
+// nextID returns the next ID of a bucket. IDs of rolled back writes are not
+// handed out again.
+func (w *writer) nextID(bucket string) (uint64, error) {
+	return w.tx.Bucket([]byte(bucket)).NextSequence()
+}
+
+// key joins strings and numbers into a composite key. Strings end in a
+// zero byte, so the key of leading strings is the prefix of longer keys.
+func key(parts ...interface{}) []byte {
+	var buf bytes.Buffer
+	for _, part := range parts {
+		switch v := part.(type) {
+		case string:
+			buf.WriteString(v)
+			buf.WriteByte(0)
+		case uint64:
+			binary.Write(&buf, binary.BigEndian, v)
+		case time.Time:
+			binary.Write(&buf, binary.BigEndian, timeKey(v))
+		default:
+			panic(fmt.Sprintf("bolt: invalid key part %T", part))
+		}
+	}
+	return buf.Bytes()
+}
+
+// prefixEnd is the first key after every key with the prefix
+func prefixEnd(p []byte) []byte {
+	end := append([]byte{}, p...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// timeKey orders times before and after 1970 alike
+func timeKey(t time.Time) uint64 {
+	return uint64(t.UnixNano()) ^ 1<<63
+}
+
+// scan calls fn for every key with the prefix from start on, until fn
+// returns false
+func scan(b *bbolt.Bucket, p, start []byte, fn func(k, v []byte) (bool, error)) error {
+	if start == nil {
+		start = p
+	}
+
+	c := b.Cursor()
+	for k, v := c.Seek(start); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+		more, err := fn(k, v)
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+// last returns the last key and value with the prefix
+func last(b *bbolt.Bucket, p []byte) ([]byte, []byte) {
+	c := b.Cursor()
+
+	var k, v []byte
+	if end := prefixEnd(p); end != nil {
+		if k, _ = c.Seek(end); k != nil {
+			k, v = c.Prev()
+		} else {
+			k, v = c.Last()
+		}
+	} else {
+		k, v = c.Last()
+	}
+
+	if k == nil || !bytes.HasPrefix(k, p) {
+		return nil, nil
+	}
+	return k, v
+}
+
+// GetProtocol retrieves a protocol by name
+func (bs *BoltStorage) GetProtocol(ctx context.Context, name string) (*models.Protocol, error) {
+	var protocol *models.Protocol
+	err := bs.view(func(tx *bbolt.Tx) error {
+		value := tx.Bucket([]byte(protocolsBucket)).Get([]byte(name))
+		if value == nil {
+			return fmt.Errorf("protocol not found: %s", name)
+		}
+		return json.Unmarshal(value, &protocol)
+	})
+	return protocol, err
+}
+
+// GetProtocols retrieves all protocols by name
+func (bs *BoltStorage) GetProtocols(ctx context.Context) ([]*models.Protocol, error) {
+	var protocols []*models.Protocol
+	err := bs.view(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(protocolsBucket)).ForEach(func(k, v []byte) error {
+			var protocol models.Protocol
+			if err := json.Unmarshal(v, &protocol); err != nil {
+				return err
+			}
+			protocols = append(protocols, &protocol)
+			return nil
+		})
+	})
+	return protocols, err
+}
+
+// SaveProtocol saves a new protocol
+func (bs *BoltStorage) SaveProtocol(ctx context.Context, protocol *models.Protocol) error {
+	return bs.update(func(w *writer) error {
+		if w.tx.Bucket([]byte(protocolsBucket)).Get([]byte(protocol.Name)) != nil {
+			return fmt.Errorf("protocol already exists: %s", protocol.Name)
+		}
+
+		id, err := w.nextID(protocolsBucket)
+		if err != nil {
+			return err
+		}
+		protocol.ID = id
+		protocol.CreatedAt = time.Now().UTC()
+		protocol.UpdatedAt = protocol.CreatedAt
+
+		return w.putJSON(protocolsBucket, []byte(protocol.Name), protocol)
+	})
+}
+
+// UpdateProtocol updates a protocol by name and replaces its contracts
+func (bs *BoltStorage) UpdateProtocol(ctx context.Context, protocol *models.Protocol) error {
+	return bs.update(func(w *writer) error {
+		value := w.tx.Bucket([]byte(protocolsBucket)).Get([]byte(protocol.Name))
+		if value == nil {
+			return fmt.Errorf("protocol not found: %s", protocol.Name)
+		}
+
+		var existing models.Protocol
+		if err := json.Unmarshal(value, &existing); err != nil {
+			return err
+		}
+		protocol.ID = existing.ID
+		protocol.CreatedAt = existing.CreatedAt
+		protocol.UpdatedAt = time.Now().UTC()
+
+		return w.putJSON(protocolsBucket, []byte(protocol.Name), protocol)
+	})
+}
+
+// DeleteProtocol deletes a protocol. Its TVL snapshots are kept.
+func (bs *BoltStorage) DeleteProtocol(ctx context.Context, name string) error {
+	return bs.update(func(w *writer) error {
+		if w.tx.Bucket([]byte(protocolsBucket)).Get([]byte(name)) == nil {
+			return fmt.Errorf("protocol not found: %s", name)
+		}
+		return w.delete(protocolsBucket, []byte(name))
+	})
+}
@@ -0,0 +1,96 @@
+// internal/storage/bolt/chains.go
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+)
+
+// GetChain retrieves a chain by name
+func (bs *BoltStorage) GetChain(ctx context.Context, name string) (*models.Chain, error) {
+	var chain *models.Chain
+	err := bs.view(func(tx *bbolt.Tx) error {
+		value := tx.Bucket([]byte(chainsBucket)).Get([]byte(name))
+		if value == nil {
+			return fmt.Errorf("chain not found: %s", name)
+		}
+		return json.Unmarshal(value, &chain)
+	})
+	return chain, err
+}
+
+// GetChains retrieves all chains by name
+func (bs *BoltStorage) GetChains(ctx context.Context) ([]*models.Chain, error) {
+	var chains []*models.Chain
+	err := bs.view(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(chainsBucket)).ForEach(func(k, v []byte) error {
+			var chain models.Chain
+			if err := json.Unmarshal(v, &chain); err != nil {
+				return err
+			}
+			chains = append(chains, &chain)
+			return nil
+		})
+	})
+	return chains, err
+}
+
+// SaveChain saves a chain, replacing the chain of the same name
+func (bs *BoltStorage) SaveChain(ctx context.Context, chain *models.Chain) error {
+	if chain.ChainID == nil || !chain.ChainID.IsInt64() {
+		return fmt.Errorf("invalid chain id for %s", chain.Name)
+	}
+
+	return bs.update(func(w *writer) error {
+		if value := w.tx.Bucket([]byte(chainsBucket)).Get([]byte(chain.Name)); value != nil {
+			var existing models.Chain
+			if err := json.Unmarshal(value, &existing); err != nil {
+				return err
+			}
+			chain.ID = existing.ID
+			chain.CreatedAt = existing.CreatedAt
+		} else {
+			id, err := w.nextID(chainsBucket)
+			if err != nil {
+				return err
+			}
+			chain.ID = id
+			chain.CreatedAt = time.Now().UTC()
+		}
+
+		return w.putJSON(chainsBucket, []byte(chain.Name), chain)
+	})
+}
+
+// DeleteChain deletes a chain. Its statistics, events and TVL are kept.
+func (bs *BoltStorage) DeleteChain(ctx context.Context, name string) error {
+	return bs.update(func(w *writer) error {
+		if w.tx.Bucket([]byte(chainsBucket)).Get([]byte(name)) == nil {
+			return fmt.Errorf("chain not found: %s", name)
+		}
+		return w.delete(chainsBucket, []byte(name))
+	})
+}
+
+// UpdateChainStats replaces the statistics of a chain
+func (bs *BoltStorage) UpdateChainStats(ctx context.Context, stats *models.ChainStats) error {
+	copied := *stats
+	if copied.LastUpdateTime.IsZero() {
+		copied.LastUpdateTime = time.Now().UTC()
+	}
+
+	value, err := encodeStats(&copied)
+	if err != nil {
+		return err
+	}
+
+	return bs.update(func(w *writer) error {
+		return w.put(chainStatsBucket, []byte(stats.Chain), value)
+	})
+}
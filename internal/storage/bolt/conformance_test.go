@@ -0,0 +1,21 @@
+// internal/storage/bolt/conformance_test.go
+package bolt_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/bolt"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, err := bolt.NewBoltStorage(filepath.Join(t.TempDir(), "tvl.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
@@ -0,0 +1,791 @@
+// internal/storage/bolt/events.go
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// SaveEvents saves events. Saving an event again only replaces it while it
+// is still tentative.
+func (bs *BoltStorage) SaveEvents(ctx context.Context, events []*models.Event) error {
+	return bs.update(func(w *writer) error {
+		return w.saveEvents(events)
+	})
+}
+
+func (w *writer) saveEvents(events []*models.Event) error {
+	for _, event := range events {
+		if event.Status == "" {
+			event.Status = models.FinalityTentative
+		}
+
+		// Same (chain, transaction, log index) means the same log, keep one copy
+		logKey := key(event.Chain, event.TransactionHash, uint64(event.LogIndex))
+		if existing := w.tx.Bucket([]byte(eventLogs)).Get(logKey); existing != nil {
+			existingKey := append([]byte{}, existing...)
+
+			var stored models.Event
+			if err := json.Unmarshal(w.tx.Bucket([]byte(eventsBucket)).Get(existingKey), &stored); err != nil {
+				return err
+			}
+			if stored.Status == models.FinalityFinal {
+				continue
+			}
+
+			event.ID = stored.ID
+			if err := w.delete(eventsBucket, existingKey); err != nil {
+				return err
+			}
+		} else {
+			id, err := w.nextID(eventsBucket)
+			if err != nil {
+				return err
+			}
+			event.ID = id
+		}
+
+		eventKey := key(event.Chain, event.BlockNumber, uint64(event.LogIndex), event.TransactionHash)
+		if err := w.putJSON(eventsBucket, eventKey, event); err != nil {
+			return err
+		}
+		if err := w.put(eventLogs, logKey, eventKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteEvent deletes an event and its log index entry
+func (w *writer) deleteEvent(eventKey []byte, event *models.Event) error {
+	if err := w.delete(eventsBucket, eventKey); err != nil {
+		return err
+	}
+	return w.delete(eventLogs, key(event.Chain, event.TransactionHash, uint64(event.LogIndex)))
+}
+
+// scanEvents calls fn for every event matching a filter, by chain, block
+// number and log index, until fn returns false
+func scanEvents(tx *bbolt.Tx, filter storage.EventFilter, fn func(k []byte, event *models.Event) (bool, error)) error {
+	var p, start []byte
+	if filter.Chain != "" {
+		p = key(filter.Chain)
+		start = key(filter.Chain, filter.FromBlock)
+	}
+
+	return scan(tx.Bucket([]byte(eventsBucket)), p, start, func(k, v []byte) (bool, error) {
+		var event models.Event
+		if err := json.Unmarshal(v, &event); err != nil {
+			return false, err
+		}
+
+		// Past the range of the filtered chain
+		if filter.Chain != "" && filter.ToBlock != 0 && event.BlockNumber > filter.ToBlock {
+			return false, nil
+		}
+		if !matchesEvent(&event, filter) {
+			return true, nil
+		}
+		return fn(k, &event)
+	})
+}
+
+func matchesEvent(event *models.Event, filter storage.EventFilter) bool {
+	return (filter.Chain == "" || event.Chain == filter.Chain) &&
+		(filter.Protocol == "" || event.Protocol == filter.Protocol) &&
+		(filter.EventName == "" || event.EventName == filter.EventName) &&
+		(filter.Address == "" || strings.EqualFold(event.Address.Hex(), filter.Address)) &&
+		(filter.Status == "" || event.Status == filter.Status) &&
+		(filter.FromBlock == 0 || event.BlockNumber >= filter.FromBlock) &&
+		(filter.ToBlock == 0 || event.BlockNumber <= filter.ToBlock)
+}
+
+// GetEvents retrieves events matching a filter, by block number and log index
+func (bs *BoltStorage) GetEvents(ctx context.Context, filter storage.EventFilter) ([]*models.Event, error) {
+	var events []*models.Event
+	err := bs.view(func(tx *bbolt.Tx) error {
+		skipped := 0
+		return scanEvents(tx, filter, func(k []byte, event *models.Event) (bool, error) {
+			if skipped < filter.Offset {
+				skipped++
+				return true, nil
+			}
+
+			events = append(events, event)
+			return filter.Limit <= 0 || len(events) < filter.Limit, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetLatestEvents returns the last of the events matching a filter of each
+// contract, by block number and log index, ignoring Limit and Offset
+func (bs *BoltStorage) GetLatestEvents(ctx context.Context, filter storage.EventFilter) ([]*models.Event, error) {
+	// Events are scanned in order, so the last one seen of a contract wins
+	latest := make(map[string]*models.Event)
+	err := bs.view(func(tx *bbolt.Tx) error {
+		return scanEvents(tx, filter, func(k []byte, event *models.Event) (bool, error) {
+			latest[event.Chain+":"+event.Address.Hex()] = event
+			return true, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*models.Event, 0, len(latest))
+	for _, event := range latest {
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Chain != events[j].Chain {
+			return events[i].Chain < events[j].Chain
+		}
+		if events[i].BlockNumber != events[j].BlockNumber {
+			return events[i].BlockNumber < events[j].BlockNumber
+		}
+		return events[i].LogIndex < events[j].LogIndex
+	})
+
+	return events, nil
+}
+
+// CountEvents counts the events matching a filter, ignoring Limit and Offset
+func (bs *BoltStorage) CountEvents(ctx context.Context, filter storage.EventFilter) (uint64, error) {
+	var count uint64
+	err := bs.view(func(tx *bbolt.Tx) error {
+		return scanEvents(tx, filter, func(k []byte, event *models.Event) (bool, error) {
+			count++
+			return true, nil
+		})
+	})
+	return count, err
+}
+
+// DeleteEvents deletes the events matching a filter, ignoring Limit and Offset
+func (bs *BoltStorage) DeleteEvents(ctx context.Context, filter storage.EventFilter) (uint64, error) {
+	var deleted uint64
+	err := bs.update(func(w *writer) error {
+		var err error
+		deleted, err = w.deleteEvents(filter)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+func (w *writer) deleteEvents(filter storage.EventFilter) (uint64, error) {
+	// Keys are collected first, bbolt cursors don't survive deletes
+	type match struct {
+		key   []byte
+		event *models.Event
+	}
+
+	var matches []match
+	err := scanEvents(w.tx, filter, func(k []byte, event *models.Event) (bool, error) {
+		matches = append(matches, match{key: append([]byte{}, k...), event: event})
+		return true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range matches {
+		if err := w.deleteEvent(m.key, m.event); err != nil {
+			return 0, err
+		}
+	}
+	return uint64(len(matches)), nil
+}
+
+// FinalizeEvents marks all events up to and including block as final
+func (bs *BoltStorage) FinalizeEvents(ctx context.Context, chain string, block uint64) error {
+	return bs.update(func(w *writer) error {
+		type match struct {
+			key   []byte
+			event *models.Event
+		}
+
+		var matches []match
+		err := scan(w.tx.Bucket([]byte(eventsBucket)), key(chain), nil, func(k, v []byte) (bool, error) {
+			var event models.Event
+			if err := json.Unmarshal(v, &event); err != nil {
+				return false, err
+			}
+			if event.BlockNumber > block {
+				return false, nil
+			}
+			if event.Status != models.FinalityFinal {
+				matches = append(matches, match{key: append([]byte{}, k...), event: &event})
+			}
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, m := range matches {
+			m.event.Status = models.FinalityFinal
+			if err := w.putJSON(eventsBucket, m.key, m.event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SaveBlock saves a block
+func (bs *BoltStorage) SaveBlock(ctx context.Context, block *models.Block) error {
+	return bs.update(func(w *writer) error {
+		return w.saveBlock(block)
+	})
+}
+
+func (w *writer) saveBlock(block *models.Block) error {
+	k := key(block.Chain, block.Number)
+	if value := w.tx.Bucket([]byte(blocksBucket)).Get(k); value != nil {
+		var existing models.Block
+		if err := json.Unmarshal(value, &existing); err != nil {
+			return err
+		}
+		block.ID = existing.ID
+	} else {
+		id, err := w.nextID(blocksBucket)
+		if err != nil {
+			return err
+		}
+		block.ID = id
+	}
+
+	return w.putJSON(blocksBucket, k, block)
+}
+
+// GetRecentBlocks returns up to limit indexed blocks for a chain, newest first
+func (bs *BoltStorage) GetRecentBlocks(ctx context.Context, chain string, limit int) ([]*models.Block, error) {
+	blocks := []*models.Block{}
+	err := bs.view(func(tx *bbolt.Tx) error {
+		p := key(chain)
+		c := tx.Bucket([]byte(blocksBucket)).Cursor()
+
+		k, v := last(tx.Bucket([]byte(blocksBucket)), p)
+		if k == nil {
+			return nil
+		}
+		for k, v = c.Seek(k); k != nil && bytes.HasPrefix(k, p); k, v = c.Prev() {
+			var block models.Block
+			if err := json.Unmarshal(v, &block); err != nil {
+				return err
+			}
+			blocks = append(blocks, &block)
+
+			if limit > 0 && len(blocks) >= limit {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// GetBlockAtTime returns the highest indexed block mined at or before t
+func (bs *BoltStorage) GetBlockAtTime(ctx context.Context, chain string, t time.Time) (*models.Block, error) {
+	var found *models.Block
+	err := bs.view(func(tx *bbolt.Tx) error {
+		p := key(chain)
+		c := tx.Bucket([]byte(blocksBucket)).Cursor()
+
+		k, v := last(tx.Bucket([]byte(blocksBucket)), p)
+		if k == nil {
+			return nil
+		}
+		for k, v = c.Seek(k); k != nil && bytes.HasPrefix(k, p); k, v = c.Prev() {
+			var block models.Block
+			if err := json.Unmarshal(v, &block); err != nil {
+				return err
+			}
+			if block.Timestamp.IsZero() || block.Timestamp.After(t) {
+				continue
+			}
+
+			found = &block
+			return nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// GetLastIndexedBlock returns the block up to which every watched contract is
+// indexed, falling back to the highest indexed block without checkpoints
+func (bs *BoltStorage) GetLastIndexedBlock(ctx context.Context, chain string) (uint64, error) {
+	var watermark uint64
+	err := bs.view(func(tx *bbolt.Tx) error {
+		first := true
+		err := scan(tx.Bucket([]byte(checkpointsBucket)), key(chain), nil, func(k, v []byte) (bool, error) {
+			var checkpoint models.Checkpoint
+			if err := json.Unmarshal(v, &checkpoint); err != nil {
+				return false, err
+			}
+			if first || checkpoint.Block < watermark {
+				watermark = checkpoint.Block
+				first = false
+			}
+			return true, nil
+		})
+		if err != nil || !first {
+			return err
+		}
+
+		_, value := last(tx.Bucket([]byte(blocksBucket)), key(chain))
+		if value == nil {
+			return nil
+		}
+
+		var block models.Block
+		if err := json.Unmarshal(value, &block); err != nil {
+			return err
+		}
+		watermark = block.Number
+		return nil
+	})
+	return watermark, err
+}
+
+// Rollback deletes all events and indexed blocks at or above fromBlock
+func (bs *BoltStorage) Rollback(ctx context.Context, chain string, fromBlock uint64) error {
+	return bs.update(func(w *writer) error {
+		if _, err := w.deleteEvents(storage.EventFilter{Chain: chain, FromBlock: fromBlock}); err != nil {
+			return err
+		}
+
+		var blocks [][]byte
+		err := scan(w.tx.Bucket([]byte(blocksBucket)), key(chain), key(chain, fromBlock), func(k, v []byte) (bool, error) {
+			blocks = append(blocks, append([]byte{}, k...))
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range blocks {
+			if err := w.delete(blocksBucket, k); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		var checkpoints []*models.Checkpoint
+		err = scan(w.tx.Bucket([]byte(checkpointsBucket)), key(chain), nil, func(k, v []byte) (bool, error) {
+			var checkpoint models.Checkpoint
+			if err := json.Unmarshal(v, &checkpoint); err != nil {
+				return false, err
+			}
+			if checkpoint.Block >= fromBlock {
+				checkpoints = append(checkpoints, &checkpoint)
+			}
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, checkpoint := range checkpoints {
+			checkpoint.Block = checkpointBefore(fromBlock)
+			checkpoint.UpdatedAt = now
+			if err := w.putJSON(checkpointsBucket, key(chain, checkpoint.Contract), checkpoint); err != nil {
+				return err
+			}
+		}
+
+		letters, err := w.deadLetters(chain, "")
+		if err != nil {
+			return err
+		}
+		for _, letter := range letters {
+			if letter.BlockNumber >= fromBlock {
+				if err := w.deleteDeadLetter(letter.ID); err != nil {
+					return err
+				}
+			}
+		}
+
+		var sinks [][]byte
+		err = scan(w.tx.Bucket([]byte(sinksBucket)), key(chain), nil, func(k, v []byte) (bool, error) {
+			if decodeUint(v) >= fromBlock {
+				sinks = append(sinks, append([]byte{}, k...))
+			}
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range sinks {
+			if err := w.put(sinksBucket, k, encodeUint(checkpointBefore(fromBlock))); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetCheckpoints returns the indexer checkpoints of a chain
+func (bs *BoltStorage) GetCheckpoints(ctx context.Context, chain string) ([]*models.Checkpoint, error) {
+	checkpoints := []*models.Checkpoint{}
+	err := bs.view(func(tx *bbolt.Tx) error {
+		// Keys are in contract order
+		return scan(tx.Bucket([]byte(checkpointsBucket)), key(chain), nil, func(k, v []byte) (bool, error) {
+			var checkpoint models.Checkpoint
+			if err := json.Unmarshal(v, &checkpoint); err != nil {
+				return false, err
+			}
+			checkpoints = append(checkpoints, &checkpoint)
+			return true, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return checkpoints, nil
+}
+
+// SaveCheckpoints upserts checkpoints without moving any of them backwards
+func (bs *BoltStorage) SaveCheckpoints(ctx context.Context, checkpoints []*models.Checkpoint) error {
+	return bs.update(func(w *writer) error {
+		return w.saveCheckpoints(checkpoints)
+	})
+}
+
+func (w *writer) saveCheckpoints(checkpoints []*models.Checkpoint) error {
+	now := time.Now()
+	for _, checkpoint := range checkpoints {
+		k := key(checkpoint.Chain, checkpoint.Contract)
+
+		stored := *checkpoint
+		if value := w.tx.Bucket([]byte(checkpointsBucket)).Get(k); value != nil {
+			var existing models.Checkpoint
+			if err := json.Unmarshal(value, &existing); err != nil {
+				return err
+			}
+			if existing.Block > checkpoint.Block {
+				stored.Block = existing.Block
+			}
+		}
+		stored.UpdatedAt = now
+
+		if err := w.putJSON(checkpointsBucket, k, &stored); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveFailedRange records a range that failed to index. Saving the same
+// range again counts another attempt.
+func (bs *BoltStorage) SaveFailedRange(ctx context.Context, failed *models.FailedRange) error {
+	return bs.update(func(w *writer) error {
+		return w.saveFailedRange(failed)
+	})
+}
+
+func (w *writer) saveFailedRange(failed *models.FailedRange) error {
+	contracts := strings.Join(failed.Contracts, ",")
+	now := time.Now()
+
+	var existing *models.FailedRange
+	err := w.tx.Bucket([]byte(failedBucket)).ForEach(func(k, v []byte) error {
+		if existing != nil {
+			return nil
+		}
+
+		var stored models.FailedRange
+		if err := json.Unmarshal(v, &stored); err != nil {
+			return err
+		}
+		if stored.Chain == failed.Chain && strings.Join(stored.Contracts, ",") == contracts &&
+			stored.FromBlock == failed.FromBlock && stored.ToBlock == failed.ToBlock {
+			existing = &stored
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		existing.Attempts++
+		existing.LastError = failed.LastError
+		existing.UpdatedAt = now
+	} else {
+		id, err := w.nextID(failedBucket)
+		if err != nil {
+			return err
+		}
+
+		stored := *failed
+		stored.ID = id
+		stored.Contracts = append([]string(nil), failed.Contracts...)
+		stored.Attempts = 1
+		stored.CreatedAt = now
+		stored.UpdatedAt = now
+		existing = &stored
+	}
+
+	failed.ID = existing.ID
+	failed.Attempts = existing.Attempts
+	return w.putJSON(failedBucket, key(existing.ID), existing)
+}
+
+// GetFailedRanges returns the failed ranges of a chain by first block
+func (bs *BoltStorage) GetFailedRanges(ctx context.Context, chain string) ([]*models.FailedRange, error) {
+	var ranges []*models.FailedRange
+	err := bs.view(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(failedBucket)).ForEach(func(k, v []byte) error {
+			var failed models.FailedRange
+			if err := json.Unmarshal(v, &failed); err != nil {
+				return err
+			}
+			if failed.Chain == chain {
+				ranges = append(ranges, &failed)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].FromBlock < ranges[j].FromBlock
+	})
+
+	return ranges, nil
+}
+
+// DeleteFailedRange forgets a failed range
+func (bs *BoltStorage) DeleteFailedRange(ctx context.Context, id uint64) error {
+	return bs.update(func(w *writer) error {
+		return w.delete(failedBucket, key(id))
+	})
+}
+
+// SaveDeadLetters stores logs that failed to decode
+func (bs *BoltStorage) SaveDeadLetters(ctx context.Context, letters []*models.DeadLetter) error {
+	return bs.update(func(w *writer) error {
+		return w.saveDeadLetters(letters)
+	})
+}
+
+func letterKey(letter *models.DeadLetter) []byte {
+	return key(letter.Chain, letter.TransactionHash, uint64(letter.LogIndex), letter.Protocol)
+}
+
+func (w *writer) saveDeadLetters(letters []*models.DeadLetter) error {
+	now := time.Now()
+	for _, letter := range letters {
+		var stored models.DeadLetter
+
+		if id := w.tx.Bucket([]byte(letterLogs)).Get(letterKey(letter)); id != nil {
+			if err := json.Unmarshal(w.tx.Bucket([]byte(lettersBucket)).Get(id), &stored); err != nil {
+				return err
+			}
+			stored.BlockHash = letter.BlockHash
+			stored.Error = letter.Error
+			stored.Timestamp = letter.Timestamp
+			stored.Attempts++
+			stored.UpdatedAt = now
+		} else {
+			id, err := w.nextID(lettersBucket)
+			if err != nil {
+				return err
+			}
+
+			stored = *letter
+			stored.ID = id
+			stored.Topics = append([]string(nil), letter.Topics...)
+			stored.Attempts = 1
+			stored.CreatedAt = now
+			stored.UpdatedAt = now
+
+			if err := w.put(letterLogs, letterKey(letter), key(id)); err != nil {
+				return err
+			}
+		}
+
+		if err := w.putJSON(lettersBucket, key(stored.ID), &stored); err != nil {
+			return err
+		}
+		letter.ID = stored.ID
+		letter.Attempts = stored.Attempts
+	}
+	return nil
+}
+
+func (w *writer) deleteDeadLetter(id uint64) error {
+	value := w.tx.Bucket([]byte(lettersBucket)).Get(key(id))
+	if value == nil {
+		return nil
+	}
+
+	var letter models.DeadLetter
+	if err := json.Unmarshal(value, &letter); err != nil {
+		return err
+	}
+	if err := w.delete(letterLogs, letterKey(&letter)); err != nil {
+		return err
+	}
+	return w.delete(lettersBucket, key(id))
+}
+
+// GetDeadLetters returns dead letters by block; empty arguments match all
+func (bs *BoltStorage) GetDeadLetters(ctx context.Context, chain, protocol string) ([]*models.DeadLetter, error) {
+	var letters []*models.DeadLetter
+	err := bs.view(func(tx *bbolt.Tx) error {
+		var err error
+		letters, err = (&writer{tx: tx}).deadLetters(chain, protocol)
+		return err
+	})
+	return letters, err
+}
+
+func (w *writer) deadLetters(chain, protocol string) ([]*models.DeadLetter, error) {
+	var letters []*models.DeadLetter
+	err := w.tx.Bucket([]byte(lettersBucket)).ForEach(func(k, v []byte) error {
+		var letter models.DeadLetter
+		if err := json.Unmarshal(v, &letter); err != nil {
+			return err
+		}
+		if (chain == "" || letter.Chain == chain) && (protocol == "" || letter.Protocol == protocol) {
+			letters = append(letters, &letter)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(letters, func(i, j int) bool {
+		if letters[i].Chain != letters[j].Chain {
+			return letters[i].Chain < letters[j].Chain
+		}
+		if letters[i].BlockNumber != letters[j].BlockNumber {
+			return letters[i].BlockNumber < letters[j].BlockNumber
+		}
+		return letters[i].LogIndex < letters[j].LogIndex
+	})
+
+	return letters, nil
+}
+
+// CountDeadLetters counts dead letters; empty arguments match all
+func (bs *BoltStorage) CountDeadLetters(ctx context.Context, chain, protocol string) (uint64, error) {
+	var count uint64
+	err := bs.view(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(lettersBucket)).ForEach(func(k, v []byte) error {
+			var letter struct {
+				Chain    string `json:"chain"`
+				Protocol string `json:"protocol"`
+			}
+			if err := json.Unmarshal(v, &letter); err != nil {
+				return err
+			}
+			if (chain == "" || letter.Chain == chain) && (protocol == "" || letter.Protocol == protocol) {
+				count++
+			}
+			return nil
+		})
+	})
+	return count, err
+}
+
+// GetSinkOffsets returns the last block delivered to each event sink
+func (bs *BoltStorage) GetSinkOffsets(ctx context.Context, chain string) (map[string]uint64, error) {
+	offsets := make(map[string]uint64)
+	err := bs.view(func(tx *bbolt.Tx) error {
+		p := key(chain)
+		return scan(tx.Bucket([]byte(sinksBucket)), p, nil, func(k, v []byte) (bool, error) {
+			sink := strings.TrimSuffix(string(k[len(p):]), "\x00")
+			offsets[sink] = decodeUint(v)
+			return true, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return offsets, nil
+}
+
+// SaveSinkOffset records the last block delivered to a sink
+func (bs *BoltStorage) SaveSinkOffset(ctx context.Context, sink, chain string, block uint64) error {
+	return bs.update(func(w *writer) error {
+		return w.put(sinksBucket, key(chain, sink), encodeUint(block))
+	})
+}
+
+// CommitBatch applies all writes of a batch in one transaction
+func (bs *BoltStorage) CommitBatch(ctx context.Context, batch *models.EventBatch) error {
+	return bs.update(func(w *writer) error {
+		if err := w.saveEvents(batch.Events); err != nil {
+			return err
+		}
+		for _, block := range batch.Blocks {
+			if err := w.saveBlock(block); err != nil {
+				return err
+			}
+		}
+		if err := w.saveCheckpoints(batch.Checkpoints); err != nil {
+			return err
+		}
+		if batch.Failed != nil {
+			if err := w.saveFailedRange(batch.Failed); err != nil {
+				return err
+			}
+		}
+		if batch.Resolved != 0 {
+			if err := w.delete(failedBucket, key(batch.Resolved)); err != nil {
+				return err
+			}
+		}
+		if err := w.saveDeadLetters(batch.DeadLetters); err != nil {
+			return err
+		}
+		for _, id := range batch.Replayed {
+			if err := w.deleteDeadLetter(id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// checkpointBefore returns the checkpoint that makes indexing resume at block
+func checkpointBefore(block uint64) uint64 {
+	if block == 0 {
+		return 0
+	}
+	return block - 1
+}
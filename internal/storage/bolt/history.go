@@ -0,0 +1,202 @@
+// internal/storage/bolt/history.go
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// GetHistoricalTVL retrieves the TVL of a protocol in [from, to] by time, on
+// every chain when chain is empty, at the resolution the window calls for
+func (bs *BoltStorage) GetHistoricalTVL(ctx context.Context, protocol, chain string, from, to time.Time) ([]*models.TVLSnapshot, error) {
+	return storage.History(ctx, bs, protocol, chain, from, to)
+}
+
+// GetTVLHistory retrieves the snapshots, or the rollups of the filter's
+// resolution, by time then ID
+func (bs *BoltStorage) GetTVLHistory(ctx context.Context, filter storage.TVLFilter) ([]*models.TVLSnapshot, error) {
+	var history []*models.TVLSnapshot
+	err := bs.view(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(snapshotsBucket))
+		var p []byte
+		if !filter.Resolution.IsRaw() {
+			b = tx.Bucket([]byte(rollupsBucket))
+			p = key(string(filter.Resolution))
+		}
+
+		// Keys of a chain are in time order, seek to From and stop past To
+		collect := func(chain []byte) (bool, error) {
+			start := chain
+			if !filter.From.IsZero() {
+				start = append(append([]byte{}, chain...), key(filter.From)...)
+			}
+			return true, scan(b, chain, start, func(k, v []byte) (bool, error) {
+				snapshot, err := decodeSnapshot(v)
+				if err != nil {
+					return false, err
+				}
+				if !filter.To.IsZero() && snapshot.Timestamp.After(filter.To) {
+					return false, nil
+				}
+
+				history = append(history, snapshot)
+				return true, nil
+			})
+		}
+
+		switch {
+		case filter.Protocol != "" && filter.Chain != "":
+			_, err := collect(append(append([]byte{}, p...), key(filter.Protocol, filter.Chain)...))
+			return err
+		case filter.Protocol != "":
+			return eachChain(b, p, filter.Protocol, collect)
+		default:
+			return scan(b, p, nil, func(k, v []byte) (bool, error) {
+				snapshot, err := decodeSnapshot(v)
+				if err != nil {
+					return false, err
+				}
+				if matchesTVL(snapshot, filter) {
+					history = append(history, snapshot)
+				}
+				return true, nil
+			})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return newerSnapshot(history[j], history[i])
+	})
+	if filter.Limit > 0 && len(history) > filter.Limit {
+		history = history[:filter.Limit]
+	}
+
+	return history, nil
+}
+
+// GetTokenHoldings retrieves the assets of the snapshots, or the rollups of
+// the filter's resolution, by time then snapshot ID then token
+func (bs *BoltStorage) GetTokenHoldings(ctx context.Context, filter storage.HoldingFilter) ([]*models.TokenHolding, error) {
+	return storage.TokenHoldings(ctx, bs, filter)
+}
+
+func matchesTVL(snapshot *models.TVLSnapshot, filter storage.TVLFilter) bool {
+	return (filter.Protocol == "" || snapshot.Protocol == filter.Protocol) &&
+		(filter.Chain == "" || snapshot.Chain == filter.Chain) &&
+		(filter.From.IsZero() || !snapshot.Timestamp.Before(filter.From)) &&
+		(filter.To.IsZero() || !snapshot.Timestamp.After(filter.To))
+}
+
+// SaveTVLRollups saves rollups, replacing the rollup of the same protocol,
+// chain, resolution and interval
+func (bs *BoltStorage) SaveTVLRollups(ctx context.Context, rollups []*models.TVLSnapshot) error {
+	return bs.update(func(w *writer) error {
+		for _, rollup := range rollups {
+			if rollup.Resolution.IsRaw() || rollup.Range == nil {
+				return fmt.Errorf("not a rollup: %s on %s at %s", rollup.Protocol, rollup.Chain, rollup.Timestamp)
+			}
+			if rollup.Status == "" {
+				rollup.Status = models.FinalityTentative
+			}
+
+			k := key(string(rollup.Resolution), rollup.Protocol, rollup.Chain, rollup.Timestamp)
+			if existing := w.tx.Bucket([]byte(rollupsBucket)).Get(k); existing != nil {
+				old, err := decodeSnapshot(existing)
+				if err != nil {
+					return err
+				}
+				rollup.ID = old.ID
+			} else {
+				id, err := w.nextID(rollupsBucket)
+				if err != nil {
+					return err
+				}
+				rollup.ID = id
+			}
+
+			value, err := encodeSnapshot(rollup)
+			if err != nil {
+				return err
+			}
+			if err := w.put(rollupsBucket, k, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteTVLHistory deletes the snapshots, or the rollups of a resolution,
+// from before a time. The latest snapshot of each protocol and chain is kept.
+func (bs *BoltStorage) DeleteTVLHistory(ctx context.Context, resolution models.Resolution, before time.Time) (uint64, error) {
+	var deleted uint64
+	err := bs.update(func(w *writer) error {
+		deleted = 0
+
+		b := w.tx.Bucket([]byte(snapshotsBucket))
+		var p []byte
+		if !resolution.IsRaw() {
+			b = w.tx.Bucket([]byte(rollupsBucket))
+			p = key(string(resolution))
+		}
+
+		// Keys are collected first, bbolt cursors don't survive deletes
+		var old []*models.TVLSnapshot
+		var keys [][]byte
+		err := scan(b, p, nil, func(k, v []byte) (bool, error) {
+			snapshot, err := decodeSnapshot(v)
+			if err != nil {
+				return false, err
+			}
+			if !snapshot.Timestamp.Before(before) {
+				return true, nil
+			}
+			if resolution.IsRaw() {
+				if latest, _ := last(b, key(snapshot.Protocol, snapshot.Chain)); bytes.Equal(latest, k) {
+					return true, nil
+				}
+			}
+
+			old = append(old, snapshot)
+			keys = append(keys, append([]byte{}, k...))
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for i, k := range keys {
+			if resolution.IsRaw() {
+				blockKey := snapshotBlockKey(old[i])
+				if indexed := w.tx.Bucket([]byte(snapshotBlocks)).Get(blockKey); indexed != nil && bytes.Equal(indexed, k) {
+					if err := w.delete(snapshotBlocks, blockKey); err != nil {
+						return err
+					}
+				}
+				if err := w.delete(snapshotsBucket, k); err != nil {
+					return err
+				}
+			} else if err := w.delete(rollupsBucket, k); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
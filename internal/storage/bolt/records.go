@@ -0,0 +1,228 @@
+// internal/storage/bolt/records.go
+package bolt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+)
+
+// numericPrec is the precision amounts are read back at. The models' JSON
+// would read them back at 64 bits, so records keep them as decimal strings.
+const numericPrec = 128
+
+// formatNumeric formats a big.Float for a record, nil stays nil
+func formatNumeric(value *big.Float) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if value.IsInf() {
+		return nil, fmt.Errorf("cannot store an infinite amount")
+	}
+
+	text := value.Text('f', -1)
+	return &text, nil
+}
+
+// parseNumeric parses a decimal string of a record
+func parseNumeric(text *string) (*big.Float, error) {
+	if text == nil {
+		return nil, nil
+	}
+
+	value, ok := new(big.Float).SetPrec(numericPrec).SetString(*text)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", *text)
+	}
+	return value, nil
+}
+
+// snapshotRecord is a TVL snapshot or rollup with its amounts at full
+// precision
+type snapshotRecord struct {
+	models.TVLSnapshot
+	TotalUSD  *string                 `json:"total_usd"`
+	Breakdown map[string]*assetRecord `json:"breakdown"`
+	Range     *rangeRecord            `json:"range,omitempty"`
+}
+
+type rangeRecord struct {
+	Open    *string `json:"open"`
+	High    *string `json:"high"`
+	Low     *string `json:"low"`
+	Close   *string `json:"close"`
+	Avg     *string `json:"avg"`
+	Samples int     `json:"samples"`
+}
+
+type assetRecord struct {
+	models.AssetTVL
+	PriceUSD *string `json:"price_usd"`
+	ValueUSD *string `json:"value_usd"`
+}
+
+func encodeSnapshot(snapshot *models.TVLSnapshot) ([]byte, error) {
+	record := snapshotRecord{TVLSnapshot: *snapshot}
+
+	var err error
+	if record.TotalUSD, err = formatNumeric(snapshot.TotalUSD); err != nil {
+		return nil, fmt.Errorf("invalid total_usd: %w", err)
+	}
+
+	if snapshot.Breakdown != nil {
+		record.Breakdown = make(map[string]*assetRecord, len(snapshot.Breakdown))
+		for name, asset := range snapshot.Breakdown {
+			if asset == nil {
+				record.Breakdown[name] = nil
+				continue
+			}
+
+			stored := &assetRecord{AssetTVL: *asset}
+			if stored.PriceUSD, err = formatNumeric(asset.PriceUSD); err != nil {
+				return nil, fmt.Errorf("invalid price_usd of %s: %w", name, err)
+			}
+			if stored.ValueUSD, err = formatNumeric(asset.ValueUSD); err != nil {
+				return nil, fmt.Errorf("invalid value_usd of %s: %w", name, err)
+			}
+			record.Breakdown[name] = stored
+		}
+	}
+
+	if snapshot.Range != nil {
+		record.Range = &rangeRecord{Samples: snapshot.Range.Samples}
+		for _, amount := range []struct {
+			name   string
+			value  *big.Float
+			target **string
+		}{
+			{"open", snapshot.Range.Open, &record.Range.Open},
+			{"high", snapshot.Range.High, &record.Range.High},
+			{"low", snapshot.Range.Low, &record.Range.Low},
+			{"close", snapshot.Range.Close, &record.Range.Close},
+			{"avg", snapshot.Range.Avg, &record.Range.Avg},
+		} {
+			if *amount.target, err = formatNumeric(amount.value); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", amount.name, err)
+			}
+		}
+	}
+
+	return json.Marshal(record)
+}
+
+func decodeSnapshot(value []byte) (*models.TVLSnapshot, error) {
+	var record snapshotRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return nil, err
+	}
+
+	snapshot := record.TVLSnapshot
+
+	var err error
+	if snapshot.TotalUSD, err = parseNumeric(record.TotalUSD); err != nil {
+		return nil, err
+	}
+
+	if record.Breakdown != nil {
+		snapshot.Breakdown = make(map[string]*models.AssetTVL, len(record.Breakdown))
+		for name, stored := range record.Breakdown {
+			if stored == nil {
+				snapshot.Breakdown[name] = nil
+				continue
+			}
+
+			asset := stored.AssetTVL
+			if asset.PriceUSD, err = parseNumeric(stored.PriceUSD); err != nil {
+				return nil, err
+			}
+			if asset.ValueUSD, err = parseNumeric(stored.ValueUSD); err != nil {
+				return nil, err
+			}
+			snapshot.Breakdown[name] = &asset
+		}
+	}
+
+	if record.Range != nil {
+		snapshot.Range = &models.TVLRange{Samples: record.Range.Samples}
+		for _, amount := range []struct {
+			text   *string
+			target **big.Float
+		}{
+			{record.Range.Open, &snapshot.Range.Open},
+			{record.Range.High, &snapshot.Range.High},
+			{record.Range.Low, &snapshot.Range.Low},
+			{record.Range.Close, &snapshot.Range.Close},
+			{record.Range.Avg, &snapshot.Range.Avg},
+		} {
+			if *amount.target, err = parseNumeric(amount.text); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &snapshot, nil
+}
+
+// priceRecord is a token price at full precision
+type priceRecord struct {
+	models.TokenPrice
+	PriceUSD *string `json:"price_usd"`
+}
+
+func encodePrice(price *models.TokenPrice) ([]byte, error) {
+	record := priceRecord{TokenPrice: *price}
+
+	var err error
+	if record.PriceUSD, err = formatNumeric(price.PriceUSD); err != nil {
+		return nil, fmt.Errorf("invalid price_usd: %w", err)
+	}
+	return json.Marshal(record)
+}
+
+func decodePrice(value []byte) (*models.TokenPrice, error) {
+	var record priceRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return nil, err
+	}
+
+	price := record.TokenPrice
+
+	var err error
+	if price.PriceUSD, err = parseNumeric(record.PriceUSD); err != nil {
+		return nil, err
+	}
+	return &price, nil
+}
+
+// statsRecord is chain statistics at full precision
+type statsRecord struct {
+	models.ChainStats
+	TotalTVL *string `json:"total_tvl"`
+}
+
+func encodeStats(stats *models.ChainStats) ([]byte, error) {
+	record := statsRecord{ChainStats: *stats}
+
+	var err error
+	if record.TotalTVL, err = formatNumeric(stats.TotalTVL); err != nil {
+		return nil, fmt.Errorf("invalid total_tvl: %w", err)
+	}
+	return json.Marshal(record)
+}
+
+// encodeUint stores a block number or ID as a value
+func encodeUint(n uint64) []byte {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, n)
+	return value
+}
+
+func decodeUint(value []byte) uint64 {
+	if len(value) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(value)
+}
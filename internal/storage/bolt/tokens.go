@@ -0,0 +1,184 @@
+// internal/storage/bolt/tokens.go
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+)
+
+func tokenKey(chain, address string) []byte {
+	return key(chain, strings.ToLower(address))
+}
+
+// latestPrice returns the latest price of a token, nil without one
+func latestPrice(tx *bbolt.Tx, tokenID uint64) (*models.TokenPrice, error) {
+	_, value := last(tx.Bucket([]byte(pricesBucket)), key(tokenID))
+	if value == nil {
+		return nil, nil
+	}
+	return decodePrice(value)
+}
+
+// GetToken retrieves a token with its latest price
+func (bs *BoltStorage) GetToken(ctx context.Context, address, chain string) (*models.Token, error) {
+	var token *models.Token
+	err := bs.view(func(tx *bbolt.Tx) error {
+		value := tx.Bucket([]byte(tokensBucket)).Get(tokenKey(chain, address))
+		if value == nil {
+			return fmt.Errorf("token not found")
+		}
+		if err := json.Unmarshal(value, &token); err != nil {
+			return err
+		}
+
+		price, err := latestPrice(tx, token.ID)
+		if err != nil {
+			return err
+		}
+		if price != nil {
+			token.PriceUSD = price.PriceUSD
+		}
+		return nil
+	})
+	return token, err
+}
+
+// SaveToken saves a token, replacing the token at the same chain and address
+func (bs *BoltStorage) SaveToken(ctx context.Context, token *models.Token) error {
+	return bs.update(func(w *writer) error {
+		k := tokenKey(token.Chain, token.Address.Hex())
+		if value := w.tx.Bucket([]byte(tokensBucket)).Get(k); value != nil {
+			var existing models.Token
+			if err := json.Unmarshal(value, &existing); err != nil {
+				return err
+			}
+			token.ID = existing.ID
+			token.CreatedAt = existing.CreatedAt
+		} else {
+			id, err := w.nextID(tokensBucket)
+			if err != nil {
+				return err
+			}
+			token.ID = id
+			token.CreatedAt = time.Now().UTC()
+		}
+		token.UpdatedAt = time.Now().UTC()
+
+		// Prices are kept with their history
+		stored := *token
+		stored.PriceUSD = nil
+		stored.MarketCap = nil
+		return w.putJSON(tokensBucket, k, &stored)
+	})
+}
+
+// UpdateTokenPrice records a price of the token with TokenID, or of every
+// stored token at Address when it is not set
+func (bs *BoltStorage) UpdateTokenPrice(ctx context.Context, price *models.TokenPrice) error {
+	timestamp := price.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	return bs.update(func(w *writer) error {
+		var tokens []*models.Token
+		err := w.tx.Bucket([]byte(tokensBucket)).ForEach(func(k, v []byte) error {
+			var token models.Token
+			if err := json.Unmarshal(v, &token); err != nil {
+				return err
+			}
+			if (price.TokenID > 0 && token.ID == price.TokenID) || (price.TokenID == 0 && token.Address == price.Address) {
+				tokens = append(tokens, &token)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(tokens) == 0 {
+			return fmt.Errorf("token not found: %s", price.Address.Hex())
+		}
+
+		for _, token := range tokens {
+			id, err := w.nextID(pricesBucket)
+			if err != nil {
+				return err
+			}
+
+			recorded := *price
+			recorded.ID = id
+			recorded.TokenID = token.ID
+			recorded.Address = token.Address
+			recorded.Symbol = token.Symbol
+			recorded.Timestamp = timestamp.UTC()
+
+			value, err := encodePrice(&recorded)
+			if err != nil {
+				return err
+			}
+			if err := w.put(pricesBucket, key(token.ID, timestamp, id), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetTokenPrices retrieves the latest price of each address, keyed by the
+// address as given. Addresses without a price are left out.
+func (bs *BoltStorage) GetTokenPrices(ctx context.Context, addresses []string) (map[string]*models.TokenPrice, error) {
+	prices := make(map[string]*models.TokenPrice)
+	if len(addresses) == 0 {
+		return prices, nil
+	}
+
+	wanted := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		wanted[strings.ToLower(address)] = true
+	}
+
+	latest := make(map[string]*models.TokenPrice)
+	err := bs.view(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(tokensBucket)).ForEach(func(k, v []byte) error {
+			var token models.Token
+			if err := json.Unmarshal(v, &token); err != nil {
+				return err
+			}
+
+			address := strings.ToLower(token.Address.Hex())
+			if !wanted[address] {
+				return nil
+			}
+
+			price, err := latestPrice(tx, token.ID)
+			if err != nil || price == nil {
+				return err
+			}
+
+			current := latest[address]
+			if current == nil || price.Timestamp.After(current.Timestamp) ||
+				(price.Timestamp.Equal(current.Timestamp) && price.ID > current.ID) {
+				latest[address] = price
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, address := range addresses {
+		if price, ok := latest[strings.ToLower(address)]; ok {
+			copied := *price
+			prices[address] = &copied
+		}
+	}
+	return prices, nil
+}
@@ -0,0 +1,283 @@
+// internal/storage/bolt/tvl.go
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// SaveTVLSnapshot saves a TVL snapshot. Saving the same protocol, chain and
+// block, or time for a snapshot without a block, again replaces it.
+func (bs *BoltStorage) SaveTVLSnapshot(ctx context.Context, snapshot *models.TVLSnapshot) error {
+	if snapshot.Status == "" {
+		snapshot.Status = models.FinalityTentative
+	}
+
+	return bs.update(func(w *writer) error {
+		blockKey := snapshotBlockKey(snapshot)
+
+		if existing := w.tx.Bucket([]byte(snapshotBlocks)).Get(blockKey); existing != nil {
+			old, err := decodeSnapshot(w.tx.Bucket([]byte(snapshotsBucket)).Get(existing))
+			if err != nil {
+				return err
+			}
+			snapshot.ID = old.ID
+			if err := w.delete(snapshotsBucket, append([]byte{}, existing...)); err != nil {
+				return err
+			}
+		} else {
+			id, err := w.nextID(snapshotsBucket)
+			if err != nil {
+				return err
+			}
+			snapshot.ID = id
+		}
+
+		value, err := encodeSnapshot(snapshot)
+		if err != nil {
+			return err
+		}
+
+		snapshotKey := key(snapshot.Protocol, snapshot.Chain, snapshot.Timestamp, snapshot.ID)
+		if err := w.put(snapshotsBucket, snapshotKey, value); err != nil {
+			return err
+		}
+		return w.put(snapshotBlocks, blockKey, snapshotKey)
+	})
+}
+
+// snapshotBlockKey is the tvl_blocks key of a snapshot. Snapshots without a
+// block are indexed under block 0 by time, which no lookup by block reaches.
+func snapshotBlockKey(snapshot *models.TVLSnapshot) []byte {
+	if snapshot.BlockNumber == 0 {
+		return key(snapshot.Protocol, snapshot.Chain, uint64(0), snapshot.Timestamp)
+	}
+	return key(snapshot.Protocol, snapshot.Chain, snapshot.BlockNumber)
+}
+
+// eachChain calls fn with the key prefix of every chain of a protocol under
+// prefix, in order, until fn returns false
+func eachChain(b *bbolt.Bucket, prefix []byte, protocol string, fn func(chain []byte) (bool, error)) error {
+	p := append(append([]byte{}, prefix...), key(protocol)...)
+
+	c := b.Cursor()
+	for k, _ := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, _ = c.Seek(prefixEnd(k)) {
+		end := bytes.IndexByte(k[len(p):], 0)
+		if end < 0 {
+			return fmt.Errorf("invalid key %x", k)
+		}
+		k = k[:len(p)+end+1]
+
+		more, err := fn(append([]byte{}, k...))
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetLatestTVL retrieves the latest TVL snapshot of a protocol, on any chain
+// when chain is empty
+func (bs *BoltStorage) GetLatestTVL(ctx context.Context, protocol, chain string) (*models.TVLSnapshot, error) {
+	var latest *models.TVLSnapshot
+	err := bs.view(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(snapshotsBucket))
+
+		check := func(p []byte) (bool, error) {
+			_, value := last(b, p)
+			if value == nil {
+				return true, nil
+			}
+
+			snapshot, err := decodeSnapshot(value)
+			if err != nil {
+				return false, err
+			}
+			if latest == nil || newerSnapshot(snapshot, latest) {
+				latest = snapshot
+			}
+			return true, nil
+		}
+
+		if chain != "" {
+			_, err := check(key(protocol, chain))
+			return err
+		}
+		return eachChain(b, nil, protocol, check)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("no TVL snapshot found")
+	}
+	return latest, nil
+}
+
+// GetTVLByBlock retrieves the snapshot of a protocol at a block
+func (bs *BoltStorage) GetTVLByBlock(ctx context.Context, protocol, chain string, blockNumber uint64) (*models.TVLSnapshot, error) {
+	var found *models.TVLSnapshot
+	err := bs.view(func(tx *bbolt.Tx) error {
+		blocks := tx.Bucket([]byte(snapshotBlocks))
+
+		// Chains are in key order, the first match is the lowest chain
+		find := func(p []byte) (bool, error) {
+			snapshotKey := blocks.Get(append(append([]byte{}, p...), key(blockNumber)...))
+			if snapshotKey == nil {
+				return true, nil
+			}
+
+			snapshot, err := decodeSnapshot(tx.Bucket([]byte(snapshotsBucket)).Get(snapshotKey))
+			if err != nil {
+				return false, err
+			}
+			found = snapshot
+			return false, nil
+		}
+
+		if chain != "" {
+			_, err := find(key(protocol, chain))
+			return err
+		}
+		return eachChain(blocks, nil, protocol, find)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("no TVL snapshot found for block %d", blockNumber)
+	}
+	return found, nil
+}
+
+// GetAggregatedTVL sums the latest snapshot of every protocol on every chain
+func (bs *BoltStorage) GetAggregatedTVL(ctx context.Context) (*models.AggregatedTVL, error) {
+	var latest []*models.TVLSnapshot
+	err := bs.view(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(snapshotsBucket))
+
+		// Jump from the last snapshot of each protocol and chain to the next
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil; {
+			protocolEnd := bytes.IndexByte(k, 0)
+			if protocolEnd < 0 {
+				return fmt.Errorf("invalid key %x", k)
+			}
+			chainEnd := bytes.IndexByte(k[protocolEnd+1:], 0)
+			if chainEnd < 0 {
+				return fmt.Errorf("invalid key %x", k)
+			}
+
+			p := append([]byte{}, k[:protocolEnd+1+chainEnd+1]...)
+			_, value := last(b, p)
+			snapshot, err := decodeSnapshot(value)
+			if err != nil {
+				return err
+			}
+			latest = append(latest, snapshot)
+
+			k, _ = c.Seek(prefixEnd(p))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.Aggregate(latest), nil
+}
+
+// newerSnapshot orders snapshots by time, then by ID
+func newerSnapshot(a, b *models.TVLSnapshot) bool {
+	if !a.Timestamp.Equal(b.Timestamp) {
+		return a.Timestamp.After(b.Timestamp)
+	}
+	return a.ID > b.ID
+}
+
+// FinalizeTVL marks the snapshots and rollups of a chain up to and including
+// block as final
+func (bs *BoltStorage) FinalizeTVL(ctx context.Context, chain string, block uint64) error {
+	return bs.update(func(w *writer) error {
+		for _, bucket := range []string{snapshotsBucket, rollupsBucket} {
+			var keys [][]byte
+			var snapshots []*models.TVLSnapshot
+			err := scan(w.tx.Bucket([]byte(bucket)), nil, nil, func(k, v []byte) (bool, error) {
+				snapshot, err := decodeSnapshot(v)
+				if err != nil {
+					return false, err
+				}
+				if snapshot.Chain == chain && snapshot.BlockNumber > 0 && snapshot.BlockNumber <= block &&
+					snapshot.Status != models.FinalityFinal {
+					keys = append(keys, append([]byte{}, k...))
+					snapshots = append(snapshots, snapshot)
+				}
+				return true, nil
+			})
+			if err != nil {
+				return err
+			}
+
+			for i, snapshot := range snapshots {
+				snapshot.Status = models.FinalityFinal
+				value, err := encodeSnapshot(snapshot)
+				if err != nil {
+					return err
+				}
+				if err := w.put(bucket, keys[i], value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// RollbackTVL deletes the snapshots of a chain at or above fromBlock, with
+// their holdings, and the rollups closing there
+func (bs *BoltStorage) RollbackTVL(ctx context.Context, chain string, fromBlock uint64) error {
+	return bs.update(func(w *writer) error {
+		for _, bucket := range []string{snapshotsBucket, rollupsBucket} {
+			// Keys are collected first, bbolt cursors don't survive deletes
+			var keys [][]byte
+			var snapshots []*models.TVLSnapshot
+			err := scan(w.tx.Bucket([]byte(bucket)), nil, nil, func(k, v []byte) (bool, error) {
+				snapshot, err := decodeSnapshot(v)
+				if err != nil {
+					return false, err
+				}
+				if snapshot.Chain == chain && snapshot.BlockNumber > 0 && snapshot.BlockNumber >= fromBlock {
+					keys = append(keys, append([]byte{}, k...))
+					snapshots = append(snapshots, snapshot)
+				}
+				return true, nil
+			})
+			if err != nil {
+				return err
+			}
+
+			for i, k := range keys {
+				if bucket == snapshotsBucket {
+					blockKey := snapshotBlockKey(snapshots[i])
+					if indexed := w.tx.Bucket([]byte(snapshotBlocks)).Get(blockKey); indexed != nil && bytes.Equal(indexed, k) {
+						if err := w.delete(snapshotBlocks, blockKey); err != nil {
+							return err
+						}
+					}
+				}
+				if err := w.delete(bucket, k); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,254 @@
+// internal/storage/history.go
+package storage
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+)
+
+// Windows of TVL history up to these lengths are served from raw snapshots
+// and hourly rollups, longer ones from daily rollups
+const (
+	RawHistoryWindow    = 2 * 24 * time.Hour
+	HourlyHistoryWindow = 90 * 24 * time.Hour
+)
+
+// resolutions from finest to coarsest
+var resolutions = []models.Resolution{
+	models.ResolutionRaw,
+	models.ResolutionHourly,
+	models.ResolutionDaily,
+}
+
+// HistoryResolution returns the finest resolution a window of TVL history
+// can be charted at: a 30 day window of minutely snapshots is 720 hourly
+// points rather than 43200 raw ones
+func HistoryResolution(from, to time.Time) models.Resolution {
+	switch window := to.Sub(from); {
+	case window <= RawHistoryWindow:
+		return models.ResolutionRaw
+	case window <= HourlyHistoryWindow:
+		return models.ResolutionHourly
+	default:
+		return models.ResolutionDaily
+	}
+}
+
+// History implements GetHistoricalTVL on GetTVLHistory. It returns the
+// window at the resolution HistoryResolution picks. Where a chain has no
+// such points at the start of the window, e.g. raw snapshots that were
+// pruned, coarser rollups fill in; where no rollup covers a range, e.g. the
+// hours not rolled up yet at the end of the window, finer rollups and raw
+// snapshots do.
+func History(ctx context.Context, s TVLStorage, protocol, chain string, from, to time.Time) ([]*models.TVLSnapshot, error) {
+	finest := HistoryResolution(from, to)
+
+	var history []*models.TVLSnapshot
+	first := make(map[string]time.Time) // chain -> first point of a finer resolution
+	covered := make(map[string][]span)  // chain -> intervals of the points so far
+	var finer []models.Resolution
+	skip := true
+	for _, resolution := range resolutions {
+		if resolution == finest {
+			skip = false
+		}
+		if skip {
+			finer = append([]models.Resolution{resolution}, finer...)
+			continue
+		}
+
+		points, err := s.GetTVLHistory(ctx, TVLFilter{
+			Protocol:   protocol,
+			Chain:      chain,
+			Resolution: resolution,
+			From:       from,
+			To:         to,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Only points whose interval ends before the finer points begin
+		earliest := make(map[string]time.Time)
+		for _, point := range points {
+			if start, ok := first[point.Chain]; ok && point.Timestamp.Add(resolution.Interval()).After(start) {
+				continue
+			}
+			history = append(history, point)
+			covered[point.Chain] = append(covered[point.Chain], pointSpan(point, resolution))
+
+			if current, ok := earliest[point.Chain]; !ok || point.Timestamp.Before(current) {
+				earliest[point.Chain] = point.Timestamp
+			}
+		}
+		for chain, start := range earliest {
+			first[chain] = start
+		}
+	}
+
+	// Finer points fill the ranges no coarser point covers, coarsest first
+	for _, resolution := range finer {
+		for chain := range covered {
+			sortSpans(covered[chain])
+		}
+
+		points, err := s.GetTVLHistory(ctx, TVLFilter{
+			Protocol:   protocol,
+			Chain:      chain,
+			Resolution: resolution,
+			From:       from,
+			To:         to,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		added := make(map[string][]span)
+		for _, point := range points {
+			interval := pointSpan(point, resolution)
+			if overlaps(covered[point.Chain], interval) {
+				continue
+			}
+			history = append(history, point)
+			added[point.Chain] = append(added[point.Chain], interval)
+		}
+		for chain, spans := range added {
+			covered[chain] = append(covered[chain], spans...)
+		}
+	}
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Timestamp.Before(history[j].Timestamp)
+	})
+
+	return history, nil
+}
+
+// span is the interval a point of history covers, [start, end)
+type span struct {
+	start, end time.Time
+}
+
+// pointSpan returns the interval of a point, the instant of a raw snapshot
+func pointSpan(point *models.TVLSnapshot, resolution models.Resolution) span {
+	interval := resolution.Interval()
+	if interval <= 0 {
+		interval = time.Nanosecond
+	}
+	return span{point.Timestamp, point.Timestamp.Add(interval)}
+}
+
+func sortSpans(spans []span) {
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start.Before(spans[j].start)
+	})
+}
+
+// overlaps tells whether an interval overlaps any of spans, which are sorted
+// and do not overlap each other
+func overlaps(spans []span, interval span) bool {
+	// The first span starting at or after the interval's end, and the one
+	// before it, the only one that may reach into the interval
+	i := sort.Search(len(spans), func(i int) bool {
+		return !spans[i].start.Before(interval.end)
+	})
+	return i > 0 && spans[i-1].end.After(interval.start)
+}
+
+// Rollup summarizes snapshots, or rollups of a finer resolution, into one
+// rollup per protocol, chain and interval of resolution. Snapshots must be
+// sorted by time. A rollup is final when everything it covers is; like its
+// block and breakdown, its source is that of its last snapshot.
+func Rollup(snapshots []*models.TVLSnapshot, resolution models.Resolution) []*models.TVLSnapshot {
+	type group struct {
+		protocol string
+		chain    string
+		start    time.Time
+	}
+
+	interval := resolution.Interval()
+	rollups := make(map[group]*models.TVLSnapshot)
+	sums := make(map[group]*big.Float) // average times samples
+	var order []group
+
+	for _, snapshot := range snapshots {
+		covered := snapshot.Range
+		if covered == nil {
+			total := snapshot.TotalUSD
+			if total == nil {
+				total = new(big.Float)
+			}
+			covered = &models.TVLRange{Open: total, High: total, Low: total, Close: total, Avg: total, Samples: 1}
+		}
+		if covered.Samples <= 0 {
+			continue
+		}
+
+		g := group{snapshot.Protocol, snapshot.Chain, snapshot.Timestamp.UTC().Truncate(interval)}
+		rollup, ok := rollups[g]
+		if !ok {
+			rollup = &models.TVLSnapshot{
+				Protocol:   snapshot.Protocol,
+				Chain:      snapshot.Chain,
+				Status:     models.FinalityFinal,
+				Timestamp:  g.start,
+				Resolution: resolution,
+				Range: &models.TVLRange{
+					Open: copyUSD(covered.Open),
+					High: copyUSD(covered.High),
+					Low:  copyUSD(covered.Low),
+				},
+			}
+			rollups[g] = rollup
+			sums[g] = new(big.Float).SetPrec(numericPrec)
+			order = append(order, g)
+		}
+
+		if covered.High.Cmp(rollup.Range.High) > 0 {
+			rollup.Range.High = copyUSD(covered.High)
+		}
+		if covered.Low.Cmp(rollup.Range.Low) < 0 {
+			rollup.Range.Low = copyUSD(covered.Low)
+		}
+		rollup.Range.Close = copyUSD(covered.Close)
+		rollup.Range.Samples += covered.Samples
+
+		weighted := new(big.Float).SetPrec(numericPrec).Mul(covered.Avg, big.NewFloat(float64(covered.Samples)))
+		sums[g].Add(sums[g], weighted)
+
+		rollup.BlockNumber = snapshot.BlockNumber
+		rollup.TotalUSD = copyUSD(covered.Close)
+		rollup.Breakdown = snapshot.Breakdown
+		rollup.Source = snapshot.Source
+		if snapshot.Status != models.FinalityFinal {
+			rollup.Status = models.FinalityTentative
+		}
+	}
+
+	result := make([]*models.TVLSnapshot, 0, len(order))
+	for _, g := range order {
+		rollup := rollups[g]
+		rollup.Range.Avg = new(big.Float).SetPrec(numericPrec).Quo(sums[g], big.NewFloat(float64(rollup.Range.Samples)))
+		result = append(result, rollup)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp.Before(result[j].Timestamp)
+	})
+
+	return result
+}
+
+// numericPrec is the precision rollup amounts are computed at
+const numericPrec = 128
+
+func copyUSD(value *big.Float) *big.Float {
+	if value == nil {
+		return new(big.Float).SetPrec(numericPrec)
+	}
+	return new(big.Float).SetPrec(numericPrec).Set(value)
+}
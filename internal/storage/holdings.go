@@ -0,0 +1,67 @@
+// internal/storage/holdings.go
+package storage
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+)
+
+// TokenHoldings implements GetTokenHoldings on GetTVLHistory, for storages
+// that keep holdings only in the breakdown of their snapshots
+func TokenHoldings(ctx context.Context, s TVLStorage, filter HoldingFilter) ([]*models.TokenHolding, error) {
+	history := filter.TVLFilter
+	history.Limit = 0
+
+	snapshots, err := s.GetTVLHistory(ctx, history)
+	if err != nil {
+		return nil, err
+	}
+
+	return Holdings(snapshots, filter), nil
+}
+
+// Holdings lists the assets in the breakdowns of snapshots that match the
+// filter's token and symbol, snapshot by snapshot and by token then symbol
+// within one
+func Holdings(snapshots []*models.TVLSnapshot, filter HoldingFilter) []*models.TokenHolding {
+	var holdings []*models.TokenHolding
+	for _, snapshot := range snapshots {
+		start := len(holdings)
+		for _, asset := range snapshot.Breakdown {
+			if asset == nil ||
+				(filter.Token != "" && !strings.EqualFold(asset.Token.Hex(), filter.Token)) ||
+				(filter.Symbol != "" && asset.Symbol != filter.Symbol) {
+				continue
+			}
+
+			holdings = append(holdings, &models.TokenHolding{
+				SnapshotID:  snapshot.ID,
+				Protocol:    snapshot.Protocol,
+				Chain:       snapshot.Chain,
+				BlockNumber: snapshot.BlockNumber,
+				Status:      snapshot.Status,
+				Timestamp:   snapshot.Timestamp,
+				Resolution:  snapshot.Resolution,
+				AssetTVL:    *asset,
+			})
+		}
+
+		added := holdings[start:]
+		sort.Slice(added, func(i, j int) bool {
+			if c := bytes.Compare(added[i].Token[:], added[j].Token[:]); c != 0 {
+				return c < 0
+			}
+			return added[i].Symbol < added[j].Symbol
+		})
+
+		if filter.Limit > 0 && len(holdings) >= filter.Limit {
+			return holdings[:filter.Limit]
+		}
+	}
+
+	return holdings
+}
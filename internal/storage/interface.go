@@ -38,11 +38,34 @@ type ProtocolStorage interface {
 
 // TVLStorage handles TVL data
 type TVLStorage interface {
+	// SaveTVLSnapshot saves a snapshot, replacing the one of the same
+	// protocol, chain and block. Snapshots without a block number replace
+	// the one of the same protocol, chain and time instead.
 	SaveTVLSnapshot(ctx context.Context, snapshot *models.TVLSnapshot) error
+	// FinalizeTVL marks the snapshots and rollups of a chain up to and
+	// including block as final
+	FinalizeTVL(ctx context.Context, chain string, block uint64) error
+	// RollbackTVL deletes the snapshots of a chain at or above fromBlock,
+	// with their holdings, and the rollups closing there
+	RollbackTVL(ctx context.Context, chain string, fromBlock uint64) error
 	GetLatestTVL(ctx context.Context, protocol, chain string) (*models.TVLSnapshot, error)
 	GetHistoricalTVL(ctx context.Context, protocol, chain string, from, to time.Time) ([]*models.TVLSnapshot, error)
 	GetTVLByBlock(ctx context.Context, protocol, chain string, blockNumber uint64) (*models.TVLSnapshot, error)
 	GetAggregatedTVL(ctx context.Context) (*models.AggregatedTVL, error)
+
+	// GetTVLHistory retrieves the snapshots, or the rollups of the filter's
+	// resolution, by time then ID
+	GetTVLHistory(ctx context.Context, filter TVLFilter) ([]*models.TVLSnapshot, error)
+	// SaveTVLRollups saves rollups, replacing the rollup of the same
+	// protocol, chain, resolution and interval
+	SaveTVLRollups(ctx context.Context, rollups []*models.TVLSnapshot) error
+	// DeleteTVLHistory deletes the snapshots, or the rollups of a resolution,
+	// from before a time. The latest snapshot of each protocol and chain is
+	// kept.
+	DeleteTVLHistory(ctx context.Context, resolution models.Resolution, before time.Time) (uint64, error)
+	// GetTokenHoldings retrieves the assets of the snapshots, or the rollups
+	// of the filter's resolution, by time then snapshot ID then token and symbol
+	GetTokenHoldings(ctx context.Context, filter HoldingFilter) ([]*models.TokenHolding, error)
 }
 
 // ChainStorage handles chain data
@@ -50,6 +73,7 @@ type ChainStorage interface {
 	GetChain(ctx context.Context, name string) (*models.Chain, error)
 	GetChains(ctx context.Context) ([]*models.Chain, error)
 	SaveChain(ctx context.Context, chain *models.Chain) error
+	DeleteChain(ctx context.Context, name string) error
 	UpdateChainStats(ctx context.Context, stats *models.ChainStats) error
 }
 
@@ -57,8 +81,50 @@ type ChainStorage interface {
 type EventStorage interface {
 	SaveEvents(ctx context.Context, events []*models.Event) error
 	GetEvents(ctx context.Context, filter EventFilter) ([]*models.Event, error)
+	// GetLatestEvents returns the last of the events matching a filter of
+	// each contract, by block number and log index, ignoring Limit and Offset
+	GetLatestEvents(ctx context.Context, filter EventFilter) ([]*models.Event, error)
 	SaveBlock(ctx context.Context, block *models.Block) error
 	GetLastIndexedBlock(ctx context.Context, chain string) (uint64, error)
+	// GetBlockAtTime returns the highest indexed block mined at or before t,
+	// or nil if there is none
+	GetBlockAtTime(ctx context.Context, chain string, t time.Time) (*models.Block, error)
+}
+
+// IndexerStorage handles the indexer's progress on top of its events
+type IndexerStorage interface {
+	EventStorage
+
+	// CommitBatch stores all writes of a batch in one transaction, so a
+	// checkpoint never covers events that were not written
+	CommitBatch(ctx context.Context, batch *models.EventBatch) error
+	GetRecentBlocks(ctx context.Context, chain string, limit int) ([]*models.Block, error)
+	// Rollback deletes events, blocks and dead letters at or above fromBlock
+	// and rewinds the checkpoints and sink offsets past it
+	Rollback(ctx context.Context, chain string, fromBlock uint64) error
+	FinalizeEvents(ctx context.Context, chain string, block uint64) error
+	// CountEvents and DeleteEvents ignore the filter's Limit and Offset
+	CountEvents(ctx context.Context, filter EventFilter) (uint64, error)
+	DeleteEvents(ctx context.Context, filter EventFilter) (uint64, error)
+
+	GetCheckpoints(ctx context.Context, chain string) ([]*models.Checkpoint, error)
+	SaveCheckpoints(ctx context.Context, checkpoints []*models.Checkpoint) error
+
+	SaveFailedRange(ctx context.Context, failed *models.FailedRange) error
+	GetFailedRanges(ctx context.Context, chain string) ([]*models.FailedRange, error)
+	DeleteFailedRange(ctx context.Context, id uint64) error
+
+	// SaveDeadLetters stores logs that failed to decode. Saving the same log
+	// for the same protocol again counts another attempt.
+	SaveDeadLetters(ctx context.Context, letters []*models.DeadLetter) error
+	// GetDeadLetters returns dead letters by block; empty arguments match all
+	GetDeadLetters(ctx context.Context, chain, protocol string) ([]*models.DeadLetter, error)
+	// CountDeadLetters counts dead letters; empty arguments match all
+	CountDeadLetters(ctx context.Context, chain, protocol string) (uint64, error)
+
+	// GetSinkOffsets returns the last block delivered to each event sink
+	GetSinkOffsets(ctx context.Context, chain string) (map[string]uint64, error)
+	SaveSinkOffset(ctx context.Context, sink, chain string, block uint64) error
 }
 
 // TokenStorage handles token data
@@ -69,7 +135,8 @@ type TokenStorage interface {
 	GetTokenPrices(ctx context.Context, addresses []string) (map[string]*models.TokenPrice, error)
 }
 
-// EventFilter for querying events
+// EventFilter for querying events. Events are returned by block number and
+// log index; zero values don't filter.
 type EventFilter struct {
 	Chain     string
 	Protocol  string
@@ -77,6 +144,26 @@ type EventFilter struct {
 	ToBlock   uint64
 	EventName string
 	Address   string
+	Status    models.FinalityStatus // any status when empty
 	Limit     int
 	Offset    int
 }
+
+// TVLFilter for querying TVL history. From and To are inclusive; zero values
+// don't filter.
+type TVLFilter struct {
+	Protocol   string
+	Chain      string
+	Resolution models.Resolution // raw snapshots when empty
+	From       time.Time
+	To         time.Time
+	Limit      int
+}
+
+// HoldingFilter for querying token holdings. Token is an address, matched
+// in any case, and Symbol matches exactly; Limit counts holdings.
+type HoldingFilter struct {
+	TVLFilter
+	Token  string
+	Symbol string
+}
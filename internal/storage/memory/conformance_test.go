@@ -0,0 +1,16 @@
+// internal/storage/memory/conformance_test.go
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/memory"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return memory.NewMemoryStorage()
+	})
+}
@@ -0,0 +1,557 @@
+// internal/storage/memory/events.go
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// SaveEvents saves events. Saving an event again only replaces it while it
+// is still tentative.
+func (ms *MemoryStorage) SaveEvents(ctx context.Context, events []*models.Event) error {
+	ms.lock(indexTable)
+	defer ms.mu.Unlock()
+
+	ms.saveEvents(events)
+	return nil
+}
+
+func (ms *MemoryStorage) saveEvents(events []*models.Event) {
+	for _, event := range events {
+		if event.Status == "" {
+			event.Status = models.FinalityTentative
+		}
+
+		// Same (chain, transaction, log index) means the same log, keep one copy
+		replaced := false
+		for i, existing := range ms.events {
+			if existing.Chain == event.Chain && existing.TransactionHash == event.TransactionHash && existing.LogIndex == event.LogIndex {
+				if existing.Status != models.FinalityFinal {
+					event.ID = existing.ID
+					ms.events[i] = event
+				}
+				replaced = true
+				break
+			}
+		}
+
+		if !replaced {
+			ms.nextEvent++
+			event.ID = ms.nextEvent
+			ms.events = append(ms.events, event)
+		}
+	}
+}
+
+// GetEvents retrieves events matching a filter, by block number and log index
+func (ms *MemoryStorage) GetEvents(ctx context.Context, filter storage.EventFilter) ([]*models.Event, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var results []*models.Event
+	for _, event := range ms.events {
+		if matchesEvent(event, filter) {
+			results = append(results, event)
+		}
+	}
+
+	sortEvents(results)
+
+	// Apply offset and limit
+	if filter.Offset > 0 {
+		if filter.Offset >= len(results) {
+			return nil, nil
+		}
+		results = results[filter.Offset:]
+	}
+	if filter.Limit > 0 && len(results) > filter.Limit {
+		results = results[:filter.Limit]
+	}
+
+	return results, nil
+}
+
+// GetLatestEvents returns the last of the events matching a filter of each
+// contract, by block number and log index, ignoring Limit and Offset
+func (ms *MemoryStorage) GetLatestEvents(ctx context.Context, filter storage.EventFilter) ([]*models.Event, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	latest := make(map[string]*models.Event)
+	for _, event := range ms.events {
+		if !matchesEvent(event, filter) {
+			continue
+		}
+		key := event.Chain + ":" + event.Address.Hex()
+		if last, ok := latest[key]; !ok || event.BlockNumber > last.BlockNumber ||
+			(event.BlockNumber == last.BlockNumber && event.LogIndex > last.LogIndex) {
+			latest[key] = event
+		}
+	}
+
+	results := make([]*models.Event, 0, len(latest))
+	for _, event := range latest {
+		results = append(results, event)
+	}
+	sortEvents(results)
+
+	return results, nil
+}
+
+// CountEvents counts the events matching a filter, ignoring Limit and Offset
+func (ms *MemoryStorage) CountEvents(ctx context.Context, filter storage.EventFilter) (uint64, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var count uint64
+	for _, event := range ms.events {
+		if matchesEvent(event, filter) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// DeleteEvents deletes the events matching a filter, ignoring Limit and Offset
+func (ms *MemoryStorage) DeleteEvents(ctx context.Context, filter storage.EventFilter) (uint64, error) {
+	ms.lock(indexTable)
+	defer ms.mu.Unlock()
+
+	var deleted uint64
+	kept := ms.events[:0]
+	for _, event := range ms.events {
+		if matchesEvent(event, filter) {
+			deleted++
+			continue
+		}
+		kept = append(kept, event)
+	}
+	ms.events = kept
+
+	return deleted, nil
+}
+
+// sortEvents sorts events by chain, block number and log index
+func sortEvents(events []*models.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Chain != events[j].Chain {
+			return events[i].Chain < events[j].Chain
+		}
+		if events[i].BlockNumber != events[j].BlockNumber {
+			return events[i].BlockNumber < events[j].BlockNumber
+		}
+		return events[i].LogIndex < events[j].LogIndex
+	})
+}
+
+func matchesEvent(event *models.Event, filter storage.EventFilter) bool {
+	return (filter.Chain == "" || event.Chain == filter.Chain) &&
+		(filter.Protocol == "" || event.Protocol == filter.Protocol) &&
+		(filter.EventName == "" || event.EventName == filter.EventName) &&
+		(filter.Address == "" || strings.EqualFold(event.Address.Hex(), filter.Address)) &&
+		(filter.Status == "" || event.Status == filter.Status) &&
+		(filter.FromBlock == 0 || event.BlockNumber >= filter.FromBlock) &&
+		(filter.ToBlock == 0 || event.BlockNumber <= filter.ToBlock)
+}
+
+// FinalizeEvents marks all events up to and including block as final
+func (ms *MemoryStorage) FinalizeEvents(ctx context.Context, chain string, block uint64) error {
+	ms.lock(indexTable)
+	defer ms.mu.Unlock()
+
+	for _, event := range ms.events {
+		if event.Chain == chain && event.BlockNumber <= block {
+			event.Status = models.FinalityFinal
+		}
+	}
+
+	return nil
+}
+
+// SaveBlock saves a block
+func (ms *MemoryStorage) SaveBlock(ctx context.Context, block *models.Block) error {
+	ms.lock(indexTable)
+	defer ms.mu.Unlock()
+
+	ms.saveBlock(block)
+	return nil
+}
+
+func (ms *MemoryStorage) saveBlock(block *models.Block) {
+	if ms.blocks[block.Chain] == nil {
+		ms.blocks[block.Chain] = make(map[uint64]*models.Block)
+	}
+	ms.blocks[block.Chain][block.Number] = block
+}
+
+// GetRecentBlocks returns up to limit indexed blocks for a chain, newest first
+func (ms *MemoryStorage) GetRecentBlocks(ctx context.Context, chain string, limit int) ([]*models.Block, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	blocks := make([]*models.Block, 0, len(ms.blocks[chain]))
+	for _, block := range ms.blocks[chain] {
+		blocks = append(blocks, block)
+	}
+
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Number > blocks[j].Number
+	})
+
+	if limit > 0 && len(blocks) > limit {
+		blocks = blocks[:limit]
+	}
+
+	return blocks, nil
+}
+
+// GetBlockAtTime returns the highest indexed block mined at or before t
+func (ms *MemoryStorage) GetBlockAtTime(ctx context.Context, chain string, t time.Time) (*models.Block, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var found *models.Block
+	for _, block := range ms.blocks[chain] {
+		if block.Timestamp.IsZero() || block.Timestamp.After(t) {
+			continue
+		}
+		if found == nil || block.Number > found.Number {
+			found = block
+		}
+	}
+
+	return found, nil
+}
+
+// GetLastIndexedBlock returns the block up to which every watched contract is
+// indexed, falling back to the highest indexed block without checkpoints
+func (ms *MemoryStorage) GetLastIndexedBlock(ctx context.Context, chain string) (uint64, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	first := true
+	var watermark uint64
+	for _, checkpoint := range ms.checkpoints[chain] {
+		if first || checkpoint.Block < watermark {
+			watermark = checkpoint.Block
+			first = false
+		}
+	}
+	if !first {
+		return watermark, nil
+	}
+
+	var last uint64
+	for number := range ms.blocks[chain] {
+		if number > last {
+			last = number
+		}
+	}
+	return last, nil
+}
+
+// Rollback deletes all events and indexed blocks at or above fromBlock
+func (ms *MemoryStorage) Rollback(ctx context.Context, chain string, fromBlock uint64) error {
+	ms.lock(indexTable)
+	defer ms.mu.Unlock()
+
+	kept := ms.events[:0]
+	for _, event := range ms.events {
+		if event.Chain != chain || event.BlockNumber < fromBlock {
+			kept = append(kept, event)
+		}
+	}
+	ms.events = kept
+
+	for number := range ms.blocks[chain] {
+		if number >= fromBlock {
+			delete(ms.blocks[chain], number)
+		}
+	}
+
+	for _, checkpoint := range ms.checkpoints[chain] {
+		if checkpoint.Block >= fromBlock {
+			checkpoint.Block = checkpointBefore(fromBlock)
+			checkpoint.UpdatedAt = time.Now()
+		}
+	}
+
+	for id, letter := range ms.deadLetters {
+		if letter.Chain == chain && letter.BlockNumber >= fromBlock {
+			delete(ms.deadLetters, id)
+		}
+	}
+
+	for sink, block := range ms.sinks[chain] {
+		if block >= fromBlock {
+			ms.sinks[chain][sink] = checkpointBefore(fromBlock)
+		}
+	}
+
+	return nil
+}
+
+// GetCheckpoints returns the indexer checkpoints of a chain
+func (ms *MemoryStorage) GetCheckpoints(ctx context.Context, chain string) ([]*models.Checkpoint, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	checkpoints := make([]*models.Checkpoint, 0, len(ms.checkpoints[chain]))
+	for _, checkpoint := range ms.checkpoints[chain] {
+		copied := *checkpoint
+		checkpoints = append(checkpoints, &copied)
+	}
+
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Contract < checkpoints[j].Contract
+	})
+
+	return checkpoints, nil
+}
+
+// SaveCheckpoints upserts checkpoints without moving any of them backwards
+func (ms *MemoryStorage) SaveCheckpoints(ctx context.Context, checkpoints []*models.Checkpoint) error {
+	ms.lock(indexTable)
+	defer ms.mu.Unlock()
+
+	ms.saveCheckpoints(checkpoints)
+	return nil
+}
+
+func (ms *MemoryStorage) saveCheckpoints(checkpoints []*models.Checkpoint) {
+	now := time.Now()
+	for _, checkpoint := range checkpoints {
+		if ms.checkpoints[checkpoint.Chain] == nil {
+			ms.checkpoints[checkpoint.Chain] = make(map[string]*models.Checkpoint)
+		}
+
+		existing, ok := ms.checkpoints[checkpoint.Chain][checkpoint.Contract]
+		if ok && existing.Block > checkpoint.Block {
+			existing.Protocol = checkpoint.Protocol
+			existing.UpdatedAt = now
+			continue
+		}
+
+		copied := *checkpoint
+		copied.UpdatedAt = now
+		ms.checkpoints[checkpoint.Chain][checkpoint.Contract] = &copied
+	}
+}
+
+// SaveFailedRange records a range that failed to index. Saving the same
+// range again counts another attempt.
+func (ms *MemoryStorage) SaveFailedRange(ctx context.Context, failed *models.FailedRange) error {
+	ms.lock(indexTable)
+	defer ms.mu.Unlock()
+
+	ms.saveFailedRange(failed)
+	return nil
+}
+
+func (ms *MemoryStorage) saveFailedRange(failed *models.FailedRange) {
+	key := strings.Join(failed.Contracts, ",")
+	now := time.Now()
+	for _, existing := range ms.failed {
+		if existing.Chain == failed.Chain && strings.Join(existing.Contracts, ",") == key &&
+			existing.FromBlock == failed.FromBlock && existing.ToBlock == failed.ToBlock {
+			existing.Attempts++
+			existing.LastError = failed.LastError
+			existing.UpdatedAt = now
+			failed.ID = existing.ID
+			failed.Attempts = existing.Attempts
+			return
+		}
+	}
+
+	ms.nextFailed++
+	stored := *failed
+	stored.ID = ms.nextFailed
+	stored.Contracts = append([]string(nil), failed.Contracts...)
+	stored.Attempts = 1
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	ms.failed[stored.ID] = &stored
+
+	failed.ID = stored.ID
+	failed.Attempts = stored.Attempts
+}
+
+// GetFailedRanges returns the failed ranges of a chain by first block
+func (ms *MemoryStorage) GetFailedRanges(ctx context.Context, chain string) ([]*models.FailedRange, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var ranges []*models.FailedRange
+	for _, failed := range ms.failed {
+		if failed.Chain == chain {
+			copied := *failed
+			ranges = append(ranges, &copied)
+		}
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].FromBlock < ranges[j].FromBlock
+	})
+
+	return ranges, nil
+}
+
+// DeleteFailedRange forgets a failed range
+func (ms *MemoryStorage) DeleteFailedRange(ctx context.Context, id uint64) error {
+	ms.lock(indexTable)
+	defer ms.mu.Unlock()
+
+	delete(ms.failed, id)
+	return nil
+}
+
+// SaveDeadLetters stores logs that failed to decode
+func (ms *MemoryStorage) SaveDeadLetters(ctx context.Context, letters []*models.DeadLetter) error {
+	ms.lock(indexTable)
+	defer ms.mu.Unlock()
+
+	ms.saveDeadLetters(letters)
+	return nil
+}
+
+func (ms *MemoryStorage) saveDeadLetters(letters []*models.DeadLetter) {
+	now := time.Now()
+	for _, letter := range letters {
+		var existing *models.DeadLetter
+		for _, stored := range ms.deadLetters {
+			if stored.Chain == letter.Chain && stored.TransactionHash == letter.TransactionHash &&
+				stored.LogIndex == letter.LogIndex && stored.Protocol == letter.Protocol {
+				existing = stored
+				break
+			}
+		}
+
+		if existing != nil {
+			existing.BlockHash = letter.BlockHash
+			existing.Error = letter.Error
+			existing.Timestamp = letter.Timestamp
+			existing.Attempts++
+			existing.UpdatedAt = now
+			letter.ID = existing.ID
+			letter.Attempts = existing.Attempts
+			continue
+		}
+
+		ms.nextLetter++
+		stored := *letter
+		stored.ID = ms.nextLetter
+		stored.Topics = append([]string(nil), letter.Topics...)
+		stored.Attempts = 1
+		stored.CreatedAt = now
+		stored.UpdatedAt = now
+		ms.deadLetters[stored.ID] = &stored
+
+		letter.ID = stored.ID
+		letter.Attempts = stored.Attempts
+	}
+}
+
+// GetDeadLetters returns dead letters by block; empty arguments match all
+func (ms *MemoryStorage) GetDeadLetters(ctx context.Context, chain, protocol string) ([]*models.DeadLetter, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var letters []*models.DeadLetter
+	for _, letter := range ms.deadLetters {
+		if (chain == "" || letter.Chain == chain) && (protocol == "" || letter.Protocol == protocol) {
+			copied := *letter
+			letters = append(letters, &copied)
+		}
+	}
+
+	sort.Slice(letters, func(i, j int) bool {
+		if letters[i].Chain != letters[j].Chain {
+			return letters[i].Chain < letters[j].Chain
+		}
+		if letters[i].BlockNumber != letters[j].BlockNumber {
+			return letters[i].BlockNumber < letters[j].BlockNumber
+		}
+		return letters[i].LogIndex < letters[j].LogIndex
+	})
+
+	return letters, nil
+}
+
+// CountDeadLetters counts dead letters; empty arguments match all
+func (ms *MemoryStorage) CountDeadLetters(ctx context.Context, chain, protocol string) (uint64, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var count uint64
+	for _, letter := range ms.deadLetters {
+		if (chain == "" || letter.Chain == chain) && (protocol == "" || letter.Protocol == protocol) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// GetSinkOffsets returns the last block delivered to each event sink
+func (ms *MemoryStorage) GetSinkOffsets(ctx context.Context, chain string) (map[string]uint64, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	offsets := make(map[string]uint64, len(ms.sinks[chain]))
+	for sink, block := range ms.sinks[chain] {
+		offsets[sink] = block
+	}
+
+	return offsets, nil
+}
+
+// SaveSinkOffset records the last block delivered to a sink
+func (ms *MemoryStorage) SaveSinkOffset(ctx context.Context, sink, chain string, block uint64) error {
+	ms.lock(indexTable)
+	defer ms.mu.Unlock()
+
+	if ms.sinks[chain] == nil {
+		ms.sinks[chain] = make(map[string]uint64)
+	}
+	ms.sinks[chain][sink] = block
+
+	return nil
+}
+
+// CommitBatch applies all writes of a batch under one lock
+func (ms *MemoryStorage) CommitBatch(ctx context.Context, batch *models.EventBatch) error {
+	ms.lock(indexTable)
+	defer ms.mu.Unlock()
+
+	ms.saveEvents(batch.Events)
+	for _, block := range batch.Blocks {
+		ms.saveBlock(block)
+	}
+	ms.saveCheckpoints(batch.Checkpoints)
+	if batch.Failed != nil {
+		ms.saveFailedRange(batch.Failed)
+	}
+	if batch.Resolved != 0 {
+		delete(ms.failed, batch.Resolved)
+	}
+	ms.saveDeadLetters(batch.DeadLetters)
+	for _, id := range batch.Replayed {
+		delete(ms.deadLetters, id)
+	}
+
+	return nil
+}
+
+// checkpointBefore returns the checkpoint that makes indexing resume at block
+func checkpointBefore(block uint64) uint64 {
+	if block == 0 {
+		return 0
+	}
+	return block - 1
+}
@@ -0,0 +1,184 @@
+// internal/storage/memory/history.go
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// GetTVLHistory retrieves the snapshots, or the rollups of the filter's
+// resolution, by time then ID
+func (ms *MemoryStorage) GetTVLHistory(ctx context.Context, filter storage.TVLFilter) ([]*models.TVLSnapshot, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	source := ms.snapshots
+	if !filter.Resolution.IsRaw() {
+		source = ms.rollups
+	}
+
+	var results []*models.TVLSnapshot
+	for _, snap := range source {
+		if matchesTVL(snap, filter) {
+			results = append(results, snap)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return newerSnapshot(results[j], results[i])
+	})
+
+	if filter.Limit > 0 && len(results) > filter.Limit {
+		results = results[:filter.Limit]
+	}
+
+	return results, nil
+}
+
+// GetTokenHoldings retrieves the assets of the snapshots, or the rollups of
+// the filter's resolution, by time then snapshot ID then token
+func (ms *MemoryStorage) GetTokenHoldings(ctx context.Context, filter storage.HoldingFilter) ([]*models.TokenHolding, error) {
+	return storage.TokenHoldings(ctx, ms, filter)
+}
+
+func matchesTVL(snap *models.TVLSnapshot, filter storage.TVLFilter) bool {
+	return (filter.Protocol == "" || snap.Protocol == filter.Protocol) &&
+		(filter.Chain == "" || snap.Chain == filter.Chain) &&
+		(filter.Resolution.IsRaw() || snap.Resolution == filter.Resolution) &&
+		(filter.From.IsZero() || !snap.Timestamp.Before(filter.From)) &&
+		(filter.To.IsZero() || !snap.Timestamp.After(filter.To))
+}
+
+// SaveTVLRollups saves rollups, replacing the rollup of the same protocol,
+// chain, resolution and interval
+func (ms *MemoryStorage) SaveTVLRollups(ctx context.Context, rollups []*models.TVLSnapshot) error {
+	for _, rollup := range rollups {
+		if rollup.Resolution.IsRaw() || rollup.Range == nil {
+			return fmt.Errorf("not a rollup: %s on %s at %s", rollup.Protocol, rollup.Chain, rollup.Timestamp)
+		}
+	}
+
+	ms.lock(tvlTable)
+	defer ms.mu.Unlock()
+
+	for _, rollup := range rollups {
+		if rollup.Status == "" {
+			rollup.Status = models.FinalityTentative
+		}
+
+		replaced := false
+		for i, existing := range ms.rollups {
+			if existing.Protocol == rollup.Protocol && existing.Chain == rollup.Chain &&
+				existing.Resolution == rollup.Resolution && existing.Timestamp.Equal(rollup.Timestamp) {
+				rollup.ID = existing.ID
+				ms.rollups[i] = rollup
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			ms.nextRollup++
+			rollup.ID = ms.nextRollup
+			ms.rollups = append(ms.rollups, rollup)
+		}
+	}
+
+	return nil
+}
+
+// FinalizeTVL marks the snapshots and rollups of a chain up to and including
+// block as final
+func (ms *MemoryStorage) FinalizeTVL(ctx context.Context, chain string, block uint64) error {
+	ms.lock(tvlTable)
+	defer ms.mu.Unlock()
+
+	// Snapshots are shared with readers and transactions, so they are
+	// replaced rather than changed
+	for _, snapshots := range [][]*models.TVLSnapshot{ms.snapshots, ms.rollups} {
+		for i, snap := range snapshots {
+			if snap.Chain == chain && snap.BlockNumber > 0 && snap.BlockNumber <= block &&
+				snap.Status != models.FinalityFinal {
+				finalized := *snap
+				finalized.Status = models.FinalityFinal
+				snapshots[i] = &finalized
+			}
+		}
+	}
+
+	return nil
+}
+
+// RollbackTVL deletes the snapshots of a chain at or above fromBlock, with
+// their holdings, and the rollups closing there
+func (ms *MemoryStorage) RollbackTVL(ctx context.Context, chain string, fromBlock uint64) error {
+	ms.lock(tvlTable)
+	defer ms.mu.Unlock()
+
+	reorged := func(snap *models.TVLSnapshot) bool {
+		return snap.Chain == chain && snap.BlockNumber > 0 && snap.BlockNumber >= fromBlock
+	}
+
+	snapshots := make([]*models.TVLSnapshot, 0, len(ms.snapshots))
+	for _, snap := range ms.snapshots {
+		if !reorged(snap) {
+			snapshots = append(snapshots, snap)
+		}
+	}
+	ms.snapshots = snapshots
+
+	rollups := make([]*models.TVLSnapshot, 0, len(ms.rollups))
+	for _, rollup := range ms.rollups {
+		if !reorged(rollup) {
+			rollups = append(rollups, rollup)
+		}
+	}
+	ms.rollups = rollups
+
+	return nil
+}
+
+// DeleteTVLHistory deletes the snapshots, or the rollups of a resolution,
+// from before a time. The latest snapshot of each protocol and chain is kept.
+func (ms *MemoryStorage) DeleteTVLHistory(ctx context.Context, resolution models.Resolution, before time.Time) (uint64, error) {
+	ms.lock(tvlTable)
+	defer ms.mu.Unlock()
+
+	var deleted uint64
+	if resolution.IsRaw() {
+		latest := make(map[[2]string]*models.TVLSnapshot)
+		for _, snap := range ms.snapshots {
+			key := [2]string{snap.Protocol, snap.Chain}
+			if latest[key] == nil || newerSnapshot(snap, latest[key]) {
+				latest[key] = snap
+			}
+		}
+
+		kept := make([]*models.TVLSnapshot, 0, len(ms.snapshots))
+		for _, snap := range ms.snapshots {
+			if snap.Timestamp.Before(before) && latest[[2]string{snap.Protocol, snap.Chain}] != snap {
+				deleted++
+				continue
+			}
+			kept = append(kept, snap)
+		}
+		ms.snapshots = kept
+		return deleted, nil
+	}
+
+	kept := make([]*models.TVLSnapshot, 0, len(ms.rollups))
+	for _, rollup := range ms.rollups {
+		if rollup.Resolution == resolution && rollup.Timestamp.Before(before) {
+			deleted++
+			continue
+		}
+		kept = append(kept, rollup)
+	}
+	ms.rollups = kept
+
+	return deleted, nil
+}
@@ -3,7 +3,8 @@ package memory
 import (
 	"context"
 	"fmt"
-	"math/big"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,24 +14,54 @@ import (
 
 // MemoryStorage implements Storage interface in memory
 type MemoryStorage struct {
-	protocols map[string]*models.Protocol
-	snapshots []*models.TVLSnapshot
-	chains    map[string]*models.Chain
-	events    []*models.Event
-	blocks    map[string]uint64
-	tokens    map[string]*models.Token
-	mu        sync.RWMutex
+	protocols  map[string]*models.Protocol
+	snapshots  []*models.TVLSnapshot
+	rollups    []*models.TVLSnapshot
+	chains     map[string]*models.Chain
+	chainStats map[string]*models.ChainStats
+	events     []*models.Event
+	blocks     map[string]map[uint64]*models.Block // chain -> number -> block
+	tokens     map[string]*models.Token            // chain-address -> token
+	prices     map[uint64]*models.TokenPrice       // token id -> latest price
+	mu         sync.RWMutex
+
+	checkpoints map[string]map[string]*models.Checkpoint // chain -> contract -> checkpoint
+	sinks       map[string]map[string]uint64             // chain -> sink -> offset
+	deadLetters map[uint64]*models.DeadLetter
+	nextLetter  uint64
+	failed      map[uint64]*models.FailedRange
+	nextEvent   uint64
+	nextFailed  uint64
+
+	nextProtocol uint64
+	nextSnapshot uint64
+	nextRollup   uint64
+	nextChain    uint64
+	nextToken    uint64
+	nextPrice    uint64
+
+	// versions counts the writes to each table, so transactions can tell
+	// what changed under them
+	versions [tables]uint64
 }
 
 // NewMemoryStorage creates a new in-memory storage
 func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
-		protocols: make(map[string]*models.Protocol),
-		snapshots: make([]*models.TVLSnapshot, 0),
-		chains:    make(map[string]*models.Chain),
-		events:    make([]*models.Event, 0),
-		blocks:    make(map[string]uint64),
-		tokens:    make(map[string]*models.Token),
+		protocols:  make(map[string]*models.Protocol),
+		snapshots:  make([]*models.TVLSnapshot, 0),
+		rollups:    make([]*models.TVLSnapshot, 0),
+		chains:     make(map[string]*models.Chain),
+		chainStats: make(map[string]*models.ChainStats),
+		events:     make([]*models.Event, 0),
+		blocks:     make(map[string]map[uint64]*models.Block),
+		tokens:     make(map[string]*models.Token),
+		prices:     make(map[uint64]*models.TokenPrice),
+
+		checkpoints: make(map[string]map[string]*models.Checkpoint),
+		sinks:       make(map[string]map[string]uint64),
+		deadLetters: make(map[uint64]*models.DeadLetter),
+		failed:      make(map[uint64]*models.FailedRange),
 	}
 }
 
@@ -46,7 +77,7 @@ func (ms *MemoryStorage) GetProtocol(ctx context.Context, name string) (*models.
 	return protocol, nil
 }
 
-// GetProtocols retrieves all protocols
+// GetProtocols retrieves all protocols by name
 func (ms *MemoryStorage) GetProtocols(ctx context.Context) ([]*models.Protocol, error) {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
@@ -55,229 +86,306 @@ func (ms *MemoryStorage) GetProtocols(ctx context.Context) ([]*models.Protocol,
 	for _, protocol := range ms.protocols {
 		protocols = append(protocols, protocol)
 	}
+	sort.Slice(protocols, func(i, j int) bool {
+		return protocols[i].Name < protocols[j].Name
+	})
 	return protocols, nil
 }
 
-// SaveProtocol saves a protocol
+// SaveProtocol saves a new protocol
 func (ms *MemoryStorage) SaveProtocol(ctx context.Context, protocol *models.Protocol) error {
-	ms.mu.Lock()
+	ms.lock(protocolTable)
 	defer ms.mu.Unlock()
 
-	if protocol.ID == 0 {
-		protocol.ID = uint64(len(ms.protocols) + 1)
+	if _, exists := ms.protocols[protocol.Name]; exists {
+		return fmt.Errorf("protocol already exists: %s", protocol.Name)
 	}
+
+	ms.nextProtocol++
+	protocol.ID = ms.nextProtocol
 	protocol.CreatedAt = time.Now()
-	protocol.UpdatedAt = time.Now()
+	protocol.UpdatedAt = protocol.CreatedAt
 
 	ms.protocols[protocol.Name] = protocol
 	return nil
 }
 
-// SaveTVLSnapshot saves a TVL snapshot
-func (ms *MemoryStorage) SaveTVLSnapshot(ctx context.Context, snapshot *models.TVLSnapshot) error {
-	ms.mu.Lock()
+// UpdateProtocol updates a protocol by name and replaces its contracts
+func (ms *MemoryStorage) UpdateProtocol(ctx context.Context, protocol *models.Protocol) error {
+	ms.lock(protocolTable)
 	defer ms.mu.Unlock()
 
-	snapshot.ID = uint64(len(ms.snapshots) + 1)
-	ms.snapshots = append(ms.snapshots, snapshot)
+	existing, exists := ms.protocols[protocol.Name]
+	if !exists {
+		return fmt.Errorf("protocol not found: %s", protocol.Name)
+	}
 
-	total, _ := snapshot.TotalUSD.Float64()
-	fmt.Printf("💾 Saved TVL: %s = $%.2f\n", snapshot.Protocol, total)
+	protocol.ID = existing.ID
+	protocol.CreatedAt = existing.CreatedAt
+	protocol.UpdatedAt = time.Now()
 
+	ms.protocols[protocol.Name] = protocol
 	return nil
 }
 
-// GetLatestTVL retrieves the latest TVL
-func (ms *MemoryStorage) GetLatestTVL(ctx context.Context, protocol, chain string) (*models.TVLSnapshot, error) {
-	ms.mu.RLock()
-	defer ms.mu.RUnlock()
+// DeleteProtocol deletes a protocol. Its TVL snapshots are kept.
+func (ms *MemoryStorage) DeleteProtocol(ctx context.Context, name string) error {
+	ms.lock(protocolTable)
+	defer ms.mu.Unlock()
 
-	for i := len(ms.snapshots) - 1; i >= 0; i-- {
-		snap := ms.snapshots[i]
-		if snap.Protocol == protocol {
-			if chain == "" || snap.Chain == chain {
-				return snap, nil
-			}
-		}
+	if _, exists := ms.protocols[name]; !exists {
+		return fmt.Errorf("protocol not found: %s", name)
 	}
-
-	return nil, fmt.Errorf("no TVL snapshot found")
+	delete(ms.protocols, name)
+	return nil
 }
 
-// GetHistoricalTVL retrieves historical TVL data
-func (ms *MemoryStorage) GetHistoricalTVL(ctx context.Context, protocol, chain string, from, to time.Time) ([]*models.TVLSnapshot, error) {
-	ms.mu.RLock()
-	defer ms.mu.RUnlock()
+// SaveTVLSnapshot saves a TVL snapshot. Saving the same protocol, chain and
+// block, or time for a snapshot without a block, again replaces it.
+func (ms *MemoryStorage) SaveTVLSnapshot(ctx context.Context, snapshot *models.TVLSnapshot) error {
+	ms.lock(tvlTable)
+	defer ms.mu.Unlock()
 
-	var results []*models.TVLSnapshot
-	for _, snap := range ms.snapshots {
-		if snap.Protocol == protocol &&
-			snap.Timestamp.After(from) &&
-			snap.Timestamp.Before(to) {
+	if snapshot.Status == "" {
+		snapshot.Status = models.FinalityTentative
+	}
 
-			if chain == "" || snap.Chain == chain {
-				results = append(results, snap)
-			}
+	replaced := false
+	for i, existing := range ms.snapshots {
+		if existing.Protocol == snapshot.Protocol && existing.Chain == snapshot.Chain && existing.BlockNumber == snapshot.BlockNumber &&
+			(snapshot.BlockNumber > 0 || existing.Timestamp.Equal(snapshot.Timestamp)) {
+			snapshot.ID = existing.ID
+			ms.snapshots[i] = snapshot
+			replaced = true
+			break
 		}
 	}
-
-	return results, nil
-}
-
-// SaveEvents saves events
-func (ms *MemoryStorage) SaveEvents(ctx context.Context, events []*models.Event) error {
-	ms.mu.Lock()
-	defer ms.mu.Unlock()
-
-	for _, event := range events {
-		event.ID = uint64(len(ms.events) + 1)
-		ms.events = append(ms.events, event)
+	if !replaced {
+		ms.nextSnapshot++
+		snapshot.ID = ms.nextSnapshot
+		ms.snapshots = append(ms.snapshots, snapshot)
 	}
 
+	total, _ := snapshot.TotalUSD.Float64()
+	fmt.Printf("💾 Saved TVL: %s = $%.2f\n", snapshot.Protocol, total)
+
 	return nil
 }
 
-// GetEvents retrieves events
-func (ms *MemoryStorage) GetEvents(ctx context.Context, filter storage.EventFilter) ([]*models.Event, error) {
+// GetLatestTVL retrieves the latest TVL snapshot of a protocol, on any chain
+// when chain is empty
+func (ms *MemoryStorage) GetLatestTVL(ctx context.Context, protocol, chain string) (*models.TVLSnapshot, error) {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 
-	var results []*models.Event
-	for _, event := range ms.events {
-		if (filter.Chain == "" || event.Chain == filter.Chain) &&
-			(filter.Protocol == "" || event.Protocol == filter.Protocol) &&
-			(filter.EventName == "" || event.EventName == filter.EventName) {
-
-			if filter.FromBlock > 0 && event.BlockNumber < filter.FromBlock {
-				continue
-			}
-			if filter.ToBlock > 0 && event.BlockNumber > filter.ToBlock {
-				continue
-			}
-
-			results = append(results, event)
+	var latest *models.TVLSnapshot
+	for _, snap := range ms.snapshots {
+		if snap.Protocol != protocol || (chain != "" && snap.Chain != chain) {
+			continue
+		}
+		if latest == nil || newerSnapshot(snap, latest) {
+			latest = snap
 		}
 	}
 
-	// Apply limit
-	if filter.Limit > 0 && len(results) > filter.Limit {
-		results = results[:filter.Limit]
+	if latest == nil {
+		return nil, fmt.Errorf("no TVL snapshot found")
 	}
-
-	return results, nil
+	return latest, nil
 }
 
-// SaveBlock saves a block
-func (ms *MemoryStorage) SaveBlock(ctx context.Context, block *models.Block) error {
-	ms.mu.Lock()
-	defer ms.mu.Unlock()
-
-	ms.blocks[block.Chain] = block.Number
-	return nil
+// GetHistoricalTVL retrieves the TVL of a protocol in [from, to] by time, on
+// every chain when chain is empty, at the resolution the window calls for
+func (ms *MemoryStorage) GetHistoricalTVL(ctx context.Context, protocol, chain string, from, to time.Time) ([]*models.TVLSnapshot, error) {
+	return storage.History(ctx, ms, protocol, chain, from, to)
 }
 
-// GetLastIndexedBlock gets the last indexed block
-func (ms *MemoryStorage) GetLastIndexedBlock(ctx context.Context, chain string) (uint64, error) {
+// GetTVLByBlock retrieves the snapshot of a protocol at a block
+func (ms *MemoryStorage) GetTVLByBlock(ctx context.Context, protocol, chain string, blockNumber uint64) (*models.TVLSnapshot, error) {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 
-	block, exists := ms.blocks[chain]
-	if !exists {
-		return 0, nil
+	// Snapshots without a block are at no block
+	var found *models.TVLSnapshot
+	for _, snap := range ms.snapshots {
+		if blockNumber == 0 || snap.Protocol != protocol || snap.BlockNumber != blockNumber || (chain != "" && snap.Chain != chain) {
+			continue
+		}
+		if found == nil || snap.Chain < found.Chain || (snap.Chain == found.Chain && snap.ID < found.ID) {
+			found = snap
+		}
 	}
-	return block, nil
-}
-
-// Additional interface implementations...
-func (ms *MemoryStorage) GetAggregatedTVL(ctx context.Context) (*models.AggregatedTVL, error) {
-	ms.mu.RLock()
-	defer ms.mu.RUnlock()
 
-	// Calculate aggregated TVL from snapshots
-	aggregated := &models.AggregatedTVL{
-		Timestamp:   time.Now(),
-		Protocols:   make(map[string]*models.TVLData),
-		TotalUSD:    big.NewFloat(0),
-		ChainTotals: make(map[string]*big.Float),
+	if found == nil {
+		return nil, fmt.Errorf("no TVL snapshot found for block %d", blockNumber)
 	}
-
-	return aggregated, nil
+	return found, nil
 }
 
-func (ms *MemoryStorage) GetTVLByBlock(ctx context.Context, protocol, chain string, blockNumber uint64) (*models.TVLSnapshot, error) {
+// GetAggregatedTVL sums the latest snapshot of every protocol on every chain
+func (ms *MemoryStorage) GetAggregatedTVL(ctx context.Context) (*models.AggregatedTVL, error) {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 
+	latest := make(map[[2]string]*models.TVLSnapshot)
 	for _, snap := range ms.snapshots {
-		if snap.Protocol == protocol && 
-		   snap.BlockNumber == blockNumber {
-			if chain == "" || snap.Chain == chain {
-				return snap, nil
-			}
+		key := [2]string{snap.Protocol, snap.Chain}
+		if latest[key] == nil || newerSnapshot(snap, latest[key]) {
+			latest[key] = snap
 		}
 	}
 
-	return nil, fmt.Errorf("no TVL snapshot found for block %d", blockNumber)
+	snapshots := make([]*models.TVLSnapshot, 0, len(latest))
+	for _, snap := range latest {
+		snapshots = append(snapshots, snap)
+	}
+
+	return storage.Aggregate(snapshots), nil
 }
 
+// newerSnapshot orders snapshots by time, then by ID
+func newerSnapshot(a, b *models.TVLSnapshot) bool {
+	if !a.Timestamp.Equal(b.Timestamp) {
+		return a.Timestamp.After(b.Timestamp)
+	}
+	return a.ID > b.ID
+}
+
+// UpdateChainStats replaces the statistics of a chain
 func (ms *MemoryStorage) UpdateChainStats(ctx context.Context, stats *models.ChainStats) error {
-	// Not implemented for memory storage
+	ms.lock(chainTable)
+	defer ms.mu.Unlock()
+
+	copied := *stats
+	if copied.LastUpdateTime.IsZero() {
+		copied.LastUpdateTime = time.Now()
+	}
+	ms.chainStats[stats.Chain] = &copied
 	return nil
 }
 
+// tokenKey keys tokens by chain and address, ignoring the address's case
+func tokenKey(chain, address string) string {
+	return fmt.Sprintf("%s-%s", chain, strings.ToLower(address))
+}
+
+// GetToken retrieves a token with its latest price
 func (ms *MemoryStorage) GetToken(ctx context.Context, address, chain string) (*models.Token, error) {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 
-	key := fmt.Sprintf("%s-%s", chain, address)
-	token, exists := ms.tokens[key]
+	token, exists := ms.tokens[tokenKey(chain, address)]
 	if !exists {
 		return nil, fmt.Errorf("token not found")
 	}
-	return token, nil
+
+	copied := *token
+	copied.PriceUSD = nil
+	if price := ms.prices[token.ID]; price != nil {
+		copied.PriceUSD = price.PriceUSD
+	}
+	return &copied, nil
 }
 
+// SaveToken saves a token, replacing the token at the same chain and address
 func (ms *MemoryStorage) SaveToken(ctx context.Context, token *models.Token) error {
-	ms.mu.Lock()
+	ms.lock(tokenTable)
 	defer ms.mu.Unlock()
 
-	key := fmt.Sprintf("%s-%s", token.Chain, token.Address.Hex())
+	key := tokenKey(token.Chain, token.Address.Hex())
+	if existing, exists := ms.tokens[key]; exists {
+		token.ID = existing.ID
+		token.CreatedAt = existing.CreatedAt
+	} else {
+		ms.nextToken++
+		token.ID = ms.nextToken
+		token.CreatedAt = time.Now()
+	}
+	token.UpdatedAt = time.Now()
+
 	ms.tokens[key] = token
 	return nil
 }
 
+// UpdateTokenPrice records a price of the token with TokenID, or of every
+// stored token at Address when it is not set
 func (ms *MemoryStorage) UpdateTokenPrice(ctx context.Context, price *models.TokenPrice) error {
-	// Not fully implemented for memory storage
+	ms.lock(tokenTable)
+	defer ms.mu.Unlock()
+
+	timestamp := price.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	updated := false
+	for _, token := range ms.tokens {
+		if price.TokenID > 0 && token.ID != price.TokenID {
+			continue
+		}
+		if price.TokenID == 0 && token.Address != price.Address {
+			continue
+		}
+
+		ms.nextPrice++
+		recorded := *price
+		recorded.ID = ms.nextPrice
+		recorded.TokenID = token.ID
+		recorded.Address = token.Address
+		recorded.Symbol = token.Symbol
+		recorded.Timestamp = timestamp
+
+		if latest := ms.prices[token.ID]; latest == nil || !latest.Timestamp.After(timestamp) {
+			ms.prices[token.ID] = &recorded
+		}
+		updated = true
+	}
+
+	if !updated {
+		return fmt.Errorf("token not found: %s", price.Address.Hex())
+	}
 	return nil
 }
 
+// GetTokenPrices retrieves the latest price of each address, keyed by the
+// address as given. Addresses without a price are left out.
 func (ms *MemoryStorage) GetTokenPrices(ctx context.Context, addresses []string) (map[string]*models.TokenPrice, error) {
-	// Not fully implemented for memory storage
-	return make(map[string]*models.TokenPrice), nil
-}
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
 
-func (ms *MemoryStorage) UpdateProtocol(ctx context.Context, protocol *models.Protocol) error {
-	return ms.SaveProtocol(ctx, protocol)
-}
+	latest := make(map[string]*models.TokenPrice)
+	for _, price := range ms.prices {
+		address := strings.ToLower(price.Address.Hex())
+		current := latest[address]
+		if current == nil || price.Timestamp.After(current.Timestamp) ||
+			(price.Timestamp.Equal(current.Timestamp) && price.ID > current.ID) {
+			latest[address] = price
+		}
+	}
 
-func (ms *MemoryStorage) DeleteProtocol(ctx context.Context, name string) error {
-	ms.mu.Lock()
-	defer ms.mu.Unlock()
-	delete(ms.protocols, name)
-	return nil
+	prices := make(map[string]*models.TokenPrice)
+	for _, address := range addresses {
+		if price, ok := latest[strings.ToLower(address)]; ok {
+			copied := *price
+			prices[address] = &copied
+		}
+	}
+	return prices, nil
 }
 
+// GetChain retrieves a chain by name
 func (ms *MemoryStorage) GetChain(ctx context.Context, name string) (*models.Chain, error) {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 
 	chain, exists := ms.chains[name]
 	if !exists {
-		return nil, fmt.Errorf("chain not found")
+		return nil, fmt.Errorf("chain not found: %s", name)
 	}
 	return chain, nil
 }
 
+// GetChains retrieves all chains by name
 func (ms *MemoryStorage) GetChains(ctx context.Context) ([]*models.Chain, error) {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
@@ -286,130 +394,45 @@ func (ms *MemoryStorage) GetChains(ctx context.Context) ([]*models.Chain, error)
 	for _, chain := range ms.chains {
 		chains = append(chains, chain)
 	}
+	sort.Slice(chains, func(i, j int) bool {
+		return chains[i].Name < chains[j].Name
+	})
 	return chains, nil
 }
 
+// SaveChain saves a chain, replacing the chain of the same name
 func (ms *MemoryStorage) SaveChain(ctx context.Context, chain *models.Chain) error {
-	ms.mu.Lock()
+	if chain.ChainID == nil || !chain.ChainID.IsInt64() {
+		return fmt.Errorf("invalid chain id for %s", chain.Name)
+	}
+
+	ms.lock(chainTable)
 	defer ms.mu.Unlock()
 
-	if chain.ID == 0 {
-		chain.ID = uint64(len(ms.chains) + 1)
+	if existing, exists := ms.chains[chain.Name]; exists {
+		chain.ID = existing.ID
+		chain.CreatedAt = existing.CreatedAt
+	} else {
+		ms.nextChain++
+		chain.ID = ms.nextChain
+		chain.CreatedAt = time.Now()
 	}
 	ms.chains[chain.Name] = chain
 	return nil
 }
 
-func (ms *MemoryStorage) BeginTx(ctx context.Context) (storage.Tx, error) {
-	// Memory storage doesn't support transactions
-	return &MemoryTx{storage: ms}, nil
-}
+// DeleteChain deletes a chain. Its statistics, events and TVL are kept.
+func (ms *MemoryStorage) DeleteChain(ctx context.Context, name string) error {
+	ms.lock(chainTable)
+	defer ms.mu.Unlock()
 
-func (ms *MemoryStorage) Close() error {
+	if _, exists := ms.chains[name]; !exists {
+		return fmt.Errorf("chain not found: %s", name)
+	}
+	delete(ms.chains, name)
 	return nil
 }
 
-// MemoryTx is a mock transaction
-type MemoryTx struct {
-	storage *MemoryStorage
-}
-
-func (mt *MemoryTx) Commit() error   { return nil }
-func (mt *MemoryTx) Rollback() error { return nil }
-
-// Delegate all methods to the underlying storage
-func (mt *MemoryTx) GetProtocol(ctx context.Context, name string) (*models.Protocol, error) {
-	return mt.storage.GetProtocol(ctx, name)
-}
-
-func (mt *MemoryTx) GetProtocols(ctx context.Context) ([]*models.Protocol, error) {
-	return mt.storage.GetProtocols(ctx)
-}
-
-func (mt *MemoryTx) SaveProtocol(ctx context.Context, protocol *models.Protocol) error {
-	return mt.storage.SaveProtocol(ctx, protocol)
-}
-
-func (mt *MemoryTx) UpdateProtocol(ctx context.Context, protocol *models.Protocol) error {
-	return mt.storage.UpdateProtocol(ctx, protocol)
-}
-
-func (mt *MemoryTx) DeleteProtocol(ctx context.Context, name string) error {
-	return mt.storage.DeleteProtocol(ctx, name)
-}
-
-func (mt *MemoryTx) SaveTVLSnapshot(ctx context.Context, snapshot *models.TVLSnapshot) error {
-	return mt.storage.SaveTVLSnapshot(ctx, snapshot)
-}
-
-func (mt *MemoryTx) GetLatestTVL(ctx context.Context, protocol, chain string) (*models.TVLSnapshot, error) {
-	return mt.storage.GetLatestTVL(ctx, protocol, chain)
-}
-
-func (mt *MemoryTx) GetHistoricalTVL(ctx context.Context, protocol, chain string, from, to time.Time) ([]*models.TVLSnapshot, error) {
-	return mt.storage.GetHistoricalTVL(ctx, protocol, chain, from, to)
-}
-
-func (mt *MemoryTx) GetTVLByBlock(ctx context.Context, protocol, chain string, blockNumber uint64) (*models.TVLSnapshot, error) {
-	return mt.storage.GetTVLByBlock(ctx, protocol, chain, blockNumber)
-}
-
-func (mt *MemoryTx) GetAggregatedTVL(ctx context.Context) (*models.AggregatedTVL, error) {
-	return mt.storage.GetAggregatedTVL(ctx)
-}
-
-func (mt *MemoryTx) GetChain(ctx context.Context, name string) (*models.Chain, error) {
-	return mt.storage.GetChain(ctx, name)
-}
-
-func (mt *MemoryTx) GetChains(ctx context.Context) ([]*models.Chain, error) {
-	return mt.storage.GetChains(ctx)
-}
-
-func (mt *MemoryTx) SaveChain(ctx context.Context, chain *models.Chain) error {
-	return mt.storage.SaveChain(ctx, chain)
-}
-
-func (mt *MemoryTx) UpdateChainStats(ctx context.Context, stats *models.ChainStats) error {
-	return mt.storage.UpdateChainStats(ctx, stats)
-}
-
-func (mt *MemoryTx) SaveEvents(ctx context.Context, events []*models.Event) error {
-	return mt.storage.SaveEvents(ctx, events)
-}
-
-func (mt *MemoryTx) GetEvents(ctx context.Context, filter storage.EventFilter) ([]*models.Event, error) {
-	return mt.storage.GetEvents(ctx, filter)
-}
-
-func (mt *MemoryTx) SaveBlock(ctx context.Context, block *models.Block) error {
-	return mt.storage.SaveBlock(ctx, block)
-}
-
-func (mt *MemoryTx) GetLastIndexedBlock(ctx context.Context, chain string) (uint64, error) {
-	return mt.storage.GetLastIndexedBlock(ctx, chain)
-}
-
-func (mt *MemoryTx) GetToken(ctx context.Context, address, chain string) (*models.Token, error) {
-	return mt.storage.GetToken(ctx, address, chain)
-}
-
-func (mt *MemoryTx) SaveToken(ctx context.Context, token *models.Token) error {
-	return mt.storage.SaveToken(ctx, token)
-}
-
-func (mt *MemoryTx) UpdateTokenPrice(ctx context.Context, price *models.TokenPrice) error {
-	return mt.storage.UpdateTokenPrice(ctx, price)
-}
-
-func (mt *MemoryTx) GetTokenPrices(ctx context.Context, addresses []string) (map[string]*models.TokenPrice, error) {
-	return mt.storage.GetTokenPrices(ctx, addresses)
-}
-
-func (mt *MemoryTx) BeginTx(ctx context.Context) (storage.Tx, error) {
-	return mt.storage.BeginTx(ctx)
-}
-
-func (mt *MemoryTx) Close() error {
-	return mt.storage.Close()
+func (ms *MemoryStorage) Close() error {
+	return nil
 }
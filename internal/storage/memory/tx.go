@@ -0,0 +1,458 @@
+// internal/storage/memory/tx.go
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// ErrConflict is returned by Commit when a table the transaction wrote to
+// was written to by someone else after the transaction copied it
+var ErrConflict = errors.New("memory: storage changed during the transaction")
+
+// table groups the data transactions check for conflicting writes
+type table int
+
+const (
+	protocolTable table = iota
+	tvlTable            // snapshots and rollups
+	chainTable          // chains and their statistics
+	tokenTable          // tokens and their prices
+	indexTable          // events, blocks, checkpoints, sink offsets, dead letters and failed ranges
+	tables
+)
+
+// lock takes the write lock and counts a write to t
+func (ms *MemoryStorage) lock(t table) {
+	ms.mu.Lock()
+	ms.versions[t]++
+}
+
+// BeginTx starts a transaction. It reads through to the storage until it
+// writes to a table, then works on a private copy of that table. Commit
+// swaps in the tables the transaction wrote to and keeps the others, so
+// writes to other tables meanwhile don't conflict with it.
+func (ms *MemoryStorage) BeginTx(ctx context.Context) (storage.Tx, error) {
+	return &MemoryTx{parent: ms, state: NewMemoryStorage()}, nil
+}
+
+// MemoryTx is a copy-on-write transaction
+type MemoryTx struct {
+	parent *MemoryStorage
+
+	mu     sync.Mutex
+	state  *MemoryStorage // copies of the tables the transaction touched
+	copied [tables]bool
+	base   [tables]uint64 // parent versions the copies were taken at
+	done   bool
+}
+
+// read returns the storage reads of t go to
+func (mt *MemoryTx) read(t table) *MemoryStorage {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	if mt.copied[t] {
+		return mt.state
+	}
+	return mt.parent
+}
+
+// write returns the private copy, taking a copy of t on its first write
+func (mt *MemoryTx) write(t table) (*MemoryStorage, error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	if mt.done {
+		return nil, sql.ErrTxDone
+	}
+	if !mt.copied[t] {
+		mt.parent.mu.RLock()
+		mt.state.mu.Lock()
+		mt.state.copyTable(mt.parent, t)
+		mt.base[t] = mt.parent.versions[t]
+		mt.state.versions[t] = mt.base[t]
+		mt.state.mu.Unlock()
+		mt.parent.mu.RUnlock()
+		mt.copied[t] = true
+	}
+	return mt.state, nil
+}
+
+// Commit swaps in the tables the transaction wrote to, failing with
+// ErrConflict if one of them was written to since it was copied
+func (mt *MemoryTx) Commit() error {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	if mt.done {
+		return sql.ErrTxDone
+	}
+	mt.done = true
+
+	mt.parent.mu.Lock()
+	defer mt.parent.mu.Unlock()
+	mt.state.mu.RLock()
+	defer mt.state.mu.RUnlock()
+
+	// A copy starts at its base version, the transaction's writes move it on
+	var written []table
+	for t := table(0); t < tables; t++ {
+		if !mt.copied[t] || mt.state.versions[t] == mt.base[t] {
+			continue
+		}
+		if mt.parent.versions[t] != mt.base[t] {
+			return ErrConflict
+		}
+		written = append(written, t)
+	}
+
+	for _, t := range written {
+		mt.parent.take(mt.state, t)
+		mt.parent.versions[t]++
+	}
+
+	return nil
+}
+
+// Rollback discards the transaction's writes
+func (mt *MemoryTx) Rollback() error {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	if mt.done {
+		return sql.ErrTxDone
+	}
+	mt.done = true
+	mt.state = NewMemoryStorage()
+	mt.copied = [tables]bool{}
+
+	return nil
+}
+
+// BeginTx starts a transaction on top of this one, committed into it. The
+// nested transaction works on a full copy, so every table is copied first.
+func (mt *MemoryTx) BeginTx(ctx context.Context) (storage.Tx, error) {
+	var state *MemoryStorage
+	for t := table(0); t < tables; t++ {
+		var err error
+		if state, err = mt.write(t); err != nil {
+			return nil, err
+		}
+	}
+	return state.BeginTx(ctx)
+}
+
+// Close rolls the transaction back unless it was committed
+func (mt *MemoryTx) Close() error {
+	if err := mt.Rollback(); err != nil && err != sql.ErrTxDone {
+		return err
+	}
+	return nil
+}
+
+// copyTable copies a table of another storage, sharing nothing it mutates
+// in place; must be called with both locks held
+func (ms *MemoryStorage) copyTable(other *MemoryStorage, t table) {
+	switch t {
+	case protocolTable:
+		ms.protocols = make(map[string]*models.Protocol, len(other.protocols))
+		for name, protocol := range other.protocols {
+			ms.protocols[name] = protocol
+		}
+		ms.nextProtocol = other.nextProtocol
+	case tvlTable:
+		ms.snapshots = append([]*models.TVLSnapshot(nil), other.snapshots...)
+		ms.rollups = append([]*models.TVLSnapshot(nil), other.rollups...)
+		ms.nextSnapshot = other.nextSnapshot
+		ms.nextRollup = other.nextRollup
+	case chainTable:
+		ms.chains = make(map[string]*models.Chain, len(other.chains))
+		for name, chain := range other.chains {
+			ms.chains[name] = chain
+		}
+		ms.chainStats = make(map[string]*models.ChainStats, len(other.chainStats))
+		for name, stats := range other.chainStats {
+			ms.chainStats[name] = stats
+		}
+		ms.nextChain = other.nextChain
+	case tokenTable:
+		ms.tokens = make(map[string]*models.Token, len(other.tokens))
+		for key, token := range other.tokens {
+			ms.tokens[key] = token
+		}
+		ms.prices = make(map[uint64]*models.TokenPrice, len(other.prices))
+		for id, price := range other.prices {
+			ms.prices[id] = price
+		}
+		ms.nextToken = other.nextToken
+		ms.nextPrice = other.nextPrice
+	case indexTable:
+		ms.events = make([]*models.Event, len(other.events))
+		for i, event := range other.events {
+			copied := *event
+			ms.events[i] = &copied
+		}
+		ms.blocks = make(map[string]map[uint64]*models.Block, len(other.blocks))
+		for chain, blocks := range other.blocks {
+			ms.blocks[chain] = make(map[uint64]*models.Block, len(blocks))
+			for number, block := range blocks {
+				ms.blocks[chain][number] = block
+			}
+		}
+		ms.checkpoints = make(map[string]map[string]*models.Checkpoint, len(other.checkpoints))
+		for chain, checkpoints := range other.checkpoints {
+			ms.checkpoints[chain] = make(map[string]*models.Checkpoint, len(checkpoints))
+			for contract, checkpoint := range checkpoints {
+				copied := *checkpoint
+				ms.checkpoints[chain][contract] = &copied
+			}
+		}
+		ms.sinks = make(map[string]map[string]uint64, len(other.sinks))
+		for chain, offsets := range other.sinks {
+			ms.sinks[chain] = make(map[string]uint64, len(offsets))
+			for sink, block := range offsets {
+				ms.sinks[chain][sink] = block
+			}
+		}
+		ms.deadLetters = make(map[uint64]*models.DeadLetter, len(other.deadLetters))
+		for id, letter := range other.deadLetters {
+			copied := *letter
+			ms.deadLetters[id] = &copied
+		}
+		ms.failed = make(map[uint64]*models.FailedRange, len(other.failed))
+		for id, failed := range other.failed {
+			copied := *failed
+			ms.failed[id] = &copied
+		}
+		ms.nextEvent = other.nextEvent
+		ms.nextLetter = other.nextLetter
+		ms.nextFailed = other.nextFailed
+	}
+}
+
+// take replaces a table with the one of another storage; must be called
+// with mu held
+func (ms *MemoryStorage) take(other *MemoryStorage, t table) {
+	switch t {
+	case protocolTable:
+		ms.protocols = other.protocols
+		ms.nextProtocol = other.nextProtocol
+	case tvlTable:
+		ms.snapshots = other.snapshots
+		ms.rollups = other.rollups
+		ms.nextSnapshot = other.nextSnapshot
+		ms.nextRollup = other.nextRollup
+	case chainTable:
+		ms.chains = other.chains
+		ms.chainStats = other.chainStats
+		ms.nextChain = other.nextChain
+	case tokenTable:
+		ms.tokens = other.tokens
+		ms.prices = other.prices
+		ms.nextToken = other.nextToken
+		ms.nextPrice = other.nextPrice
+	case indexTable:
+		ms.events = other.events
+		ms.blocks = other.blocks
+		ms.checkpoints = other.checkpoints
+		ms.sinks = other.sinks
+		ms.deadLetters = other.deadLetters
+		ms.failed = other.failed
+		ms.nextEvent = other.nextEvent
+		ms.nextLetter = other.nextLetter
+		ms.nextFailed = other.nextFailed
+	}
+}
+
+// Reads of a table go to the storage until the transaction writes to it,
+// then to its copy
+func (mt *MemoryTx) GetProtocol(ctx context.Context, name string) (*models.Protocol, error) {
+	return mt.read(protocolTable).GetProtocol(ctx, name)
+}
+
+func (mt *MemoryTx) GetProtocols(ctx context.Context) ([]*models.Protocol, error) {
+	return mt.read(protocolTable).GetProtocols(ctx)
+}
+
+func (mt *MemoryTx) SaveProtocol(ctx context.Context, protocol *models.Protocol) error {
+	state, err := mt.write(protocolTable)
+	if err != nil {
+		return err
+	}
+	return state.SaveProtocol(ctx, protocol)
+}
+
+func (mt *MemoryTx) UpdateProtocol(ctx context.Context, protocol *models.Protocol) error {
+	state, err := mt.write(protocolTable)
+	if err != nil {
+		return err
+	}
+	return state.UpdateProtocol(ctx, protocol)
+}
+
+func (mt *MemoryTx) DeleteProtocol(ctx context.Context, name string) error {
+	state, err := mt.write(protocolTable)
+	if err != nil {
+		return err
+	}
+	return state.DeleteProtocol(ctx, name)
+}
+
+func (mt *MemoryTx) SaveTVLSnapshot(ctx context.Context, snapshot *models.TVLSnapshot) error {
+	state, err := mt.write(tvlTable)
+	if err != nil {
+		return err
+	}
+	return state.SaveTVLSnapshot(ctx, snapshot)
+}
+
+func (mt *MemoryTx) GetLatestTVL(ctx context.Context, protocol, chain string) (*models.TVLSnapshot, error) {
+	return mt.read(tvlTable).GetLatestTVL(ctx, protocol, chain)
+}
+
+func (mt *MemoryTx) GetHistoricalTVL(ctx context.Context, protocol, chain string, from, to time.Time) ([]*models.TVLSnapshot, error) {
+	return mt.read(tvlTable).GetHistoricalTVL(ctx, protocol, chain, from, to)
+}
+
+func (mt *MemoryTx) GetTVLHistory(ctx context.Context, filter storage.TVLFilter) ([]*models.TVLSnapshot, error) {
+	return mt.read(tvlTable).GetTVLHistory(ctx, filter)
+}
+
+func (mt *MemoryTx) SaveTVLRollups(ctx context.Context, rollups []*models.TVLSnapshot) error {
+	state, err := mt.write(tvlTable)
+	if err != nil {
+		return err
+	}
+	return state.SaveTVLRollups(ctx, rollups)
+}
+
+func (mt *MemoryTx) FinalizeTVL(ctx context.Context, chain string, block uint64) error {
+	state, err := mt.write(tvlTable)
+	if err != nil {
+		return err
+	}
+	return state.FinalizeTVL(ctx, chain, block)
+}
+
+func (mt *MemoryTx) RollbackTVL(ctx context.Context, chain string, fromBlock uint64) error {
+	state, err := mt.write(tvlTable)
+	if err != nil {
+		return err
+	}
+	return state.RollbackTVL(ctx, chain, fromBlock)
+}
+
+func (mt *MemoryTx) DeleteTVLHistory(ctx context.Context, resolution models.Resolution, before time.Time) (uint64, error) {
+	state, err := mt.write(tvlTable)
+	if err != nil {
+		return 0, err
+	}
+	return state.DeleteTVLHistory(ctx, resolution, before)
+}
+
+func (mt *MemoryTx) GetTokenHoldings(ctx context.Context, filter storage.HoldingFilter) ([]*models.TokenHolding, error) {
+	return mt.read(tvlTable).GetTokenHoldings(ctx, filter)
+}
+
+func (mt *MemoryTx) GetTVLByBlock(ctx context.Context, protocol, chain string, blockNumber uint64) (*models.TVLSnapshot, error) {
+	return mt.read(tvlTable).GetTVLByBlock(ctx, protocol, chain, blockNumber)
+}
+
+func (mt *MemoryTx) GetAggregatedTVL(ctx context.Context) (*models.AggregatedTVL, error) {
+	return mt.read(tvlTable).GetAggregatedTVL(ctx)
+}
+
+func (mt *MemoryTx) GetChain(ctx context.Context, name string) (*models.Chain, error) {
+	return mt.read(chainTable).GetChain(ctx, name)
+}
+
+func (mt *MemoryTx) GetChains(ctx context.Context) ([]*models.Chain, error) {
+	return mt.read(chainTable).GetChains(ctx)
+}
+
+func (mt *MemoryTx) SaveChain(ctx context.Context, chain *models.Chain) error {
+	state, err := mt.write(chainTable)
+	if err != nil {
+		return err
+	}
+	return state.SaveChain(ctx, chain)
+}
+
+func (mt *MemoryTx) DeleteChain(ctx context.Context, name string) error {
+	state, err := mt.write(chainTable)
+	if err != nil {
+		return err
+	}
+	return state.DeleteChain(ctx, name)
+}
+
+func (mt *MemoryTx) UpdateChainStats(ctx context.Context, stats *models.ChainStats) error {
+	state, err := mt.write(chainTable)
+	if err != nil {
+		return err
+	}
+	return state.UpdateChainStats(ctx, stats)
+}
+
+func (mt *MemoryTx) SaveEvents(ctx context.Context, events []*models.Event) error {
+	state, err := mt.write(indexTable)
+	if err != nil {
+		return err
+	}
+	return state.SaveEvents(ctx, events)
+}
+
+func (mt *MemoryTx) GetEvents(ctx context.Context, filter storage.EventFilter) ([]*models.Event, error) {
+	return mt.read(indexTable).GetEvents(ctx, filter)
+}
+
+func (mt *MemoryTx) GetLatestEvents(ctx context.Context, filter storage.EventFilter) ([]*models.Event, error) {
+	return mt.read(indexTable).GetLatestEvents(ctx, filter)
+}
+
+func (mt *MemoryTx) SaveBlock(ctx context.Context, block *models.Block) error {
+	state, err := mt.write(indexTable)
+	if err != nil {
+		return err
+	}
+	return state.SaveBlock(ctx, block)
+}
+
+func (mt *MemoryTx) GetLastIndexedBlock(ctx context.Context, chain string) (uint64, error) {
+	return mt.read(indexTable).GetLastIndexedBlock(ctx, chain)
+}
+
+func (mt *MemoryTx) GetBlockAtTime(ctx context.Context, chain string, t time.Time) (*models.Block, error) {
+	return mt.read(indexTable).GetBlockAtTime(ctx, chain, t)
+}
+
+func (mt *MemoryTx) GetToken(ctx context.Context, address, chain string) (*models.Token, error) {
+	return mt.read(tokenTable).GetToken(ctx, address, chain)
+}
+
+func (mt *MemoryTx) SaveToken(ctx context.Context, token *models.Token) error {
+	state, err := mt.write(tokenTable)
+	if err != nil {
+		return err
+	}
+	return state.SaveToken(ctx, token)
+}
+
+func (mt *MemoryTx) UpdateTokenPrice(ctx context.Context, price *models.TokenPrice) error {
+	state, err := mt.write(tokenTable)
+	if err != nil {
+		return err
+	}
+	return state.UpdateTokenPrice(ctx, price)
+}
+
+func (mt *MemoryTx) GetTokenPrices(ctx context.Context, addresses []string) (map[string]*models.TokenPrice, error) {
+	return mt.read(tokenTable).GetTokenPrices(ctx, addresses)
+}
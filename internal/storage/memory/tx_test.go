@@ -0,0 +1,76 @@
+// internal/storage/memory/tx_test.go
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/memory"
+)
+
+func TestTxConflict(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewMemoryStorage()
+
+	tx, err := s.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.SaveProtocol(ctx, &models.Protocol{Name: "inside", Type: models.ProtocolTypeDEX}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Swapping in the transaction's protocols would lose this one
+	if err := s.SaveProtocol(ctx, &models.Protocol{Name: "outside", Type: models.ProtocolTypeDEX}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Commit(); !errors.Is(err, memory.ErrConflict) {
+		t.Fatalf("Commit after a write to the same table = %v, want ErrConflict", err)
+	}
+	if _, err := s.GetProtocol(ctx, "inside"); err == nil {
+		t.Error("GetProtocol found the protocol of a conflicting transaction")
+	}
+	if _, err := s.GetProtocol(ctx, "outside"); err != nil {
+		t.Errorf("GetProtocol(outside) after the conflict: %v", err)
+	}
+}
+
+func TestInTx(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewMemoryStorage()
+
+	failed := errors.New("failed")
+	err := storage.InTx(ctx, s, func(tx storage.Tx) error {
+		if err := tx.SaveProtocol(ctx, &models.Protocol{Name: "dropped", Type: models.ProtocolTypeDEX}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("InTx = %v, want the error of its function", err)
+	}
+	if _, err := s.GetProtocol(ctx, "dropped"); err == nil {
+		t.Error("GetProtocol found the protocol of a failed InTx")
+	}
+
+	err = storage.InTx(ctx, s, func(tx storage.Tx) error {
+		// Tables the transaction didn't write are read through
+		if err := s.SaveToken(ctx, &models.Token{Chain: "ethereum", Symbol: "USDC", Decimals: 6}); err != nil {
+			return err
+		}
+		if _, err := tx.GetToken(ctx, "0x0000000000000000000000000000000000000000", "ethereum"); err != nil {
+			return err
+		}
+		return tx.SaveProtocol(ctx, &models.Protocol{Name: "kept", Type: models.ProtocolTypeDEX})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetProtocol(ctx, "kept"); err != nil {
+		t.Errorf("GetProtocol after InTx: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+// internal/storage/postgres/chains.go
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+)
+
+const chainColumns = `id, name, chain_id, COALESCE(rpc_endpoint, ''), COALESCE(ws_endpoint, ''),
+        COALESCE(explorer, ''), COALESCE(native_token, ''), COALESCE(block_time, 0),
+        finality, confirmations, COALESCE(is_testnet, false), COALESCE(is_active, true), source, created_at`
+
+// GetChain retrieves a chain by name
+func (ps *PostgresStorage) GetChain(ctx context.Context, name string) (*models.Chain, error) {
+	query := `SELECT ` + chainColumns + ` FROM chains WHERE name = $1`
+
+	chain, err := scanChain(ps.conn.QueryRowContext(ctx, query, name))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("chain not found: %s", name)
+	}
+	return chain, err
+}
+
+// GetChains retrieves all chains by name
+func (ps *PostgresStorage) GetChains(ctx context.Context) ([]*models.Chain, error) {
+	rows, err := ps.conn.QueryContext(ctx, `SELECT `+chainColumns+` FROM chains ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chains []*models.Chain
+	for rows.Next() {
+		chain, err := scanChain(rows)
+		if err != nil {
+			return nil, err
+		}
+		chains = append(chains, chain)
+	}
+
+	return chains, rows.Err()
+}
+
+// SaveChain saves a chain, replacing the chain of the same name
+func (ps *PostgresStorage) SaveChain(ctx context.Context, chain *models.Chain) error {
+	if chain.ChainID == nil || !chain.ChainID.IsInt64() {
+		return fmt.Errorf("invalid chain id for %s", chain.Name)
+	}
+
+	query := `
+        INSERT INTO chains (name, chain_id, rpc_endpoint, ws_endpoint, explorer, native_token, block_time,
+            finality, confirmations, is_testnet, is_active, source)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+        ON CONFLICT (name) DO UPDATE SET
+            chain_id = EXCLUDED.chain_id,
+            rpc_endpoint = EXCLUDED.rpc_endpoint,
+            ws_endpoint = EXCLUDED.ws_endpoint,
+            explorer = EXCLUDED.explorer,
+            native_token = EXCLUDED.native_token,
+            block_time = EXCLUDED.block_time,
+            finality = EXCLUDED.finality,
+            confirmations = EXCLUDED.confirmations,
+            is_testnet = EXCLUDED.is_testnet,
+            is_active = EXCLUDED.is_active,
+            source = EXCLUDED.source
+        RETURNING id, created_at
+    `
+
+	return ps.conn.QueryRowContext(ctx, query,
+		chain.Name,
+		chain.ChainID.Int64(),
+		chain.RPCEndpoint,
+		chain.WSEndpoint,
+		chain.Explorer,
+		chain.NativeToken,
+		chain.BlockTime,
+		chain.Finality,
+		chain.Confirmations,
+		chain.IsTestnet,
+		chain.IsActive,
+		chain.Source,
+	).Scan(&chain.ID, &chain.CreatedAt)
+}
+
+// DeleteChain deletes a chain. Its statistics, events and TVL are kept.
+func (ps *PostgresStorage) DeleteChain(ctx context.Context, name string) error {
+	result, err := ps.conn.ExecContext(ctx, "DELETE FROM chains WHERE name = $1", name)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return fmt.Errorf("chain not found: %s", name)
+	}
+	return nil
+}
+
+// UpdateChainStats saves the latest statistics of a chain
+func (ps *PostgresStorage) UpdateChainStats(ctx context.Context, stats *models.ChainStats) error {
+	totalTVL, err := formatNumeric(stats.TotalTVL)
+	if err != nil {
+		return fmt.Errorf("invalid total_tvl: %w", err)
+	}
+
+	updated := stats.LastUpdateTime
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+
+	query := `
+        INSERT INTO chain_stats (chain, last_indexed_block, current_block, total_protocols, total_tvl, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (chain) DO UPDATE SET
+            last_indexed_block = EXCLUDED.last_indexed_block,
+            current_block = EXCLUDED.current_block,
+            total_protocols = EXCLUDED.total_protocols,
+            total_tvl = EXCLUDED.total_tvl,
+            updated_at = EXCLUDED.updated_at
+    `
+
+	_, err = ps.conn.ExecContext(ctx, query,
+		stats.Chain,
+		stats.LastIndexedBlock,
+		stats.CurrentBlock,
+		stats.TotalProtocols,
+		totalTVL,
+		updated.UTC(),
+	)
+	return err
+}
+
+// scanChain scans the chainColumns of a row
+func scanChain(row interface{ Scan(...interface{}) error }) (*models.Chain, error) {
+	var chain models.Chain
+	var chainID int64
+
+	err := row.Scan(
+		&chain.ID,
+		&chain.Name,
+		&chainID,
+		&chain.RPCEndpoint,
+		&chain.WSEndpoint,
+		&chain.Explorer,
+		&chain.NativeToken,
+		&chain.BlockTime,
+		&chain.Finality,
+		&chain.Confirmations,
+		&chain.IsTestnet,
+		&chain.IsActive,
+		&chain.Source,
+		&chain.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	chain.ChainID = big.NewInt(chainID)
+	return &chain, nil
+}
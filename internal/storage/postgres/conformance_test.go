@@ -0,0 +1,13 @@
+// internal/storage/postgres/conformance_test.go
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/storagetest"
+)
+
+// TestConformance runs against TEST_DATABASE_URL and is skipped without it
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, storagetest.Postgres)
+}
@@ -0,0 +1,770 @@
+// internal/storage/postgres/events.go
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// SaveEvents saves events. Saving an event again replaces it, decoded data
+// included, while it is still tentative.
+func (ps *PostgresStorage) SaveEvents(ctx context.Context, events []*models.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	return ps.atomic(ctx, func(tx conn) error {
+		return saveEvents(ctx, tx, events)
+	})
+}
+
+func saveEvents(ctx context.Context, db conn, events []*models.Event) error {
+	stmt, err := db.PrepareContext(ctx, `
+        INSERT INTO events (
+            chain, protocol, block_number, block_hash, transaction_hash, log_index,
+            address, event_name, event_signature, data, status, timestamp
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+        ON CONFLICT (chain, transaction_hash, log_index)
+        DO UPDATE SET
+            protocol = EXCLUDED.protocol,
+            block_number = EXCLUDED.block_number,
+            block_hash = EXCLUDED.block_hash,
+            address = EXCLUDED.address,
+            event_name = EXCLUDED.event_name,
+            event_signature = EXCLUDED.event_signature,
+            data = EXCLUDED.data,
+            status = EXCLUDED.status,
+            timestamp = EXCLUDED.timestamp
+        WHERE events.status <> 'final'
+        RETURNING id
+    `)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, event := range events {
+		data, err := json.Marshal(event.Data)
+		if err != nil {
+			return err
+		}
+
+		err = stmt.QueryRowContext(ctx,
+			event.Chain,
+			event.Protocol,
+			event.BlockNumber,
+			event.BlockHash,
+			event.TransactionHash,
+			event.LogIndex,
+			event.Address.Hex(),
+			event.EventName,
+			event.EventSignature,
+			data,
+			eventStatus(event),
+			nullTime(event.Timestamp),
+		).Scan(&event.ID)
+		if err == sql.ErrNoRows {
+			// Already stored as final
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save event %s:%d: %w", event.TransactionHash, event.LogIndex, err)
+		}
+	}
+
+	return nil
+}
+
+// eventConditions builds the WHERE clause of an event filter
+func eventConditions(filter storage.EventFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Chain != "" {
+		where("chain = $%d", filter.Chain)
+	}
+	if filter.Protocol != "" {
+		where("protocol = $%d", filter.Protocol)
+	}
+	if filter.EventName != "" {
+		where("event_name = $%d", filter.EventName)
+	}
+	if filter.Address != "" {
+		where("LOWER(address) = LOWER($%d)", filter.Address)
+	}
+	if filter.Status != "" {
+		where("status = $%d", string(filter.Status))
+	}
+	if filter.FromBlock > 0 {
+		where("block_number >= $%d", filter.FromBlock)
+	}
+	if filter.ToBlock > 0 {
+		where("block_number <= $%d", filter.ToBlock)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "\n        WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// GetEvents retrieves events matching a filter, by block number and log index
+func (ps *PostgresStorage) GetEvents(ctx context.Context, filter storage.EventFilter) ([]*models.Event, error) {
+	conditions, args := eventConditions(filter)
+
+	query := `
+        SELECT id, chain, COALESCE(protocol, ''), block_number, COALESCE(block_hash, ''),
+            transaction_hash, log_index, address, COALESCE(event_name, ''),
+            COALESCE(event_signature, ''), data, status, timestamp
+        FROM events` + conditions
+	query += "\n        ORDER BY chain, block_number, log_index"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", filter.Offset)
+	}
+
+	return ps.queryEvents(ctx, query, args...)
+}
+
+// GetLatestEvents returns the last of the events matching a filter of each
+// contract, by block number and log index, ignoring Limit and Offset
+func (ps *PostgresStorage) GetLatestEvents(ctx context.Context, filter storage.EventFilter) ([]*models.Event, error) {
+	conditions, args := eventConditions(filter)
+
+	query := `
+        SELECT * FROM (
+            SELECT DISTINCT ON (chain, LOWER(address))
+                id, chain, COALESCE(protocol, ''), block_number, COALESCE(block_hash, ''),
+                transaction_hash, log_index, address, COALESCE(event_name, ''),
+                COALESCE(event_signature, ''), data, status, timestamp
+            FROM events` + conditions + `
+            ORDER BY chain, LOWER(address), block_number DESC, log_index DESC
+        ) latest
+        ORDER BY chain, block_number, log_index`
+
+	return ps.queryEvents(ctx, query, args...)
+}
+
+// queryEvents runs a query selecting the columns of GetEvents
+func (ps *PostgresStorage) queryEvents(ctx context.Context, query string, args ...interface{}) ([]*models.Event, error) {
+	rows, err := ps.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*models.Event
+	for rows.Next() {
+		var event models.Event
+		var address string
+		var data []byte
+		var timestamp sql.NullTime
+
+		if err := rows.Scan(
+			&event.ID,
+			&event.Chain,
+			&event.Protocol,
+			&event.BlockNumber,
+			&event.BlockHash,
+			&event.TransactionHash,
+			&event.LogIndex,
+			&address,
+			&event.EventName,
+			&event.EventSignature,
+			&data,
+			&event.Status,
+			&timestamp,
+		); err != nil {
+			return nil, err
+		}
+
+		event.Address = common.HexToAddress(address)
+		event.Timestamp = timestamp.Time
+		if data != nil {
+			if err := json.Unmarshal(data, &event.Data); err != nil {
+				return nil, err
+			}
+		}
+
+		events = append(events, &event)
+	}
+
+	return events, rows.Err()
+}
+
+// CountEvents counts the events matching a filter, ignoring Limit and Offset
+func (ps *PostgresStorage) CountEvents(ctx context.Context, filter storage.EventFilter) (uint64, error) {
+	conditions, args := eventConditions(filter)
+
+	var count uint64
+	err := ps.conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM events"+conditions, args...).Scan(&count)
+	return count, err
+}
+
+// DeleteEvents deletes the events matching a filter, final or not, ignoring
+// Limit and Offset
+func (ps *PostgresStorage) DeleteEvents(ctx context.Context, filter storage.EventFilter) (uint64, error) {
+	conditions, args := eventConditions(filter)
+
+	result, err := ps.conn.ExecContext(ctx, "DELETE FROM events"+conditions, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	return uint64(deleted), err
+}
+
+// FinalizeEvents marks all events up to and including block as final
+func (ps *PostgresStorage) FinalizeEvents(ctx context.Context, chain string, block uint64) error {
+	_, err := ps.conn.ExecContext(ctx, `
+        UPDATE events SET status = 'final'
+        WHERE chain = $1 AND block_number <= $2 AND status <> 'final'
+    `, chain, block)
+
+	return err
+}
+
+// SaveBlock records an indexed block
+func (ps *PostgresStorage) SaveBlock(ctx context.Context, block *models.Block) error {
+	return saveBlock(ctx, ps.conn, block)
+}
+
+func saveBlock(ctx context.Context, db conn, block *models.Block) error {
+	query := `
+        INSERT INTO indexed_blocks (chain, number, hash, parent_hash, timestamp, indexed_at)
+        VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+        ON CONFLICT (chain, number)
+        DO UPDATE SET
+            hash = EXCLUDED.hash,
+            parent_hash = EXCLUDED.parent_hash,
+            timestamp = EXCLUDED.timestamp,
+            indexed_at = EXCLUDED.indexed_at
+    `
+
+	_, err := db.ExecContext(ctx, query,
+		block.Chain,
+		block.Number,
+		block.Hash,
+		block.ParentHash,
+		nullTime(block.Timestamp),
+	)
+
+	return err
+}
+
+// GetRecentBlocks returns up to limit indexed blocks for a chain, newest first
+func (ps *PostgresStorage) GetRecentBlocks(ctx context.Context, chain string, limit int) ([]*models.Block, error) {
+	query := `
+        SELECT id, chain, number, COALESCE(hash, ''), COALESCE(parent_hash, ''), timestamp
+        FROM indexed_blocks
+        WHERE chain = $1
+        ORDER BY number DESC
+        LIMIT $2
+    `
+
+	rows, err := ps.conn.QueryContext(ctx, query, chain, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []*models.Block
+	for rows.Next() {
+		block, err := scanBlock(rows)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks, rows.Err()
+}
+
+// GetBlockAtTime returns the highest indexed block mined at or before t, or
+// nil if there is none
+func (ps *PostgresStorage) GetBlockAtTime(ctx context.Context, chain string, t time.Time) (*models.Block, error) {
+	query := `
+        SELECT id, chain, number, COALESCE(hash, ''), COALESCE(parent_hash, ''), timestamp
+        FROM indexed_blocks
+        WHERE chain = $1 AND timestamp <= $2
+        ORDER BY number DESC
+        LIMIT 1
+    `
+
+	block, err := scanBlock(ps.conn.QueryRowContext(ctx, query, chain, t.UTC()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return block, err
+}
+
+func scanBlock(row interface{ Scan(...interface{}) error }) (*models.Block, error) {
+	var block models.Block
+	var timestamp sql.NullTime
+
+	if err := row.Scan(
+		&block.ID,
+		&block.Chain,
+		&block.Number,
+		&block.Hash,
+		&block.ParentHash,
+		&timestamp,
+	); err != nil {
+		return nil, err
+	}
+	block.Timestamp = timestamp.Time
+
+	return &block, nil
+}
+
+// GetLastIndexedBlock returns the block up to which every watched contract is
+// indexed, falling back to the highest indexed block without checkpoints
+func (ps *PostgresStorage) GetLastIndexedBlock(ctx context.Context, chain string) (uint64, error) {
+	var watermark sql.NullInt64
+	if err := ps.conn.QueryRowContext(ctx,
+		"SELECT MIN(last_processed_block) FROM indexer_checkpoints WHERE chain = $1",
+		chain,
+	).Scan(&watermark); err != nil {
+		return 0, err
+	}
+	if watermark.Valid {
+		return uint64(watermark.Int64), nil
+	}
+
+	var last sql.NullInt64
+	if err := ps.conn.QueryRowContext(ctx,
+		"SELECT MAX(number) FROM indexed_blocks WHERE chain = $1",
+		chain,
+	).Scan(&last); err != nil {
+		return 0, err
+	}
+
+	return uint64(last.Int64), nil
+}
+
+// Rollback deletes all events and indexed blocks at or above fromBlock
+func (ps *PostgresStorage) Rollback(ctx context.Context, chain string, fromBlock uint64) error {
+	return ps.atomic(ctx, func(tx conn) error {
+		if _, err := tx.ExecContext(ctx,
+			"DELETE FROM events WHERE chain = $1 AND block_number >= $2",
+			chain, fromBlock,
+		); err != nil {
+			return fmt.Errorf("failed to delete events: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			"DELETE FROM indexed_blocks WHERE chain = $1 AND number >= $2",
+			chain, fromBlock,
+		); err != nil {
+			return fmt.Errorf("failed to delete blocks: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE indexer_checkpoints SET last_processed_block = $2, updated_at = $3
+         WHERE chain = $1 AND last_processed_block >= $2`,
+			chain, checkpointBefore(fromBlock), time.Now(),
+		); err != nil {
+			return fmt.Errorf("failed to rewind checkpoints: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			"DELETE FROM indexer_dead_letters WHERE chain = $1 AND block_number >= $2",
+			chain, fromBlock,
+		); err != nil {
+			return fmt.Errorf("failed to delete dead letters: %w", err)
+		}
+
+		// Events past the fork are re-indexed and have to be delivered again
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE indexer_sink_offsets SET block = $2, updated_at = $3
+         WHERE chain = $1 AND block >= $2`,
+			chain, checkpointBefore(fromBlock), time.Now(),
+		); err != nil {
+			return fmt.Errorf("failed to rewind sink offsets: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetCheckpoints returns the indexer checkpoints of a chain
+func (ps *PostgresStorage) GetCheckpoints(ctx context.Context, chain string) ([]*models.Checkpoint, error) {
+	query := `
+        SELECT chain, contract_address, COALESCE(protocol, ''), last_processed_block, updated_at
+        FROM indexer_checkpoints
+        WHERE chain = $1
+        ORDER BY contract_address
+    `
+
+	rows, err := ps.conn.QueryContext(ctx, query, chain)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checkpoints []*models.Checkpoint
+	for rows.Next() {
+		var checkpoint models.Checkpoint
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&checkpoint.Chain, &checkpoint.Contract, &checkpoint.Protocol, &checkpoint.Block, &updatedAt); err != nil {
+			return nil, err
+		}
+		checkpoint.UpdatedAt = updatedAt.Time
+		checkpoints = append(checkpoints, &checkpoint)
+	}
+
+	return checkpoints, rows.Err()
+}
+
+// SaveCheckpoints upserts checkpoints. A checkpoint never moves backwards
+// here; only Rollback rewinds them.
+func (ps *PostgresStorage) SaveCheckpoints(ctx context.Context, checkpoints []*models.Checkpoint) error {
+	if len(checkpoints) == 0 {
+		return nil
+	}
+
+	return ps.atomic(ctx, func(tx conn) error {
+		return saveCheckpoints(ctx, tx, checkpoints)
+	})
+}
+
+func saveCheckpoints(ctx context.Context, db conn, checkpoints []*models.Checkpoint) error {
+	stmt, err := db.PrepareContext(ctx, `
+        INSERT INTO indexer_checkpoints (chain, contract_address, protocol, last_processed_block, updated_at)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (chain, contract_address) DO UPDATE SET
+            protocol = EXCLUDED.protocol,
+            last_processed_block = GREATEST(indexer_checkpoints.last_processed_block, EXCLUDED.last_processed_block),
+            updated_at = EXCLUDED.updated_at
+    `)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, checkpoint := range checkpoints {
+		if _, err := stmt.ExecContext(ctx,
+			checkpoint.Chain,
+			checkpoint.Contract,
+			checkpoint.Protocol,
+			checkpoint.Block,
+			now,
+		); err != nil {
+			return fmt.Errorf("failed to save checkpoint: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SaveFailedRange records a range that failed to index. Saving the same
+// range again counts another attempt.
+func (ps *PostgresStorage) SaveFailedRange(ctx context.Context, failed *models.FailedRange) error {
+	return saveFailedRange(ctx, ps.conn, failed)
+}
+
+func saveFailedRange(ctx context.Context, db conn, failed *models.FailedRange) error {
+	query := `
+        INSERT INTO indexer_failed_ranges (chain, contracts, from_block, to_block, attempts, last_error, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, 1, $5, $6, $6)
+        ON CONFLICT (chain, contracts, from_block, to_block) DO UPDATE SET
+            attempts = indexer_failed_ranges.attempts + 1,
+            last_error = EXCLUDED.last_error,
+            updated_at = EXCLUDED.updated_at
+        RETURNING id, attempts
+    `
+
+	return db.QueryRowContext(ctx, query,
+		failed.Chain,
+		strings.Join(failed.Contracts, ","),
+		failed.FromBlock,
+		failed.ToBlock,
+		failed.LastError,
+		time.Now(),
+	).Scan(&failed.ID, &failed.Attempts)
+}
+
+// GetFailedRanges returns the failed ranges of a chain by first block
+func (ps *PostgresStorage) GetFailedRanges(ctx context.Context, chain string) ([]*models.FailedRange, error) {
+	query := `
+        SELECT id, chain, contracts, from_block, to_block, attempts, COALESCE(last_error, ''), created_at, updated_at
+        FROM indexer_failed_ranges
+        WHERE chain = $1
+        ORDER BY from_block
+    `
+
+	rows, err := ps.conn.QueryContext(ctx, query, chain)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ranges []*models.FailedRange
+	for rows.Next() {
+		var failed models.FailedRange
+		var contracts string
+		if err := rows.Scan(
+			&failed.ID,
+			&failed.Chain,
+			&contracts,
+			&failed.FromBlock,
+			&failed.ToBlock,
+			&failed.Attempts,
+			&failed.LastError,
+			&failed.CreatedAt,
+			&failed.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		failed.Contracts = strings.Split(contracts, ",")
+		ranges = append(ranges, &failed)
+	}
+
+	return ranges, rows.Err()
+}
+
+// DeleteFailedRange forgets a failed range
+func (ps *PostgresStorage) DeleteFailedRange(ctx context.Context, id uint64) error {
+	_, err := ps.conn.ExecContext(ctx, "DELETE FROM indexer_failed_ranges WHERE id = $1", id)
+	return err
+}
+
+// SaveDeadLetters stores logs that failed to decode
+func (ps *PostgresStorage) SaveDeadLetters(ctx context.Context, letters []*models.DeadLetter) error {
+	if len(letters) == 0 {
+		return nil
+	}
+
+	return ps.atomic(ctx, func(tx conn) error {
+		return saveDeadLetters(ctx, tx, letters)
+	})
+}
+
+func saveDeadLetters(ctx context.Context, db conn, letters []*models.DeadLetter) error {
+	stmt, err := db.PrepareContext(ctx, `
+        INSERT INTO indexer_dead_letters (
+            chain, protocol, address, block_number, block_hash, transaction_hash, log_index,
+            topics, data, error, attempts, timestamp, created_at, updated_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, 1, $11, $12, $12)
+        ON CONFLICT (chain, transaction_hash, log_index, protocol) DO UPDATE SET
+            block_hash = EXCLUDED.block_hash,
+            error = EXCLUDED.error,
+            attempts = indexer_dead_letters.attempts + 1,
+            timestamp = EXCLUDED.timestamp,
+            updated_at = EXCLUDED.updated_at
+        RETURNING id, attempts
+    `)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, letter := range letters {
+		if err := stmt.QueryRowContext(ctx,
+			letter.Chain,
+			letter.Protocol,
+			letter.Address.Hex(),
+			letter.BlockNumber,
+			letter.BlockHash,
+			letter.TransactionHash,
+			letter.LogIndex,
+			strings.Join(letter.Topics, ","),
+			letter.Data,
+			letter.Error,
+			nullTime(letter.Timestamp),
+			now,
+		).Scan(&letter.ID, &letter.Attempts); err != nil {
+			return fmt.Errorf("failed to save dead letter %s:%d: %w", letter.TransactionHash, letter.LogIndex, err)
+		}
+	}
+
+	return nil
+}
+
+// GetDeadLetters returns dead letters by block; empty arguments match all
+func (ps *PostgresStorage) GetDeadLetters(ctx context.Context, chain, protocol string) ([]*models.DeadLetter, error) {
+	query := `
+        SELECT id, chain, protocol, address, block_number, COALESCE(block_hash, ''), transaction_hash,
+            log_index, topics, data, COALESCE(error, ''), attempts, timestamp, created_at, updated_at
+        FROM indexer_dead_letters
+        WHERE ($1 = '' OR chain = $1) AND ($2 = '' OR protocol = $2)
+        ORDER BY chain, block_number, log_index
+    `
+
+	rows, err := ps.conn.QueryContext(ctx, query, chain, protocol)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var letters []*models.DeadLetter
+	for rows.Next() {
+		var letter models.DeadLetter
+		var address, topics string
+		var timestamp sql.NullTime
+		if err := rows.Scan(
+			&letter.ID,
+			&letter.Chain,
+			&letter.Protocol,
+			&address,
+			&letter.BlockNumber,
+			&letter.BlockHash,
+			&letter.TransactionHash,
+			&letter.LogIndex,
+			&topics,
+			&letter.Data,
+			&letter.Error,
+			&letter.Attempts,
+			&timestamp,
+			&letter.CreatedAt,
+			&letter.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		letter.Address = common.HexToAddress(address)
+		letter.Timestamp = timestamp.Time
+		if topics != "" {
+			letter.Topics = strings.Split(topics, ",")
+		}
+		letters = append(letters, &letter)
+	}
+
+	return letters, rows.Err()
+}
+
+// CountDeadLetters counts dead letters; empty arguments match all
+func (ps *PostgresStorage) CountDeadLetters(ctx context.Context, chain, protocol string) (uint64, error) {
+	var count uint64
+	err := ps.conn.QueryRowContext(ctx, `
+        SELECT COUNT(*) FROM indexer_dead_letters
+        WHERE ($1 = '' OR chain = $1) AND ($2 = '' OR protocol = $2)
+    `, chain, protocol).Scan(&count)
+	return count, err
+}
+
+// GetSinkOffsets returns the last block delivered to each event sink
+func (ps *PostgresStorage) GetSinkOffsets(ctx context.Context, chain string) (map[string]uint64, error) {
+	rows, err := ps.conn.QueryContext(ctx, "SELECT sink, block FROM indexer_sink_offsets WHERE chain = $1", chain)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	offsets := make(map[string]uint64)
+	for rows.Next() {
+		var sink string
+		var block uint64
+		if err := rows.Scan(&sink, &block); err != nil {
+			return nil, err
+		}
+		offsets[sink] = block
+	}
+
+	return offsets, rows.Err()
+}
+
+// SaveSinkOffset records the last block delivered to a sink
+func (ps *PostgresStorage) SaveSinkOffset(ctx context.Context, sink, chain string, block uint64) error {
+	_, err := ps.conn.ExecContext(ctx, `
+        INSERT INTO indexer_sink_offsets (sink, chain, block, updated_at)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (sink, chain) DO UPDATE SET
+            block = EXCLUDED.block,
+            updated_at = EXCLUDED.updated_at
+    `, sink, chain, block, time.Now())
+
+	return err
+}
+
+// CommitBatch stores the events, blocks, checkpoints and failed range of a
+// batch in one transaction
+func (ps *PostgresStorage) CommitBatch(ctx context.Context, batch *models.EventBatch) error {
+	return ps.atomic(ctx, func(tx conn) error {
+		if len(batch.Events) > 0 {
+			if err := saveEvents(ctx, tx, batch.Events); err != nil {
+				return fmt.Errorf("failed to save events: %w", err)
+			}
+		}
+
+		for _, block := range batch.Blocks {
+			if err := saveBlock(ctx, tx, block); err != nil {
+				return fmt.Errorf("failed to save block %d: %w", block.Number, err)
+			}
+		}
+
+		if len(batch.Checkpoints) > 0 {
+			if err := saveCheckpoints(ctx, tx, batch.Checkpoints); err != nil {
+				return err
+			}
+		}
+
+		if batch.Failed != nil {
+			if err := saveFailedRange(ctx, tx, batch.Failed); err != nil {
+				return fmt.Errorf("failed to save failed range: %w", err)
+			}
+		}
+
+		if batch.Resolved != 0 {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM indexer_failed_ranges WHERE id = $1", batch.Resolved); err != nil {
+				return fmt.Errorf("failed to delete failed range: %w", err)
+			}
+		}
+
+		if len(batch.DeadLetters) > 0 {
+			if err := saveDeadLetters(ctx, tx, batch.DeadLetters); err != nil {
+				return err
+			}
+		}
+
+		for _, id := range batch.Replayed {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM indexer_dead_letters WHERE id = $1", id); err != nil {
+				return fmt.Errorf("failed to delete dead letter %d: %w", id, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// checkpointBefore returns the checkpoint that makes indexing resume at block
+func checkpointBefore(block uint64) uint64 {
+	if block == 0 {
+		return 0
+	}
+	return block - 1
+}
+
+// nullTime stores a zero time as NULL
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC()
+}
+
+// eventStatus defaults events without an explicit status to tentative
+func eventStatus(event *models.Event) models.FinalityStatus {
+	if event.Status == "" {
+		return models.FinalityTentative
+	}
+	return event.Status
+}
@@ -0,0 +1,254 @@
+// internal/storage/postgres/history.go
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+const rollupColumns = `id, protocol, chain, COALESCE(block_number, 0), total_usd, breakdown, status, timestamp, source,
+            resolution, open_usd, high_usd, low_usd, avg_usd, samples`
+
+// tvlConditions builds the WHERE clause of a TVL filter
+func tvlConditions(filter storage.TVLFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Protocol != "" {
+		where("protocol = $%d", filter.Protocol)
+	}
+	if filter.Chain != "" {
+		where("chain = $%d", filter.Chain)
+	}
+	if !filter.Resolution.IsRaw() {
+		where("resolution = $%d", filter.Resolution)
+	}
+	if !filter.From.IsZero() {
+		where("timestamp >= $%d", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		where("timestamp <= $%d", filter.To.UTC())
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "\n        WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// GetHistoricalTVL retrieves the TVL of a protocol in [from, to] by time, on
+// every chain when chain is empty, at the resolution the window calls for
+func (ps *PostgresStorage) GetHistoricalTVL(ctx context.Context, protocol, chain string, from, to time.Time) ([]*models.TVLSnapshot, error) {
+	return storage.History(ctx, ps, protocol, chain, from, to)
+}
+
+// GetTVLHistory retrieves the snapshots, or the rollups of the filter's
+// resolution, by time then ID
+func (ps *PostgresStorage) GetTVLHistory(ctx context.Context, filter storage.TVLFilter) ([]*models.TVLSnapshot, error) {
+	conditions, args := tvlConditions(filter)
+
+	raw := filter.Resolution.IsRaw()
+	query := `
+        SELECT ` + snapshotColumns + `
+        FROM tvl_snapshots` + conditions
+	if !raw {
+		query = `
+        SELECT ` + rollupColumns + `
+        FROM tvl_rollups` + conditions
+	}
+	query += "\n        ORDER BY timestamp, id"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := ps.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []*models.TVLSnapshot
+	for rows.Next() {
+		var snapshot *models.TVLSnapshot
+		if raw {
+			snapshot, err = scanSnapshot(rows)
+		} else {
+			snapshot, err = scanRollup(rows)
+		}
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// SaveTVLRollups saves rollups, replacing the rollup of the same protocol,
+// chain, resolution and interval
+func (ps *PostgresStorage) SaveTVLRollups(ctx context.Context, rollups []*models.TVLSnapshot) error {
+	query := `
+        INSERT INTO tvl_rollups (protocol, chain, resolution, timestamp, block_number, total_usd,
+            open_usd, high_usd, low_usd, avg_usd, samples, breakdown, status, source)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+        ON CONFLICT (protocol, chain, resolution, timestamp)
+        DO UPDATE SET block_number = EXCLUDED.block_number, total_usd = EXCLUDED.total_usd,
+                      open_usd = EXCLUDED.open_usd, high_usd = EXCLUDED.high_usd,
+                      low_usd = EXCLUDED.low_usd, avg_usd = EXCLUDED.avg_usd,
+                      samples = EXCLUDED.samples, breakdown = EXCLUDED.breakdown,
+                      status = EXCLUDED.status, source = EXCLUDED.source
+        RETURNING id
+    `
+
+	for _, rollup := range rollups {
+		if rollup.Resolution.IsRaw() || rollup.Range == nil {
+			return fmt.Errorf("not a rollup: %s on %s at %s", rollup.Protocol, rollup.Chain, rollup.Timestamp)
+		}
+
+		breakdown, err := encodeBreakdown(rollup.Breakdown)
+		if err != nil {
+			return err
+		}
+
+		totalUSD, err := formatNumeric(rollup.TotalUSD)
+		if err != nil {
+			return fmt.Errorf("invalid total_usd: %w", err)
+		}
+		open, err := formatNumeric(rollup.Range.Open)
+		if err != nil {
+			return fmt.Errorf("invalid open: %w", err)
+		}
+		high, err := formatNumeric(rollup.Range.High)
+		if err != nil {
+			return fmt.Errorf("invalid high: %w", err)
+		}
+		low, err := formatNumeric(rollup.Range.Low)
+		if err != nil {
+			return fmt.Errorf("invalid low: %w", err)
+		}
+		avg, err := formatNumeric(rollup.Range.Avg)
+		if err != nil {
+			return fmt.Errorf("invalid avg: %w", err)
+		}
+
+		status := rollup.Status
+		if status == "" {
+			status = models.FinalityTentative
+		}
+
+		err = ps.conn.QueryRowContext(ctx, query,
+			rollup.Protocol,
+			rollup.Chain,
+			rollup.Resolution,
+			rollup.Timestamp.UTC(),
+			rollup.BlockNumber,
+			totalUSD,
+			open,
+			high,
+			low,
+			avg,
+			rollup.Range.Samples,
+			breakdown,
+			status,
+			rollup.Source,
+		).Scan(&rollup.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteTVLHistory deletes the snapshots, or the rollups of a resolution,
+// from before a time. The latest snapshot of each protocol and chain is kept.
+func (ps *PostgresStorage) DeleteTVLHistory(ctx context.Context, resolution models.Resolution, before time.Time) (uint64, error) {
+	var result sql.Result
+	var err error
+	if resolution.IsRaw() {
+		query := `
+            DELETE FROM tvl_snapshots s
+            WHERE s.timestamp < $1 AND EXISTS (
+                SELECT 1 FROM tvl_snapshots n
+                WHERE n.protocol = s.protocol AND n.chain IS NOT DISTINCT FROM s.chain
+                  AND (n.timestamp, n.id) > (s.timestamp, s.id)
+            )
+        `
+		result, err = ps.conn.ExecContext(ctx, query, before.UTC())
+	} else {
+		result, err = ps.conn.ExecContext(ctx, `DELETE FROM tvl_rollups WHERE resolution = $1 AND timestamp < $2`, resolution, before.UTC())
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(deleted), nil
+}
+
+// scanRollup scans the rollupColumns of a row
+func scanRollup(row interface{ Scan(...interface{}) error }) (*models.TVLSnapshot, error) {
+	var rollup models.TVLSnapshot
+	var totalUSD sql.NullString
+	var open, high, low, avg string
+	var breakdown []byte
+
+	rollup.Range = &models.TVLRange{}
+	err := row.Scan(
+		&rollup.ID,
+		&rollup.Protocol,
+		&rollup.Chain,
+		&rollup.BlockNumber,
+		&totalUSD,
+		&breakdown,
+		&rollup.Status,
+		&rollup.Timestamp,
+		&rollup.Source,
+		&rollup.Resolution,
+		&open,
+		&high,
+		&low,
+		&avg,
+		&rollup.Range.Samples,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if rollup.TotalUSD, err = parseNumeric(totalUSD); err != nil {
+		return nil, err
+	}
+	if rollup.Breakdown, err = decodeBreakdown(breakdown); err != nil {
+		return nil, err
+	}
+	for _, amount := range []struct {
+		text   string
+		target **big.Float
+	}{
+		{open, &rollup.Range.Open},
+		{high, &rollup.Range.High},
+		{low, &rollup.Range.Low},
+		{avg, &rollup.Range.Avg},
+	} {
+		if *amount.target, err = parseDecimal(amount.text); err != nil {
+			return nil, err
+		}
+	}
+	rollup.Range.Close = rollup.TotalUSD
+
+	return &rollup, nil
+}
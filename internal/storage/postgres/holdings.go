@@ -0,0 +1,117 @@
+// internal/storage/postgres/holdings.go
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// holdingConditions builds the WHERE clause of a holding filter on
+// protocol_token_holdings h joined to tvl_snapshots s
+func holdingConditions(filter storage.HoldingFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Protocol != "" {
+		where("s.protocol = $%d", filter.Protocol)
+	}
+	if filter.Chain != "" {
+		where("s.chain = $%d", filter.Chain)
+	}
+	if !filter.From.IsZero() {
+		where("s.timestamp >= $%d", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		where("s.timestamp <= $%d", filter.To.UTC())
+	}
+	if filter.Token != "" {
+		where("LOWER(h.token) = LOWER($%d)", filter.Token)
+	}
+	if filter.Symbol != "" {
+		where("h.symbol = $%d", filter.Symbol)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "\n        WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// GetTokenHoldings retrieves the assets of the snapshots, or the rollups of
+// the filter's resolution, by time then snapshot ID then token
+func (ps *PostgresStorage) GetTokenHoldings(ctx context.Context, filter storage.HoldingFilter) ([]*models.TokenHolding, error) {
+	// Rollups keep their last snapshot's breakdown only
+	if !filter.Resolution.IsRaw() {
+		return storage.TokenHoldings(ctx, ps, filter)
+	}
+
+	conditions, args := holdingConditions(filter)
+
+	query := `
+        SELECT h.snapshot_id, s.protocol, COALESCE(s.chain, ''), COALESCE(s.block_number, 0), s.status, s.timestamp,
+            h.token, h.symbol, h.name, h.decimals, h.amount, h.price_usd, h.value_usd, h.percentage
+        FROM protocol_token_holdings h
+        JOIN tvl_snapshots s ON s.id = h.snapshot_id` + conditions
+	query += "\n        ORDER BY s.timestamp, s.id, LOWER(h.token), h.symbol"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := ps.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holdings []*models.TokenHolding
+	for rows.Next() {
+		var holding models.TokenHolding
+		var token string
+		var amount, price, value sql.NullString
+
+		err := rows.Scan(
+			&holding.SnapshotID,
+			&holding.Protocol,
+			&holding.Chain,
+			&holding.BlockNumber,
+			&holding.Status,
+			&holding.Timestamp,
+			&token,
+			&holding.Symbol,
+			&holding.Name,
+			&holding.Decimals,
+			&amount,
+			&price,
+			&value,
+			&holding.Percentage,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		holding.Token = common.HexToAddress(token)
+		if holding.Amount, err = parseInt(amount); err != nil {
+			return nil, err
+		}
+		if holding.PriceUSD, err = parseNumeric(price); err != nil {
+			return nil, err
+		}
+		if holding.ValueUSD, err = parseNumeric(value); err != nil {
+			return nil, err
+		}
+
+		holdings = append(holdings, &holding)
+	}
+
+	return holdings, rows.Err()
+}
@@ -1,125 +1,345 @@
+// internal/storage/postgres/migrations.go
 package postgres
 
-const migrationSQL = `
--- Protocols table
-CREATE TABLE IF NOT EXISTS protocols (
-    id SERIAL PRIMARY KEY,
-    name VARCHAR(100) UNIQUE NOT NULL,
-    type VARCHAR(50) NOT NULL,
-    description TEXT,
-    website VARCHAR(255),
-    logo VARCHAR(255),
-    is_active BOOLEAN DEFAULT true,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
--- Chains table
-CREATE TABLE IF NOT EXISTS chains (
-    id SERIAL PRIMARY KEY,
-    name VARCHAR(50) UNIQUE NOT NULL,
-    chain_id BIGINT UNIQUE NOT NULL,
-    rpc_endpoint TEXT,
-    ws_endpoint TEXT,
-    explorer VARCHAR(255),
-    native_token VARCHAR(10),
-    block_time INT DEFAULT 12,
-    is_testnet BOOLEAN DEFAULT false,
-    is_active BOOLEAN DEFAULT true,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
--- Contracts table
-CREATE TABLE IF NOT EXISTS contracts (
-    id SERIAL PRIMARY KEY,
-    protocol_id INT REFERENCES protocols(id) ON DELETE CASCADE,
-    chain VARCHAR(50),
-    address VARCHAR(42) NOT NULL,
-    name VARCHAR(100),
-    type VARCHAR(50),
-    version VARCHAR(20),
-    tokens JSONB,
-    pool_id VARCHAR(100),
-    vault_id VARCHAR(100),
-    deploy_block BIGINT,
-    abi TEXT,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    UNIQUE(chain, address)
-);
-
--- TVL snapshots table
-CREATE TABLE IF NOT EXISTS tvl_snapshots (
-    id SERIAL PRIMARY KEY,
-    protocol VARCHAR(100) NOT NULL,
-    chain VARCHAR(50),
-    block_number BIGINT,
-    total_usd NUMERIC(30, 8),
-    breakdown JSONB,
-    timestamp TIMESTAMP NOT NULL,
-    UNIQUE(protocol, chain, block_number)
-);
-
--- Events table
-CREATE TABLE IF NOT EXISTS events (
-    id SERIAL PRIMARY KEY,
-    chain VARCHAR(50) NOT NULL,
-    protocol VARCHAR(100),
-    block_number BIGINT NOT NULL,
-    block_hash VARCHAR(66),
-    transaction_hash VARCHAR(66) NOT NULL,
-    log_index INT NOT NULL,
-    address VARCHAR(42) NOT NULL,
-    event_name VARCHAR(100),
-    event_signature VARCHAR(100),
-    data JSONB,
-    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    UNIQUE(chain, transaction_hash, log_index)
-);
-
--- Indexed blocks table
-CREATE TABLE IF NOT EXISTS indexed_blocks (
-    id SERIAL PRIMARY KEY,
-    chain VARCHAR(50) NOT NULL,
-    number BIGINT NOT NULL,
-    hash VARCHAR(66),
-    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    UNIQUE(chain, number)
-);
-
--- Tokens table
-CREATE TABLE IF NOT EXISTS tokens (
-    id SERIAL PRIMARY KEY,
-    address VARCHAR(42) NOT NULL,
-    chain VARCHAR(50) NOT NULL,
-    symbol VARCHAR(20),
-    name VARCHAR(100),
-    decimals INT DEFAULT 18,
-    total_supply NUMERIC(78, 0),
-    logo VARCHAR(255),
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    UNIQUE(chain, address)
-);
-
--- Token prices table
-CREATE TABLE IF NOT EXISTS token_prices (
-    id SERIAL PRIMARY KEY,
-    token_id INT REFERENCES tokens(id),
-    price_usd NUMERIC(30, 18),
-    source VARCHAR(50),
-    confidence FLOAT DEFAULT 1.0,
-    timestamp TIMESTAMP NOT NULL
-);
-
--- Create indexes
-CREATE INDEX IF NOT EXISTS idx_tvl_protocol_time ON tvl_snapshots(protocol, timestamp DESC);
-CREATE INDEX IF NOT EXISTS idx_tvl_chain_time ON tvl_snapshots(chain, timestamp DESC);
-CREATE INDEX IF NOT EXISTS idx_events_chain_block ON events(chain, block_number);
-CREATE INDEX IF NOT EXISTS idx_events_protocol ON events(protocol);
-CREATE INDEX IF NOT EXISTS idx_token_prices_time ON token_prices(token_id, timestamp DESC);
-`
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Migrations are NNNN_name.up.sql files with a matching NNNN_name.down.sql,
+// applied in version order
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// migrationLock is the advisory lock key held while migrating, so services
+// starting together don't migrate concurrently
+const migrationLock = 7240519
+
+const migrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    checksum VARCHAR(64) NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// Migration is one version of the schema
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of Up
+}
+
+// MigrationState tells whether a migration is applied
+type MigrationState string
+
+const (
+	MigrationPending MigrationState = "pending"
+	MigrationApplied MigrationState = "applied"
+	// Applied, but the file changed since
+	MigrationModified MigrationState = "modified"
+	// Applied, but not part of this build
+	MigrationUnknown MigrationState = "unknown"
+)
+
+// MigrationStatus is the state of a migration in a database
+type MigrationStatus struct {
+	Version   int            `json:"version"`
+	Name      string         `json:"name"`
+	State     MigrationState `json:"state"`
+	AppliedAt time.Time      `json:"applied_at,omitempty"`
+}
+
+// Migrator applies the embedded migrations to a database
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []*Migration
+}
+
+// appliedMigration is a row of schema_migrations
+type appliedMigration struct {
+	Version   int       `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// NewMigrator creates a migrator for the embedded migrations
+func NewMigrator(db *sqlx.DB) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Migrations returns the migrations of this build by version
+func (m *Migrator) Migrations() []*Migration {
+	return m.migrations
+}
+
+func loadMigrations(files fs.FS) ([]*Migration, error) {
+	paths, err := fs.Glob(files, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, p := range paths {
+		match := migrationName.FindStringSubmatch(path.Base(p))
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", p)
+		}
+		version, _ := strconv.Atoi(match[1])
+
+		content, err := fs.ReadFile(files, p)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+			sum := sha256.Sum256(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies every pending migration, each in its own transaction. It
+// refuses to run when an applied migration was modified or is unknown.
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	var applied []*Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.check(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+
+			if err := m.apply(ctx, conn, migration.Up,
+				`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+				migration.Version, migration.Name, migration.Checksum,
+			); err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, newest first
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	var reverted []*Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.check(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+
+			if err := m.apply(ctx, conn, migration.Down,
+				`DELETE FROM schema_migrations WHERE version = $1`,
+				migration.Version,
+			); err != nil {
+				return fmt.Errorf("reverting migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status lists every migration of this build and every applied one
+func (m *Migrator) Status(ctx context.Context) ([]*MigrationStatus, error) {
+	if _, err := m.db.ExecContext(ctx, migrationsTable); err != nil {
+		return nil, err
+	}
+
+	done, err := appliedMigrations(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []*MigrationStatus
+	known := make(map[int]bool)
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+		status := &MigrationStatus{Version: migration.Version, Name: migration.Name, State: MigrationPending}
+
+		if applied, ok := done[migration.Version]; ok {
+			status.State = MigrationApplied
+			status.AppliedAt = applied.AppliedAt
+			if applied.Checksum != migration.Checksum {
+				status.State = MigrationModified
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	for version, applied := range done {
+		if !known[version] {
+			statuses = append(statuses, &MigrationStatus{
+				Version:   version,
+				Name:      applied.Name,
+				State:     MigrationUnknown,
+				AppliedAt: applied.AppliedAt,
+			})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+// locked runs fn on one connection holding the migration lock
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLock); err != nil {
+		return fmt.Errorf("failed to take the migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLock)
+
+	if _, err := conn.ExecContext(ctx, migrationsTable); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// check returns the applied migrations, failing on drift
+func (m *Migrator) check(ctx context.Context, conn *sql.Conn) (map[int]*appliedMigration, error) {
+	done, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int]*Migration)
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, applied := range done {
+		migration, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("migration %d_%s is applied but unknown to this build", version, applied.Name)
+		}
+		if applied.Checksum != migration.Checksum {
+			return nil, fmt.Errorf("migration %d_%s was modified after it was applied", version, migration.Name)
+		}
+	}
+
+	return done, nil
+}
+
+// apply runs a migration script and records it in one transaction
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, script, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func appliedMigrations(ctx context.Context, db interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}) (map[int]*appliedMigration, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := make(map[int]*appliedMigration)
+	for rows.Next() {
+		var applied appliedMigration
+		if err := rows.Scan(&applied.Version, &applied.Name, &applied.Checksum, &applied.AppliedAt); err != nil {
+			return nil, err
+		}
+		done[applied.Version] = &applied
+	}
+
+	return done, rows.Err()
+}
 
 func (ps *PostgresStorage) migrate() error {
-	_, err := ps.db.Exec(migrationSQL)
+	migrator, err := NewMigrator(ps.db)
+	if err != nil {
+		return err
+	}
+
+	_, err = migrator.Up(context.Background())
 	return err
 }
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS indexer_sink_offsets;
+DROP TABLE IF EXISTS indexer_dead_letters;
+DROP TABLE IF EXISTS token_prices;
+DROP TABLE IF EXISTS tokens;
+DROP TABLE IF EXISTS indexer_failed_ranges;
+DROP TABLE IF EXISTS indexer_checkpoints;
+DROP TABLE IF EXISTS indexed_blocks;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS tvl_snapshots;
+DROP TABLE IF EXISTS contracts;
+DROP TABLE IF EXISTS chain_stats;
+DROP TABLE IF EXISTS chains;
+DROP TABLE IF EXISTS protocols;
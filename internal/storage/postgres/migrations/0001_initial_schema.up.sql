@@ -0,0 +1,225 @@
+-- Initial schema. Every statement is idempotent, so databases created
+-- before versioned migrations adopt it unchanged.
+
+-- Protocols table
+CREATE TABLE IF NOT EXISTS protocols (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) UNIQUE NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    description TEXT,
+    website VARCHAR(255),
+    logo VARCHAR(255),
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Chains table
+CREATE TABLE IF NOT EXISTS chains (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) UNIQUE NOT NULL,
+    chain_id BIGINT UNIQUE NOT NULL,
+    rpc_endpoint TEXT,
+    ws_endpoint TEXT,
+    explorer VARCHAR(255),
+    native_token VARCHAR(10),
+    block_time INT DEFAULT 12,
+    is_testnet BOOLEAN DEFAULT false,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Latest statistics of each chain
+CREATE TABLE IF NOT EXISTS chain_stats (
+    chain VARCHAR(50) PRIMARY KEY,
+    last_indexed_block BIGINT NOT NULL DEFAULT 0,
+    current_block BIGINT NOT NULL DEFAULT 0,
+    total_protocols INT NOT NULL DEFAULT 0,
+    total_tvl NUMERIC(30, 8),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Contracts table
+CREATE TABLE IF NOT EXISTS contracts (
+    id SERIAL PRIMARY KEY,
+    protocol_id INT REFERENCES protocols(id) ON DELETE CASCADE,
+    chain VARCHAR(50),
+    address VARCHAR(42) NOT NULL,
+    name VARCHAR(100),
+    type VARCHAR(50),
+    version VARCHAR(20),
+    tokens JSONB,
+    pool_id VARCHAR(100),
+    vault_id VARCHAR(100),
+    deploy_block BIGINT,
+    abi TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(chain, address)
+);
+
+-- TVL snapshots table
+CREATE TABLE IF NOT EXISTS tvl_snapshots (
+    id SERIAL PRIMARY KEY,
+    protocol VARCHAR(100) NOT NULL,
+    chain VARCHAR(50),
+    block_number BIGINT,
+    total_usd NUMERIC(30, 8),
+    breakdown JSONB,
+    status VARCHAR(16) NOT NULL DEFAULT 'tentative',
+    timestamp TIMESTAMP NOT NULL,
+    UNIQUE(protocol, chain, block_number)
+);
+
+-- Events table
+CREATE TABLE IF NOT EXISTS events (
+    id SERIAL PRIMARY KEY,
+    chain VARCHAR(50) NOT NULL,
+    protocol VARCHAR(100),
+    block_number BIGINT NOT NULL,
+    block_hash VARCHAR(66),
+    transaction_hash VARCHAR(66) NOT NULL,
+    log_index INT NOT NULL,
+    address VARCHAR(42) NOT NULL,
+    event_name VARCHAR(100),
+    event_signature VARCHAR(100),
+    data JSONB,
+    status VARCHAR(16) NOT NULL DEFAULT 'tentative',
+    timestamp TIMESTAMP,
+    UNIQUE(chain, transaction_hash, log_index)
+);
+
+-- Indexed blocks table
+CREATE TABLE IF NOT EXISTS indexed_blocks (
+    id SERIAL PRIMARY KEY,
+    chain VARCHAR(50) NOT NULL,
+    number BIGINT NOT NULL,
+    hash VARCHAR(66),
+    parent_hash VARCHAR(66),
+    timestamp TIMESTAMP,
+    indexed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(chain, number)
+);
+
+-- Indexer progress per contract, '' is the checkpoint of processors
+-- watching every address
+CREATE TABLE IF NOT EXISTS indexer_checkpoints (
+    id SERIAL PRIMARY KEY,
+    chain VARCHAR(50) NOT NULL,
+    contract_address VARCHAR(42) NOT NULL DEFAULT '',
+    protocol VARCHAR(100),
+    last_processed_block BIGINT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Block ranges the indexer has to retry
+CREATE TABLE IF NOT EXISTS indexer_failed_ranges (
+    id SERIAL PRIMARY KEY,
+    chain VARCHAR(50) NOT NULL,
+    contracts TEXT NOT NULL DEFAULT '',
+    from_block BIGINT NOT NULL,
+    to_block BIGINT NOT NULL,
+    attempts INT NOT NULL DEFAULT 1,
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(chain, contracts, from_block, to_block)
+);
+
+-- Tokens table
+CREATE TABLE IF NOT EXISTS tokens (
+    id SERIAL PRIMARY KEY,
+    address VARCHAR(42) NOT NULL,
+    chain VARCHAR(50) NOT NULL,
+    symbol VARCHAR(20),
+    name VARCHAR(100),
+    decimals INT DEFAULT 18,
+    total_supply NUMERIC(78, 0),
+    logo VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(chain, address)
+);
+
+-- Token prices table
+CREATE TABLE IF NOT EXISTS token_prices (
+    id SERIAL PRIMARY KEY,
+    token_id INT REFERENCES tokens(id),
+    price_usd NUMERIC(30, 18),
+    source VARCHAR(50),
+    confidence FLOAT DEFAULT 1.0,
+    timestamp TIMESTAMP NOT NULL
+);
+
+-- Logs a processor failed to decode, topics comma-joined
+CREATE TABLE IF NOT EXISTS indexer_dead_letters (
+    id SERIAL PRIMARY KEY,
+    chain VARCHAR(50) NOT NULL,
+    protocol VARCHAR(100) NOT NULL,
+    address VARCHAR(42) NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_hash VARCHAR(66),
+    transaction_hash VARCHAR(66) NOT NULL,
+    log_index INT NOT NULL,
+    topics TEXT NOT NULL DEFAULT '',
+    data TEXT NOT NULL DEFAULT '',
+    error TEXT,
+    attempts INT NOT NULL DEFAULT 1,
+    timestamp TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(chain, transaction_hash, log_index, protocol)
+);
+
+-- Last block delivered to each event sink
+CREATE TABLE IF NOT EXISTS indexer_sink_offsets (
+    sink TEXT NOT NULL,
+    chain VARCHAR(50) NOT NULL,
+    block BIGINT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(sink, chain)
+);
+
+-- Tables created by the indexer before it shared this schema name their
+-- columns after the block
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'indexed_blocks' AND column_name = 'block_number') THEN
+        ALTER TABLE indexed_blocks RENAME COLUMN block_number TO number;
+        ALTER TABLE indexed_blocks RENAME COLUMN block_hash TO hash;
+    END IF;
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'indexed_blocks' AND column_name = 'block_timestamp') THEN
+        ALTER TABLE indexed_blocks RENAME COLUMN block_timestamp TO timestamp;
+    END IF;
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'events' AND column_name = 'block_timestamp') THEN
+        ALTER TABLE events RENAME COLUMN block_timestamp TO timestamp;
+    END IF;
+END $$;
+
+-- Columns added after the initial release
+ALTER TABLE tvl_snapshots ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'tentative';
+ALTER TABLE events ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'tentative';
+ALTER TABLE events ADD COLUMN IF NOT EXISTS event_signature VARCHAR(100);
+ALTER TABLE events ADD COLUMN IF NOT EXISTS timestamp TIMESTAMP;
+ALTER TABLE events ALTER COLUMN timestamp DROP DEFAULT;
+ALTER TABLE indexed_blocks ADD COLUMN IF NOT EXISTS parent_hash VARCHAR(66);
+ALTER TABLE indexed_blocks ADD COLUMN IF NOT EXISTS timestamp TIMESTAMP;
+ALTER TABLE indexed_blocks ADD COLUMN IF NOT EXISTS indexed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE indexed_blocks ALTER COLUMN timestamp DROP DEFAULT;
+
+-- Checkpoint tables of the retired scripts/migrations schema were keyed by chain_id
+ALTER TABLE indexer_checkpoints ADD COLUMN IF NOT EXISTS chain VARCHAR(50);
+ALTER TABLE indexer_checkpoints ADD COLUMN IF NOT EXISTS protocol VARCHAR(100);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_tvl_protocol_time ON tvl_snapshots(protocol, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_tvl_chain_time ON tvl_snapshots(chain, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_events_chain_block ON events(chain, block_number);
+CREATE INDEX IF NOT EXISTS idx_events_protocol ON events(protocol);
+CREATE INDEX IF NOT EXISTS idx_events_address ON events(address);
+CREATE INDEX IF NOT EXISTS idx_events_name ON events(event_name);
+CREATE INDEX IF NOT EXISTS idx_indexed_blocks_chain_timestamp ON indexed_blocks(chain, timestamp);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_indexer_checkpoints_chain_contract ON indexer_checkpoints(chain, contract_address);
+CREATE INDEX IF NOT EXISTS idx_token_prices_time ON token_prices(token_id, timestamp DESC);
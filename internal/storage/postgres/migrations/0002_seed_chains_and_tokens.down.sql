@@ -0,0 +1,22 @@
+-- Seeded tokens with recorded prices are kept
+DELETE FROM tokens t
+WHERE (t.chain, t.address) IN (
+    ('ethereum', '0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48'),
+    ('ethereum', '0x6B175474E89094C44Da98b954EedeAC495271d0F'),
+    ('ethereum', '0xdAC17F958D2ee523a2206206994597C13D831ec7'),
+    ('ethereum', '0x2260FAC5E5542a773Aa44fBCfeDf7C193bc2C599'),
+    ('ethereum', '0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2'),
+    ('polygon', '0x2791Bca1f2de4661ED88A30C99A7a9449Aa84174'),
+    ('polygon', '0x8f3Cf7ad23Cd3CaDbD9735AFf958023239c6A063'),
+    ('polygon', '0xc2132D05D31c914a87C6611C10748AEb04B58e8F'),
+    ('bsc', '0x8AC76a51cc950d9822D68b83fE1Ad97B32Cd580d'),
+    ('bsc', '0x55d398326f99059fF775485246999027B3197955'),
+    ('bsc', '0xe9e7CEA3DedcA5984780Bafc599bD69ADd087D56'),
+    ('arbitrum', '0xFF970A61A04b1cA14834A43f5dE4533eBDDB5CC8'),
+    ('arbitrum', '0xDA10009cBd5D07dd0CeCc66161FC93D7c9000da1'),
+    ('arbitrum', '0xFd086bC7CD5C481DCC9C85ebE478A1C0b69FCbb9')
+)
+AND NOT EXISTS (SELECT 1 FROM token_prices p WHERE p.token_id = t.id);
+
+DELETE FROM chains
+WHERE name IN ('ethereum', 'bsc', 'polygon', 'arbitrum', 'optimism', 'avalanche', 'base', 'fantom');
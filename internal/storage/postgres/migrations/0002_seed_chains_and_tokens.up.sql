@@ -0,0 +1,35 @@
+-- Well-known chains and tokens. Existing rows are left alone, so chains
+-- configured with private RPC endpoints keep them. Only Ethereum is active;
+-- the others are known but not connected until activated.
+
+INSERT INTO chains (name, chain_id, rpc_endpoint, ws_endpoint, explorer, native_token, block_time, is_active) VALUES
+('ethereum', 1, 'https://ethereum-rpc.publicnode.com', 'wss://ethereum-rpc.publicnode.com', 'https://etherscan.io', 'ETH', 12, true),
+('bsc', 56, 'https://bsc-dataseed1.binance.org', NULL, 'https://bscscan.com', 'BNB', 3, false),
+('polygon', 137, 'https://polygon-rpc.com', NULL, 'https://polygonscan.com', 'MATIC', 2, false),
+('arbitrum', 42161, 'https://arb1.arbitrum.io/rpc', NULL, 'https://arbiscan.io', 'ETH', 1, false),
+('optimism', 10, 'https://mainnet.optimism.io', NULL, 'https://optimistic.etherscan.io', 'ETH', 2, false),
+('avalanche', 43114, 'https://api.avax.network/ext/bc/C/rpc', 'wss://api.avax.network/ext/bc/C/ws', 'https://snowtrace.io', 'AVAX', 2, false),
+('base', 8453, 'https://mainnet.base.org', NULL, 'https://basescan.org', 'ETH', 2, false),
+('fantom', 250, 'https://rpc.ftm.tools', NULL, 'https://ftmscan.com', 'FTM', 1, false)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO tokens (address, chain, symbol, name, decimals) VALUES
+-- Ethereum
+('0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48', 'ethereum', 'USDC', 'USD Coin', 6),
+('0x6B175474E89094C44Da98b954EedeAC495271d0F', 'ethereum', 'DAI', 'Dai Stablecoin', 18),
+('0xdAC17F958D2ee523a2206206994597C13D831ec7', 'ethereum', 'USDT', 'Tether USD', 6),
+('0x2260FAC5E5542a773Aa44fBCfeDf7C193bc2C599', 'ethereum', 'WBTC', 'Wrapped BTC', 8),
+('0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2', 'ethereum', 'WETH', 'Wrapped Ether', 18),
+-- Polygon
+('0x2791Bca1f2de4661ED88A30C99A7a9449Aa84174', 'polygon', 'USDC', 'USD Coin (PoS)', 6),
+('0x8f3Cf7ad23Cd3CaDbD9735AFf958023239c6A063', 'polygon', 'DAI', 'Dai Stablecoin (PoS)', 18),
+('0xc2132D05D31c914a87C6611C10748AEb04B58e8F', 'polygon', 'USDT', 'Tether USD (PoS)', 6),
+-- BSC
+('0x8AC76a51cc950d9822D68b83fE1Ad97B32Cd580d', 'bsc', 'USDC', 'USD Coin', 18),
+('0x55d398326f99059fF775485246999027B3197955', 'bsc', 'USDT', 'Tether USD', 18),
+('0xe9e7CEA3DedcA5984780Bafc599bD69ADd087D56', 'bsc', 'BUSD', 'BUSD Token', 18),
+-- Arbitrum
+('0xFF970A61A04b1cA14834A43f5dE4533eBDDB5CC8', 'arbitrum', 'USDC.e', 'USD Coin (Arb1)', 6),
+('0xDA10009cBd5D07dd0CeCc66161FC93D7c9000da1', 'arbitrum', 'DAI', 'Dai Stablecoin', 18),
+('0xFd086bC7CD5C481DCC9C85ebE478A1C0b69FCbb9', 'arbitrum', 'USDT', 'Tether USD', 6)
+ON CONFLICT DO NOTHING;
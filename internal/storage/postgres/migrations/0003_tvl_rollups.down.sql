@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_tvl_snapshots_time;
+DROP TABLE IF EXISTS tvl_rollups;
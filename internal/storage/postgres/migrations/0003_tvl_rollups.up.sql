@@ -0,0 +1,25 @@
+-- Hourly and daily rollups of tvl_snapshots, kept after the snapshots they
+-- summarize are pruned. timestamp is the start of the interval; block_number,
+-- total_usd and breakdown are those of its last snapshot.
+CREATE TABLE IF NOT EXISTS tvl_rollups (
+    id SERIAL PRIMARY KEY,
+    protocol VARCHAR(100) NOT NULL,
+    chain VARCHAR(50) NOT NULL DEFAULT '',
+    resolution VARCHAR(16) NOT NULL,
+    timestamp TIMESTAMP NOT NULL,
+    block_number BIGINT,
+    total_usd NUMERIC(30, 8),
+    open_usd NUMERIC(30, 8) NOT NULL,
+    high_usd NUMERIC(30, 8) NOT NULL,
+    low_usd NUMERIC(30, 8) NOT NULL,
+    avg_usd NUMERIC(30, 8) NOT NULL,
+    samples INT NOT NULL,
+    breakdown JSONB,
+    status VARCHAR(16) NOT NULL DEFAULT 'tentative',
+    UNIQUE(protocol, chain, resolution, timestamp)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tvl_rollups_resolution_time ON tvl_rollups(resolution, timestamp);
+
+-- Retention prunes snapshots by time across protocols
+CREATE INDEX IF NOT EXISTS idx_tvl_snapshots_time ON tvl_snapshots(timestamp);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS protocol_token_holdings;
@@ -0,0 +1,34 @@
+-- The assets of each TVL snapshot, one row per token, so history can be
+-- sliced by token and chain. Snapshots keep their breakdown as well.
+CREATE TABLE IF NOT EXISTS protocol_token_holdings (
+    id SERIAL PRIMARY KEY,
+    snapshot_id INT NOT NULL REFERENCES tvl_snapshots(id) ON DELETE CASCADE,
+    token VARCHAR(42) NOT NULL,
+    symbol TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL DEFAULT '',
+    decimals INT NOT NULL DEFAULT 18,
+    amount NUMERIC(78, 0),
+    price_usd NUMERIC(30, 18),
+    value_usd NUMERIC(30, 8),
+    percentage FLOAT NOT NULL DEFAULT 0,
+    UNIQUE(snapshot_id, token)
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_holdings_token ON protocol_token_holdings(LOWER(token), snapshot_id);
+
+-- Snapshots saved before holdings were
+INSERT INTO protocol_token_holdings (snapshot_id, token, symbol, name, decimals, amount, price_usd, value_usd, percentage)
+SELECT s.id,
+       asset.value->>'token',
+       COALESCE(asset.value->>'symbol', ''),
+       COALESCE(asset.value->>'name', ''),
+       COALESCE((asset.value->>'decimals')::INT, 18),
+       (asset.value->>'amount')::NUMERIC,
+       (asset.value->>'price_usd')::NUMERIC,
+       (asset.value->>'value_usd')::NUMERIC,
+       COALESCE((asset.value->>'percentage')::FLOAT, 0)
+FROM tvl_snapshots s, jsonb_each(s.breakdown) asset
+WHERE jsonb_typeof(s.breakdown) = 'object'
+  AND jsonb_typeof(asset.value) = 'object'
+  AND asset.value->>'token' IS NOT NULL
+ON CONFLICT (snapshot_id, token) DO NOTHING;
@@ -0,0 +1,10 @@
+ALTER TABLE protocol_token_holdings DROP CONSTRAINT IF EXISTS protocol_token_holdings_snapshot_id_token_symbol_key;
+DELETE FROM protocol_token_holdings h
+USING protocol_token_holdings first
+WHERE h.snapshot_id = first.snapshot_id AND h.token = first.token AND h.id > first.id;
+ALTER TABLE protocol_token_holdings ADD CONSTRAINT protocol_token_holdings_snapshot_id_token_key
+    UNIQUE (snapshot_id, token);
+
+DROP INDEX IF EXISTS idx_tvl_snapshots_time_unique;
+ALTER TABLE tvl_rollups DROP COLUMN IF EXISTS source;
+ALTER TABLE tvl_snapshots DROP COLUMN IF EXISTS source;
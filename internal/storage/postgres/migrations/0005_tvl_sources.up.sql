@@ -0,0 +1,16 @@
+-- Where TVL history came from: empty for snapshots the aggregator computed,
+-- the name of the dataset for imported ones
+ALTER TABLE tvl_snapshots ADD COLUMN IF NOT EXISTS source VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE tvl_rollups ADD COLUMN IF NOT EXISTS source VARCHAR(50) NOT NULL DEFAULT '';
+
+-- Snapshots without a block, such as imported ones, have a NULL block_number
+-- and are unique by time instead. There was at most one block 0 snapshot of
+-- a protocol and chain.
+UPDATE tvl_snapshots SET block_number = NULL WHERE block_number = 0;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tvl_snapshots_time_unique
+    ON tvl_snapshots(protocol, chain, timestamp) WHERE block_number IS NULL;
+
+-- Imported holdings may only be known by symbol, under the zero address
+ALTER TABLE protocol_token_holdings DROP CONSTRAINT IF EXISTS protocol_token_holdings_snapshot_id_token_key;
+ALTER TABLE protocol_token_holdings ADD CONSTRAINT protocol_token_holdings_snapshot_id_token_symbol_key
+    UNIQUE (snapshot_id, token, symbol);
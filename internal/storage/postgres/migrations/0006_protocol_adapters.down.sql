@@ -0,0 +1,2 @@
+ALTER TABLE contracts DROP COLUMN IF EXISTS adapter;
+ALTER TABLE protocols DROP COLUMN IF EXISTS adapter;
@@ -0,0 +1,4 @@
+-- The calculator adapter reading a protocol's TVL, or a contract's where it
+-- differs. Empty uses the default adapter of the protocol's type.
+ALTER TABLE protocols ADD COLUMN IF NOT EXISTS adapter VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE contracts ADD COLUMN IF NOT EXISTS adapter VARCHAR(50) NOT NULL DEFAULT '';
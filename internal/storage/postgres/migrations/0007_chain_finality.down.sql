@@ -0,0 +1,2 @@
+ALTER TABLE chains DROP COLUMN IF EXISTS confirmations;
+ALTER TABLE chains DROP COLUMN IF EXISTS finality;
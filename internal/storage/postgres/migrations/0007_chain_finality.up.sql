@@ -0,0 +1,4 @@
+-- The block consumers of a chain treat as its head. An empty finality is the
+-- latest block less the confirmations, or the consumer's default with none.
+ALTER TABLE chains ADD COLUMN IF NOT EXISTS finality VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE chains ADD COLUMN IF NOT EXISTS confirmations BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE chains DROP COLUMN IF EXISTS source;
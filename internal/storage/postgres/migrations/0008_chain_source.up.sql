@@ -0,0 +1,3 @@
+-- The file a chain is defined in, so the chain registry deletes the chains
+-- dropped from its files. Empty for chains of the table alone.
+ALTER TABLE chains ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,114 @@
+// internal/storage/postgres/numeric.go
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+)
+
+// numericPrec is the precision NUMERIC values are read at. NUMERIC(30, 8)
+// and NUMERIC(30, 18) need about 100 bits, so any stored value reads back
+// with the digits it was written with.
+const numericPrec = 128
+
+// formatNumeric writes a big.Float as a NUMERIC literal, nil as NULL
+func formatNumeric(f *big.Float) (interface{}, error) {
+	if f == nil {
+		return nil, nil
+	}
+	if f.IsInf() {
+		return nil, fmt.Errorf("cannot store infinite value")
+	}
+	return f.Text('f', -1), nil
+}
+
+// parseNumeric reads a NUMERIC column, NULL as nil
+func parseNumeric(s sql.NullString) (*big.Float, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return parseDecimal(s.String)
+}
+
+func parseDecimal(s string) (*big.Float, error) {
+	f, _, err := big.ParseFloat(s, 10, numericPrec, big.ToNearestEven)
+	if err != nil {
+		return nil, fmt.Errorf("invalid numeric %q: %w", s, err)
+	}
+	return f, nil
+}
+
+// formatInt writes a big.Int as a NUMERIC literal, nil as NULL
+func formatInt(i *big.Int) interface{} {
+	if i == nil {
+		return nil
+	}
+	return i.String()
+}
+
+// parseInt reads an integer NUMERIC column, NULL as nil
+func parseInt(s sql.NullString) (*big.Int, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	i, ok := new(big.Int).SetString(s.String, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer %q", s.String)
+	}
+	return i, nil
+}
+
+// breakdownAsset decodes the prices of a stored AssetTVL at numericPrec;
+// big.Float's own decoding rounds them to 64 bits
+type breakdownAsset struct {
+	models.AssetTVL
+	PriceUSD *string `json:"price_usd"`
+	ValueUSD *string `json:"value_usd"`
+}
+
+func encodeBreakdown(breakdown map[string]*models.AssetTVL) ([]byte, error) {
+	return json.Marshal(breakdown)
+}
+
+// decodeBreakdown reads a breakdown JSONB column, NULL as nil
+func decodeBreakdown(data []byte) (map[string]*models.AssetTVL, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var stored map[string]*breakdownAsset
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("invalid breakdown: %w", err)
+	}
+	if stored == nil {
+		return nil, nil
+	}
+
+	breakdown := make(map[string]*models.AssetTVL, len(stored))
+	for key, b := range stored {
+		if b == nil {
+			breakdown[key] = nil
+			continue
+		}
+
+		asset := b.AssetTVL
+		var err error
+		if b.PriceUSD != nil {
+			if asset.PriceUSD, err = parseDecimal(*b.PriceUSD); err != nil {
+				return nil, err
+			}
+		}
+		if b.ValueUSD != nil {
+			if asset.ValueUSD, err = parseDecimal(*b.ValueUSD); err != nil {
+				return nil, err
+			}
+		}
+		breakdown[key] = &asset
+	}
+
+	return breakdown, nil
+}
@@ -6,9 +6,10 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"math/big"
+	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
@@ -18,157 +19,148 @@ import (
 // PostgresStorage implements the Storage interface
 type PostgresStorage struct {
 	db *sqlx.DB
+	// conn runs the queries: db, or the transaction of a PostgresTx
+	conn conn
+	tx   *sqlx.Tx
+	// savepoints numbers the savepoints of nested transactions
+	savepoints *uint64
 }
 
-// PostgresTx implements the Tx interface for PostgreSQL
-type PostgresTx struct {
-	tx *sqlx.Tx
-}
-
-// Commit commits the transaction
-func (ptx *PostgresTx) Commit() error {
-	return ptx.tx.Commit()
-}
-
-// Rollback rolls back the transaction
-func (ptx *PostgresTx) Rollback() error {
-	return ptx.tx.Rollback()
-}
-
-// All other interface methods should be implemented by delegating to PostgresStorage
-// with the transaction context. For brevity, I'll add minimal implementations.
-func (ptx *PostgresTx) BeginTx(ctx context.Context) (storage.Tx, error) {
-	// Can't nest transactions in PostgreSQL in this simple implementation
-	return ptx, nil
-}
-
-func (ptx *PostgresTx) Close() error {
-	return ptx.tx.Rollback() // Rollback if not committed
-}
-
-// Protocol methods - these should use the transaction
-func (ptx *PostgresTx) GetProtocol(ctx context.Context, name string) (*models.Protocol, error) {
-	// TODO: Implement with transaction
-	return nil, fmt.Errorf("not implemented")
-}
-
-func (ptx *PostgresTx) GetProtocols(ctx context.Context) ([]*models.Protocol, error) {
-	return nil, fmt.Errorf("not implemented")
-}
-
-func (ptx *PostgresTx) SaveProtocol(ctx context.Context, protocol *models.Protocol) error {
-	return fmt.Errorf("not implemented")
+// conn is satisfied by both *sqlx.DB and *sqlx.Tx, so the same queries run
+// on their own or as part of a transaction
+type conn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
 }
 
-func (ptx *PostgresTx) UpdateProtocol(ctx context.Context, protocol *models.Protocol) error {
-	return fmt.Errorf("not implemented")
-}
-
-func (ptx *PostgresTx) DeleteProtocol(ctx context.Context, name string) error {
-	return fmt.Errorf("not implemented")
-}
-
-// TVL methods
-func (ptx *PostgresTx) SaveTVLSnapshot(ctx context.Context, snapshot *models.TVLSnapshot) error {
-	return fmt.Errorf("not implemented")
-}
+// NewPostgresStorage creates a new PostgreSQL storage
+func NewPostgresStorage(connectionString string) (*PostgresStorage, error) {
+	db, err := Connect(connectionString)
+	if err != nil {
+		return nil, err
+	}
 
-func (ptx *PostgresTx) GetLatestTVL(ctx context.Context, protocol, chain string) (*models.TVLSnapshot, error) {
-	return nil, fmt.Errorf("not implemented")
-}
+	ps := &PostgresStorage{db: db, conn: db}
 
-func (ptx *PostgresTx) GetHistoricalTVL(ctx context.Context, protocol, chain string, from, to time.Time) ([]*models.TVLSnapshot, error) {
-	return nil, fmt.Errorf("not implemented")
-}
+	// Run migrations
+	if err := ps.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
 
-func (ptx *PostgresTx) GetTVLByBlock(ctx context.Context, protocol, chain string, blockNumber uint64) (*models.TVLSnapshot, error) {
-	return nil, fmt.Errorf("not implemented")
+	return ps, nil
 }
 
-func (ptx *PostgresTx) GetAggregatedTVL(ctx context.Context) (*models.AggregatedTVL, error) {
-	return nil, fmt.Errorf("not implemented")
-}
+// Connect opens a connection pool without migrating the schema
+func Connect(connectionString string) (*sqlx.DB, error) {
+	db, err := sqlx.Connect("postgres", connectionString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
 
-// Chain methods
-func (ptx *PostgresTx) GetChain(ctx context.Context, name string) (*models.Chain, error) {
-	return nil, fmt.Errorf("not implemented")
-}
+	// Configure connection pool
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(5 * time.Minute)
 
-func (ptx *PostgresTx) GetChains(ctx context.Context) ([]*models.Chain, error) {
-	return nil, fmt.Errorf("not implemented")
+	return db, nil
 }
 
-func (ptx *PostgresTx) SaveChain(ctx context.Context, chain *models.Chain) error {
-	return fmt.Errorf("not implemented")
+// PostgresTx implements the Tx interface for PostgreSQL. Every storage
+// method runs inside the transaction; a transaction begun from a PostgresTx
+// is a savepoint of it.
+type PostgresTx struct {
+	*PostgresStorage
+	savepoint string // empty for the outermost transaction
+	done      bool
 }
 
-func (ptx *PostgresTx) UpdateChainStats(ctx context.Context, stats *models.ChainStats) error {
-	return fmt.Errorf("not implemented")
+// BeginTx starts a new transaction, or a savepoint inside the current one
+func (ps *PostgresStorage) BeginTx(ctx context.Context) (storage.Tx, error) {
+	return ps.begin(ctx)
 }
 
-// Event methods
-func (ptx *PostgresTx) SaveEvents(ctx context.Context, events []*models.Event) error {
-	return fmt.Errorf("not implemented")
-}
+func (ps *PostgresStorage) begin(ctx context.Context) (*PostgresTx, error) {
+	if ps.tx != nil {
+		savepoint := fmt.Sprintf("sp_%d", atomic.AddUint64(ps.savepoints, 1))
+		if _, err := ps.tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+			return nil, err
+		}
+		return &PostgresTx{PostgresStorage: ps, savepoint: savepoint}, nil
+	}
 
-func (ptx *PostgresTx) GetEvents(ctx context.Context, filter storage.EventFilter) ([]*models.Event, error) {
-	return nil, fmt.Errorf("not implemented")
-}
+	tx, err := ps.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
 
-func (ptx *PostgresTx) SaveBlock(ctx context.Context, block *models.Block) error {
-	return fmt.Errorf("not implemented")
+	return &PostgresTx{
+		PostgresStorage: &PostgresStorage{
+			db:         ps.db,
+			conn:       tx,
+			tx:         tx,
+			savepoints: new(uint64),
+		},
+	}, nil
 }
 
-func (ptx *PostgresTx) GetLastIndexedBlock(ctx context.Context, chain string) (uint64, error) {
-	return 0, fmt.Errorf("not implemented")
-}
+// atomic runs fn in a transaction, or in a savepoint when the storage is a
+// transaction already, so a failing write leaves nothing behind either way
+func (ps *PostgresStorage) atomic(ctx context.Context, fn func(tx conn) error) error {
+	tx, err := ps.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-// Token methods
-func (ptx *PostgresTx) GetToken(ctx context.Context, address, chain string) (*models.Token, error) {
-	return nil, fmt.Errorf("not implemented")
-}
+	if err := fn(tx.conn); err != nil {
+		return err
+	}
 
-func (ptx *PostgresTx) SaveToken(ctx context.Context, token *models.Token) error {
-	return fmt.Errorf("not implemented")
+	return tx.Commit()
 }
 
-func (ptx *PostgresTx) UpdateTokenPrice(ctx context.Context, price *models.TokenPrice) error {
-	return fmt.Errorf("not implemented")
-}
+// Commit commits the transaction, or releases its savepoint
+func (ptx *PostgresTx) Commit() error {
+	if ptx.done {
+		return sql.ErrTxDone
+	}
+	ptx.done = true
 
-func (ptx *PostgresTx) GetTokenPrices(ctx context.Context, addresses []string) (map[string]*models.TokenPrice, error) {
-	return nil, fmt.Errorf("not implemented")
+	if ptx.savepoint == "" {
+		return ptx.tx.Commit()
+	}
+	_, err := ptx.tx.Exec("RELEASE SAVEPOINT " + ptx.savepoint)
+	return err
 }
 
-// NewPostgresStorage creates a new PostgreSQL storage
-func NewPostgresStorage(connectionString string) (*PostgresStorage, error) {
-	db, err := sqlx.Connect("postgres", connectionString)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
+// Rollback rolls back the transaction, or everything since its savepoint
+func (ptx *PostgresTx) Rollback() error {
+	if ptx.done {
+		return sql.ErrTxDone
 	}
+	ptx.done = true
 
-	// Configure connection pool
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
-
-	ps := &PostgresStorage{db: db}
-
-	// Run migrations
-	if err := ps.migrate(); err != nil {
-		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	if ptx.savepoint == "" {
+		return ptx.tx.Rollback()
 	}
-
-	return ps, nil
+	if _, err := ptx.tx.Exec("ROLLBACK TO SAVEPOINT " + ptx.savepoint); err != nil {
+		return err
+	}
+	_, err := ptx.tx.Exec("RELEASE SAVEPOINT " + ptx.savepoint)
+	return err
 }
 
-// BeginTx starts a new transaction
-func (ps *PostgresStorage) BeginTx(ctx context.Context) (storage.Tx, error) {
-	tx, err := ps.db.BeginTxx(ctx, nil)
-	if err != nil {
-		return nil, err
+// Close rolls the transaction back unless it was committed
+func (ptx *PostgresTx) Close() error {
+	if ptx.done {
+		return nil
 	}
-	return &PostgresTx{tx: tx}, nil
+	return ptx.Rollback()
 }
 
 // Close closes the database connection
@@ -176,16 +168,19 @@ func (ps *PostgresStorage) Close() error {
 	return ps.db.Close()
 }
 
+const protocolColumns = `id, name, type, COALESCE(description, '') AS description,
+        COALESCE(website, '') AS website, COALESCE(logo, '') AS logo, adapter, created_at, updated_at`
+
 // GetProtocol retrieves a protocol by name
 func (ps *PostgresStorage) GetProtocol(ctx context.Context, name string) (*models.Protocol, error) {
 	var protocol models.Protocol
 	query := `
-        SELECT id, name, type, description, website, logo, created_at, updated_at
+        SELECT ` + protocolColumns + `
         FROM protocols
         WHERE name = $1
     `
 
-	err := ps.db.GetContext(ctx, &protocol, query, name)
+	err := ps.conn.GetContext(ctx, &protocol, query, name)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("protocol not found: %s", name)
 	}
@@ -206,14 +201,14 @@ func (ps *PostgresStorage) GetProtocol(ctx context.Context, name string) (*model
 // GetProtocols retrieves all protocols
 func (ps *PostgresStorage) GetProtocols(ctx context.Context) ([]*models.Protocol, error) {
 	query := `
-        SELECT id, name, type, description, website, logo, created_at, updated_at
+        SELECT ` + protocolColumns + `
         FROM protocols
         WHERE is_active = true
         ORDER BY name
     `
 
 	var protocols []*models.Protocol
-	err := ps.db.SelectContext(ctx, &protocols, query)
+	err := ps.conn.SelectContext(ctx, &protocols, query)
 	if err != nil {
 		return nil, err
 	}
@@ -222,7 +217,7 @@ func (ps *PostgresStorage) GetProtocols(ctx context.Context) ([]*models.Protocol
 	for _, protocol := range protocols {
 		contracts, err := ps.getProtocolContracts(ctx, protocol.ID)
 		if err != nil {
-			continue
+			return nil, err
 		}
 		protocol.Chains = contracts
 	}
@@ -232,113 +227,98 @@ func (ps *PostgresStorage) GetProtocols(ctx context.Context) ([]*models.Protocol
 
 // SaveProtocol saves a new protocol
 func (ps *PostgresStorage) SaveProtocol(ctx context.Context, protocol *models.Protocol) error {
-	tx, err := ps.db.BeginTxx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
 	query := `
-        INSERT INTO protocols (name, type, description, website, logo)
-        VALUES ($1, $2, $3, $4, $5)
-        RETURNING id
+        INSERT INTO protocols (name, type, description, website, logo, adapter)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at, updated_at
     `
 
-	err = tx.GetContext(ctx, &protocol.ID, query,
-		protocol.Name, protocol.Type, protocol.Description, protocol.Website, protocol.Logo)
-	if err != nil {
-		return err
-	}
+	return ps.atomic(ctx, func(tx conn) error {
+		err := tx.QueryRowContext(ctx, query,
+			protocol.Name, protocol.Type, protocol.Description, protocol.Website, protocol.Logo, protocol.Adapter,
+		).Scan(&protocol.ID, &protocol.CreatedAt, &protocol.UpdatedAt)
+		if err != nil {
+			return err
+		}
 
-	// Save contracts
-	for chain, contracts := range protocol.Chains {
-		for _, contract := range contracts {
-			if err := ps.saveContractTx(ctx, tx, protocol.ID, chain, contract); err != nil {
-				return err
+		// Save contracts
+		for chain, contracts := range protocol.Chains {
+			for _, contract := range contracts {
+				if err := saveContract(ctx, tx, protocol.ID, chain, contract); err != nil {
+					return err
+				}
 			}
 		}
-	}
 
-	return tx.Commit()
+		return nil
+	})
 }
 
-// SaveTVLSnapshot saves a TVL snapshot
-func (ps *PostgresStorage) SaveTVLSnapshot(ctx context.Context, snapshot *models.TVLSnapshot) error {
-	breakdown, err := json.Marshal(snapshot.Breakdown)
-	if err != nil {
-		return err
-	}
-
+// UpdateProtocol updates a protocol by name and replaces its contracts
+func (ps *PostgresStorage) UpdateProtocol(ctx context.Context, protocol *models.Protocol) error {
 	query := `
-        INSERT INTO tvl_snapshots (protocol, chain, block_number, total_usd, breakdown, timestamp)
-        VALUES ($1, $2, $3, $4, $5, $6)
-        ON CONFLICT (protocol, chain, block_number) 
-        DO UPDATE SET total_usd = EXCLUDED.total_usd, breakdown = EXCLUDED.breakdown
+        UPDATE protocols
+        SET type = $2, description = $3, website = $4, logo = $5, adapter = $6, updated_at = CURRENT_TIMESTAMP
+        WHERE name = $1
+        RETURNING id, created_at, updated_at
     `
 
-	totalUSD, _ := snapshot.TotalUSD.Float64()
+	return ps.atomic(ctx, func(tx conn) error {
+		err := tx.QueryRowContext(ctx, query,
+			protocol.Name, protocol.Type, protocol.Description, protocol.Website, protocol.Logo, protocol.Adapter,
+		).Scan(&protocol.ID, &protocol.CreatedAt, &protocol.UpdatedAt)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("protocol not found: %s", protocol.Name)
+		}
+		if err != nil {
+			return err
+		}
 
-	_, err = ps.db.ExecContext(ctx, query,
-		snapshot.Protocol,
-		snapshot.Chain,
-		snapshot.BlockNumber,
-		totalUSD,
-		breakdown,
-		snapshot.Timestamp,
-	)
+		if _, err := tx.ExecContext(ctx, "DELETE FROM contracts WHERE protocol_id = $1", protocol.ID); err != nil {
+			return err
+		}
+		for chain, contracts := range protocol.Chains {
+			for _, contract := range contracts {
+				if err := saveContract(ctx, tx, protocol.ID, chain, contract); err != nil {
+					return err
+				}
+			}
+		}
 
-	return err
+		return nil
+	})
 }
 
-// GetLatestTVL retrieves the latest TVL snapshot
-func (ps *PostgresStorage) GetLatestTVL(ctx context.Context, protocol, chain string) (*models.TVLSnapshot, error) {
-	var snapshot models.TVLSnapshot
-	var breakdown []byte
-	var totalUSD float64
-
-	query := `
-        SELECT id, protocol, chain, block_number, total_usd, breakdown, timestamp
-        FROM tvl_snapshots
-        WHERE protocol = $1 AND ($2 = '' OR chain = $2)
-        ORDER BY timestamp DESC
-        LIMIT 1
-    `
-
-	err := ps.db.QueryRowContext(ctx, query, protocol, chain).Scan(
-		&snapshot.ID,
-		&snapshot.Protocol,
-		&snapshot.Chain,
-		&snapshot.BlockNumber,
-		&totalUSD,
-		&breakdown,
-		&snapshot.Timestamp,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
+// DeleteProtocol deletes a protocol and its contracts. Its TVL snapshots
+// are kept.
+func (ps *PostgresStorage) DeleteProtocol(ctx context.Context, name string) error {
+	result, err := ps.conn.ExecContext(ctx, "DELETE FROM protocols WHERE name = $1", name)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	snapshot.TotalUSD = new(big.Float).SetFloat64(totalUSD)
-
-	if err := json.Unmarshal(breakdown, &snapshot.Breakdown); err != nil {
-		return nil, err
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
 	}
-
-	return &snapshot, nil
+	if deleted == 0 {
+		return fmt.Errorf("protocol not found: %s", name)
+	}
+	return nil
 }
 
 // Helper methods
 func (ps *PostgresStorage) getProtocolContracts(ctx context.Context, protocolID uint64) (map[string][]models.ContractConfig, error) {
 	query := `
-        SELECT chain, address, name, type, version, tokens, pool_id, vault_id, deploy_block
+        SELECT COALESCE(chain, ''), address, COALESCE(name, ''), COALESCE(type, ''), COALESCE(version, ''),
+               tokens, COALESCE(pool_id, ''), COALESCE(vault_id, ''), COALESCE(deploy_block, 0), COALESCE(abi, ''),
+               adapter
         FROM contracts
         WHERE protocol_id = $1
+        ORDER BY id
     `
 
-	rows, err := ps.db.QueryContext(ctx, query, protocolID)
+	rows, err := ps.conn.QueryContext(ctx, query, protocolID)
 	if err != nil {
 		return nil, err
 	}
@@ -347,13 +327,13 @@ func (ps *PostgresStorage) getProtocolContracts(ctx context.Context, protocolID
 	contracts := make(map[string][]models.ContractConfig)
 
 	for rows.Next() {
-		var chain string
+		var chain, address string
 		var contract models.ContractConfig
 		var tokens []byte
 
 		err := rows.Scan(
 			&chain,
-			&contract.Address,
+			&address,
 			&contract.Name,
 			&contract.Type,
 			&contract.Version,
@@ -361,35 +341,42 @@ func (ps *PostgresStorage) getProtocolContracts(ctx context.Context, protocolID
 			&contract.PoolID,
 			&contract.VaultID,
 			&contract.DeployBlock,
+			&contract.ABI,
+			&contract.Adapter,
 		)
 		if err != nil {
-			continue
+			return nil, err
 		}
+		contract.Address = common.HexToAddress(address)
 
 		if tokens != nil {
-			json.Unmarshal(tokens, &contract.Tokens)
+			if err := json.Unmarshal(tokens, &contract.Tokens); err != nil {
+				return nil, fmt.Errorf("invalid tokens of contract %s: %w", address, err)
+			}
 		}
 
 		contracts[chain] = append(contracts[chain], contract)
 	}
 
-	return contracts, nil
+	return contracts, rows.Err()
 }
 
-func (ps *PostgresStorage) saveContractTx(ctx context.Context, tx *sqlx.Tx, protocolID uint64, chain string, contract models.ContractConfig) error {
-	tokens, _ := json.Marshal(contract.Tokens)
+func saveContract(ctx context.Context, db conn, protocolID uint64, chain string, contract models.ContractConfig) error {
+	tokens, err := json.Marshal(contract.Tokens)
+	if err != nil {
+		return err
+	}
 
 	query := `
-        INSERT INTO contracts (protocol_id, chain, address, name, type, version, tokens, pool_id, vault_id, deploy_block)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        INSERT INTO contracts (protocol_id, chain, address, name, type, version, tokens, pool_id, vault_id, deploy_block, abi, adapter)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
     `
 
-	_, err := tx.ExecContext(ctx, query,
+	_, err = db.ExecContext(ctx, query,
 		protocolID, chain, contract.Address.Hex(), contract.Name, contract.Type,
-		contract.Version, tokens, contract.PoolID, contract.VaultID, contract.DeployBlock,
+		contract.Version, tokens, contract.PoolID, contract.VaultID, contract.DeployBlock, contract.ABI,
+		contract.Adapter,
 	)
 
 	return err
 }
-
-// Additional methods implementation...
@@ -0,0 +1,199 @@
+// internal/storage/postgres/tokens.go
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/lib/pq"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+)
+
+// GetToken retrieves a token with its latest price
+func (ps *PostgresStorage) GetToken(ctx context.Context, address, chain string) (*models.Token, error) {
+	query := `
+        SELECT t.id, t.address, t.chain, COALESCE(t.symbol, ''), COALESCE(t.name, ''), COALESCE(t.decimals, 18),
+               t.total_supply, COALESCE(t.logo, ''), t.created_at, t.updated_at, p.price_usd
+        FROM tokens t
+        LEFT JOIN LATERAL (
+            SELECT price_usd FROM token_prices
+            WHERE token_id = t.id
+            ORDER BY timestamp DESC, id DESC
+            LIMIT 1
+        ) p ON true
+        WHERE t.chain = $1 AND LOWER(t.address) = LOWER($2)
+    `
+
+	var token models.Token
+	var tokenAddress string
+	var totalSupply, price sql.NullString
+
+	err := ps.conn.QueryRowContext(ctx, query, chain, address).Scan(
+		&token.ID,
+		&tokenAddress,
+		&token.Chain,
+		&token.Symbol,
+		&token.Name,
+		&token.Decimals,
+		&totalSupply,
+		&token.Logo,
+		&token.CreatedAt,
+		&token.UpdatedAt,
+		&price,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("token not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	token.Address = common.HexToAddress(tokenAddress)
+	if token.TotalSupply, err = parseInt(totalSupply); err != nil {
+		return nil, err
+	}
+	if token.PriceUSD, err = parseNumeric(price); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// SaveToken saves a token, replacing the token at the same chain and address
+func (ps *PostgresStorage) SaveToken(ctx context.Context, token *models.Token) error {
+	query := `
+        INSERT INTO tokens (address, chain, symbol, name, decimals, total_supply, logo)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        ON CONFLICT (chain, address) DO UPDATE SET
+            symbol = EXCLUDED.symbol,
+            name = EXCLUDED.name,
+            decimals = EXCLUDED.decimals,
+            total_supply = EXCLUDED.total_supply,
+            logo = EXCLUDED.logo,
+            updated_at = CURRENT_TIMESTAMP
+        RETURNING id, created_at, updated_at
+    `
+
+	return ps.conn.QueryRowContext(ctx, query,
+		token.Address.Hex(),
+		token.Chain,
+		token.Symbol,
+		token.Name,
+		token.Decimals,
+		formatInt(token.TotalSupply),
+		token.Logo,
+	).Scan(&token.ID, &token.CreatedAt, &token.UpdatedAt)
+}
+
+// UpdateTokenPrice records a price of the token with TokenID, or of every
+// stored token at Address when it is not set
+func (ps *PostgresStorage) UpdateTokenPrice(ctx context.Context, price *models.TokenPrice) error {
+	priceUSD, err := formatNumeric(price.PriceUSD)
+	if err != nil {
+		return fmt.Errorf("invalid price_usd: %w", err)
+	}
+
+	timestamp := price.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	query := `
+        INSERT INTO token_prices (token_id, price_usd, source, confidence, timestamp)
+        SELECT id, $3, $4, $5, $6
+        FROM tokens
+        WHERE CASE WHEN $1 > 0 THEN id = $1 ELSE LOWER(address) = LOWER($2) END
+    `
+
+	result, err := ps.conn.ExecContext(ctx, query,
+		price.TokenID,
+		price.Address.Hex(),
+		priceUSD,
+		price.Source,
+		price.Confidence,
+		timestamp.UTC(),
+	)
+	if err != nil {
+		return err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		return fmt.Errorf("token not found: %s", price.Address.Hex())
+	}
+	return nil
+}
+
+// GetTokenPrices retrieves the latest price of each address, keyed by the
+// address as given. Addresses without a price are left out.
+func (ps *PostgresStorage) GetTokenPrices(ctx context.Context, addresses []string) (map[string]*models.TokenPrice, error) {
+	prices := make(map[string]*models.TokenPrice)
+	if len(addresses) == 0 {
+		return prices, nil
+	}
+
+	keys := make(map[string][]string)
+	lowered := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		lower := strings.ToLower(address)
+		if _, ok := keys[lower]; !ok {
+			lowered = append(lowered, lower)
+		}
+		keys[lower] = append(keys[lower], address)
+	}
+
+	query := `
+        SELECT DISTINCT ON (LOWER(t.address))
+               p.id, p.token_id, t.address, COALESCE(t.symbol, ''), p.price_usd,
+               COALESCE(p.source, ''), COALESCE(p.confidence, 1.0), p.timestamp
+        FROM token_prices p
+        JOIN tokens t ON t.id = p.token_id
+        WHERE LOWER(t.address) = ANY($1)
+        ORDER BY LOWER(t.address), p.timestamp DESC, p.id DESC
+    `
+
+	rows, err := ps.conn.QueryContext(ctx, query, pq.Array(lowered))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var price models.TokenPrice
+		var address string
+		var priceUSD sql.NullString
+
+		err := rows.Scan(
+			&price.ID,
+			&price.TokenID,
+			&address,
+			&price.Symbol,
+			&priceUSD,
+			&price.Source,
+			&price.Confidence,
+			&price.Timestamp,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		price.Address = common.HexToAddress(address)
+		if price.PriceUSD, err = parseNumeric(priceUSD); err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys[strings.ToLower(address)] {
+			p := price
+			prices[key] = &p
+		}
+	}
+
+	return prices, rows.Err()
+}
@@ -0,0 +1,247 @@
+// internal/storage/postgres/tvl.go
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+const snapshotColumns = `id, protocol, COALESCE(chain, ''), COALESCE(block_number, 0), total_usd, breakdown, status, timestamp, source`
+
+// SaveTVLSnapshot saves a TVL snapshot and its holdings. Saving the same
+// protocol, chain and block, or time for a snapshot without a block, again
+// replaces them.
+func (ps *PostgresStorage) SaveTVLSnapshot(ctx context.Context, snapshot *models.TVLSnapshot) error {
+	breakdown, err := encodeBreakdown(snapshot.Breakdown)
+	if err != nil {
+		return err
+	}
+
+	totalUSD, err := formatNumeric(snapshot.TotalUSD)
+	if err != nil {
+		return fmt.Errorf("invalid total_usd: %w", err)
+	}
+
+	status := snapshot.Status
+	if status == "" {
+		status = models.FinalityTentative
+	}
+
+	// A snapshot without a block has a NULL block_number
+	conflict := "(protocol, chain, block_number)"
+	if snapshot.BlockNumber == 0 {
+		conflict = "(protocol, chain, timestamp) WHERE block_number IS NULL"
+	}
+
+	query := `
+        INSERT INTO tvl_snapshots (protocol, chain, block_number, total_usd, breakdown, status, timestamp, source)
+        VALUES ($1, $2, NULLIF($3::BIGINT, 0), $4, $5, $6, $7, $8)
+        ON CONFLICT ` + conflict + `
+        DO UPDATE SET total_usd = EXCLUDED.total_usd, breakdown = EXCLUDED.breakdown,
+                      status = EXCLUDED.status, timestamp = EXCLUDED.timestamp,
+                      source = EXCLUDED.source
+        RETURNING id
+    `
+
+	return ps.atomic(ctx, func(tx conn) error {
+		err := tx.QueryRowContext(ctx, query,
+			snapshot.Protocol,
+			snapshot.Chain,
+			snapshot.BlockNumber,
+			totalUSD,
+			breakdown,
+			status,
+			snapshot.Timestamp.UTC(),
+			snapshot.Source,
+		).Scan(&snapshot.ID)
+		if err != nil {
+			return err
+		}
+
+		return saveHoldings(ctx, tx, snapshot)
+	})
+}
+
+// saveHoldings replaces the protocol_token_holdings rows of a snapshot with
+// its breakdown
+func saveHoldings(ctx context.Context, tx conn, snapshot *models.TVLSnapshot) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM protocol_token_holdings WHERE snapshot_id = $1`, snapshot.ID); err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO protocol_token_holdings (snapshot_id, token, symbol, name, decimals, amount, price_usd, value_usd, percentage)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        ON CONFLICT (snapshot_id, token, symbol) DO NOTHING
+    `
+
+	// The first key of a token and symbol wins when a breakdown lists it twice
+	keys := make([]string, 0, len(snapshot.Breakdown))
+	for key, asset := range snapshot.Breakdown {
+		if asset != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		asset := snapshot.Breakdown[key]
+
+		price, err := formatNumeric(asset.PriceUSD)
+		if err != nil {
+			return fmt.Errorf("invalid price_usd of %s: %w", key, err)
+		}
+		value, err := formatNumeric(asset.ValueUSD)
+		if err != nil {
+			return fmt.Errorf("invalid value_usd of %s: %w", key, err)
+		}
+
+		_, err = tx.ExecContext(ctx, query,
+			snapshot.ID,
+			asset.Token.Hex(),
+			asset.Symbol,
+			asset.Name,
+			asset.Decimals,
+			formatInt(asset.Amount),
+			price,
+			value,
+			asset.Percentage,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetLatestTVL retrieves the latest TVL snapshot of a protocol, on any chain
+// when chain is empty
+func (ps *PostgresStorage) GetLatestTVL(ctx context.Context, protocol, chain string) (*models.TVLSnapshot, error) {
+	query := `
+        SELECT ` + snapshotColumns + `
+        FROM tvl_snapshots
+        WHERE protocol = $1 AND ($2 = '' OR chain = $2)
+        ORDER BY timestamp DESC, id DESC
+        LIMIT 1
+    `
+
+	snapshot, err := scanSnapshot(ps.conn.QueryRowContext(ctx, query, protocol, chain))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no TVL snapshot found")
+	}
+	return snapshot, err
+}
+
+// GetTVLByBlock retrieves the snapshot of a protocol at a block
+func (ps *PostgresStorage) GetTVLByBlock(ctx context.Context, protocol, chain string, blockNumber uint64) (*models.TVLSnapshot, error) {
+	query := `
+        SELECT ` + snapshotColumns + `
+        FROM tvl_snapshots
+        WHERE protocol = $1 AND ($2 = '' OR chain = $2) AND block_number = $3
+        ORDER BY chain, id
+        LIMIT 1
+    `
+
+	snapshot, err := scanSnapshot(ps.conn.QueryRowContext(ctx, query, protocol, chain, blockNumber))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no TVL snapshot found for block %d", blockNumber)
+	}
+	return snapshot, err
+}
+
+// GetAggregatedTVL sums the latest snapshot of every protocol on every chain
+func (ps *PostgresStorage) GetAggregatedTVL(ctx context.Context) (*models.AggregatedTVL, error) {
+	query := `
+        SELECT DISTINCT ON (protocol, chain) ` + snapshotColumns + `
+        FROM tvl_snapshots
+        ORDER BY protocol, chain, timestamp DESC, id DESC
+    `
+
+	rows, err := ps.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var latest []*models.TVLSnapshot
+	for rows.Next() {
+		snapshot, err := scanSnapshot(rows)
+		if err != nil {
+			return nil, err
+		}
+		latest = append(latest, snapshot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return storage.Aggregate(latest), nil
+}
+
+// scanSnapshot scans the snapshotColumns of a row
+func scanSnapshot(row interface{ Scan(...interface{}) error }) (*models.TVLSnapshot, error) {
+	var snapshot models.TVLSnapshot
+	var totalUSD sql.NullString
+	var breakdown []byte
+
+	err := row.Scan(
+		&snapshot.ID,
+		&snapshot.Protocol,
+		&snapshot.Chain,
+		&snapshot.BlockNumber,
+		&totalUSD,
+		&breakdown,
+		&snapshot.Status,
+		&snapshot.Timestamp,
+		&snapshot.Source,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshot.TotalUSD, err = parseNumeric(totalUSD); err != nil {
+		return nil, err
+	}
+	if snapshot.Breakdown, err = decodeBreakdown(breakdown); err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// FinalizeTVL marks the snapshots and rollups of a chain up to and including
+// block as final
+func (ps *PostgresStorage) FinalizeTVL(ctx context.Context, chain string, block uint64) error {
+	return ps.atomic(ctx, func(tx conn) error {
+		for _, table := range []string{"tvl_snapshots", "tvl_rollups"} {
+			_, err := tx.ExecContext(ctx, `
+                UPDATE `+table+` SET status = 'final'
+                WHERE chain = $1 AND block_number <= $2 AND status <> 'final'
+            `, chain, block)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RollbackTVL deletes the snapshots of a chain at or above fromBlock, with
+// their holdings, and the rollups closing there
+func (ps *PostgresStorage) RollbackTVL(ctx context.Context, chain string, fromBlock uint64) error {
+	return ps.atomic(ctx, func(tx conn) error {
+		for _, table := range []string{"tvl_snapshots", "tvl_rollups"} {
+			_, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE chain = $1 AND block_number >= $2`, chain, fromBlock)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,265 @@
+// internal/storage/retention/retention.go
+
+// Package retention bounds the TVL history a storage keeps. Raw snapshots
+// are rolled up into hourly and daily rollups and pruned once they are older
+// than the policy keeps them; the rollups outlive them, so long windows of
+// history stay available at a coarser resolution.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// Snapshots are read a day at a time
+const chunk = 24 * time.Hour
+
+// Policy is how long each resolution of TVL history is kept. Zero keeps it
+// forever.
+type Policy struct {
+	Raw    time.Duration
+	Hourly time.Duration
+	Daily  time.Duration
+}
+
+// DefaultPolicy keeps raw snapshots for a week, hourly rollups for 90 days
+// and daily rollups forever
+func DefaultPolicy() Policy {
+	return Policy{
+		Raw:    7 * 24 * time.Hour,
+		Hourly: 90 * 24 * time.Hour,
+	}
+}
+
+// Validate checks that every resolution is kept at least as long as the
+// finer one it is rolled up from
+func (p Policy) Validate() error {
+	if p.Raw < 0 || p.Hourly < 0 || p.Daily < 0 {
+		return fmt.Errorf("retention periods must not be negative")
+	}
+	if p.Hourly > 0 && (p.Raw == 0 || p.Hourly < p.Raw) {
+		return fmt.Errorf("hourly rollups must be kept at least as long as raw snapshots")
+	}
+	if p.Daily > 0 && (p.Hourly == 0 || p.Daily < p.Hourly) {
+		return fmt.Errorf("daily rollups must be kept at least as long as hourly rollups")
+	}
+	return nil
+}
+
+// Result counts the rollups an Apply wrote and the records it pruned
+type Result struct {
+	HourlyRollups int    `json:"hourly_rollups"`
+	DailyRollups  int    `json:"daily_rollups"`
+	RawPruned     uint64 `json:"raw_pruned"`
+	HourlyPruned  uint64 `json:"hourly_pruned"`
+	DailyPruned   uint64 `json:"daily_pruned"`
+}
+
+// Engine applies a retention policy to a storage
+type Engine struct {
+	storage storage.TVLStorage
+	policy  Policy
+
+	mu sync.Mutex
+	// rolledUntil is the time of the last Apply and rawCutoff what it
+	// pruned snapshots before. Both are zero until the first Apply.
+	rolledUntil time.Time
+	rawCutoff   time.Time
+}
+
+// NewEngine creates an engine applying policy to storage
+func NewEngine(storage storage.TVLStorage, policy Policy) (*Engine, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Engine{
+		storage: storage,
+		policy:  policy,
+	}, nil
+}
+
+// Run applies the policy every interval until the context is cancelled
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := e.Apply(ctx, time.Now())
+		if err != nil {
+			log.Printf("Retention failed: %v", err)
+		} else if result.RawPruned+result.HourlyPruned+result.DailyPruned > 0 {
+			log.Printf("Retention pruned %d snapshots, %d hourly and %d daily rollups",
+				result.RawPruned, result.HourlyPruned, result.DailyPruned)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Apply rolls up the snapshots saved since the last Apply into hourly and
+// daily rollups, then prunes every resolution past its retention.
+//
+// The first Apply rolls up everything there is. Hours about to be pruned
+// are rolled up once more so snapshots that arrived late with an old
+// timestamp are not lost, but only where they have no rollup yet: an hour
+// or day whose snapshots were pruned keeps the rollup it got while it was
+// whole.
+func (e *Engine) Apply(ctx context.Context, now time.Time) (*Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now = now.UTC()
+	result := &Result{}
+
+	// Pruning whole hours and days leaves no rollup with part of what it
+	// covers gone
+	var rawCutoff, hourlyCutoff, dailyCutoff time.Time
+	if e.policy.Raw > 0 {
+		rawCutoff = now.Add(-e.policy.Raw).Truncate(time.Hour)
+	}
+	if e.policy.Hourly > 0 {
+		hourlyCutoff = now.Add(-e.policy.Hourly).Truncate(24 * time.Hour)
+	}
+	if e.policy.Daily > 0 {
+		dailyCutoff = now.Add(-e.policy.Daily).Truncate(24 * time.Hour)
+	}
+
+	days := make(map[time.Time]bool)
+	end := now.Truncate(time.Hour).Add(time.Hour)
+	if e.rolledUntil.IsZero() {
+		oldest, err := e.storage.GetTVLHistory(ctx, storage.TVLFilter{Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(oldest) > 0 {
+			from := oldest[0].Timestamp.UTC().Truncate(time.Hour)
+			if err := e.rollup(ctx, from, end, rawCutoff, days, result); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		if !rawCutoff.IsZero() && e.rawCutoff.Before(rawCutoff) {
+			if err := e.rollup(ctx, e.rawCutoff, rawCutoff, rawCutoff, days, result); err != nil {
+				return nil, err
+			}
+		}
+		if err := e.rollup(ctx, e.rolledUntil.Truncate(time.Hour), end, rawCutoff, days, result); err != nil {
+			return nil, err
+		}
+	}
+
+	// Days are rebuilt from all of their hourly rollups
+	for day := range days {
+		hourly, err := e.storage.GetTVLHistory(ctx, storage.TVLFilter{
+			Resolution: models.ResolutionHourly,
+			From:       day,
+			To:         day.Add(24*time.Hour - time.Nanosecond),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		daily := storage.Rollup(hourly, models.ResolutionDaily)
+		if err := e.storage.SaveTVLRollups(ctx, daily); err != nil {
+			return nil, fmt.Errorf("failed to save daily rollups: %w", err)
+		}
+		result.DailyRollups += len(daily)
+	}
+
+	var err error
+	if !rawCutoff.IsZero() {
+		if result.RawPruned, err = e.storage.DeleteTVLHistory(ctx, models.ResolutionRaw, rawCutoff); err != nil {
+			return nil, err
+		}
+	}
+	if !hourlyCutoff.IsZero() {
+		if result.HourlyPruned, err = e.storage.DeleteTVLHistory(ctx, models.ResolutionHourly, hourlyCutoff); err != nil {
+			return nil, err
+		}
+	}
+	if !dailyCutoff.IsZero() {
+		if result.DailyPruned, err = e.storage.DeleteTVLHistory(ctx, models.ResolutionDaily, dailyCutoff); err != nil {
+			return nil, err
+		}
+	}
+
+	e.rolledUntil = now
+	e.rawCutoff = rawCutoff
+	return result, nil
+}
+
+// rollup rolls the snapshots of [from, to) up into hourly rollups a day at
+// a time, recording the days it touched. Hours before rawCutoff are left
+// alone when they, or their day, have a rollup already.
+func (e *Engine) rollup(ctx context.Context, from, to, rawCutoff time.Time, days map[time.Time]bool, result *Result) error {
+	for start := from; start.Before(to); start = start.Add(chunk) {
+		stop := start.Add(chunk)
+		if stop.After(to) {
+			stop = to
+		}
+
+		snapshots, err := e.storage.GetTVLHistory(ctx, storage.TVLFilter{
+			From: start,
+			To:   stop.Add(-time.Nanosecond),
+		})
+		if err != nil {
+			return err
+		}
+		hourly := storage.Rollup(snapshots, models.ResolutionHourly)
+
+		if start.Before(rawCutoff) {
+			rolled := make(map[string]bool)
+			for _, resolution := range []models.Resolution{models.ResolutionHourly, models.ResolutionDaily} {
+				existing, err := e.storage.GetTVLHistory(ctx, storage.TVLFilter{
+					Resolution: resolution,
+					From:       start.Truncate(resolution.Interval()),
+					To:         stop.Add(-time.Nanosecond),
+				})
+				if err != nil {
+					return err
+				}
+				for _, rollup := range existing {
+					rolled[rollupKey(rollup, rollup.Timestamp)] = true
+				}
+			}
+
+			kept := hourly[:0]
+			for _, rollup := range hourly {
+				day := rollup.Timestamp.Truncate(24 * time.Hour)
+				if rollup.Timestamp.Before(rawCutoff) &&
+					(rolled[rollupKey(rollup, rollup.Timestamp)] || rolled[rollupKey(rollup, day)]) {
+					continue
+				}
+				kept = append(kept, rollup)
+			}
+			hourly = kept
+		}
+
+		if err := e.storage.SaveTVLRollups(ctx, hourly); err != nil {
+			return fmt.Errorf("failed to save hourly rollups: %w", err)
+		}
+		result.HourlyRollups += len(hourly)
+
+		for _, rollup := range hourly {
+			days[rollup.Timestamp.Truncate(24*time.Hour)] = true
+		}
+	}
+
+	return nil
+}
+
+// rollupKey identifies the interval of a protocol and chain starting at start
+func rollupKey(rollup *models.TVLSnapshot, start time.Time) string {
+	return rollup.Protocol + "|" + rollup.Chain + "|" + start.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,100 @@
+// internal/storage/storagetest/chains.go
+package storagetest
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+func testChains(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	if _, err := s.GetChain(ctx, "missing"); err == nil {
+		t.Error("GetChain of a missing chain: want an error")
+	}
+	if err := s.SaveChain(ctx, &models.Chain{Name: "nochainid"}); err == nil {
+		t.Error("SaveChain without a chain ID: want an error")
+	}
+
+	for i, name := range []string{"testnet-b", "testnet-a", "testnet-c"} {
+		chain := &models.Chain{
+			Name:        name,
+			ChainID:     big.NewInt(int64(900001 + i)),
+			RPCEndpoint: "http://localhost:8545",
+			BlockTime:   12,
+			IsTestnet:   true,
+			IsActive:    true,
+		}
+		must(t, "SaveChain", s.SaveChain(ctx, chain))
+		if chain.ID == 0 {
+			t.Errorf("SaveChain(%s) did not set the ID", name)
+		}
+	}
+
+	chains, err := s.GetChains(ctx)
+	must(t, "GetChains", err)
+	var names []string
+	for _, chain := range chains {
+		names = append(names, chain.Name)
+	}
+	if len(names) != 3 || names[0] != "testnet-a" || names[1] != "testnet-b" || names[2] != "testnet-c" {
+		t.Errorf("GetChains = %v, want [testnet-a testnet-b testnet-c]", names)
+	}
+
+	saved, err := s.GetChain(ctx, "testnet-a")
+	must(t, "GetChain", err)
+	if saved.ChainID == nil || saved.ChainID.Int64() != 900002 || saved.BlockTime != 12 || !saved.IsTestnet {
+		t.Errorf("GetChain(testnet-a) = %+v, want the saved chain", saved)
+	}
+
+	// Saving the same name replaces the chain
+	replaced := &models.Chain{Name: "testnet-a", ChainID: big.NewInt(900002), RPCEndpoint: "http://localhost:9545", BlockTime: 2,
+		Finality: "latest", Confirmations: 6, Source: "chains.yaml"}
+	must(t, "SaveChain", s.SaveChain(ctx, replaced))
+	if replaced.ID != saved.ID {
+		t.Errorf("saving testnet-a again got ID %d, want %d", replaced.ID, saved.ID)
+	}
+
+	saved, err = s.GetChain(ctx, "testnet-a")
+	must(t, "GetChain", err)
+	if saved.RPCEndpoint != "http://localhost:9545" || saved.BlockTime != 2 || saved.IsActive ||
+		saved.Finality != "latest" || saved.Confirmations != 6 || saved.Source != "chains.yaml" {
+		t.Errorf("replaced chain = %+v, want the second save", saved)
+	}
+
+	chains, err = s.GetChains(ctx)
+	must(t, "GetChains", err)
+	if len(chains) != 3 {
+		t.Errorf("GetChains after a replace returned %d chains, want 3", len(chains))
+	}
+
+	must(t, "DeleteChain", s.DeleteChain(ctx, "testnet-c"))
+	if _, err := s.GetChain(ctx, "testnet-c"); err == nil {
+		t.Error("GetChain of a deleted chain: want an error")
+	}
+	if err := s.DeleteChain(ctx, "testnet-c"); err == nil {
+		t.Error("DeleteChain of a missing chain: want an error")
+	}
+	chains, err = s.GetChains(ctx)
+	must(t, "GetChains", err)
+	if len(chains) != 2 {
+		t.Errorf("GetChains after a delete returned %d chains, want 2", len(chains))
+	}
+
+	must(t, "UpdateChainStats", s.UpdateChainStats(ctx, &models.ChainStats{
+		Chain:            "testnet-a",
+		LastIndexedBlock: 100,
+		CurrentBlock:     110,
+		TotalProtocols:   2,
+		TotalTVL:         usd("1234.5"),
+	}))
+	must(t, "UpdateChainStats", s.UpdateChainStats(ctx, &models.ChainStats{
+		Chain:            "testnet-a",
+		LastIndexedBlock: 110,
+		CurrentBlock:     120,
+	}))
+}
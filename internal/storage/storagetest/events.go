@@ -0,0 +1,201 @@
+// internal/storage/storagetest/events.go
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+func event(chain, protocol, name string, block uint64, logIndex uint, contract int64) *models.Event {
+	return &models.Event{
+		Chain:           chain,
+		Protocol:        protocol,
+		BlockNumber:     block,
+		BlockHash:       fmt.Sprintf("0x%064x", block),
+		TransactionHash: fmt.Sprintf("0x%062x%02x", block, logIndex),
+		LogIndex:        logIndex,
+		Address:         address(contract),
+		EventName:       name,
+		Data:            models.EventData{"amount": "1"},
+		Timestamp:       at(int(block)),
+	}
+}
+
+// positions lists the chain, block and log index of each event
+func positions(events []*models.Event) string {
+	var list []string
+	for _, event := range events {
+		list = append(list, fmt.Sprintf("%s/%d/%d", event.Chain, event.BlockNumber, event.LogIndex))
+	}
+	return strings.Join(list, " ")
+}
+
+func testEvents(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	// Saved out of order, returned by chain, block and log index
+	events := []*models.Event{
+		event("ethereum", "dex", "Swap", 12, 0, 1),
+		event("ethereum", "dex", "Sync", 10, 1, 1),
+		event("arbitrum", "dex", "Swap", 50, 0, 2),
+		event("ethereum", "lend", "Supply", 10, 0, 3),
+		event("ethereum", "dex", "Swap", 11, 4, 1),
+		event("ethereum", "dex", "Swap", 11, 2, 1),
+	}
+	must(t, "SaveEvents", s.SaveEvents(ctx, events))
+	for _, saved := range events {
+		if saved.ID == 0 {
+			t.Fatalf("SaveEvents did not set the ID of %s/%d/%d", saved.Chain, saved.BlockNumber, saved.LogIndex)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter storage.EventFilter
+		want   string
+	}{
+		{"all", storage.EventFilter{},
+			"arbitrum/50/0 ethereum/10/0 ethereum/10/1 ethereum/11/2 ethereum/11/4 ethereum/12/0"},
+		{"chain", storage.EventFilter{Chain: "ethereum"},
+			"ethereum/10/0 ethereum/10/1 ethereum/11/2 ethereum/11/4 ethereum/12/0"},
+		{"protocol", storage.EventFilter{Chain: "ethereum", Protocol: "lend"},
+			"ethereum/10/0"},
+		{"event name", storage.EventFilter{EventName: "Swap"},
+			"arbitrum/50/0 ethereum/11/2 ethereum/11/4 ethereum/12/0"},
+		{"address in any case", storage.EventFilter{Address: strings.ToLower(address(1).Hex())},
+			"ethereum/10/1 ethereum/11/2 ethereum/11/4 ethereum/12/0"},
+		{"block range", storage.EventFilter{Chain: "ethereum", FromBlock: 11, ToBlock: 11},
+			"ethereum/11/2 ethereum/11/4"},
+		{"from block", storage.EventFilter{FromBlock: 12},
+			"arbitrum/50/0 ethereum/12/0"},
+		{"limit", storage.EventFilter{Chain: "ethereum", Limit: 2},
+			"ethereum/10/0 ethereum/10/1"},
+		{"offset", storage.EventFilter{Chain: "ethereum", Limit: 2, Offset: 3},
+			"ethereum/11/4 ethereum/12/0"},
+		{"offset past the end", storage.EventFilter{Chain: "ethereum", Offset: 10},
+			""},
+		{"no match", storage.EventFilter{Chain: "optimism"},
+			""},
+	}
+	for _, test := range tests {
+		got, err := s.GetEvents(ctx, test.filter)
+		must(t, "GetEvents", err)
+		if positions(got) != test.want {
+			t.Errorf("GetEvents(%s) = [%s], want [%s]", test.name, positions(got), test.want)
+		}
+	}
+
+	latest := []struct {
+		name   string
+		filter storage.EventFilter
+		want   string
+	}{
+		{"all", storage.EventFilter{},
+			"arbitrum/50/0 ethereum/10/0 ethereum/12/0"},
+		{"to block", storage.EventFilter{Chain: "ethereum", ToBlock: 11},
+			"ethereum/10/0 ethereum/11/4"},
+		{"event name", storage.EventFilter{Chain: "ethereum", EventName: "Sync"},
+			"ethereum/10/1"},
+		{"limit ignored", storage.EventFilter{Chain: "ethereum", Limit: 1},
+			"ethereum/10/0 ethereum/12/0"},
+		{"no match", storage.EventFilter{Chain: "optimism"},
+			""},
+	}
+	for _, test := range latest {
+		got, err := s.GetLatestEvents(ctx, test.filter)
+		must(t, "GetLatestEvents", err)
+		if positions(got) != test.want {
+			t.Errorf("GetLatestEvents(%s) = [%s], want [%s]", test.name, positions(got), test.want)
+		}
+	}
+
+	got, err := s.GetEvents(ctx, storage.EventFilter{Chain: "arbitrum"})
+	must(t, "GetEvents", err)
+	if len(got) != 1 || got[0].Address != address(2) || got[0].EventName != "Swap" || got[0].Data["amount"] != "1" ||
+		!got[0].Timestamp.Equal(at(50)) || got[0].Status != models.FinalityTentative {
+		t.Errorf("GetEvents(arbitrum) = %+v, want the saved swap", got)
+	}
+
+	// Saving the same log again replaces it rather than adding one
+	again := event("ethereum", "dex", "Swap", 12, 0, 1)
+	again.BlockHash = fmt.Sprintf("0x%064x", 1012)
+	must(t, "SaveEvents", s.SaveEvents(ctx, []*models.Event{again}))
+
+	got, err = s.GetEvents(ctx, storage.EventFilter{Chain: "ethereum", FromBlock: 12})
+	must(t, "GetEvents", err)
+	if len(got) != 1 || got[0].BlockHash != again.BlockHash || got[0].ID != events[0].ID {
+		t.Errorf("GetEvents after saving a log again = %+v, want the one replaced log", got)
+	}
+
+	// A log decoded again, e.g. after a processor fix, replaces what was
+	// decoded from it before
+	decoded := event("ethereum", "lend", "Withdraw", 12, 0, 5)
+	decoded.EventSignature = fmt.Sprintf("0x%064x", 99)
+	decoded.Data = models.EventData{"amount": "2", "user": address(6).Hex()}
+	must(t, "SaveEvents", s.SaveEvents(ctx, []*models.Event{decoded}))
+
+	got, err = s.GetEvents(ctx, storage.EventFilter{Chain: "ethereum", FromBlock: 12})
+	must(t, "GetEvents", err)
+	if len(got) != 1 || got[0].ID != events[0].ID || got[0].Protocol != "lend" || got[0].EventName != "Withdraw" ||
+		got[0].EventSignature != decoded.EventSignature || got[0].Address != address(5) ||
+		got[0].Data["amount"] != "2" || got[0].Data["user"] != address(6).Hex() {
+		t.Errorf("GetEvents after decoding a log again = %+v, want the newly decoded log", got)
+	}
+}
+
+func testBlocks(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	last, err := s.GetLastIndexedBlock(ctx, "unknown")
+	if err != nil || last != 0 {
+		t.Errorf("GetLastIndexedBlock of an unknown chain = %d, %v, want 0 and no error", last, err)
+	}
+
+	block, err := s.GetBlockAtTime(ctx, "unknown", at(100))
+	if err != nil || block != nil {
+		t.Errorf("GetBlockAtTime of an unknown chain = %+v, %v, want nil and no error", block, err)
+	}
+
+	for _, number := range []uint64{20, 10, 30} {
+		must(t, "SaveBlock", s.SaveBlock(ctx, &models.Block{
+			Chain:     "ethereum",
+			Number:    number,
+			Hash:      fmt.Sprintf("0x%064x", number),
+			Timestamp: at(int(number)),
+		}))
+	}
+	must(t, "SaveBlock", s.SaveBlock(ctx, &models.Block{Chain: "arbitrum", Number: 1000, Timestamp: at(1000)}))
+
+	last, err = s.GetLastIndexedBlock(ctx, "ethereum")
+	must(t, "GetLastIndexedBlock", err)
+	if last != 30 {
+		t.Errorf("GetLastIndexedBlock(ethereum) = %d, want 30", last)
+	}
+
+	tests := []struct {
+		minute int
+		want   uint64
+	}{
+		{5, 0},   // before the first block
+		{10, 10}, // mined exactly then
+		{25, 20},
+		{100, 30},
+	}
+	for _, test := range tests {
+		block, err := s.GetBlockAtTime(ctx, "ethereum", at(test.minute))
+		must(t, "GetBlockAtTime", err)
+
+		var got uint64
+		if block != nil {
+			got = block.Number
+		}
+		if got != test.want {
+			t.Errorf("GetBlockAtTime(minute %d) = block %d, want %d", test.minute, got, test.want)
+		}
+	}
+}
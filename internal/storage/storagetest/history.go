@@ -0,0 +1,256 @@
+// internal/storage/storagetest/history.go
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+func testTVLHistory(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	for _, snap := range []*models.TVLSnapshot{
+		snapshot("dex", "ethereum", 100, 10, "1000"),
+		snapshot("dex", "ethereum", 101, 30, "3000"),
+		snapshot("dex", "ethereum", 102, 50, "2000"),
+		snapshot("dex", "ethereum", 103, 70, "4000"),
+		snapshot("dex", "ethereum", 104, 130, "5000"),
+		snapshot("dex", "arbitrum", 500, 20, "500"),
+	} {
+		must(t, "SaveTVLSnapshot", s.SaveTVLSnapshot(ctx, snap))
+	}
+
+	raw, err := s.GetTVLHistory(ctx, storage.TVLFilter{Protocol: "dex", Chain: "ethereum", From: at(30), To: at(70)})
+	must(t, "GetTVLHistory", err)
+	if got := totals(raw); !sameTotals(got, "3000", "2000", "4000") {
+		t.Errorf("GetTVLHistory(ethereum, 30-70) = %v, want [3000 2000 4000]", got)
+	}
+
+	raw, err = s.GetTVLHistory(ctx, storage.TVLFilter{Limit: 2})
+	must(t, "GetTVLHistory", err)
+	if got := totals(raw); !sameTotals(got, "1000", "500") {
+		t.Errorf("GetTVLHistory(limit 2) = %v, want [1000 500]", got)
+	}
+
+	if err := s.SaveTVLRollups(ctx, raw[:1]); err == nil {
+		t.Error("SaveTVLRollups of a raw snapshot: want an error")
+	}
+
+	all, err := s.GetTVLHistory(ctx, storage.TVLFilter{Protocol: "dex"})
+	must(t, "GetTVLHistory", err)
+	hourly := storage.Rollup(all, models.ResolutionHourly)
+	if len(hourly) != 4 {
+		t.Fatalf("Rollup made %d hourly rollups, want 4", len(hourly))
+	}
+	must(t, "SaveTVLRollups", s.SaveTVLRollups(ctx, hourly))
+	for _, rollup := range hourly {
+		if rollup.ID == 0 {
+			t.Errorf("SaveTVLRollups(%s, %v) did not set the ID", rollup.Chain, rollup.Timestamp)
+		}
+	}
+
+	// Saving the same interval again replaces the rollup
+	first := hourly[0]
+	again := storage.Rollup(all[:1], models.ResolutionHourly)
+	must(t, "SaveTVLRollups", s.SaveTVLRollups(ctx, again))
+	if again[0].ID != first.ID {
+		t.Errorf("saving hour 0 again got ID %d, want %d", again[0].ID, first.ID)
+	}
+	must(t, "SaveTVLRollups", s.SaveTVLRollups(ctx, hourly[:1]))
+
+	rollups, err := s.GetTVLHistory(ctx, storage.TVLFilter{
+		Protocol:   "dex",
+		Chain:      "ethereum",
+		Resolution: models.ResolutionHourly,
+	})
+	must(t, "GetTVLHistory", err)
+	if got := totals(rollups); !sameTotals(got, "2000", "4000", "5000") {
+		t.Fatalf("hourly ethereum rollups = %v, want [2000 4000 5000]", got)
+	}
+	hour := rollups[0]
+	if hour.Resolution != models.ResolutionHourly || !hour.Timestamp.Equal(at(0)) || hour.BlockNumber != 102 {
+		t.Errorf("hour 0 = %s at %v block %d, want hourly at %v block 102", hour.Resolution, hour.Timestamp, hour.BlockNumber, at(0))
+	}
+	if hour.Range == nil {
+		t.Fatal("hour 0 has no range")
+	}
+	checkUSD(t, "open", hour.Range.Open, "1000")
+	checkUSD(t, "high", hour.Range.High, "3000")
+	checkUSD(t, "low", hour.Range.Low, "1000")
+	checkUSD(t, "close", hour.Range.Close, "2000")
+	checkUSD(t, "avg", hour.Range.Avg, "2000")
+	if hour.Range.Samples != 3 {
+		t.Errorf("samples = %d, want 3", hour.Range.Samples)
+	}
+
+	daily := storage.Rollup(rollups, models.ResolutionDaily)
+	must(t, "SaveTVLRollups", s.SaveTVLRollups(ctx, daily))
+	days, err := s.GetTVLHistory(ctx, storage.TVLFilter{Resolution: models.ResolutionDaily})
+	must(t, "GetTVLHistory", err)
+	if len(days) != 1 || days[0].Range == nil || days[0].Range.Samples != 5 {
+		t.Fatalf("daily rollups = %+v, want one of 5 samples", days)
+	}
+	checkUSD(t, "daily high", days[0].Range.High, "5000")
+	checkUSD(t, "daily avg", days[0].Range.Avg, "3000")
+
+	// Pruning keeps the latest snapshot of each chain
+	deleted, err := s.DeleteTVLHistory(ctx, models.ResolutionRaw, at(120))
+	must(t, "DeleteTVLHistory", err)
+	if deleted != 4 {
+		t.Errorf("DeleteTVLHistory(raw) deleted %d, want 4", deleted)
+	}
+	latest, err := s.GetLatestTVL(ctx, "dex", "arbitrum")
+	must(t, "GetLatestTVL", err)
+	checkUSD(t, "latest arbitrum TVL", latest.TotalUSD, "500")
+	if _, err := s.GetTVLByBlock(ctx, "dex", "ethereum", 100); err == nil {
+		t.Error("GetTVLByBlock of a pruned snapshot: want an error")
+	}
+
+	// Hourly rollups fill in before the first raw snapshot left
+	history, err := s.GetHistoricalTVL(ctx, "dex", "ethereum", at(0), at(180))
+	must(t, "GetHistoricalTVL", err)
+	if got := totals(history); !sameTotals(got, "2000", "4000", "5000") {
+		t.Errorf("GetHistoricalTVL after pruning = %v, want [2000 4000 5000]", got)
+	}
+	if len(history) == 3 && (history[0].Resolution != models.ResolutionHourly || !history[2].Resolution.IsRaw()) {
+		t.Errorf("resolutions = %s %s %s, want hourly hourly raw", history[0].Resolution, history[1].Resolution, history[2].Resolution)
+	}
+
+	deleted, err = s.DeleteTVLHistory(ctx, models.ResolutionHourly, at(60))
+	must(t, "DeleteTVLHistory", err)
+	if deleted != 2 {
+		t.Errorf("DeleteTVLHistory(hourly) deleted %d, want 2", deleted)
+	}
+	days, err = s.GetTVLHistory(ctx, storage.TVLFilter{Resolution: models.ResolutionDaily})
+	must(t, "GetTVLHistory", err)
+	if len(days) != 1 {
+		t.Errorf("pruning hourly rollups left %d daily rollups, want 1", len(days))
+	}
+}
+
+func testHistoryFallback(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	raw := []*models.TVLSnapshot{
+		snapshot("dex", "ethereum", 100, 10, "1000"),
+		snapshot("dex", "ethereum", 101, 50, "2000"),
+		snapshot("dex", "ethereum", 102, 70, "3000"),
+		snapshot("dex", "ethereum", 103, 130, "4000"),
+		snapshot("dex", "ethereum", 104, 150, "5000"),
+		snapshot("dex", "arbitrum", 500, 20, "500"),
+	}
+	for _, snap := range raw {
+		must(t, "SaveTVLSnapshot", s.SaveTVLSnapshot(ctx, snap))
+	}
+	must(t, "SaveTVLRollups", s.SaveTVLRollups(ctx, storage.Rollup(raw[:2], models.ResolutionHourly)))
+
+	// Only hour 0 of ethereum is rolled up; raw snapshots fill in after it
+	// and where a chain has no rollups at all
+	week := 7 * 24 * 60
+	history, err := s.GetHistoricalTVL(ctx, "dex", "", at(0), at(week))
+	must(t, "GetHistoricalTVL", err)
+	if got := totals(history); !sameTotals(got, "2000", "500", "3000", "4000", "5000") {
+		t.Errorf("GetHistoricalTVL(week) = %v, want [2000 500 3000 4000 5000]", got)
+	}
+	if len(history) == 5 && (history[0].Resolution != models.ResolutionHourly || !history[2].Resolution.IsRaw()) {
+		t.Errorf("resolutions = %s then %s, want hourly then raw", history[0].Resolution, history[2].Resolution)
+	}
+
+	// A daily window falls back to hourly rollups, then raw snapshots
+	day := 24 * 60
+	next := []*models.TVLSnapshot{
+		snapshot("dex", "ethereum", 200, day+10, "6000"),
+		snapshot("dex", "ethereum", 201, day+70, "7000"),
+	}
+	for _, snap := range next {
+		must(t, "SaveTVLSnapshot", s.SaveTVLSnapshot(ctx, snap))
+	}
+	must(t, "SaveTVLRollups", s.SaveTVLRollups(ctx, storage.Rollup(next[:1], models.ResolutionHourly)))
+	must(t, "SaveTVLRollups", s.SaveTVLRollups(ctx, storage.Rollup(raw[:5], models.ResolutionDaily)))
+
+	history, err = s.GetHistoricalTVL(ctx, "dex", "", at(0), at(100*day))
+	must(t, "GetHistoricalTVL", err)
+	if got := totals(history); !sameTotals(got, "5000", "500", "6000", "7000") {
+		t.Fatalf("GetHistoricalTVL(100 days) = %v, want [5000 500 6000 7000]", got)
+	}
+	want := []models.Resolution{models.ResolutionDaily, "", models.ResolutionHourly, ""}
+	for i, point := range history {
+		if point.Resolution != want[i] && !(want[i] == "" && point.Resolution.IsRaw()) {
+			t.Errorf("point %d is %s, want %s", i, point.Resolution, want[i])
+		}
+	}
+}
+
+func testFinalizeTVL(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	raw := []*models.TVLSnapshot{
+		snapshot("dex", "ethereum", 100, 10, "1000"),
+		snapshot("dex", "ethereum", 101, 20, "2000"),
+		snapshot("dex", "ethereum", 102, 70, "3000"),
+		snapshot("dex", "arbitrum", 500, 20, "500"),
+	}
+	for _, snap := range raw {
+		must(t, "SaveTVLSnapshot", s.SaveTVLSnapshot(ctx, snap))
+	}
+	must(t, "SaveTVLRollups", s.SaveTVLRollups(ctx, storage.Rollup(raw, models.ResolutionHourly)))
+
+	must(t, "FinalizeTVL", s.FinalizeTVL(ctx, "ethereum", 101))
+
+	// Snapshots and the rollups closing at or below the block are final
+	for _, resolution := range []models.Resolution{models.ResolutionRaw, models.ResolutionHourly} {
+		history, err := s.GetTVLHistory(ctx, storage.TVLFilter{Resolution: resolution})
+		must(t, "GetTVLHistory", err)
+		for _, snap := range history {
+			want := models.FinalityTentative
+			if snap.Chain == "ethereum" && snap.BlockNumber <= 101 {
+				want = models.FinalityFinal
+			}
+			if snap.Status != want {
+				t.Errorf("%s %s/%d after FinalizeTVL = %s, want %s", resolution, snap.Chain, snap.BlockNumber, snap.Status, want)
+			}
+		}
+	}
+}
+
+func testRollbackTVL(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	raw := []*models.TVLSnapshot{
+		snapshot("dex", "ethereum", 100, 10, "1000"),
+		snapshot("dex", "ethereum", 101, 20, "2000"),
+		snapshot("dex", "ethereum", 102, 70, "3000"),
+		snapshot("dex", "arbitrum", 500, 20, "500"),
+	}
+	for _, snap := range raw {
+		must(t, "SaveTVLSnapshot", s.SaveTVLSnapshot(ctx, snap))
+	}
+	must(t, "SaveTVLRollups", s.SaveTVLRollups(ctx, storage.Rollup(raw, models.ResolutionHourly)))
+
+	must(t, "RollbackTVL", s.RollbackTVL(ctx, "ethereum", 101))
+
+	history, err := s.GetTVLHistory(ctx, storage.TVLFilter{})
+	must(t, "GetTVLHistory", err)
+	if got := totals(history); !sameTotals(got, "1000", "500") {
+		t.Errorf("snapshots after RollbackTVL(ethereum, 101) = %v, want [1000 500]", got)
+	}
+	if _, err := s.GetTVLByBlock(ctx, "dex", "ethereum", 101); err == nil {
+		t.Error("GetTVLByBlock of a rolled back snapshot: want an error")
+	}
+
+	// Hour 0 of ethereum closed at block 101, hour 1 at 102
+	rollups, err := s.GetTVLHistory(ctx, storage.TVLFilter{Resolution: models.ResolutionHourly})
+	must(t, "GetTVLHistory", err)
+	if got := totals(rollups); !sameTotals(got, "500") {
+		t.Errorf("rollups after RollbackTVL(ethereum, 101) = %v, want [500]", got)
+	}
+
+	// The fork is indexed again
+	must(t, "SaveTVLSnapshot", s.SaveTVLSnapshot(ctx, snapshot("dex", "ethereum", 101, 25, "2500")))
+	latest, err := s.GetLatestTVL(ctx, "dex", "ethereum")
+	must(t, "GetLatestTVL", err)
+	checkUSD(t, "latest ethereum TVL", latest.TotalUSD, "2500")
+}
@@ -0,0 +1,130 @@
+// internal/storage/storagetest/holdings.go
+package storagetest
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// holding returns the breakdown entry of amount units of token n
+func holding(n int64, symbol string, amount int64, value string) *models.AssetTVL {
+	return &models.AssetTVL{
+		Token:      address(n),
+		Symbol:     symbol,
+		Amount:     big.NewInt(amount),
+		Decimals:   6,
+		PriceUSD:   usd("1"),
+		ValueUSD:   usd(value),
+		Percentage: 50,
+	}
+}
+
+// symbols lists the chain and symbol of each holding
+func symbols(holdings []*models.TokenHolding) string {
+	var list []string
+	for _, h := range holdings {
+		list = append(list, h.Chain+":"+h.Symbol)
+	}
+	return strings.Join(list, " ")
+}
+
+func testTokenHoldings(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	holdings, err := s.GetTokenHoldings(ctx, storage.HoldingFilter{})
+	must(t, "GetTokenHoldings", err)
+	if len(holdings) != 0 {
+		t.Errorf("GetTokenHoldings without snapshots returned %d holdings", len(holdings))
+	}
+
+	first := snapshot("dex", "ethereum", 100, 10, "300")
+	first.Breakdown = map[string]*models.AssetTVL{
+		address(2).Hex(): holding(2, "USDT", 200_000_000, "200"),
+		address(1).Hex(): holding(1, "USDC", 100_000_000, "100"),
+	}
+	arbitrum := snapshot("dex", "arbitrum", 500, 20, "50")
+	arbitrum.Breakdown = map[string]*models.AssetTVL{
+		address(1).Hex(): holding(1, "USDC", 50_000_000, "50"),
+	}
+	second := snapshot("dex", "ethereum", 101, 30, "150")
+	second.Breakdown = map[string]*models.AssetTVL{
+		address(1).Hex(): holding(1, "USDC", 150_000_000, "150"),
+	}
+	for _, snap := range []*models.TVLSnapshot{first, arbitrum, second, snapshot("other", "ethereum", 100, 10, "0")} {
+		must(t, "SaveTVLSnapshot", s.SaveTVLSnapshot(ctx, snap))
+	}
+
+	holdings, err = s.GetTokenHoldings(ctx, storage.HoldingFilter{TVLFilter: storage.TVLFilter{Protocol: "dex"}})
+	must(t, "GetTokenHoldings", err)
+	if got := symbols(holdings); got != "ethereum:USDC ethereum:USDT arbitrum:USDC ethereum:USDC" {
+		t.Fatalf("GetTokenHoldings(dex) = %s, want USDC and USDT on ethereum, then arbitrum, then ethereum", got)
+	}
+
+	h := holdings[1]
+	if h.SnapshotID != first.ID || h.Protocol != "dex" || h.BlockNumber != 100 || !h.Timestamp.Equal(at(10)) || h.Status != models.FinalityTentative {
+		t.Errorf("USDT holding = %+v, want it from the snapshot at block 100", h)
+	}
+	if h.Token != address(2) || h.Decimals != 6 || h.Amount == nil || h.Amount.Int64() != 200_000_000 || h.Percentage != 50 {
+		t.Errorf("USDT holding = %+v, want 200 USDT", h.AssetTVL)
+	}
+	checkUSD(t, "USDT price", h.PriceUSD, "1")
+	checkUSD(t, "USDT value", h.ValueUSD, "200")
+
+	// By token in any case, by symbol, by chain and time
+	holdings, err = s.GetTokenHoldings(ctx, storage.HoldingFilter{Token: strings.ToLower(address(1).Hex())})
+	must(t, "GetTokenHoldings", err)
+	if got := symbols(holdings); got != "ethereum:USDC arbitrum:USDC ethereum:USDC" {
+		t.Errorf("GetTokenHoldings(token) = %s, want the three USDC holdings", got)
+	}
+
+	holdings, err = s.GetTokenHoldings(ctx, storage.HoldingFilter{
+		TVLFilter: storage.TVLFilter{Protocol: "dex", Chain: "ethereum", From: at(20)},
+		Symbol:    "USDC",
+	})
+	must(t, "GetTokenHoldings", err)
+	if len(holdings) != 1 || holdings[0].SnapshotID != second.ID {
+		t.Errorf("GetTokenHoldings(ethereum USDC from 20) = %s, want the one at block 101", symbols(holdings))
+	}
+
+	holdings, err = s.GetTokenHoldings(ctx, storage.HoldingFilter{TVLFilter: storage.TVLFilter{Limit: 3}})
+	must(t, "GetTokenHoldings", err)
+	if got := symbols(holdings); got != "ethereum:USDC ethereum:USDT arbitrum:USDC" {
+		t.Errorf("GetTokenHoldings(limit 3) = %s, want the first three", got)
+	}
+
+	// Replacing a snapshot replaces its holdings
+	again := snapshot("dex", "ethereum", 100, 10, "100")
+	again.Breakdown = map[string]*models.AssetTVL{
+		address(1).Hex(): holding(1, "USDC", 100_000_000, "100"),
+	}
+	must(t, "SaveTVLSnapshot", s.SaveTVLSnapshot(ctx, again))
+	holdings, err = s.GetTokenHoldings(ctx, storage.HoldingFilter{TVLFilter: storage.TVLFilter{Protocol: "dex", Chain: "ethereum"}})
+	must(t, "GetTokenHoldings", err)
+	if got := symbols(holdings); got != "ethereum:USDC ethereum:USDC" {
+		t.Errorf("GetTokenHoldings after a replace = %s, want USDC twice", got)
+	}
+
+	// Rollups hold what their last snapshot did
+	all, err := s.GetTVLHistory(ctx, storage.TVLFilter{Protocol: "dex"})
+	must(t, "GetTVLHistory", err)
+	must(t, "SaveTVLRollups", s.SaveTVLRollups(ctx, storage.Rollup(all, models.ResolutionHourly)))
+	holdings, err = s.GetTokenHoldings(ctx, storage.HoldingFilter{TVLFilter: storage.TVLFilter{Resolution: models.ResolutionHourly, Chain: "ethereum"}})
+	must(t, "GetTokenHoldings", err)
+	if len(holdings) != 1 || holdings[0].Resolution != models.ResolutionHourly || holdings[0].BlockNumber != 101 {
+		t.Errorf("GetTokenHoldings(hourly) = %+v, want the USDC of block 101", holdings)
+	}
+
+	// Pruned snapshots take their holdings with them
+	_, err = s.DeleteTVLHistory(ctx, models.ResolutionRaw, at(60))
+	must(t, "DeleteTVLHistory", err)
+	holdings, err = s.GetTokenHoldings(ctx, storage.HoldingFilter{TVLFilter: storage.TVLFilter{Protocol: "dex"}})
+	must(t, "GetTokenHoldings", err)
+	if got := symbols(holdings); got != "arbitrum:USDC ethereum:USDC" {
+		t.Errorf("GetTokenHoldings after pruning = %s, want the latest of each chain", got)
+	}
+}
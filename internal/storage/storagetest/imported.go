@@ -0,0 +1,110 @@
+// internal/storage/storagetest/imported.go
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// imported returns a snapshot without a block from a dataset
+func imported(minute int, total string) *models.TVLSnapshot {
+	snap := snapshot("dex", "ethereum", 0, minute, total)
+	snap.Source = "dataset"
+	snap.Status = models.FinalityFinal
+	return snap
+}
+
+// sources lists the source of each snapshot, - for computed ones
+func sources(snapshots []*models.TVLSnapshot) []string {
+	var list []string
+	for _, snapshot := range snapshots {
+		source := snapshot.Source
+		if source == "" {
+			source = "-"
+		}
+		list = append(list, source)
+	}
+	return list
+}
+
+func testImportedSnapshots(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	// Snapshots without a block are told apart by time, not block 0
+	first := imported(10, "100")
+	first.Breakdown = map[string]*models.AssetTVL{
+		"USDC": {Symbol: "USDC", ValueUSD: usd("60"), Decimals: 18},
+		"DAI":  {Symbol: "DAI", ValueUSD: usd("40"), Decimals: 18},
+	}
+	second := imported(20, "200")
+	for _, snap := range []*models.TVLSnapshot{first, second, snapshot("dex", "ethereum", 100, 30, "300")} {
+		must(t, "SaveTVLSnapshot", s.SaveTVLSnapshot(ctx, snap))
+	}
+	if first.ID == second.ID {
+		t.Fatalf("snapshots without a block at different times got the same ID %d", first.ID)
+	}
+
+	history, err := s.GetTVLHistory(ctx, storage.TVLFilter{Protocol: "dex"})
+	must(t, "GetTVLHistory", err)
+	if got := totals(history); !sameTotals(got, "100", "200", "300") {
+		t.Fatalf("GetTVLHistory = %v, want [100 200 300]", got)
+	}
+	if got := sources(history); len(got) != 3 || got[0] != "dataset" || got[1] != "dataset" || got[2] != "-" {
+		t.Errorf("sources = %v, want [dataset dataset -]", got)
+	}
+	if history[0].BlockNumber != 0 {
+		t.Errorf("block number = %d, want 0", history[0].BlockNumber)
+	}
+
+	// Saving the same time again replaces the snapshot
+	again := imported(20, "250")
+	must(t, "SaveTVLSnapshot", s.SaveTVLSnapshot(ctx, again))
+	if again.ID != second.ID {
+		t.Errorf("saving the snapshot at 20 again got ID %d, want %d", again.ID, second.ID)
+	}
+	history, err = s.GetTVLHistory(ctx, storage.TVLFilter{Protocol: "dex"})
+	must(t, "GetTVLHistory", err)
+	if got := totals(history); !sameTotals(got, "100", "250", "300") {
+		t.Errorf("GetTVLHistory after a replace = %v, want [100 250 300]", got)
+	}
+
+	if _, err := s.GetTVLByBlock(ctx, "dex", "ethereum", 0); err == nil {
+		t.Error("GetTVLByBlock(0): want an error, snapshots without a block are at none")
+	}
+
+	// Holdings known by symbol only share the zero address
+	holdings, err := s.GetTokenHoldings(ctx, storage.HoldingFilter{TVLFilter: storage.TVLFilter{Protocol: "dex"}})
+	must(t, "GetTokenHoldings", err)
+	if got := symbols(holdings); got != "ethereum:DAI ethereum:USDC" {
+		t.Errorf("GetTokenHoldings = %s, want DAI then USDC", got)
+	}
+	if len(holdings) > 0 && holdings[0].Token != (common.Address{}) {
+		t.Errorf("token = %s, want the zero address", holdings[0].Token.Hex())
+	}
+
+	// Rollups keep the source of their last snapshot
+	must(t, "SaveTVLRollups", s.SaveTVLRollups(ctx, storage.Rollup(history[:2], models.ResolutionHourly)))
+	rollups, err := s.GetTVLHistory(ctx, storage.TVLFilter{Resolution: models.ResolutionHourly})
+	must(t, "GetTVLHistory", err)
+	if got := sources(rollups); len(got) != 1 || got[0] != "dataset" {
+		t.Errorf("rollup sources = %v, want [dataset]", got)
+	}
+
+	// Pruning snapshots without a block frees their time
+	deleted, err := s.DeleteTVLHistory(ctx, models.ResolutionRaw, at(25))
+	must(t, "DeleteTVLHistory", err)
+	if deleted != 2 {
+		t.Errorf("DeleteTVLHistory deleted %d snapshots, want 2", deleted)
+	}
+	resaved := imported(10, "110")
+	must(t, "SaveTVLSnapshot", s.SaveTVLSnapshot(ctx, resaved))
+	history, err = s.GetTVLHistory(ctx, storage.TVLFilter{Protocol: "dex"})
+	must(t, "GetTVLHistory", err)
+	if got := totals(history); !sameTotals(got, "110", "300") {
+		t.Errorf("GetTVLHistory after pruning and saving again = %v, want [110 300]", got)
+	}
+}
@@ -0,0 +1,382 @@
+// internal/storage/storagetest/indexer.go
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// indexerStorage returns the indexer storage of a backend, skipping the test
+// of backends without one
+func indexerStorage(t *testing.T, s storage.Storage) storage.IndexerStorage {
+	t.Helper()
+
+	indexed, ok := s.(storage.IndexerStorage)
+	if !ok {
+		t.Skip("storage does not implement storage.IndexerStorage")
+	}
+	return indexed
+}
+
+func block(chain string, number uint64) *models.Block {
+	return &models.Block{
+		Chain:      chain,
+		Number:     number,
+		Hash:       fmt.Sprintf("0x%064x", number),
+		ParentHash: fmt.Sprintf("0x%064x", number-1),
+		Timestamp:  at(int(number)),
+	}
+}
+
+func deadLetter(chain, protocol string, block uint64, logIndex uint) *models.DeadLetter {
+	return &models.DeadLetter{
+		Chain:           chain,
+		Protocol:        protocol,
+		Address:         address(1),
+		BlockNumber:     block,
+		BlockHash:       fmt.Sprintf("0x%064x", block),
+		TransactionHash: fmt.Sprintf("0x%062x%02x", block, logIndex),
+		LogIndex:        logIndex,
+		Topics:          []string{fmt.Sprintf("0x%064x", 1)},
+		Data:            "0x",
+		Error:           "failed to decode",
+		Timestamp:       at(int(block)),
+	}
+}
+
+// checkpointList lists the contract and block of each checkpoint
+func checkpointList(checkpoints []*models.Checkpoint) string {
+	var list []string
+	for _, checkpoint := range checkpoints {
+		list = append(list, fmt.Sprintf("%s@%d", checkpoint.Contract, checkpoint.Block))
+	}
+	return strings.Join(list, " ")
+}
+
+func testCommitBatch(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	is := indexerStorage(t, s)
+
+	failed := &models.FailedRange{Chain: "ethereum", Contracts: []string{"a"}, FromBlock: 1, ToBlock: 9, LastError: "timeout"}
+	must(t, "SaveFailedRange", is.SaveFailedRange(ctx, failed))
+	replayed := deadLetter("ethereum", "dex", 5, 0)
+	must(t, "SaveDeadLetters", is.SaveDeadLetters(ctx, []*models.DeadLetter{replayed}))
+
+	batch := &models.EventBatch{
+		Chain: "ethereum",
+		Events: []*models.Event{
+			event("ethereum", "dex", "Swap", 3, 0, 1),
+			event("ethereum", "dex", "Swap", 7, 1, 1),
+		},
+		Blocks:      []*models.Block{block("ethereum", 3), block("ethereum", 7), block("ethereum", 9)},
+		Checkpoints: []*models.Checkpoint{{Chain: "ethereum", Contract: "a", Protocol: "dex", Block: 9}},
+		DeadLetters: []*models.DeadLetter{deadLetter("ethereum", "dex", 8, 2)},
+		Failed:      &models.FailedRange{Chain: "ethereum", Contracts: []string{"b"}, FromBlock: 1, ToBlock: 9, LastError: "reverted"},
+		Resolved:    failed.ID,
+		Replayed:    []uint64{replayed.ID},
+	}
+	must(t, "CommitBatch", is.CommitBatch(ctx, batch))
+
+	events, err := is.GetEvents(ctx, storage.EventFilter{Chain: "ethereum"})
+	must(t, "GetEvents", err)
+	if positions(events) != "ethereum/3/0 ethereum/7/1" {
+		t.Errorf("GetEvents after CommitBatch = [%s], want the batch's events", positions(events))
+	}
+
+	blocks, err := is.GetRecentBlocks(ctx, "ethereum", 2)
+	must(t, "GetRecentBlocks", err)
+	if len(blocks) != 2 || blocks[0].Number != 9 || blocks[1].Number != 7 || blocks[0].Hash != block("ethereum", 9).Hash {
+		t.Errorf("GetRecentBlocks(2) = %+v, want blocks 9 and 7 newest first", blocks)
+	}
+
+	checkpoints, err := is.GetCheckpoints(ctx, "ethereum")
+	must(t, "GetCheckpoints", err)
+	if checkpointList(checkpoints) != "a@9" || checkpoints[0].Protocol != "dex" {
+		t.Errorf("GetCheckpoints after CommitBatch = [%s], want [a@9] of dex", checkpointList(checkpoints))
+	}
+
+	ranges, err := is.GetFailedRanges(ctx, "ethereum")
+	must(t, "GetFailedRanges", err)
+	if len(ranges) != 1 || ranges[0].Contracts[0] != "b" || ranges[0].LastError != "reverted" || batch.Failed.ID == 0 {
+		t.Errorf("GetFailedRanges after CommitBatch = %+v, want only the batch's failed range", ranges)
+	}
+
+	letters, err := is.GetDeadLetters(ctx, "ethereum", "")
+	must(t, "GetDeadLetters", err)
+	if len(letters) != 1 || letters[0].BlockNumber != 8 {
+		t.Errorf("GetDeadLetters after CommitBatch = %+v, want only the batch's dead letter", letters)
+	}
+}
+
+func testRollback(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	is := indexerStorage(t, s)
+
+	for _, chain := range []string{"ethereum", "arbitrum"} {
+		must(t, "CommitBatch", is.CommitBatch(ctx, &models.EventBatch{
+			Chain: chain,
+			Events: []*models.Event{
+				event(chain, "dex", "Swap", 10, 0, 1),
+				event(chain, "dex", "Swap", 20, 0, 1),
+				event(chain, "dex", "Swap", 30, 0, 1),
+			},
+			Blocks: []*models.Block{block(chain, 10), block(chain, 20), block(chain, 30)},
+			Checkpoints: []*models.Checkpoint{
+				{Chain: chain, Contract: "a", Block: 30},
+				{Chain: chain, Contract: "b", Block: 15},
+			},
+			DeadLetters: []*models.DeadLetter{deadLetter(chain, "dex", 10, 1), deadLetter(chain, "dex", 25, 1)},
+		}))
+		must(t, "SaveSinkOffset", is.SaveSinkOffset(ctx, "webhook", chain, 30))
+		must(t, "SaveSinkOffset", is.SaveSinkOffset(ctx, "kafka", chain, 10))
+	}
+
+	must(t, "Rollback", is.Rollback(ctx, "ethereum", 20))
+
+	events, err := is.GetEvents(ctx, storage.EventFilter{})
+	must(t, "GetEvents", err)
+	if positions(events) != "arbitrum/10/0 arbitrum/20/0 arbitrum/30/0 ethereum/10/0" {
+		t.Errorf("GetEvents after Rollback = [%s], want the events before the fork and the other chain's", positions(events))
+	}
+
+	blocks, err := is.GetRecentBlocks(ctx, "ethereum", 0)
+	must(t, "GetRecentBlocks", err)
+	if len(blocks) != 1 || blocks[0].Number != 10 {
+		t.Errorf("GetRecentBlocks after Rollback = %+v, want block 10", blocks)
+	}
+
+	checkpoints, err := is.GetCheckpoints(ctx, "ethereum")
+	must(t, "GetCheckpoints", err)
+	if checkpointList(checkpoints) != "a@19 b@15" {
+		t.Errorf("GetCheckpoints after Rollback = [%s], want [a@19 b@15]", checkpointList(checkpoints))
+	}
+
+	letters, err := is.GetDeadLetters(ctx, "ethereum", "")
+	must(t, "GetDeadLetters", err)
+	if len(letters) != 1 || letters[0].BlockNumber != 10 {
+		t.Errorf("GetDeadLetters after Rollback = %+v, want the one before the fork", letters)
+	}
+
+	offsets, err := is.GetSinkOffsets(ctx, "ethereum")
+	must(t, "GetSinkOffsets", err)
+	if len(offsets) != 2 || offsets["webhook"] != 19 || offsets["kafka"] != 10 {
+		t.Errorf("GetSinkOffsets after Rollback = %v, want webhook at 19 and kafka at 10", offsets)
+	}
+
+	// The other chain is untouched
+	offsets, err = is.GetSinkOffsets(ctx, "arbitrum")
+	must(t, "GetSinkOffsets", err)
+	if offsets["webhook"] != 30 {
+		t.Errorf("GetSinkOffsets(arbitrum) after Rollback = %v, want webhook at 30", offsets)
+	}
+	checkpoints, err = is.GetCheckpoints(ctx, "arbitrum")
+	must(t, "GetCheckpoints", err)
+	if checkpointList(checkpoints) != "a@30 b@15" {
+		t.Errorf("GetCheckpoints(arbitrum) after Rollback = [%s], want [a@30 b@15]", checkpointList(checkpoints))
+	}
+}
+
+func testFinalizeEvents(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	is := indexerStorage(t, s)
+
+	must(t, "SaveEvents", is.SaveEvents(ctx, []*models.Event{
+		event("ethereum", "dex", "Swap", 10, 0, 1),
+		event("ethereum", "dex", "Sync", 20, 0, 1),
+		event("ethereum", "lend", "Supply", 30, 0, 2),
+		event("arbitrum", "dex", "Swap", 10, 0, 1),
+	}))
+	must(t, "FinalizeEvents", is.FinalizeEvents(ctx, "ethereum", 20))
+
+	events, err := is.GetEvents(ctx, storage.EventFilter{})
+	must(t, "GetEvents", err)
+	for _, saved := range events {
+		want := models.FinalityTentative
+		if saved.Chain == "ethereum" && saved.BlockNumber <= 20 {
+			want = models.FinalityFinal
+		}
+		if saved.Status != want {
+			t.Errorf("status of %s/%d after FinalizeEvents = %s, want %s", saved.Chain, saved.BlockNumber, saved.Status, want)
+		}
+	}
+
+	final, err := is.GetEvents(ctx, storage.EventFilter{Status: models.FinalityFinal})
+	must(t, "GetEvents", err)
+	if positions(final) != "ethereum/10/0 ethereum/20/0" {
+		t.Errorf("GetEvents(final) = [%s], want [ethereum/10/0 ethereum/20/0]", positions(final))
+	}
+
+	count, err := is.CountEvents(ctx, storage.EventFilter{Chain: "ethereum", Protocol: "dex", Limit: 1})
+	must(t, "CountEvents", err)
+	if count != 2 {
+		t.Errorf("CountEvents(ethereum dex) = %d, want 2 whatever the limit", count)
+	}
+
+	deleted, err := is.DeleteEvents(ctx, storage.EventFilter{Chain: "ethereum", ToBlock: 20})
+	must(t, "DeleteEvents", err)
+	if deleted != 2 {
+		t.Errorf("DeleteEvents(ethereum to 20) = %d, want 2", deleted)
+	}
+	events, err = is.GetEvents(ctx, storage.EventFilter{})
+	must(t, "GetEvents", err)
+	if positions(events) != "arbitrum/10/0 ethereum/30/0" {
+		t.Errorf("GetEvents after DeleteEvents = [%s], want [arbitrum/10/0 ethereum/30/0]", positions(events))
+	}
+}
+
+func testCheckpoints(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	is := indexerStorage(t, s)
+
+	checkpoints, err := is.GetCheckpoints(ctx, "ethereum")
+	must(t, "GetCheckpoints", err)
+	if len(checkpoints) != 0 {
+		t.Errorf("GetCheckpoints of an unknown chain = [%s], want none", checkpointList(checkpoints))
+	}
+
+	must(t, "SaveCheckpoints", is.SaveCheckpoints(ctx, []*models.Checkpoint{
+		{Chain: "ethereum", Contract: "b", Protocol: "dex", Block: 50},
+		{Chain: "ethereum", Contract: "a", Protocol: "dex", Block: 40},
+		{Chain: "ethereum", Contract: "", Block: 60},
+		{Chain: "arbitrum", Contract: "a", Block: 5},
+	}))
+
+	// Checkpoints never move backwards
+	must(t, "SaveCheckpoints", is.SaveCheckpoints(ctx, []*models.Checkpoint{
+		{Chain: "ethereum", Contract: "b", Protocol: "dex", Block: 45},
+		{Chain: "ethereum", Contract: "a", Protocol: "dex", Block: 70},
+	}))
+
+	checkpoints, err = is.GetCheckpoints(ctx, "ethereum")
+	must(t, "GetCheckpoints", err)
+	if checkpointList(checkpoints) != "@60 a@70 b@50" {
+		t.Errorf("GetCheckpoints = [%s], want [@60 a@70 b@50]", checkpointList(checkpoints))
+	}
+
+	// The watermark is the lowest checkpoint
+	last, err := is.GetLastIndexedBlock(ctx, "ethereum")
+	must(t, "GetLastIndexedBlock", err)
+	if last != 50 {
+		t.Errorf("GetLastIndexedBlock with checkpoints = %d, want 50", last)
+	}
+}
+
+func testFailedRanges(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	is := indexerStorage(t, s)
+
+	later := &models.FailedRange{Chain: "ethereum", Contracts: []string{"a", "b"}, FromBlock: 100, ToBlock: 199, LastError: "timeout"}
+	earlier := &models.FailedRange{Chain: "ethereum", Contracts: []string{""}, FromBlock: 1, ToBlock: 99, LastError: "timeout"}
+	other := &models.FailedRange{Chain: "arbitrum", Contracts: []string{"a"}, FromBlock: 1, ToBlock: 99, LastError: "timeout"}
+	for _, failed := range []*models.FailedRange{later, earlier, other} {
+		must(t, "SaveFailedRange", is.SaveFailedRange(ctx, failed))
+		if failed.ID == 0 || failed.Attempts != 1 {
+			t.Errorf("SaveFailedRange set ID %d and %d attempts, want an ID and 1 attempt", failed.ID, failed.Attempts)
+		}
+	}
+
+	// Failing the same range again counts an attempt
+	again := &models.FailedRange{Chain: "ethereum", Contracts: []string{"a", "b"}, FromBlock: 100, ToBlock: 199, LastError: "reverted"}
+	must(t, "SaveFailedRange", is.SaveFailedRange(ctx, again))
+	if again.ID != later.ID || again.Attempts != 2 {
+		t.Errorf("SaveFailedRange again set ID %d and %d attempts, want ID %d and 2 attempts", again.ID, again.Attempts, later.ID)
+	}
+
+	ranges, err := is.GetFailedRanges(ctx, "ethereum")
+	must(t, "GetFailedRanges", err)
+	if len(ranges) != 2 || ranges[0].ID != earlier.ID || ranges[1].ID != later.ID {
+		t.Fatalf("GetFailedRanges = %+v, want the two ethereum ranges by first block", ranges)
+	}
+	if got := ranges[1]; len(got.Contracts) != 2 || got.Contracts[1] != "b" || got.Attempts != 2 || got.LastError != "reverted" {
+		t.Errorf("GetFailedRanges returned %+v, want contracts a and b, 2 attempts and the last error", got)
+	}
+
+	must(t, "DeleteFailedRange", is.DeleteFailedRange(ctx, earlier.ID))
+	ranges, err = is.GetFailedRanges(ctx, "ethereum")
+	must(t, "GetFailedRanges", err)
+	if len(ranges) != 1 || ranges[0].ID != later.ID {
+		t.Errorf("GetFailedRanges after DeleteFailedRange = %+v, want the later range", ranges)
+	}
+}
+
+func testDeadLetters(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	is := indexerStorage(t, s)
+
+	letters := []*models.DeadLetter{
+		deadLetter("ethereum", "dex", 20, 0),
+		deadLetter("ethereum", "lend", 10, 3),
+		deadLetter("arbitrum", "dex", 5, 0),
+	}
+	must(t, "SaveDeadLetters", is.SaveDeadLetters(ctx, letters))
+
+	// The same log of the same protocol counts another attempt
+	again := deadLetter("ethereum", "dex", 20, 0)
+	again.Error = "still failing"
+	must(t, "SaveDeadLetters", is.SaveDeadLetters(ctx, []*models.DeadLetter{again}))
+	if again.ID != letters[0].ID || again.Attempts != 2 {
+		t.Errorf("SaveDeadLetters again set ID %d and %d attempts, want ID %d and 2 attempts", again.ID, again.Attempts, letters[0].ID)
+	}
+
+	tests := []struct {
+		chain, protocol string
+		want            string
+	}{
+		{"", "", "arbitrum/5 ethereum/10 ethereum/20"},
+		{"ethereum", "", "ethereum/10 ethereum/20"},
+		{"", "dex", "arbitrum/5 ethereum/20"},
+		{"optimism", "", ""},
+	}
+	for _, test := range tests {
+		got, err := is.GetDeadLetters(ctx, test.chain, test.protocol)
+		must(t, "GetDeadLetters", err)
+
+		var list []string
+		for _, letter := range got {
+			list = append(list, fmt.Sprintf("%s/%d", letter.Chain, letter.BlockNumber))
+		}
+		if strings.Join(list, " ") != test.want {
+			t.Errorf("GetDeadLetters(%q, %q) = [%s], want [%s]", test.chain, test.protocol, strings.Join(list, " "), test.want)
+		}
+
+		count, err := is.CountDeadLetters(ctx, test.chain, test.protocol)
+		must(t, "CountDeadLetters", err)
+		if count != uint64(len(list)) {
+			t.Errorf("CountDeadLetters(%q, %q) = %d, want %d", test.chain, test.protocol, count, len(list))
+		}
+	}
+
+	got, err := is.GetDeadLetters(ctx, "ethereum", "dex")
+	must(t, "GetDeadLetters", err)
+	if len(got) != 1 || got[0].Attempts != 2 || got[0].Error != "still failing" || got[0].Address != address(1) ||
+		len(got[0].Topics) != 1 || got[0].Data != "0x" {
+		t.Errorf("GetDeadLetters(ethereum, dex) = %+v, want the saved log with 2 attempts", got)
+	}
+}
+
+func testSinkOffsets(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	is := indexerStorage(t, s)
+
+	offsets, err := is.GetSinkOffsets(ctx, "ethereum")
+	must(t, "GetSinkOffsets", err)
+	if len(offsets) != 0 {
+		t.Errorf("GetSinkOffsets of an unknown chain = %v, want none", offsets)
+	}
+
+	must(t, "SaveSinkOffset", is.SaveSinkOffset(ctx, "webhook", "ethereum", 100))
+	must(t, "SaveSinkOffset", is.SaveSinkOffset(ctx, "kafka", "ethereum", 90))
+	must(t, "SaveSinkOffset", is.SaveSinkOffset(ctx, "webhook", "arbitrum", 7))
+	must(t, "SaveSinkOffset", is.SaveSinkOffset(ctx, "webhook", "ethereum", 120))
+
+	offsets, err = is.GetSinkOffsets(ctx, "ethereum")
+	must(t, "GetSinkOffsets", err)
+	if len(offsets) != 2 || offsets["webhook"] != 120 || offsets["kafka"] != 90 {
+		t.Errorf("GetSinkOffsets = %v, want webhook at 120 and kafka at 90", offsets)
+	}
+}
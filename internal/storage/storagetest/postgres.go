@@ -0,0 +1,48 @@
+// internal/storage/storagetest/postgres.go
+package storagetest
+
+import (
+	"os"
+	"testing"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage/postgres"
+)
+
+// PostgresURLEnv names the database Postgres runs the suite against. It is
+// emptied before every test, so never point it at a database you use.
+const PostgresURLEnv = "TEST_DATABASE_URL"
+
+// postgresTables are the tables the suite empties, seeded chains and tokens
+// included
+const postgresTables = `protocols, contracts, tvl_snapshots, tvl_rollups, chains, chain_stats, events,
+    indexed_blocks, indexer_checkpoints, indexer_failed_ranges, tokens, token_prices,
+    indexer_dead_letters, indexer_sink_offsets`
+
+// Postgres is an Opener for the PostgreSQL database at TEST_DATABASE_URL,
+// such as the one of docker-compose. Tests are skipped when it is not set.
+func Postgres(t *testing.T) storage.Storage {
+	url := os.Getenv(PostgresURLEnv)
+	if url == "" {
+		t.Skipf("%s is not set", PostgresURLEnv)
+	}
+
+	db, err := postgres.Connect(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Migrate before emptying, a fresh database has no tables yet
+	s, err := postgres.NewPostgresStorage(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Exec("TRUNCATE " + postgresTables + " RESTART IDENTITY CASCADE"); err != nil {
+		s.Close()
+		t.Fatalf("failed to empty the test database: %v", err)
+	}
+
+	return s
+}
@@ -0,0 +1,98 @@
+// internal/storage/storagetest/protocols.go
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+func testProtocols(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	if _, err := s.GetProtocol(ctx, "missing"); err == nil {
+		t.Error("GetProtocol of a missing protocol: want an error")
+	}
+
+	for i, name := range []string{"zeta", "alpha", "mid"} {
+		protocol := &models.Protocol{
+			Name: name,
+			Type: models.ProtocolTypeDEX,
+			Chains: map[string][]models.ContractConfig{
+				"ethereum": {{Address: address(int64(i)), Name: name + "-pool", Type: "uniswap-v2", DeployBlock: 100}},
+			},
+		}
+		must(t, "SaveProtocol", s.SaveProtocol(ctx, protocol))
+		if protocol.ID == 0 {
+			t.Errorf("SaveProtocol(%s) did not set the ID", name)
+		}
+	}
+
+	if err := s.SaveProtocol(ctx, &models.Protocol{Name: "alpha", Type: models.ProtocolTypeDEX}); err == nil {
+		t.Error("SaveProtocol of an existing name: want an error")
+	}
+
+	protocols, err := s.GetProtocols(ctx)
+	must(t, "GetProtocols", err)
+	var names []string
+	for _, protocol := range protocols {
+		names = append(names, protocol.Name)
+	}
+	if len(names) != 3 || names[0] != "alpha" || names[1] != "mid" || names[2] != "zeta" {
+		t.Errorf("GetProtocols = %v, want [alpha mid zeta]", names)
+	}
+
+	alpha, err := s.GetProtocol(ctx, "alpha")
+	must(t, "GetProtocol", err)
+	contracts := alpha.Chains["ethereum"]
+	if len(contracts) != 1 || contracts[0].Address != address(1) || contracts[0].DeployBlock != 100 {
+		t.Errorf("GetProtocol(alpha) contracts = %+v, want the saved pool", alpha.Chains)
+	}
+
+	updated := &models.Protocol{
+		Name:    "alpha",
+		Type:    models.ProtocolTypeLending,
+		Adapter: "aave-v3",
+		Chains: map[string][]models.ContractConfig{
+			"arbitrum": {{Address: address(10), Name: "market", Adapter: "compound-v3"}},
+		},
+	}
+	must(t, "UpdateProtocol", s.UpdateProtocol(ctx, updated))
+	if updated.ID != alpha.ID {
+		t.Errorf("UpdateProtocol changed the ID from %d to %d", alpha.ID, updated.ID)
+	}
+
+	alpha, err = s.GetProtocol(ctx, "alpha")
+	must(t, "GetProtocol", err)
+	if alpha.Type != models.ProtocolTypeLending {
+		t.Errorf("updated type = %s, want %s", alpha.Type, models.ProtocolTypeLending)
+	}
+	if alpha.Adapter != "aave-v3" {
+		t.Errorf("updated adapter = %q, want aave-v3", alpha.Adapter)
+	}
+	if len(alpha.Chains["ethereum"]) != 0 || len(alpha.Chains["arbitrum"]) != 1 {
+		t.Errorf("updated contracts = %+v, want only the arbitrum market", alpha.Chains)
+	} else if adapter := alpha.Chains["arbitrum"][0].Adapter; adapter != "compound-v3" {
+		t.Errorf("updated contract adapter = %q, want compound-v3", adapter)
+	}
+
+	if err := s.UpdateProtocol(ctx, &models.Protocol{Name: "missing"}); err == nil {
+		t.Error("UpdateProtocol of a missing protocol: want an error")
+	}
+
+	must(t, "DeleteProtocol", s.DeleteProtocol(ctx, "alpha"))
+	if _, err := s.GetProtocol(ctx, "alpha"); err == nil {
+		t.Error("GetProtocol of a deleted protocol: want an error")
+	}
+	if err := s.DeleteProtocol(ctx, "alpha"); err == nil {
+		t.Error("DeleteProtocol of a missing protocol: want an error")
+	}
+
+	protocols, err = s.GetProtocols(ctx)
+	must(t, "GetProtocols", err)
+	if len(protocols) != 2 {
+		t.Errorf("GetProtocols after a delete returned %d protocols, want 2", len(protocols))
+	}
+}
@@ -0,0 +1,122 @@
+// internal/storage/storagetest/storagetest.go
+
+// Package storagetest is a conformance suite for storage.Storage
+// implementations, so every backend sorts, filters, reports missing records
+// and handles transactions the same way. The indexer's storage is tested too
+// where a backend implements storage.IndexerStorage. A backend runs it from
+// a test:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.Storage {
+//			return memory.NewMemoryStorage()
+//		})
+//	}
+//
+// PostgreSQL runs it with storagetest.Postgres as the opener.
+package storagetest
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+// Opener returns an empty storage for one test of the suite
+type Opener func(t *testing.T) storage.Storage
+
+// epoch is the time the suite's records start at. Times are whole seconds
+// in UTC, so every backend stores them exactly.
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Run runs the conformance suite. Tests run one after another, each on a
+// storage from open that is closed when it ends.
+func Run(t *testing.T, open Opener) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s storage.Storage)
+	}{
+		{"Protocols", testProtocols},
+		{"TVLSnapshots", testTVLSnapshots},
+		{"AggregatedTVL", testAggregatedTVL},
+		{"TVLHistory", testTVLHistory},
+		{"HistoryFallback", testHistoryFallback},
+		{"FinalizeTVL", testFinalizeTVL},
+		{"RollbackTVL", testRollbackTVL},
+		{"TokenHoldings", testTokenHoldings},
+		{"ImportedSnapshots", testImportedSnapshots},
+		{"Chains", testChains},
+		{"Events", testEvents},
+		{"Blocks", testBlocks},
+		{"Tokens", testTokens},
+		{"CommitBatch", testCommitBatch},
+		{"Rollback", testRollback},
+		{"FinalizeEvents", testFinalizeEvents},
+		{"Checkpoints", testCheckpoints},
+		{"FailedRanges", testFailedRanges},
+		{"DeadLetters", testDeadLetters},
+		{"SinkOffsets", testSinkOffsets},
+		{"ConcurrentWrites", testConcurrentWrites},
+		{"TxCommit", testTxCommit},
+		{"TxRollback", testTxRollback},
+		{"TxNested", testTxNested},
+		{"TxOutsideWrites", testTxOutsideWrites},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			s := open(t)
+			defer func() {
+				if err := s.Close(); err != nil {
+					t.Errorf("Close: %v", err)
+				}
+			}()
+
+			test.run(t, s)
+		})
+	}
+}
+
+// at returns the time minutes after epoch
+func at(minutes int) time.Time {
+	return epoch.Add(time.Duration(minutes) * time.Minute)
+}
+
+// address returns a distinct address for n
+func address(n int64) common.Address {
+	return common.BigToAddress(big.NewInt(0xa000 + n))
+}
+
+// usd parses a dollar amount
+func usd(amount string) *big.Float {
+	value, ok := new(big.Float).SetPrec(128).SetString(amount)
+	if !ok {
+		panic("storagetest: invalid amount " + amount)
+	}
+	return value
+}
+
+// checkUSD compares a dollar amount to the cents
+func checkUSD(t *testing.T, what string, got *big.Float, want string) {
+	t.Helper()
+
+	if got == nil {
+		t.Errorf("%s = nil, want %s", what, want)
+		return
+	}
+	if got.Text('f', 2) != usd(want).Text('f', 2) {
+		t.Errorf("%s = %s, want %s", what, got.Text('f', 2), want)
+	}
+}
+
+// must fails the test on an error
+func must(t *testing.T, what string, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+}
@@ -0,0 +1,104 @@
+// internal/storage/storagetest/tokens.go
+package storagetest
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+func testTokens(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	usdc, weth := address(100), address(101)
+
+	if _, err := s.GetToken(ctx, usdc.Hex(), "ethereum"); err == nil {
+		t.Error("GetToken of a missing token: want an error")
+	}
+	if err := s.UpdateTokenPrice(ctx, &models.TokenPrice{Address: usdc, PriceUSD: usd("1")}); err == nil {
+		t.Error("UpdateTokenPrice of a missing token: want an error")
+	}
+
+	token := &models.Token{
+		Address:     usdc,
+		Chain:       "ethereum",
+		Symbol:      "USDC",
+		Name:        "USD Coin",
+		Decimals:    6,
+		TotalSupply: big.NewInt(1_000_000),
+	}
+	must(t, "SaveToken", s.SaveToken(ctx, token))
+	if token.ID == 0 {
+		t.Error("SaveToken did not set the ID")
+	}
+	must(t, "SaveToken", s.SaveToken(ctx, &models.Token{Address: usdc, Chain: "arbitrum", Symbol: "USDC", Decimals: 6}))
+	must(t, "SaveToken", s.SaveToken(ctx, &models.Token{Address: weth, Chain: "ethereum", Symbol: "WETH", Decimals: 18}))
+
+	// Addresses match in any case
+	got, err := s.GetToken(ctx, strings.ToLower(usdc.Hex()), "ethereum")
+	must(t, "GetToken", err)
+	if got.ID != token.ID || got.Symbol != "USDC" || got.Decimals != 6 || got.TotalSupply == nil || got.TotalSupply.Int64() != 1_000_000 {
+		t.Errorf("GetToken = %+v, want the saved USDC", got)
+	}
+	if got.PriceUSD != nil {
+		t.Errorf("GetToken price = %s before any price, want nil", got.PriceUSD.Text('f', 2))
+	}
+	if _, err := s.GetToken(ctx, usdc.Hex(), "optimism"); err == nil {
+		t.Error("GetToken on another chain: want an error")
+	}
+
+	// Saving the same chain and address replaces the token
+	renamed := &models.Token{Address: usdc, Chain: "ethereum", Symbol: "USDC.e", Decimals: 6}
+	must(t, "SaveToken", s.SaveToken(ctx, renamed))
+	if renamed.ID != token.ID {
+		t.Errorf("saving USDC again got ID %d, want %d", renamed.ID, token.ID)
+	}
+
+	// The latest price is by timestamp, not by the order of the updates
+	must(t, "UpdateTokenPrice", s.UpdateTokenPrice(ctx, &models.TokenPrice{
+		TokenID: token.ID, PriceUSD: usd("1.01"), Source: "test", Confidence: 1, Timestamp: at(20),
+	}))
+	must(t, "UpdateTokenPrice", s.UpdateTokenPrice(ctx, &models.TokenPrice{
+		TokenID: token.ID, PriceUSD: usd("0.99"), Source: "test", Confidence: 1, Timestamp: at(10),
+	}))
+	must(t, "UpdateTokenPrice", s.UpdateTokenPrice(ctx, &models.TokenPrice{
+		Address: weth, PriceUSD: usd("3000.5"), Source: "test", Confidence: 1, Timestamp: at(10),
+	}))
+
+	got, err = s.GetToken(ctx, usdc.Hex(), "ethereum")
+	must(t, "GetToken", err)
+	if got.Symbol != "USDC.e" {
+		t.Errorf("GetToken symbol = %q, want USDC.e", got.Symbol)
+	}
+	checkUSD(t, "USDC price", got.PriceUSD, "1.01")
+
+	missing := address(102).Hex()
+	lowerWETH := strings.ToLower(weth.Hex())
+	prices, err := s.GetTokenPrices(ctx, []string{usdc.Hex(), lowerWETH, missing})
+	must(t, "GetTokenPrices", err)
+	if len(prices) != 2 {
+		t.Errorf("GetTokenPrices returned %d prices, want 2", len(prices))
+	}
+	if price := prices[usdc.Hex()]; price == nil {
+		t.Error("GetTokenPrices is missing USDC")
+	} else {
+		checkUSD(t, "USDC price", price.PriceUSD, "1.01")
+		if price.Address != usdc || !price.Timestamp.Equal(at(20)) {
+			t.Errorf("USDC price = %+v, want the update at minute 20", price)
+		}
+	}
+	if price := prices[lowerWETH]; price == nil {
+		t.Error("GetTokenPrices is missing WETH, keyed by the address as given")
+	} else {
+		checkUSD(t, "WETH price", price.PriceUSD, "3000.5")
+	}
+
+	empty, err := s.GetTokenPrices(ctx, nil)
+	must(t, "GetTokenPrices", err)
+	if len(empty) != 0 {
+		t.Errorf("GetTokenPrices(nil) returned %d prices", len(empty))
+	}
+}
@@ -0,0 +1,191 @@
+// internal/storage/storagetest/tvl.go
+package storagetest
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+func snapshot(protocol, chain string, block uint64, minute int, total string) *models.TVLSnapshot {
+	return &models.TVLSnapshot{
+		Protocol:    protocol,
+		Chain:       chain,
+		BlockNumber: block,
+		TotalUSD:    usd(total),
+		Timestamp:   at(minute),
+	}
+}
+
+// totals lists the total of each snapshot
+func totals(snapshots []*models.TVLSnapshot) []string {
+	var list []string
+	for _, snapshot := range snapshots {
+		list = append(list, snapshot.TotalUSD.Text('f', 2))
+	}
+	return list
+}
+
+func sameTotals(got []string, want ...string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != usd(want[i]).Text('f', 2) {
+			return false
+		}
+	}
+	return true
+}
+
+func testTVLSnapshots(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	if _, err := s.GetLatestTVL(ctx, "dex", ""); err == nil {
+		t.Error("GetLatestTVL without snapshots: want an error")
+	}
+	if _, err := s.GetTVLByBlock(ctx, "dex", "ethereum", 100); err == nil {
+		t.Error("GetTVLByBlock without snapshots: want an error")
+	}
+
+	// Saved out of time order, latest is by time
+	arbitrum := snapshot("dex", "arbitrum", 500, 25, "500")
+	arbitrum.Breakdown = map[string]*models.AssetTVL{
+		"USDC": {
+			Token:    address(1),
+			Symbol:   "USDC",
+			Amount:   big.NewInt(500_000_000),
+			Decimals: 6,
+			PriceUSD: usd("1"),
+			ValueUSD: usd("500"),
+		},
+	}
+	replaced := snapshot("dex", "ethereum", 101, 20, "2000")
+	for _, snap := range []*models.TVLSnapshot{
+		snapshot("dex", "ethereum", 100, 10, "1000"),
+		snapshot("dex", "ethereum", 102, 30, "3000"),
+		replaced,
+		arbitrum,
+		snapshot("other", "ethereum", 100, 40, "9"),
+	} {
+		must(t, "SaveTVLSnapshot", s.SaveTVLSnapshot(ctx, snap))
+		if snap.ID == 0 {
+			t.Errorf("SaveTVLSnapshot(%s, %d) did not set the ID", snap.Chain, snap.BlockNumber)
+		}
+	}
+
+	latest, err := s.GetLatestTVL(ctx, "dex", "")
+	must(t, "GetLatestTVL", err)
+	checkUSD(t, "latest TVL", latest.TotalUSD, "3000")
+	if latest.Status != models.FinalityTentative {
+		t.Errorf("status = %q, want %q", latest.Status, models.FinalityTentative)
+	}
+
+	latest, err = s.GetLatestTVL(ctx, "dex", "arbitrum")
+	must(t, "GetLatestTVL", err)
+	checkUSD(t, "latest arbitrum TVL", latest.TotalUSD, "500")
+	asset := latest.Breakdown["USDC"]
+	if asset == nil || asset.Token != address(1) || asset.Amount == nil || asset.Amount.Int64() != 500_000_000 || asset.Decimals != 6 {
+		t.Fatalf("breakdown = %+v, want the saved USDC holding", latest.Breakdown)
+	}
+	checkUSD(t, "asset value", asset.ValueUSD, "500")
+
+	// Both ends of the range are included
+	history, err := s.GetHistoricalTVL(ctx, "dex", "", at(10), at(25))
+	must(t, "GetHistoricalTVL", err)
+	if got := totals(history); !sameTotals(got, "1000", "2000", "500") {
+		t.Errorf("GetHistoricalTVL(all chains) = %v, want [1000 2000 500]", got)
+	}
+
+	history, err = s.GetHistoricalTVL(ctx, "dex", "ethereum", at(0), at(60))
+	must(t, "GetHistoricalTVL", err)
+	if got := totals(history); !sameTotals(got, "1000", "2000", "3000") {
+		t.Errorf("GetHistoricalTVL(ethereum) = %v, want [1000 2000 3000]", got)
+	}
+
+	history, err = s.GetHistoricalTVL(ctx, "dex", "", at(100), at(200))
+	must(t, "GetHistoricalTVL", err)
+	if len(history) != 0 {
+		t.Errorf("GetHistoricalTVL of an empty range returned %d snapshots", len(history))
+	}
+
+	// Saving the same protocol, chain and block replaces the snapshot
+	again := snapshot("dex", "ethereum", 101, 20, "2500")
+	must(t, "SaveTVLSnapshot", s.SaveTVLSnapshot(ctx, again))
+	if again.ID != replaced.ID {
+		t.Errorf("saving block 101 again got ID %d, want %d", again.ID, replaced.ID)
+	}
+
+	byBlock, err := s.GetTVLByBlock(ctx, "dex", "ethereum", 101)
+	must(t, "GetTVLByBlock", err)
+	checkUSD(t, "TVL at block 101", byBlock.TotalUSD, "2500")
+
+	history, err = s.GetHistoricalTVL(ctx, "dex", "ethereum", at(0), at(60))
+	must(t, "GetHistoricalTVL", err)
+	if got := totals(history); !sameTotals(got, "1000", "2500", "3000") {
+		t.Errorf("GetHistoricalTVL after a replace = %v, want [1000 2500 3000]", got)
+	}
+
+	byBlock, err = s.GetTVLByBlock(ctx, "dex", "", 500)
+	must(t, "GetTVLByBlock", err)
+	if byBlock.Chain != "arbitrum" {
+		t.Errorf("GetTVLByBlock(any chain, 500) chain = %q, want arbitrum", byBlock.Chain)
+	}
+	if _, err := s.GetTVLByBlock(ctx, "dex", "ethereum", 500); err == nil {
+		t.Error("GetTVLByBlock of a block on another chain: want an error")
+	}
+}
+
+func testAggregatedTVL(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	aggregated, err := s.GetAggregatedTVL(ctx)
+	must(t, "GetAggregatedTVL", err)
+	checkUSD(t, "empty total", aggregated.TotalUSD, "0")
+	if len(aggregated.Protocols) != 0 {
+		t.Errorf("GetAggregatedTVL without snapshots has %d protocols", len(aggregated.Protocols))
+	}
+
+	for _, snap := range []*models.TVLSnapshot{
+		snapshot("dex", "ethereum", 100, 10, "1000"),
+		snapshot("dex", "ethereum", 101, 20, "1500"), // latest on ethereum
+		snapshot("dex", "arbitrum", 500, 15, "200"),
+		snapshot("dex", "", 0, 30, "99999"), // ignored next to per-chain snapshots
+		snapshot("lend", "ethereum", 100, 5, "300"),
+		snapshot("vault", "", 0, 10, "40"),
+		snapshot("vault", "", 1, 20, "50"), // counts, as vault has no chains
+	} {
+		must(t, "SaveTVLSnapshot", s.SaveTVLSnapshot(ctx, snap))
+	}
+
+	aggregated, err = s.GetAggregatedTVL(ctx)
+	must(t, "GetAggregatedTVL", err)
+
+	checkUSD(t, "total", aggregated.TotalUSD, "2050")
+	checkUSD(t, "ethereum total", aggregated.ChainTotals["ethereum"], "1800")
+	checkUSD(t, "arbitrum total", aggregated.ChainTotals["arbitrum"], "200")
+	if len(aggregated.ChainTotals) != 2 {
+		t.Errorf("chain totals = %v, want ethereum and arbitrum", aggregated.ChainTotals)
+	}
+
+	dex := aggregated.Protocols["dex"]
+	if dex == nil {
+		t.Fatal("GetAggregatedTVL is missing dex")
+	}
+	checkUSD(t, "dex total", dex.TotalUSD, "1700")
+	if ethereum := dex.Chains["ethereum"]; ethereum == nil || ethereum.BlockNumber != 101 {
+		t.Errorf("dex ethereum = %+v, want block 101", ethereum)
+	}
+	if !dex.Timestamp.Equal(at(20)) {
+		t.Errorf("dex timestamp = %v, want %v", dex.Timestamp, at(20))
+	}
+
+	if vault := aggregated.Protocols["vault"]; vault == nil {
+		t.Error("GetAggregatedTVL is missing vault")
+	} else {
+		checkUSD(t, "vault total", vault.TotalUSD, "50")
+	}
+}
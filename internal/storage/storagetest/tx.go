@@ -0,0 +1,218 @@
+// internal/storage/storagetest/tx.go
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zacksfF/evm-tvl-aggregator/internal/models"
+	"github.com/zacksfF/evm-tvl-aggregator/internal/storage"
+)
+
+func testConcurrentWrites(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	const writers, writes = 8, 25
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < writes; i++ {
+				block := uint64(w*writes + i + 1)
+				if err := s.SaveEvents(ctx, []*models.Event{event("ethereum", "dex", "Swap", block, 0, int64(w))}); err != nil {
+					errs <- fmt.Errorf("SaveEvents: %w", err)
+					return
+				}
+				if err := s.SaveTVLSnapshot(ctx, snapshot("dex", "ethereum", block, int(block), "1")); err != nil {
+					errs <- fmt.Errorf("SaveTVLSnapshot: %w", err)
+					return
+				}
+				if _, err := s.GetEvents(ctx, storage.EventFilter{Chain: "ethereum", Limit: 10}); err != nil {
+					errs <- fmt.Errorf("GetEvents: %w", err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	events, err := s.GetEvents(ctx, storage.EventFilter{Chain: "ethereum"})
+	must(t, "GetEvents", err)
+	if len(events) != writers*writes {
+		t.Errorf("GetEvents after concurrent writes returned %d events, want %d", len(events), writers*writes)
+	}
+
+	ids := make(map[uint64]bool)
+	for _, saved := range events {
+		if ids[saved.ID] {
+			t.Errorf("event ID %d was given twice", saved.ID)
+		}
+		ids[saved.ID] = true
+	}
+
+	history, err := s.GetHistoricalTVL(ctx, "dex", "ethereum", at(0), at(writers*writes))
+	must(t, "GetHistoricalTVL", err)
+	if len(history) != writers*writes {
+		t.Errorf("GetHistoricalTVL after concurrent writes returned %d snapshots, want %d", len(history), writers*writes)
+	}
+}
+
+func testTxCommit(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	tx, err := s.BeginTx(ctx)
+	must(t, "BeginTx", err)
+
+	must(t, "SaveProtocol", tx.SaveProtocol(ctx, &models.Protocol{Name: "txp", Type: models.ProtocolTypeDEX}))
+	must(t, "SaveTVLSnapshot", tx.SaveTVLSnapshot(ctx, snapshot("txp", "ethereum", 100, 10, "42")))
+
+	// The transaction reads its own writes, nobody else does before Commit
+	if _, err := tx.GetProtocol(ctx, "txp"); err != nil {
+		t.Errorf("GetProtocol in the transaction: %v", err)
+	}
+	if _, err := s.GetProtocol(ctx, "txp"); err == nil {
+		t.Error("GetProtocol outside the transaction found an uncommitted protocol")
+	}
+	if _, err := s.GetLatestTVL(ctx, "txp", ""); err == nil {
+		t.Error("GetLatestTVL outside the transaction found an uncommitted snapshot")
+	}
+
+	must(t, "Commit", tx.Commit())
+
+	if _, err := s.GetProtocol(ctx, "txp"); err != nil {
+		t.Errorf("GetProtocol after Commit: %v", err)
+	}
+	latest, err := s.GetLatestTVL(ctx, "txp", "")
+	must(t, "GetLatestTVL after Commit", err)
+	checkUSD(t, "committed TVL", latest.TotalUSD, "42")
+
+	if err := tx.Commit(); err == nil {
+		t.Error("Commit of a committed transaction: want an error")
+	}
+	if err := tx.Rollback(); err == nil {
+		t.Error("Rollback of a committed transaction: want an error")
+	}
+}
+
+func testTxRollback(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	tx, err := s.BeginTx(ctx)
+	must(t, "BeginTx", err)
+
+	must(t, "SaveProtocol", tx.SaveProtocol(ctx, &models.Protocol{Name: "txp", Type: models.ProtocolTypeDEX}))
+	must(t, "SaveTVLSnapshot", tx.SaveTVLSnapshot(ctx, snapshot("txp", "ethereum", 100, 10, "42")))
+	must(t, "SaveEvents", tx.SaveEvents(ctx, []*models.Event{event("ethereum", "txp", "Swap", 100, 0, 1)}))
+	must(t, "SaveBlock", tx.SaveBlock(ctx, &models.Block{Chain: "ethereum", Number: 100, Timestamp: at(100)}))
+
+	must(t, "Rollback", tx.Rollback())
+
+	if _, err := s.GetProtocol(ctx, "txp"); err == nil {
+		t.Error("GetProtocol found a rolled back protocol")
+	}
+	if _, err := s.GetLatestTVL(ctx, "txp", ""); err == nil {
+		t.Error("GetLatestTVL found a rolled back snapshot")
+	}
+	events, err := s.GetEvents(ctx, storage.EventFilter{})
+	must(t, "GetEvents", err)
+	if len(events) != 0 {
+		t.Errorf("GetEvents found %d rolled back events", len(events))
+	}
+	last, err := s.GetLastIndexedBlock(ctx, "ethereum")
+	must(t, "GetLastIndexedBlock", err)
+	if last != 0 {
+		t.Errorf("GetLastIndexedBlock = %d after a rolled back block, want 0", last)
+	}
+
+	if err := tx.SaveProtocol(ctx, &models.Protocol{Name: "late", Type: models.ProtocolTypeDEX}); err == nil {
+		t.Error("SaveProtocol in a rolled back transaction: want an error")
+	}
+	if err := tx.Rollback(); err == nil {
+		t.Error("Rollback of a rolled back transaction: want an error")
+	}
+}
+
+func testTxNested(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	tx, err := s.BeginTx(ctx)
+	must(t, "BeginTx", err)
+	must(t, "SaveProtocol", tx.SaveProtocol(ctx, &models.Protocol{Name: "outer", Type: models.ProtocolTypeDEX}))
+
+	// A nested transaction that rolls back leaves the outer one's writes
+	discarded, err := tx.BeginTx(ctx)
+	must(t, "nested BeginTx", err)
+	must(t, "SaveProtocol", discarded.SaveProtocol(ctx, &models.Protocol{Name: "discarded", Type: models.ProtocolTypeDEX}))
+	must(t, "nested Rollback", discarded.Rollback())
+
+	if _, err := tx.GetProtocol(ctx, "outer"); err != nil {
+		t.Errorf("GetProtocol(outer) after a nested rollback: %v", err)
+	}
+	if _, err := tx.GetProtocol(ctx, "discarded"); err == nil {
+		t.Error("GetProtocol found the protocol of a rolled back nested transaction")
+	}
+
+	kept, err := tx.BeginTx(ctx)
+	must(t, "nested BeginTx", err)
+	must(t, "SaveProtocol", kept.SaveProtocol(ctx, &models.Protocol{Name: "kept", Type: models.ProtocolTypeDEX}))
+	must(t, "nested Commit", kept.Commit())
+
+	// A nested commit only lands with the outer transaction
+	if _, err := s.GetProtocol(ctx, "kept"); err == nil {
+		t.Error("GetProtocol outside found a nested commit before the outer Commit")
+	}
+
+	must(t, "Commit", tx.Commit())
+
+	protocols, err := s.GetProtocols(ctx)
+	must(t, "GetProtocols", err)
+	var names []string
+	for _, protocol := range protocols {
+		names = append(names, protocol.Name)
+	}
+	if len(names) != 2 || names[0] != "kept" || names[1] != "outer" {
+		t.Errorf("GetProtocols after the outer Commit = %v, want [kept outer]", names)
+	}
+}
+
+func testTxOutsideWrites(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	usdc := address(100)
+
+	tx, err := s.BeginTx(ctx)
+	must(t, "BeginTx", err)
+	must(t, "SaveProtocol", tx.SaveProtocol(ctx, &models.Protocol{Name: "txp", Type: models.ProtocolTypeDEX}))
+
+	// A write outside the transaction to data it didn't touch either lands
+	// at once or waits for it to end, but never makes it fail
+	saved := make(chan error, 1)
+	go func() {
+		saved <- s.SaveToken(ctx, &models.Token{Address: usdc, Chain: "ethereum", Symbol: "USDC", Decimals: 6})
+	}()
+	select {
+	case err := <-saved:
+		must(t, "SaveToken during the transaction", err)
+		saved <- nil
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	must(t, "Commit", tx.Commit())
+	must(t, "SaveToken", <-saved)
+
+	if _, err := s.GetProtocol(ctx, "txp"); err != nil {
+		t.Errorf("GetProtocol after Commit: %v", err)
+	}
+	if _, err := s.GetToken(ctx, usdc.Hex(), "ethereum"); err != nil {
+		t.Errorf("GetToken of the token saved during the transaction: %v", err)
+	}
+}
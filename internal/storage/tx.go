@@ -0,0 +1,19 @@
+// internal/storage/tx.go
+package storage
+
+import "context"
+
+// InTx runs fn in a transaction of s, committing it when fn succeeds and
+// rolling it back otherwise
+func InTx(ctx context.Context, s Storage, fn func(tx Tx) error) error {
+	tx, err := s.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -143,6 +143,21 @@ func (c *APIClient) GetStats() (*models.StatsResponse, error) {
 	return &stats, nil
 }
 
+// GetIndexerStatus retrieves the indexing progress and errors
+func (c *APIClient) GetIndexerStatus() (*models.IndexerStatusResponse, error) {
+	data, err := c.get("/api/v1/indexer")
+	if err != nil {
+		return nil, err
+	}
+
+	var status models.IndexerStatusResponse
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal indexer status response: %w", err)
+	}
+
+	return &status, nil
+}
+
 // GetHistoricalTVL retrieves historical TVL data for a protocol
 func (c *APIClient) GetHistoricalTVL(protocol, chain, period string) (*models.HistoricalTVLResponse, error) {
 	endpoint := fmt.Sprintf("/api/v1/tvl/%s/history", protocol)
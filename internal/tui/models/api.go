@@ -74,6 +74,51 @@ type StatsResponse struct {
 	LastUpdated   time.Time `json:"last_updated"`
 }
 
+// IndexerStatusResponse represents the aggregated indexer status
+type IndexerStatusResponse struct {
+	Status     string                 `json:"status"`
+	Chains     []IndexerChainStatus   `json:"chains"`
+	Processors []IndexerProcessorStat `json:"processors"`
+	Totals     IndexerTotals          `json:"totals"`
+	Errors     map[string]string      `json:"errors"`
+	Timestamp  time.Time              `json:"timestamp"`
+}
+
+// IndexerChainStatus represents the indexing progress of a chain
+type IndexerChainStatus struct {
+	Chain            string    `json:"chain"`
+	LastIndexedBlock uint64    `json:"last_indexed_block"`
+	CurrentBlock     uint64    `json:"current_block"`
+	Lag              uint64    `json:"lag"`
+	EventsCount      uint64    `json:"events_count"`
+	EventsPerSecond  float64   `json:"events_per_second"`
+	DeadLetters      int       `json:"dead_letters"`
+	FailedRanges     int       `json:"failed_ranges"`
+	IndexingProgress float64   `json:"indexing_progress"`
+	LastUpdateTime   time.Time `json:"last_update_time"`
+}
+
+// IndexerProcessorStat represents the indexing progress of a processor
+type IndexerProcessorStat struct {
+	Protocol         string  `json:"protocol"`
+	Chain            string  `json:"chain"`
+	LastIndexedBlock uint64  `json:"last_indexed_block"`
+	Lag              uint64  `json:"lag"`
+	EventsCount      uint64  `json:"events_count"`
+	EventsPerSecond  float64 `json:"events_per_second"`
+	DecodeErrors     uint64  `json:"decode_errors"`
+}
+
+// IndexerTotals represents indexer totals across chains
+type IndexerTotals struct {
+	EventsPerSecond float64 `json:"events_per_second"`
+	EventsCount     uint64  `json:"events_count"`
+	MaxLag          uint64  `json:"max_lag"`
+	Errors          uint64  `json:"errors"`
+	DeadLetters     int     `json:"dead_letters"`
+	FailedRanges    int     `json:"failed_ranges"`
+}
+
 // HistoricalTVLResponse represents historical TVL data
 type HistoricalTVLResponse struct {
 	Protocol string              `json:"protocol"`
@@ -86,6 +131,7 @@ type HistoricalTVLResponse struct {
 type HistoricalTVLData struct {
 	Timestamp time.Time `json:"timestamp"`
 	TVL       float64   `json:"tvl"`
+	Status    string    `json:"status,omitempty"`
 }
 
 // UIState represents the current state of the UI
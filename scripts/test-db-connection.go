@@ -86,7 +86,7 @@ func main() {
 			fmt.Printf("  Protocols configured: %d\n", protocolCount)
 		}
 	} else {
-		fmt.Println(" No tables found. Run migrations to set up the schema.")
+		fmt.Println(" No tables found. Run `make migrate-up` to set up the schema.")
 	}
 
 	fmt.Println("\n Database test completed successfully!")